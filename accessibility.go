@@ -0,0 +1,228 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// AnnouncementKind is the kind of change described by an Announcement.
+type AnnouncementKind int
+
+const (
+	// AnnounceText is for rows whose text has changed.  Text is the new
+	// text of those rows, one per line, and Row is the first of them.
+	AnnounceText AnnouncementKind = iota
+
+	// AnnounceCursor is for the cursor moving.  If it moved to another
+	// row, Text is the text of that row; if it moved along the row, Text
+	// is the word (or character) under it.  Row and Col are the position
+	// of the cursor.
+	AnnounceCursor
+)
+
+// Announcement is a change to the screen, described for a screen reader
+// or other speech tool.  See Screen.EnableAccessibility.
+type Announcement struct {
+	Kind AnnouncementKind
+	Row  int
+	Col  int
+	Text string
+}
+
+// accessibility keeps the logical text of the screen, as last shown, so
+// that changes to it can be announced.
+type accessibility struct {
+	fn     func(Announcement)
+	lines  []string // text of each row
+	cx, cy int      // cursor, -1 if hidden
+	l      sync.Mutex
+}
+
+// decorativeRune returns true for runes that draw lines, boxes, bars and
+// such, rather than text, and so are left out of the logical text.
+func decorativeRune(r rune) bool {
+	switch {
+	case r >= 0x2500 && r <= 0x259F: // box drawing and block elements
+		return true
+	case r >= 0x2800 && r <= 0x28FF: // braille patterns, used for plots
+		return true
+	case r >= 0x1FB00 && r <= 0x1FBFF: // legacy computing (sextants)
+		return true
+	}
+	return false
+}
+
+// accessibleRow returns the logical text of row y: the characters read
+// from left to right, without decorations, and with runs of spaces
+// collapsed to one.
+func accessibleRow(cells *CellBuffer, y int) string {
+	w, _ := cells.Size()
+	var sb strings.Builder
+	space := false
+	for x := 0; x < w; {
+		mainc, combc, _, width := cells.GetContent(x, y)
+		if width < 1 {
+			width = 1
+		}
+		x += width
+		if mainc == 0 || unicode.IsSpace(mainc) || decorativeRune(mainc) {
+			space = true
+			continue
+		}
+		if space && sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		space = false
+		sb.WriteRune(mainc)
+		for _, r := range combc {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// joinLines joins the lines that have text, leaving out lines that are
+// the same as the one before, so that repeated rows are only read once.
+func joinLines(lines []string) string {
+	var out []string
+	for _, line := range lines {
+		if line != "" && (len(out) == 0 || out[len(out)-1] != line) {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// wordAt returns the word of the row at column x, or the character there
+// if it is not part of a word.
+func wordAt(cells *CellBuffer, x, y int) string {
+	w, _ := cells.Size()
+	isWord := func(x int) bool {
+		r, _, _, _ := cells.GetContent(x, y)
+		return r != 0 && !IsWordSpace(r) && !decorativeRune(r)
+	}
+	if x < 0 || x >= w {
+		return ""
+	}
+	if !isWord(x) {
+		r, _, _, _ := cells.GetContent(x, y)
+		if r == 0 || r == ' ' || decorativeRune(r) {
+			return ""
+		}
+		return string(r)
+	}
+	x0, x1 := x, x
+	for x0 > 0 && isWord(x0-1) {
+		x0--
+	}
+	for x1 < w-1 && isWord(x1+1) {
+		x1++
+	}
+	var sb strings.Builder
+	for x := x0; x <= x1; x++ {
+		r, combc, _, _ := cells.GetContent(x, y)
+		sb.WriteRune(r)
+		for _, c := range combc {
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+func (b *baseScreen) EnableAccessibility(fn func(Announcement)) {
+	b.access.l.Lock()
+	b.access.fn = fn
+	// everything shown next is new
+	b.access.lines = nil
+	b.access.cx, b.access.cy = -1, -1
+	b.access.l.Unlock()
+}
+
+func (b *baseScreen) DisableAccessibility() {
+	b.access.l.Lock()
+	b.access.fn = nil
+	b.access.lines = nil
+	b.access.l.Unlock()
+}
+
+func (b *baseScreen) AccessibleText() string {
+	cells := b.GetCells()
+	b.Lock()
+	defer b.Unlock()
+	_, h := cells.Size()
+	lines := make([]string, h)
+	for y := range lines {
+		lines[y] = accessibleRow(cells, y)
+	}
+	return joinLines(lines)
+}
+
+// updateAccessibility works out the announcements for what is about to be
+// shown, compared with what was shown before.  They are made by the
+// returned function, which must be called after the screen lock is
+// released, as the callback may use the screen.
+func (b *baseScreen) updateAccessibility() func() {
+	b.access.l.Lock()
+	defer b.access.l.Unlock()
+	fn := b.access.fn
+	if fn == nil {
+		return func() {}
+	}
+	cells := b.GetCells()
+	b.Lock()
+	cx, cy, _, _ := b.getCursor()
+	_, h := cells.Size()
+	lines := make([]string, h)
+	for y := range lines {
+		lines[y] = accessibleRow(cells, y)
+	}
+
+	var anns []Announcement
+	var changed []string
+	first := -1
+	for y, line := range lines {
+		if y < len(b.access.lines) && b.access.lines[y] == line {
+			continue
+		}
+		if first < 0 {
+			first = y
+		}
+		changed = append(changed, line)
+	}
+	if text := joinLines(changed); text != "" {
+		anns = append(anns, Announcement{Kind: AnnounceText, Row: first, Text: text})
+	}
+	if cy >= 0 && cy < h && (cx != b.access.cx || cy != b.access.cy) {
+		ann := Announcement{Kind: AnnounceCursor, Row: cy, Col: cx}
+		if cy != b.access.cy {
+			ann.Text = lines[cy]
+		} else {
+			ann.Text = wordAt(cells, cx, cy)
+		}
+		anns = append(anns, ann)
+	}
+	b.Unlock()
+
+	b.access.lines = lines
+	b.access.cx, b.access.cy = cx, cy
+	return func() {
+		for _, ann := range anns {
+			fn(ann)
+		}
+	}
+}
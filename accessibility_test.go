@@ -0,0 +1,90 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAccessibility(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+	s.SetSize(20, 5)
+
+	var anns []Announcement
+	s.EnableAccessibility(func(a Announcement) {
+		anns = append(anns, a)
+	})
+	show := func() []Announcement {
+		anns = nil
+		s.Show()
+		return anns
+	}
+	put := func(x, y int, text string, style Style) {
+		for _, r := range text {
+			s.SetContent(x, y, r, nil, style)
+			x++
+		}
+	}
+
+	// a box, with a title and two identical rows
+	put(0, 0, "┌─ Files ──────────┐", StyleDefault)
+	put(0, 1, "│ a.txt    12 KB   │", StyleDefault)
+	put(0, 2, "│ a.txt    12 KB   │", StyleDefault)
+	put(0, 3, "└──────────────────┘", StyleDefault)
+	s.HideCursor()
+	expected := []Announcement{{Kind: AnnounceText, Text: "Files\na.txt 12 KB"}}
+	if got := show(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("first show: got %+v, expected %+v", got, expected)
+	}
+	if text := s.AccessibleText(); text != "Files\na.txt 12 KB" {
+		t.Errorf("wrong accessible text: %q", text)
+	}
+
+	// changes to styles and decorations are not announced
+	put(2, 1, "a.txt", StyleDefault.Reverse(true))
+	put(0, 3, "╘══════════════════╛", StyleDefault)
+	if got := show(); len(got) != 0 {
+		t.Errorf("decorative change announced: %+v", got)
+	}
+
+	put(2, 2, "b.txt", StyleDefault)
+	expected = []Announcement{{Kind: AnnounceText, Row: 2, Text: "b.txt 12 KB"}}
+	if got := show(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("changed row: got %+v, expected %+v", got, expected)
+	}
+
+	// the cursor moving to a row reads the row, and along it the word
+	s.ShowCursor(4, 1)
+	expected = []Announcement{{Kind: AnnounceCursor, Row: 1, Col: 4, Text: "a.txt 12 KB"}}
+	if got := show(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("cursor to row: got %+v, expected %+v", got, expected)
+	}
+	s.ShowCursor(11, 1)
+	expected = []Announcement{{Kind: AnnounceCursor, Row: 1, Col: 11, Text: "12"}}
+	if got := show(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("cursor along row: got %+v, expected %+v", got, expected)
+	}
+	if got := show(); len(got) != 0 {
+		t.Errorf("nothing changed, but announced: %+v", got)
+	}
+
+	s.DisableAccessibility()
+	put(2, 1, "c.txt", StyleDefault)
+	if got := show(); len(got) != 0 {
+		t.Errorf("announced when disabled: %+v", got)
+	}
+}
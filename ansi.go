@@ -0,0 +1,285 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// StyledCell is the content of a single character cell, as produced by
+// ParseANSI.  The fields correspond to the arguments of SetContent.
+type StyledCell struct {
+	Main  rune
+	Comb  []rune
+	Style Style
+	Width int // 2 for wide characters, otherwise 1
+}
+
+// ANSIOptions control how ParseANSI converts text.
+type ANSIOptions struct {
+	// TabWidth is the distance between tab stops.  Zero means 8, and a
+	// negative value replaces each tab with a single space.
+	TabWidth int
+
+	// Links enables hyperlinks (OSC 8), which are converted to the Url
+	// and UrlId of the style.  Otherwise they are ignored, and only the
+	// link text is kept.
+	Links bool
+}
+
+// ParseANSI converts text containing ANSI escape sequences, such as the
+// output of "ls --color" or a log file with colors, into lines of styled
+// cells that can be drawn with SetContent.  The base style is used for
+// text without any attributes, and is restored by SGR 0 (reset).
+//
+// SGR sequences (colors and attributes, including 256 color and RGB forms,
+// and styled underlines) are converted.  Other escape sequences, and
+// control characters other than tab and newline, are discarded, so the
+// result is safe to display.  Invalid UTF-8 is replaced with U+FFFD.
+func ParseANSI(data []byte, base Style, opts ANSIOptions) [][]StyledCell {
+	tab := opts.TabWidth
+	if tab == 0 {
+		tab = 8
+	}
+	style := base
+	lines := [][]StyledCell{}
+	var line []StyledCell
+	col := 0
+
+	for i := 0; i < len(data); {
+		b := data[i]
+		switch {
+		case b == '\x1b':
+			n, seq, final := ansiSequence(data[i:])
+			i += n
+			switch {
+			case final == 'm' && seq[0] == '[':
+				style = applySGR(style, base, string(seq[1:len(seq)-1]))
+			case seq[0] == ']' && opts.Links:
+				style = applyOSC8(style, string(seq[1:]))
+			}
+			continue
+		case b == '\n':
+			lines = append(lines, line)
+			line = nil
+			col = 0
+		case b == '\t':
+			n := 1
+			if tab > 0 {
+				n = tab - col%tab
+			}
+			for j := 0; j < n; j++ {
+				line = append(line, StyledCell{Main: ' ', Style: style, Width: 1})
+			}
+			col += n
+		case b < ' ' || b == 0x7f:
+			// other control characters are dropped
+		default:
+			r, n := utf8.DecodeRune(data[i:])
+			i += n
+			if r < 0xa0 && r >= 0x80 {
+				// C1 controls
+				continue
+			}
+			w := runewidth.RuneWidth(r)
+			if w == 0 && len(line) > 0 {
+				last := &line[len(line)-1]
+				last.Comb = append(last.Comb, r)
+				continue
+			}
+			if w == 0 {
+				line = append(line, StyledCell{Main: ' ', Comb: []rune{r}, Style: style, Width: 1})
+				col++
+				continue
+			}
+			line = append(line, StyledCell{Main: r, Style: style, Width: w})
+			col += w
+			continue
+		}
+		i++
+	}
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// ansiSequence returns the length of the escape sequence at the start of
+// data, its body (excluding the ESC, and for OSC the terminator), and for
+// CSI sequences the final byte.  Unterminated sequences consume the rest
+// of the data.
+func ansiSequence(data []byte) (int, []byte, byte) {
+	if len(data) < 2 {
+		return len(data), []byte{0}, 0
+	}
+	switch data[1] {
+	case '[':
+		for i := 2; i < len(data); i++ {
+			if data[i] >= 0x40 && data[i] <= 0x7e {
+				return i + 1, data[1 : i+1], data[i]
+			}
+		}
+	case ']', 'P', '_', '^', 'X':
+		// string sequences end with BEL or ST (ESC \)
+		for i := 2; i < len(data); i++ {
+			if data[i] == '\a' {
+				return i + 1, data[1:i], 0
+			}
+			if data[i] == '\x1b' && i+1 < len(data) && data[i+1] == '\\' {
+				return i + 2, data[1:i], 0
+			}
+		}
+	default:
+		// intermediate bytes, then a final byte
+		for i := 1; i < len(data); i++ {
+			if data[i] < 0x20 || data[i] > 0x2f {
+				return i + 1, data[1 : i+1], data[i]
+			}
+		}
+	}
+	return len(data), data[1:], 0
+}
+
+// applyOSC8 handles a hyperlink sequence, which has the form
+// ]8;params;url where the params may include id=value.
+func applyOSC8(style Style, body string) Style {
+	parts := strings.SplitN(body, ";", 3)
+	if len(parts) != 3 || parts[0] != "8" {
+		return style
+	}
+	style.url = parts[2]
+	style.urlId = ""
+	if parts[2] == "" {
+		return style
+	}
+	for _, p := range strings.Split(parts[1], ":") {
+		if strings.HasPrefix(p, "id=") {
+			style.urlId = p
+		}
+	}
+	return style
+}
+
+// applySGR applies the parameters of an SGR sequence to the style.
+func applySGR(style Style, base Style, params string) Style {
+	var args [][]int
+	for _, p := range strings.Split(params, ";") {
+		var sub []int
+		for _, s := range strings.Split(p, ":") {
+			v, _ := strconv.Atoi(s)
+			sub = append(sub, v)
+		}
+		args = append(args, sub)
+	}
+
+	// color parses an extended color, from either sub-parameters
+	// (38:5:n or 38:2::r:g:b) or following parameters (38;5;n).
+	color := func(i int) (Color, int) {
+		a := args[i]
+		if len(a) > 1 {
+			switch {
+			case a[1] == 5 && len(a) > 2:
+				return PaletteColor(a[2]), i
+			case a[1] == 2 && len(a) > 5:
+				return NewRGBColor(int32(a[3]), int32(a[4]), int32(a[5])), i
+			case a[1] == 2 && len(a) > 4:
+				return NewRGBColor(int32(a[2]), int32(a[3]), int32(a[4])), i
+			}
+			return ColorDefault, i
+		}
+		if i+2 < len(args) && args[i+1][0] == 5 {
+			return PaletteColor(args[i+2][0]), i + 2
+		}
+		if i+4 < len(args) && args[i+1][0] == 2 {
+			return NewRGBColor(int32(args[i+2][0]), int32(args[i+3][0]), int32(args[i+4][0])), i + 4
+		}
+		return ColorDefault, len(args)
+	}
+
+	for i := 0; i < len(args); i++ {
+		switch v := args[i][0]; {
+		case v == 0:
+			url, id := style.url, style.urlId
+			style = base
+			style.url, style.urlId = url, id
+		case v == 1:
+			style = style.Bold(true)
+		case v == 2:
+			style = style.Dim(true)
+		case v == 3:
+			style = style.Italic(true)
+		case v == 4:
+			ul := UnderlineStyleSolid
+			if len(args[i]) > 1 {
+				ul = UnderlineStyle(args[i][1])
+				if ul > UnderlineStyleDashed {
+					ul = UnderlineStyleSolid
+				}
+			}
+			style = style.Underline(ul)
+		case v == 5 || v == 6:
+			style = style.Blink(true)
+		case v == 7:
+			style = style.Reverse(true)
+		case v == 9:
+			style = style.StrikeThrough(true)
+		case v == 21:
+			style = style.Underline(UnderlineStyleDouble)
+		case v == 22:
+			style = style.Bold(false).Dim(false)
+		case v == 23:
+			style = style.Italic(false)
+		case v == 24:
+			style = style.Underline(false)
+		case v == 25:
+			style = style.Blink(false)
+		case v == 27:
+			style = style.Reverse(false)
+		case v == 29:
+			style = style.StrikeThrough(false)
+		case v >= 30 && v <= 37:
+			style = style.Foreground(PaletteColor(v - 30))
+		case v == 38:
+			var c Color
+			c, i = color(i)
+			style = style.Foreground(c)
+		case v == 39:
+			style = style.Foreground(base.fg)
+		case v >= 40 && v <= 47:
+			style = style.Background(PaletteColor(v - 40))
+		case v == 48:
+			var c Color
+			c, i = color(i)
+			style = style.Background(c)
+		case v == 49:
+			style = style.Background(base.bg)
+		case v == 58:
+			var c Color
+			c, i = color(i)
+			style = style.Underline(c)
+		case v == 59:
+			style = style.Underline(base.ulColor)
+		case v >= 90 && v <= 97:
+			style = style.Foreground(PaletteColor(v - 90 + 8))
+		case v >= 100 && v <= 107:
+			style = style.Background(PaletteColor(v - 100 + 8))
+		}
+	}
+	return style
+}
@@ -0,0 +1,144 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func ansiText(line []StyledCell) string {
+	rs := []rune{}
+	for _, c := range line {
+		rs = append(rs, c.Main)
+		rs = append(rs, c.Comb...)
+	}
+	return string(rs)
+}
+
+func TestParseANSI(t *testing.T) {
+	data := []byte("\x1b[1;31mred\x1b[0m plain\n\x1b[38;5;200mx\x1b[48:2::1:2:3my\x1b[4:3mz\x1b[m\x1b]0;title\a\x1b[2J.")
+	lines := ParseANSI(data, StyleDefault, ANSIOptions{})
+	if len(lines) != 2 {
+		t.Fatalf("Wrong number of lines: %d", len(lines))
+	}
+	if s := ansiText(lines[0]); s != "red plain" {
+		t.Errorf("Wrong text: %q", s)
+	}
+	if lines[0][0].Style != StyleDefault.Bold(true).Foreground(ColorMaroon) {
+		t.Errorf("Wrong style for red")
+	}
+	if lines[0][4].Style != StyleDefault {
+		t.Errorf("Style not reset")
+	}
+	if s := ansiText(lines[1]); s != "xyz." {
+		t.Errorf("Wrong text: %q", s)
+	}
+	x, y, z := lines[1][0].Style, lines[1][1].Style, lines[1][2].Style
+	if x != StyleDefault.Foreground(PaletteColor(200)) {
+		t.Errorf("Wrong 256 color")
+	}
+	if y != x.Background(NewRGBColor(1, 2, 3)) {
+		t.Errorf("Wrong RGB color")
+	}
+	if z != y.Underline(UnderlineStyleCurly) {
+		t.Errorf("Wrong underline")
+	}
+	if lines[1][3].Style != StyleDefault {
+		t.Errorf("Style not reset")
+	}
+}
+
+func TestParseANSIOptions(t *testing.T) {
+	data := []byte("a\tb\x1b]8;id=x;http://example.com\x1b\\link\x1b]8;;\x1b\\ 日é")
+	lines := ParseANSI(data, StyleDefault, ANSIOptions{TabWidth: 4})
+	if s := ansiText(lines[0]); s != "a   blink 日é" {
+		t.Errorf("Wrong text: %q", s)
+	}
+	if lines[0][5].Style != StyleDefault {
+		t.Errorf("Link applied when disabled")
+	}
+	if lines[0][10].Width != 2 {
+		t.Errorf("Wide character not detected")
+	}
+
+	lines = ParseANSI(data, StyleDefault, ANSIOptions{TabWidth: -1, Links: true})
+	if s := ansiText(lines[0]); s != "a blink 日é" {
+		t.Errorf("Wrong text: %q", s)
+	}
+	if lines[0][3].Style != StyleDefault.Url("http://example.com").UrlId("x") {
+		t.Errorf("Link not applied")
+	}
+	if lines[0][7].Style != StyleDefault {
+		t.Errorf("Link not ended")
+	}
+}
+
+func TestExportRegion(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+	s.SetSize(10, 3)
+
+	styles := []Style{
+		StyleDefault.Bold(true).Foreground(ColorRed),
+		StyleDefault.Foreground(PaletteColor(100)).Background(NewRGBColor(1, 2, 3)),
+		StyleDefault.Underline(UnderlineStyleCurly, ColorBlue).Italic(true),
+		StyleDefault.Url("http://example.com").UrlId("x"),
+		StyleDefault,
+	}
+	for i, st := range styles {
+		s.SetContent(i, 0, rune('a'+i), nil, st)
+	}
+	s.SetContent(1, 1, '日', nil, styles[0])
+
+	out := s.ExportRegion(0, 0, 10, 2)
+	lines := ParseANSI(out, StyleDefault, ANSIOptions{Links: true})
+	if len(lines) != 2 {
+		t.Fatalf("Wrong number of lines: %d in %q", len(lines), out)
+	}
+	if txt := ansiText(lines[0]); txt != "abcde" {
+		t.Errorf("Wrong text: %q", txt)
+	}
+	for i, st := range styles {
+		if lines[0][i].Style != st {
+			t.Errorf("Style %d did not round trip: %q", i, out)
+		}
+	}
+	if txt := ansiText(lines[1]); txt != " 日" {
+		t.Errorf("Wrong second line: %q", txt)
+	}
+	if out := s.ExportRegion(5, 0, 5, 1); len(out) != 0 {
+		t.Errorf("Blank region not empty: %q", out)
+	}
+
+	// a region partly off the screen is clipped to it
+	done := make(chan []byte)
+	go func() { done <- s.ExportRegion(-2, 1, 20, 5) }()
+	select {
+	case out := <-done:
+		if expected := s.ExportRegion(0, 1, 10, 2); string(out) != string(expected) {
+			t.Errorf("Wrong clipped region: %q, expected %q", out, expected)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Exporting a region off the screen did not finish")
+	}
+
+	// a transparent cell never gets a background
+	s.SetContent(0, 2, 'x', nil, StyleDefault.Background(ColorRed).Transparent(true))
+	if out := string(s.ExportRegion(0, 2, 1, 1)); strings.Contains(out, "41") {
+		t.Errorf("Transparent cell has a background: %q", out)
+	}
+}
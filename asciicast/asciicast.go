@@ -0,0 +1,328 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package asciicast records the output of a tcell application in the
+// asciicast v2 format used by asciinema, and can play such recordings back.
+// This is useful for documentation, demos, and for capturing bug reports.
+//
+// Recording is done by wrapping the Tty that the screen uses, so that
+// everything the screen sends to the terminal is captured exactly:
+//
+//	tty, _ := tcell.NewDevTty()
+//	rec := asciicast.NewRecorder(tty, file)
+//	screen, _ := tcell.NewTerminfoScreenFromTty(rec)
+//
+// Recordings are played back by writing the output to a terminal (or any
+// io.Writer), as asciinema does.  They cannot be played into a tcell
+// Screen, as that would need a terminal emulator to interpret the output.
+//
+// See https://docs.asciinema.org/manual/asciicast/v2/ for the format.
+package asciicast
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Event types used in asciicast files.
+const (
+	EventOutput = "o" // data written to the terminal
+	EventInput  = "i" // data read from the keyboard
+	EventResize = "r" // terminal resized, data is "COLSxROWS"
+)
+
+// ErrBadFormat is returned when a recording cannot be decoded.
+var ErrBadFormat = errors.New("not an asciicast v2 recording")
+
+// Header is the first line of an asciicast v2 recording.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Event is a single timestamped entry in a recording.  Time is measured
+// from the start of the recording.
+type Event struct {
+	Time time.Duration
+	Type string
+	Data string
+}
+
+// MarshalJSON encodes the event in the asciicast array form.
+func (ev Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{ev.Time.Seconds(), ev.Type, ev.Data})
+}
+
+// UnmarshalJSON decodes an event from the asciicast array form.
+func (ev *Event) UnmarshalJSON(b []byte) error {
+	var arr []json.RawMessage
+	if err := json.Unmarshal(b, &arr); err != nil {
+		return err
+	}
+	if len(arr) != 3 {
+		return ErrBadFormat
+	}
+	var secs float64
+	if err := json.Unmarshal(arr[0], &secs); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(arr[1], &ev.Type); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(arr[2], &ev.Data); err != nil {
+		return err
+	}
+	ev.Time = time.Duration(secs * float64(time.Second))
+	return nil
+}
+
+// Recorder is a tcell.Tty that passes everything through to another Tty,
+// while recording the output in asciicast v2 format.  The header is written
+// when the Tty is first started, and events are written as they occur, so
+// that a recording is still usable if the program terminates abruptly.
+type Recorder struct {
+	tcell.Tty
+	w       io.Writer
+	title   string
+	input   bool
+	started bool
+	start   time.Time
+	err     error
+	outRest []byte // incomplete UTF-8 at the end of the output
+	inRest  []byte // incomplete UTF-8 at the end of the input
+	l       sync.Mutex
+}
+
+// NewRecorder returns a Recorder that wraps the given Tty, writing
+// the recording to w.
+func NewRecorder(tty tcell.Tty, w io.Writer) *Recorder {
+	return &Recorder{Tty: tty, w: w}
+}
+
+// SetTitle sets the title that will be stored in the header.  It must
+// be called before the recording is started to have any effect.
+func (r *Recorder) SetTitle(title string) {
+	r.l.Lock()
+	r.title = title
+	r.l.Unlock()
+}
+
+// RecordInput enables or disables recording of input data.  This is off
+// by default, as keyboard input may contain sensitive content.
+func (r *Recorder) RecordInput(on bool) {
+	r.l.Lock()
+	r.input = on
+	r.inRest = nil
+	r.l.Unlock()
+}
+
+// Err returns the first error encountered writing the recording, if any.
+// Errors writing the recording do not affect the wrapped Tty.
+func (r *Recorder) Err() error {
+	r.l.Lock()
+	defer r.l.Unlock()
+	return r.err
+}
+
+// Start starts the underlying Tty, and on first use writes the header.
+func (r *Recorder) Start() error {
+	if err := r.Tty.Start(); err != nil {
+		return err
+	}
+	r.l.Lock()
+	defer r.l.Unlock()
+	if !r.started {
+		r.started = true
+		r.start = time.Now()
+		hdr := Header{
+			Version:   2,
+			Timestamp: r.start.Unix(),
+			Title:     r.title,
+			Env:       map[string]string{"TERM": r.Getenv("TERM")},
+		}
+		if ws, err := r.Tty.WindowSize(); err == nil {
+			hdr.Width, hdr.Height = ws.Width, ws.Height
+		}
+		r.emit(hdr)
+	}
+	return nil
+}
+
+// Getenv implements tcell.TtyEnviron, so that the screen still sees the
+// environment of the wrapped Tty, if it has one (as a telnet.Tty does).
+// Otherwise it is the environment of the process, as for the Tty itself.
+func (r *Recorder) Getenv(name string) string {
+	if env, ok := r.Tty.(tcell.TtyEnviron); ok {
+		return env.Getenv(name)
+	}
+	return os.Getenv(name)
+}
+
+// NotifyResize registers the callback with the underlying Tty, recording
+// a resize event each time the size changes.
+func (r *Recorder) NotifyResize(cb func()) {
+	if cb == nil {
+		r.Tty.NotifyResize(nil)
+		return
+	}
+	r.Tty.NotifyResize(func() {
+		if ws, err := r.Tty.WindowSize(); err == nil {
+			r.record(EventResize, fmt.Sprintf("%dx%d", ws.Width, ws.Height))
+		}
+		cb()
+	})
+}
+
+// Write writes to the underlying Tty and records the output.
+func (r *Recorder) Write(b []byte) (int, error) {
+	n, err := r.Tty.Write(b)
+	if n > 0 {
+		r.recordBytes(EventOutput, b[:n], &r.outRest)
+	}
+	return n, err
+}
+
+// Read reads from the underlying Tty, recording the input if enabled.
+func (r *Recorder) Read(b []byte) (int, error) {
+	n, err := r.Tty.Read(b)
+	if n > 0 {
+		r.recordBytes(EventInput, b[:n], &r.inRest)
+	}
+	return n, err
+}
+
+func (r *Recorder) record(typ string, data string) {
+	r.l.Lock()
+	defer r.l.Unlock()
+	if !r.started {
+		return
+	}
+	r.emit(Event{Time: time.Since(r.start), Type: typ, Data: data})
+}
+
+// recordBytes records output or input.  A character may be split
+// across writes (or reads), so any incomplete UTF-8 at the end is kept in
+// rest and recorded with the data that follows, rather than being
+// recorded as invalid in both events.
+func (r *Recorder) recordBytes(typ string, b []byte, rest *[]byte) {
+	r.l.Lock()
+	defer r.l.Unlock()
+	if !r.started || (typ == EventInput && !r.input) {
+		return
+	}
+	data := append(*rest, b...)
+	n := completeUTF8(data)
+	*rest = append([]byte(nil), data[n:]...)
+	if n > 0 {
+		r.emit(Event{Time: time.Since(r.start), Type: typ, Data: string(data[:n])})
+	}
+}
+
+// completeUTF8 returns the length of b without the start of a character
+// that is incomplete at its end.
+func completeUTF8(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(b)
+}
+
+// emit writes a single line of JSON.  Must be called with the lock held.
+func (r *Recorder) emit(v interface{}) {
+	if r.err != nil {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err == nil {
+		_, err = r.w.Write(append(b, '\n'))
+	}
+	r.err = err
+}
+
+// Decode reads a complete recording.
+func Decode(rd io.Reader) (*Header, []Event, error) {
+	sc := bufio.NewScanner(rd)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrBadFormat
+	}
+	hdr := &Header{}
+	if err := json.Unmarshal(sc.Bytes(), hdr); err != nil || hdr.Version != 2 {
+		return nil, nil, ErrBadFormat
+	}
+	var evs []Event
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			return nil, nil, err
+		}
+		evs = append(evs, ev)
+	}
+	return hdr, evs, sc.Err()
+}
+
+// Play reads a recording, and writes the output events to w, with the
+// original timing scaled by speed.  (A speed of 2 plays twice as fast,
+// and a speed of zero or less writes everything without delay.)  Idle
+// periods longer than maxIdle are shortened to maxIdle, unless maxIdle
+// is zero.  Input and resize events are ignored.  The destination is
+// typically a terminal, for example os.Stdout.
+func Play(rd io.Reader, w io.Writer, speed float64, maxIdle time.Duration) error {
+	_, evs, err := Decode(rd)
+	if err != nil {
+		return err
+	}
+	var last time.Duration
+	for _, ev := range evs {
+		if ev.Type != EventOutput {
+			continue
+		}
+		if speed > 0 {
+			delay := ev.Time - last
+			if maxIdle > 0 && delay > maxIdle {
+				delay = maxIdle
+			}
+			time.Sleep(time.Duration(float64(delay) / speed))
+		}
+		last = ev.Time
+		if _, err := io.WriteString(w, ev.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
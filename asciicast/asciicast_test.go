@@ -0,0 +1,150 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package asciicast
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+type fakeTty struct {
+	bytes.Buffer
+	cb func()
+}
+
+func (*fakeTty) Start() error             { return nil }
+func (*fakeTty) Stop() error              { return nil }
+func (*fakeTty) Drain() error             { return nil }
+func (*fakeTty) Close() error             { return nil }
+func (t *fakeTty) NotifyResize(cb func()) { t.cb = cb }
+func (*fakeTty) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
+func (*fakeTty) WindowSize() (tcell.WindowSize, error) {
+	return tcell.WindowSize{Width: 40, Height: 10}, nil
+}
+
+func TestRecordAndPlay(t *testing.T) {
+	tty := &fakeTty{}
+	out := &bytes.Buffer{}
+	rec := NewRecorder(tty, out)
+	rec.SetTitle("demo")
+	rec.NotifyResize(func() {})
+
+	_, _ = rec.Write([]byte("ignored"))
+	if err := rec.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	_, _ = rec.Write([]byte("\x1b[Hhello"))
+	tty.cb()
+	_, _ = rec.Write([]byte(" world"))
+	if err := rec.Err(); err != nil {
+		t.Fatalf("recording failed: %v", err)
+	}
+
+	hdr, evs, err := Decode(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if hdr.Width != 40 || hdr.Height != 10 || hdr.Title != "demo" {
+		t.Errorf("bad header: %+v", hdr)
+	}
+	if len(evs) != 3 {
+		t.Fatalf("wrong event count: %d", len(evs))
+	}
+	if evs[1].Type != EventResize || evs[1].Data != "40x10" {
+		t.Errorf("bad resize event: %+v", evs[1])
+	}
+
+	played := &bytes.Buffer{}
+	if err := Play(bytes.NewReader(out.Bytes()), played, 0, 0); err != nil {
+		t.Fatalf("play failed: %v", err)
+	}
+	if played.String() != "\x1b[Hhello world" {
+		t.Errorf("wrong playback: %q", played.String())
+	}
+	if tty.String() != "ignored\x1b[Hhello world" {
+		t.Errorf("output not passed through: %q", tty.String())
+	}
+}
+
+func TestRecordSplitUTF8(t *testing.T) {
+	tty := &fakeTty{}
+	out := &bytes.Buffer{}
+	rec := NewRecorder(tty, out)
+	if err := rec.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	// a character split across writes is recorded whole, in the event
+	// of the write that completes it
+	text := []byte("aé世b")
+	for _, w := range [][]byte{text[:2], text[2:4], text[4:6], text[6:]} {
+		_, _ = rec.Write(w)
+	}
+
+	_, evs, err := Decode(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	var data []string
+	for _, ev := range evs {
+		data = append(data, ev.Data)
+	}
+	want := []string{"a", "é", "世", "b"}
+	if len(data) != len(want) {
+		t.Fatalf("wrong events: %q", data)
+	}
+	for i := range want {
+		if data[i] != want[i] {
+			t.Errorf("event %d: got %q, want %q", i, data[i], want[i])
+		}
+	}
+}
+
+// envTty is a fakeTty with its own environment, like a telnet.Tty.
+type envTty struct {
+	fakeTty
+	env map[string]string
+}
+
+func (t *envTty) Getenv(name string) string { return t.env[name] }
+
+func TestRecordEnviron(t *testing.T) {
+	tty := &envTty{env: map[string]string{"TERM": "vt220", "LANG": "C"}}
+	out := &bytes.Buffer{}
+	rec := NewRecorder(tty, out)
+	if rec.Getenv("LANG") != "C" {
+		t.Errorf("environment not passed through: %q", rec.Getenv("LANG"))
+	}
+	if err := rec.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	hdr, _, err := Decode(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if hdr.Env["TERM"] != "vt220" {
+		t.Errorf("wrong TERM in header: %q", hdr.Env["TERM"])
+	}
+}
+
+func TestDecodeBad(t *testing.T) {
+	if _, _, err := Decode(bytes.NewBufferString("{\"version\": 1}\n")); err != ErrBadFormat {
+		t.Errorf("expected bad format, got %v", err)
+	}
+}
@@ -29,6 +29,7 @@ const (
 	AttrDim
 	AttrItalic
 	AttrStrikeThrough
+	AttrTransparent
 	AttrInvalid AttrMask = 1 << 31 // Mark the style or attributes invalid
 	AttrNone    AttrMask = 0       // Just normal text.
 )
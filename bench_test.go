@@ -0,0 +1,157 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// benchTty is a renderTty whose reads block until input arrives or the
+// tty is drained, so that waiting for input does not allocate timers.
+type benchTty struct {
+	*renderTty
+	drained chan struct{}
+	once    sync.Once
+}
+
+func (tty *benchTty) Drain() error {
+	tty.once.Do(func() { close(tty.drained) })
+	return nil
+}
+
+func (tty *benchTty) Read(b []byte) (int, error) {
+	select {
+	case data := <-tty.input:
+		return copy(b, data), nil
+	case <-tty.drained:
+		return 0, nil
+	}
+}
+
+// BenchmarkPollEvent measures a storm of mouse motion, from the SGR
+// reports arriving from the terminal, through parsing, to delivery by
+// PollEvent.  Each event allocates the EventMouse itself, as events are
+// pointers the application may keep; nothing else should allocate.
+func BenchmarkPollEvent(b *testing.B) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		b.Skipf("No terminfo: %v", err)
+	}
+	tty := &benchTty{renderTty: newRenderTty(80, 25), drained: make(chan struct{})}
+	s, err := NewTerminfoScreenFromTtyTerminfo(tty, ti)
+	if err != nil {
+		b.Fatalf("Failed to create screen: %v", err)
+	}
+	if err := s.Init(); err != nil {
+		b.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	s.EnableMouse(MouseMotionEvents)
+	reports := make([][]byte, 80)
+	for x := range reports {
+		reports[x] = []byte(fmt.Sprintf("\x1b[<35;%d;5M", x+1))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x := i % len(reports)
+		tty.input <- reports[x]
+		for {
+			ev := s.PollEvent()
+			if ev == nil {
+				b.Fatalf("Screen finished")
+			}
+			if mev, ok := ev.(*EventMouse); ok {
+				if px, _ := mev.Position(); px != x {
+					b.Fatalf("Wrong position %d, expected %d", px, x)
+				}
+				break
+			}
+		}
+	}
+}
+
+func benchmarkInput(b *testing.B, input string) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		b.Skipf("No terminfo: %v", err)
+	}
+	t, err := newTScreen(nil, ti)
+	if err != nil {
+		b.Fatalf("Failed to create screen: %v", err)
+	}
+	t.cells.Resize(80, 25)
+	buf := &bytes.Buffer{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.WriteString(input)
+		if evs := t.collectEventsFromInput(buf, false); len(evs) != 1 {
+			b.Fatalf("Expected one event, got %d", len(evs))
+		}
+	}
+}
+
+// BenchmarkMouseMotion measures parsing of SGR mouse motion reports.
+// The one allocation is the EventMouse itself.
+func BenchmarkMouseMotion(b *testing.B) {
+	benchmarkInput(b, "\x1b[<35;10;5M")
+}
+
+// BenchmarkKeyInput measures parsing of a simple key press.
+func BenchmarkKeyInput(b *testing.B) {
+	benchmarkInput(b, "a")
+}
+
+// benchmarkTyping measures showing a key typed on a full screen, which
+// changes one cell of a row.
+func benchmarkTyping(b *testing.B, show func(s Screen, x, y int)) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		b.Skipf("No terminfo: %v", err)
+	}
+	tty := newRenderTty(200, 60)
+	s, err := NewTerminfoScreenFromTtyTerminfo(tty, ti)
+	if err != nil {
+		b.Fatalf("Failed to create screen: %v", err)
+	}
+	if err := s.Init(); err != nil {
+		b.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	s.Fill('x', StyleDefault)
+	s.Show()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x := i % 200
+		s.SetContent(x, 30, rune('a'+i%26), nil, StyleDefault)
+		show(s, x, 30)
+	}
+}
+
+// BenchmarkTypingShow shows a typed key with Show, which looks at every
+// cell of the screen.
+func BenchmarkTypingShow(b *testing.B) {
+	benchmarkTyping(b, func(s Screen, x, y int) { s.Show() })
+}
+
+// BenchmarkTypingShowCells shows a typed key with ShowCells.
+func BenchmarkTypingShowCells(b *testing.B) {
+	benchmarkTyping(b, func(s Screen, x, y int) { s.ShowCells(x, y, 1) })
+}
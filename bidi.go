@@ -0,0 +1,298 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"golang.org/x/text/unicode/bidi"
+)
+
+// TextDirection is the base direction of a paragraph of text.
+type TextDirection int
+
+const (
+	// DirectionAuto uses the direction of the first strongly directional
+	// character in the paragraph, or left to right if there is none.
+	DirectionAuto = TextDirection(iota)
+
+	// DirectionLTR is left to right, as used by Latin scripts.
+	DirectionLTR
+
+	// DirectionRTL is right to left, as used by Hebrew and Arabic.
+	DirectionRTL
+)
+
+// BidiParagraph is a single paragraph (line) of text that has been
+// reordered for display using the Unicode Bidirectional Algorithm.
+// Terminals themselves (mostly) do not reorder text, so applications that
+// display mixed left to right and right to left text need to draw it in
+// visual order, which is what this does.
+//
+// This implements the implicit part of the algorithm; explicit directional
+// embeddings, overrides, and isolates are treated as neutral characters.
+// Combining characters are kept together with the character they modify.
+// Positions are rune indices.
+type BidiParagraph struct {
+	logical []rune
+	visual  []rune
+	levels  []int
+	l2v     []int
+	v2l     []int
+	rtl     bool
+}
+
+// bidiMirrors is the set of paired characters that are mirrored when
+// displayed right to left.
+var bidiMirrors = map[rune]rune{
+	'(': ')', ')': '(',
+	'[': ']', ']': '[',
+	'{': '}', '}': '{',
+	'<': '>', '>': '<',
+	'«': '»', '»': '«',
+	'‹': '›', '›': '‹',
+}
+
+// NewBidiParagraph returns the text reordered for display.  Newlines
+// are not treated specially; callers should split text into lines first.
+func NewBidiParagraph(text string, dir TextDirection) *BidiParagraph {
+	p := &BidiParagraph{logical: []rune(text)}
+	n := len(p.logical)
+
+	classes := make([]bidi.Class, n)
+	for i, r := range p.logical {
+		props, _ := bidi.LookupRune(r)
+		classes[i] = props.Class()
+	}
+
+	switch dir {
+	case DirectionRTL:
+		p.rtl = true
+	case DirectionAuto:
+	scan:
+		for _, c := range classes {
+			switch c {
+			case bidi.L:
+				break scan
+			case bidi.R, bidi.AL:
+				p.rtl = true
+				break scan
+			}
+		}
+	}
+	base := 0
+	if p.rtl {
+		base = 1
+	}
+
+	// W1: non-spacing marks take the class of the preceding character.
+	for i, c := range classes {
+		if c == bidi.NSM {
+			if i > 0 {
+				classes[i] = classes[i-1]
+			} else if p.rtl {
+				classes[i] = bidi.R
+			} else {
+				classes[i] = bidi.L
+			}
+		}
+	}
+
+	// W2, W3, W7: European numbers after Arabic letters are Arabic
+	// numbers, AL is R, and European numbers after L are L.
+	last := bidi.L
+	if p.rtl {
+		last = bidi.R
+	}
+	for i, c := range classes {
+		switch c {
+		case bidi.L, bidi.R:
+			last = c
+		case bidi.AL:
+			last = c
+			classes[i] = bidi.R
+		case bidi.EN:
+			if last == bidi.AL {
+				classes[i] = bidi.AN
+			} else if last == bidi.L {
+				classes[i] = bidi.L
+			}
+		}
+	}
+
+	// strong returns the direction of a resolved class for the purpose
+	// of resolving neutrals, where numbers count as right to left.
+	strong := func(c bidi.Class) (bidi.Class, bool) {
+		switch c {
+		case bidi.L:
+			return bidi.L, true
+		case bidi.R, bidi.EN, bidi.AN:
+			return bidi.R, true
+		}
+		return 0, false
+	}
+	sos := bidi.L
+	if p.rtl {
+		sos = bidi.R
+	}
+
+	// N1, N2: sequences of neutrals take the direction of the surrounding
+	// text if both sides agree, and the paragraph direction otherwise.
+	for i := 0; i < n; {
+		if _, ok := strong(classes[i]); ok {
+			i++
+			continue
+		}
+		j := i
+		for j < n {
+			if _, ok := strong(classes[j]); ok {
+				break
+			}
+			j++
+		}
+		before, after := sos, sos
+		if i > 0 {
+			before, _ = strong(classes[i-1])
+		}
+		if j < n {
+			after, _ = strong(classes[j])
+		}
+		dir := sos
+		if before == after {
+			dir = before
+		}
+		for k := i; k < j; k++ {
+			classes[k] = dir
+		}
+		i = j
+	}
+
+	// I1, I2: resolve the levels.
+	p.levels = make([]int, n)
+	for i, c := range classes {
+		lvl := base
+		switch {
+		case base == 0 && c == bidi.R:
+			lvl = 1
+		case base == 0 && (c == bidi.EN || c == bidi.AN):
+			lvl = 2
+		case base == 1 && c != bidi.R:
+			lvl = 2
+		}
+		p.levels[i] = lvl
+	}
+
+	// L1: trailing whitespace is reset to the paragraph level.
+	for i := n - 1; i >= 0; i-- {
+		props, _ := bidi.LookupRune(p.logical[i])
+		if c := props.Class(); c != bidi.WS && c != bidi.NSM {
+			break
+		}
+		p.levels[i] = base
+	}
+
+	// L2: reverse runs, from the highest level down to the lowest
+	// odd level.  This is done on clusters, so that combining characters
+	// stay after the character they modify.
+	var clusters [][]int
+	for i := range p.logical {
+		if i > 0 && len(clusters) > 0 && isNSM(p.logical[i]) {
+			clusters[len(clusters)-1] = append(clusters[len(clusters)-1], i)
+			continue
+		}
+		clusters = append(clusters, []int{i})
+	}
+	high := 0
+	for _, l := range p.levels {
+		if l > high {
+			high = l
+		}
+	}
+	for lvl := high; lvl >= 1; lvl-- {
+		for i := 0; i < len(clusters); {
+			if p.levels[clusters[i][0]] < lvl {
+				i++
+				continue
+			}
+			j := i
+			for j < len(clusters) && p.levels[clusters[j][0]] >= lvl {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				clusters[a], clusters[b] = clusters[b], clusters[a]
+			}
+			i = j
+		}
+	}
+
+	p.v2l = make([]int, 0, n)
+	for _, c := range clusters {
+		p.v2l = append(p.v2l, c...)
+	}
+	p.l2v = make([]int, n)
+	p.visual = make([]rune, n)
+	for v, l := range p.v2l {
+		p.l2v[l] = v
+		r := p.logical[l]
+		if p.levels[l]%2 == 1 {
+			if m, ok := bidiMirrors[r]; ok {
+				r = m
+			}
+		}
+		p.visual[v] = r
+	}
+	return p
+}
+
+func isNSM(r rune) bool {
+	props, _ := bidi.LookupRune(r)
+	return props.Class() == bidi.NSM
+}
+
+// RTL returns true if the paragraph direction is right to left.
+// Such paragraphs are normally displayed aligned to the right.
+func (p *BidiParagraph) RTL() bool {
+	return p.rtl
+}
+
+// Runes returns the text in visual order, left to right.  Characters
+// displayed right to left are mirrored if needed, so that for example
+// an opening parenthesis still opens the parenthesized text.
+func (p *BidiParagraph) Runes() []rune {
+	return append([]rune{}, p.visual...)
+}
+
+// String returns the text in visual order.
+func (p *BidiParagraph) String() string {
+	return string(p.visual)
+}
+
+// LogicalToVisual returns the visual position of the rune at the given
+// logical position.  Positions past the end of the text are returned
+// unchanged.
+func (p *BidiParagraph) LogicalToVisual(pos int) int {
+	if pos < 0 || pos >= len(p.l2v) {
+		return pos
+	}
+	return p.l2v[pos]
+}
+
+// VisualToLogical returns the logical position of the rune displayed
+// at the given visual position.  Positions past the end of the text are
+// returned unchanged.
+func (p *BidiParagraph) VisualToLogical(pos int) int {
+	if pos < 0 || pos >= len(p.v2l) {
+		return pos
+	}
+	return p.v2l[pos]
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"testing"
+)
+
+func TestBidiParagraph(t *testing.T) {
+	cases := []struct {
+		text   string
+		dir    TextDirection
+		visual string
+		rtl    bool
+	}{
+		{"hello world", DirectionAuto, "hello world", false},
+		{"abc אבג def", DirectionAuto, "abc גבא def", false},
+		{"אבג abc דהו", DirectionAuto, "והד abc גבא", true},
+		{"אבג 123", DirectionAuto, "123 גבא", true},
+		{"(אבג)", DirectionAuto, "(גבא)", true},
+		{"abc", DirectionRTL, "abc", true},
+		{"abc אבג", DirectionRTL, "גבא abc", true},
+		{"אבג ", DirectionLTR, "גבא ", false},
+	}
+	for _, c := range cases {
+		p := NewBidiParagraph(c.text, c.dir)
+		if p.String() != c.visual {
+			t.Errorf("%q: got %q expected %q", c.text, p.String(), c.visual)
+		}
+		if p.RTL() != c.rtl {
+			t.Errorf("%q: wrong direction", c.text)
+		}
+	}
+}
+
+func TestBidiPositions(t *testing.T) {
+	// combining marks stay after their base character
+	p := NewBidiParagraph("aאָב", DirectionLTR)
+	if p.String() != "aבאָ" {
+		t.Errorf("Wrong order: %q", p.String())
+	}
+	for l := 0; l < 4; l++ {
+		if v := p.LogicalToVisual(l); p.VisualToLogical(v) != l {
+			t.Errorf("Position %d does not round trip", l)
+		}
+	}
+	if p.LogicalToVisual(1) != 2 || p.VisualToLogical(1) != 3 {
+		t.Errorf("Wrong mapping")
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"math"
+)
+
+// Buffer is a snapshot of the contents of a Screen, made by
+// CaptureContents, and put back by RestoreContents.  It is not
+// modified by later changes to the screen.
+type Buffer struct {
+	w, h        int
+	cells       []cell
+	cursorX     int
+	cursorY     int
+	cursorStyle CursorStyle
+	cursorColor Color
+	style       Style
+}
+
+// Size returns the size of the captured contents.
+func (buf *Buffer) Size() (int, int) {
+	return buf.w, buf.h
+}
+
+func (b *baseScreen) CaptureContents() *Buffer {
+	buf := &Buffer{}
+	cells := b.GetCells()
+	b.Lock()
+	buf.cursorX, buf.cursorY, buf.cursorStyle, buf.cursorColor = b.getCursor()
+	buf.style = b.getStyle()
+	buf.w, buf.h = cells.Size()
+	buf.cells = make([]cell, len(cells.cells))
+	for i := range cells.cells {
+		c := &cells.cells[i]
+		buf.cells[i] = cell{
+			currMain:  c.currMain,
+			currComb:  append([]rune(nil), c.currComb...),
+			currStyle: c.currStyle,
+			width:     c.width,
+			tag:       c.tag,
+		}
+	}
+	b.Unlock()
+	return buf
+}
+
+func (b *baseScreen) RestoreContents(buf *Buffer) {
+	if buf == nil {
+		return
+	}
+	cells := b.GetCells()
+	b.Lock()
+	w, h := cells.Size()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x >= buf.w || y >= buf.h {
+				cells.SetContent(x, y, ' ', nil, StyleDefault)
+				continue
+			}
+			c := &buf.cells[y*buf.w+x]
+			cells.SetContentWithTag(x, y, c.currMain, c.currComb, c.currStyle, c.tag)
+		}
+	}
+	b.Unlock()
+
+	b.SetStyle(buf.style)
+	b.SetCursor(buf.cursorStyle, buf.cursorColor)
+	b.ShowCursor(buf.cursorX, buf.cursorY)
+}
+
+// blendColor returns the color moved towards the target by the factor,
+// or false if the color has no known RGB value.
+func blendColor(c, target Color, factor float64) (Color, bool) {
+	r, g, b := c.RGB()
+	tr, tg, tb := target.RGB()
+	if r < 0 || tr < 0 {
+		return c, false
+	}
+	mix := func(v, t int32) int32 {
+		return int32(math.Floor(float64(v) + float64(t-v)*factor + 0.5))
+	}
+	return NewRGBColor(mix(r, tr), mix(g, tg), mix(b, tb)), true
+}
+
+// Shade blends the colors of the cells in the region towards the target
+// color, by the given factor between 0 (no change) and 1 (entirely the
+// target color).  This is used to dim (with a dark target) or wash out
+// (with a light one) content shown behind an overlay such as a modal
+// dialog.  For example, to dim the screen behind a dialog:
+//
+//	buf := s.CaptureContents()
+//	w, h := buf.Size()
+//	buf.Shade(Rect{Width: w, Height: h}, ColorBlack, 0.6)
+//	s.RestoreContents(buf)
+//	// draw the dialog ...
+//
+// The result uses RGB colors, which are approximated with the palette on
+// terminals that do not support true color.  Cells using the default
+// colors are blended using the colors of the captured default style, if
+// it has them; otherwise the foreground is dimmed (with AttrDim) instead,
+// and the background is left unchanged, since the actual default colors
+// of the terminal are not known.
+func (buf *Buffer) Shade(r Rect, target Color, factor float64) {
+	if factor <= 0 {
+		return
+	}
+	if factor > 1 {
+		factor = 1
+	}
+	r = r.intersect(Rect{Width: buf.w, Height: buf.h})
+	for y := r.Y; y < r.Y+r.Height; y++ {
+		for x := r.X; x < r.X+r.Width; x++ {
+			c := &buf.cells[y*buf.w+x]
+			st := c.currStyle
+			fg, bg := st.fg, st.bg
+			if fg == ColorDefault {
+				fg = buf.style.fg
+			}
+			if bg == ColorDefault {
+				bg = buf.style.bg
+			}
+			if nfg, ok := blendColor(fg, target, factor); ok {
+				st.fg = nfg
+			} else {
+				st.attrs |= AttrDim
+			}
+			if nbg, ok := blendColor(bg, target, factor); ok {
+				st.bg = nbg
+			}
+			c.currStyle = st
+		}
+	}
+}
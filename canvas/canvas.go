@@ -0,0 +1,304 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package canvas provides a surface of pixels for tcell, which is drawn
+// using characters that divide a cell into dots or blocks.  This allows
+// simple charts, plots, and diagrams to be drawn in any terminal that has
+// a suitable font, without support for an image protocol.
+//
+//	c := canvas.New(40, 10, canvas.Braille)
+//	c.Line(0, 0, 79, 39, tcell.ColorGreen)
+//	c.Circle(40, 20, 15, tcell.ColorDefault)
+//	c.Draw(screen, 0, 0, tcell.StyleDefault)
+package canvas
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// Mode is the way pixels are mapped onto cells.
+type Mode int
+
+const (
+	// Braille uses the braille patterns, with two by four pixels in each
+	// cell.  This has the highest resolution, but all of the pixels in a
+	// cell have the same color.
+	Braille = Mode(iota)
+
+	// HalfBlocks uses the upper and lower half blocks, with one by two
+	// pixels in each cell.  Each of the pixels can have its own color,
+	// as one is drawn in the foreground, and the other in the background.
+	HalfBlocks
+
+	// Quadrants uses the quadrant block elements, with two by two pixels
+	// in each cell, which all have the same color.
+	Quadrants
+)
+
+// cellSize returns the number of pixels in a cell, across and down.
+func (m Mode) cellSize() (int, int) {
+	switch m {
+	case HalfBlocks:
+		return 1, 2
+	case Quadrants:
+		return 2, 2
+	}
+	return 2, 4
+}
+
+// braille dots, by row and column of the pixel within the cell
+var brailleDots = [4][2]uint8{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+// quadrants by bit mask: upper left, upper right, lower left, lower right
+var quadrantRunes = [16]rune{
+	' ', '▘', '▝', '▀', '▖', '▌', '▞', '▛',
+	'▗', '▚', '▐', '▜', '▄', '▙', '▟', '█',
+}
+
+// cell is the state of a cell, with a bit for each pixel that is set.
+// Half blocks keep a color for each of the two pixels; other modes keep
+// the color of the pixel that was last set in the first.
+type cell struct {
+	bits  uint8
+	color [2]tcell.Color
+}
+
+// Canvas is a surface of pixels, which is drawn on cells.  The origin is
+// at the top left.  Pixels are either set, with a color, or clear.  The
+// color tcell.ColorDefault uses the foreground of the style given to Draw.
+type Canvas struct {
+	mode   Mode
+	width  int // in cells
+	height int
+	cells  []cell
+}
+
+// New creates a clear Canvas that is width cells across and height cells
+// down.  The size in pixels depends on the mode.
+func New(width, height int, mode Mode) *Canvas {
+	if width < 0 {
+		width = 0
+	}
+	if height < 0 {
+		height = 0
+	}
+	return &Canvas{
+		mode:   mode,
+		width:  width,
+		height: height,
+		cells:  make([]cell, width*height),
+	}
+}
+
+// Size returns the size of the canvas in pixels.
+func (c *Canvas) Size() (int, int) {
+	cw, ch := c.mode.cellSize()
+	return c.width * cw, c.height * ch
+}
+
+// CellSize returns the size of the canvas in cells.
+func (c *Canvas) CellSize() (int, int) {
+	return c.width, c.height
+}
+
+// locate returns the cell of the pixel, and the bit for it.
+func (c *Canvas) locate(x, y int) (*cell, uint8) {
+	cw, ch := c.mode.cellSize()
+	if x < 0 || y < 0 || x >= c.width*cw || y >= c.height*ch {
+		return nil, 0
+	}
+	cl := &c.cells[(y/ch)*c.width+x/cw]
+	x, y = x%cw, y%ch
+	switch c.mode {
+	case HalfBlocks:
+		return cl, 1 << uint(y)
+	case Quadrants:
+		return cl, 1 << uint(y*2+x)
+	}
+	return cl, brailleDots[y][x]
+}
+
+// Set sets the pixel, in the color.  Pixels outside the canvas are ignored.
+func (c *Canvas) Set(x, y int, color tcell.Color) {
+	if cl, bit := c.locate(x, y); cl != nil {
+		cl.bits |= bit
+		if c.mode == HalfBlocks && bit == 2 {
+			cl.color[1] = color
+		} else {
+			cl.color[0] = color
+		}
+	}
+}
+
+// Unset clears the pixel.
+func (c *Canvas) Unset(x, y int) {
+	if cl, bit := c.locate(x, y); cl != nil {
+		cl.bits &^= bit
+	}
+}
+
+// Get returns true if the pixel is set.
+func (c *Canvas) Get(x, y int) bool {
+	cl, bit := c.locate(x, y)
+	return cl != nil && cl.bits&bit != 0
+}
+
+// Clear clears all of the pixels.
+func (c *Canvas) Clear() {
+	for i := range c.cells {
+		c.cells[i] = cell{}
+	}
+}
+
+// Line draws a straight line between two pixels, including both.
+func (c *Canvas) Line(x0, y0, x1, y1 int, color tcell.Color) {
+	// Bresenham's algorithm, for all octants
+	dx, sx := x1-x0, 1
+	if dx < 0 {
+		dx, sx = -dx, -1
+	}
+	dy, sy := y1-y0, 1
+	if dy < 0 {
+		dy, sy = -dy, -1
+	}
+	err := dx - dy
+	for {
+		c.Set(x0, y0, color)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := err * 2
+		if e2 > -dy {
+			err -= dy
+			x0 += sx
+		}
+		if e2 < dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+// Circle draws a circle with its center and radius in pixels.  As cells
+// are usually about twice as tall as they are wide, circles only look
+// round in modes where the pixels are about square, which are Braille and
+// HalfBlocks.
+func (c *Canvas) Circle(cx, cy, radius int, color tcell.Color) {
+	// the midpoint algorithm, plotting eight octants at once
+	x, y, err := radius, 0, 1-radius
+	for x >= y {
+		for _, p := range [][2]int{{x, y}, {y, x}, {-y, x}, {-x, y}, {-x, -y}, {-y, -x}, {y, -x}, {x, -y}} {
+			c.Set(cx+p[0], cy+p[1], color)
+		}
+		y++
+		if err < 0 {
+			err += 2*y + 1
+		} else {
+			x--
+			err += 2*(y-x) + 1
+		}
+	}
+}
+
+// Plot draws a line chart of the values across the whole width of the
+// canvas.  The value min is at the bottom, and max at the top.  If min is
+// not less than max, the range of the values is used instead.
+func (c *Canvas) Plot(values []float64, min, max float64, color tcell.Color) {
+	if len(values) == 0 {
+		return
+	}
+	if min >= max {
+		min, max = values[0], values[0]
+		for _, v := range values {
+			if v < min {
+				min = v
+			}
+			if v > max {
+				max = v
+			}
+		}
+	}
+	w, h := c.Size()
+	point := func(i int) (int, int) {
+		x := 0
+		if len(values) > 1 {
+			x = i * (w - 1) / (len(values) - 1)
+		}
+		y := h - 1
+		if max > min {
+			y -= int((values[i]-min)/(max-min)*float64(h-1) + 0.5)
+		}
+		return x, y
+	}
+	px, py := point(0)
+	c.Set(px, py, color)
+	for i := 1; i < len(values); i++ {
+		x, y := point(i)
+		c.Line(px, py, x, y, color)
+		px, py = x, y
+	}
+}
+
+// Cell returns the character and style for a cell of the canvas, given
+// the style to draw with.
+func (c *Canvas) Cell(x, y int, style tcell.Style) (rune, tcell.Style) {
+	if x < 0 || y < 0 || x >= c.width || y >= c.height {
+		return ' ', style
+	}
+	cl := c.cells[y*c.width+x]
+	fg := func(color tcell.Color) tcell.Style {
+		if color == tcell.ColorDefault {
+			return style
+		}
+		return style.Foreground(color)
+	}
+	switch c.mode {
+	case HalfBlocks:
+		switch cl.bits {
+		case 1:
+			return '▀', fg(cl.color[0])
+		case 2:
+			return '▄', fg(cl.color[1])
+		case 3:
+			if cl.color[0] == cl.color[1] {
+				return '█', fg(cl.color[0])
+			}
+			bg := cl.color[1]
+			if bg == tcell.ColorDefault {
+				_, bg, _ = style.Decompose()
+			}
+			return '▀', fg(cl.color[0]).Background(bg)
+		}
+	case Quadrants:
+		if cl.bits != 0 {
+			return quadrantRunes[cl.bits], fg(cl.color[0])
+		}
+	default:
+		if cl.bits != 0 {
+			return 0x2800 | rune(cl.bits), fg(cl.color[0])
+		}
+	}
+	return ' ', style
+}
+
+// Draw draws the canvas, with its top left cell at the given position.
+// Cells with no pixels set are drawn as spaces in the style.
+func (c *Canvas) Draw(t tcell.DrawTarget, x, y int, style tcell.Style) {
+	for row := 0; row < c.height; row++ {
+		for col := 0; col < c.width; col++ {
+			r, st := c.Cell(col, row, style)
+			t.SetContent(x+col, y+row, r, nil, st)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package canvas
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func rows(c *Canvas) []string {
+	w, h := c.CellSize()
+	var res []string
+	for y := 0; y < h; y++ {
+		var row []rune
+		for x := 0; x < w; x++ {
+			r, _ := c.Cell(x, y, tcell.StyleDefault)
+			row = append(row, r)
+		}
+		res = append(res, string(row))
+	}
+	return res
+}
+
+func check(t *testing.T, c *Canvas, expect ...string) {
+	t.Helper()
+	got := rows(c)
+	for i := range expect {
+		if i >= len(got) || got[i] != expect[i] {
+			t.Errorf("got %q, expected %q", got, expect)
+			return
+		}
+	}
+}
+
+func TestModes(t *testing.T) {
+	c := New(2, 1, Braille)
+	if w, h := c.Size(); w != 4 || h != 4 {
+		t.Errorf("wrong braille size %dx%d", w, h)
+	}
+	c.Set(0, 0, tcell.ColorDefault)
+	c.Set(1, 3, tcell.ColorDefault)
+	c.Set(9, 9, tcell.ColorDefault) // outside, ignored
+	check(t, c, "⢁ ")
+	if !c.Get(1, 3) || c.Get(2, 0) {
+		t.Errorf("wrong pixels")
+	}
+	c.Unset(1, 3)
+	check(t, c, "⠁ ")
+
+	c = New(2, 1, Quadrants)
+	c.Set(0, 0, tcell.ColorDefault)
+	c.Set(1, 1, tcell.ColorDefault)
+	c.Set(2, 1, tcell.ColorDefault)
+	check(t, c, "▚▖")
+
+	c = New(3, 1, HalfBlocks)
+	c.Set(0, 0, tcell.ColorRed)
+	c.Set(1, 1, tcell.ColorRed)
+	c.Set(2, 0, tcell.ColorRed)
+	c.Set(2, 1, tcell.ColorBlue)
+	check(t, c, "▀▄▀")
+	r, style := c.Cell(2, 0, tcell.StyleDefault)
+	if fg, bg, _ := style.Decompose(); r != '▀' || fg != tcell.ColorRed || bg != tcell.ColorBlue {
+		t.Errorf("half block colors wrong: %q %v %v", r, fg, bg)
+	}
+	c.Clear()
+	check(t, c, "   ")
+}
+
+func TestShapes(t *testing.T) {
+	c := New(4, 2, Quadrants)
+	c.Line(0, 0, 7, 3, tcell.ColorDefault)
+	check(t, c, "▀▄  ", "  ▀▄")
+	c.Clear()
+	c.Circle(3, 1, 1, tcell.ColorDefault)
+	for _, p := range [][2]int{{4, 1}, {2, 1}, {3, 0}, {3, 2}} {
+		if !c.Get(p[0], p[1]) {
+			t.Errorf("circle missing %v", p)
+		}
+	}
+	if c.Get(3, 1) {
+		t.Errorf("circle filled")
+	}
+
+	c = New(4, 1, Braille)
+	c.Plot([]float64{0, 1, 2, 3}, 0, 0, tcell.ColorDefault)
+	for _, p := range [][2]int{{0, 3}, {2, 2}, {4, 1}, {7, 0}} {
+		if !c.Get(p[0], p[1]) {
+			t.Errorf("plot missing %v", p)
+		}
+	}
+
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	s.SetSize(10, 2)
+	c.Draw(s, 2, 1, tcell.StyleDefault)
+	if r, _, _, _ := s.GetContent(2, 1); r != '⣀' {
+		t.Errorf("canvas not drawn: %q", r)
+	}
+}
@@ -30,6 +30,7 @@ type cell struct {
 	lastComb  []rune
 	width     int
 	lock      bool
+	tag       interface{}
 }
 
 // CellBuffer represents a two-dimensional array of character cells.
@@ -42,12 +43,15 @@ type CellBuffer struct {
 	w     int
 	h     int
 	cells []cell
+
+	clusters bool                 // widths are for whole grapheme clusters
+	cond     *runewidth.Condition // for widths of runes, nil for the default
 }
 
 // SetContent sets the contents (primary rune, combining runes,
 // and style) for a cell at a given location.  If the background or
 // foreground of the style is set to ColorNone, then the respective
-// color is left un changed.
+// color is left un changed.  Any tag on the cell is removed.
 func (cb *CellBuffer) SetContent(x int, y int,
 	mainc rune, combc []rune, style Style,
 ) {
@@ -57,17 +61,20 @@ func (cb *CellBuffer) SetContent(x int, y int,
 		// Wide characters: we want to mark the "wide" cells
 		// dirty as well as the base cell, to make sure we consider
 		// both cells as dirty together.  We only need to do this
-		// if we're changing content
+		// if we're changing content.  (The base cell is dirty anyway,
+		// and what was last displayed there is kept for scrolling.)
 		if (c.width > 0) && (mainc != c.currMain || len(combc) != len(c.currComb) || (len(combc) > 0 && !reflect.DeepEqual(combc, c.currComb))) {
-			for i := 0; i < c.width; i++ {
+			for i := 1; i < c.width; i++ {
 				cb.SetDirty(x+i, y, true)
 			}
 		}
 
 		c.currComb = append([]rune{}, combc...)
 
-		if c.currMain != mainc {
-			c.width = runewidth.RuneWidth(mainc)
+		if cb.clusters {
+			c.width = clusterWidth(cb.cond, mainc, combc)
+		} else if c.currMain != mainc {
+			c.width = runeWidth(cb.cond, mainc)
 		}
 		c.currMain = mainc
 		if style.fg == ColorNone {
@@ -77,7 +84,28 @@ func (cb *CellBuffer) SetContent(x int, y int,
 			style.bg = c.currStyle.bg
 		}
 		c.currStyle = style
+		c.tag = nil
+	}
+}
+
+// SetContentWithTag is like SetContent, but also attaches the tag to the
+// cell.  The tag has no effect on the display.
+func (cb *CellBuffer) SetContentWithTag(x int, y int,
+	mainc rune, combc []rune, style Style, tag interface{},
+) {
+	cb.SetContent(x, y, mainc, combc, style)
+	if x >= 0 && y >= 0 && x < cb.w && y < cb.h {
+		cb.cells[(y*cb.w)+x].tag = tag
+	}
+}
+
+// GetTag returns the tag attached to the cell by SetContentWithTag,
+// or nil if there is none.
+func (cb *CellBuffer) GetTag(x, y int) interface{} {
+	if x >= 0 && y >= 0 && x < cb.w && y < cb.h {
+		return cb.cells[(y*cb.w)+x].tag
 	}
+	return nil
 }
 
 // GetContent returns the contents of a character cell, including the
@@ -161,6 +189,45 @@ func (cb *CellBuffer) SetDirty(x, y int, dirty bool) {
 	}
 }
 
+// rowShown returns true if row y of the content is what was last
+// displayed on row from, which differ when the content has scrolled.
+func (cb *CellBuffer) rowShown(y, from int) bool {
+	if y < 0 || from < 0 || y >= cb.h || from >= cb.h {
+		return false
+	}
+	for x := 0; x < cb.w; x++ {
+		c := &cb.cells[y*cb.w+x]
+		l := &cb.cells[from*cb.w+x]
+		if c.lock || l.lock || l.lastMain == rune(0) ||
+			l.lastMain != c.currMain || l.lastStyle != c.currStyle ||
+			len(l.lastComb) != len(c.currComb) {
+			return false
+		}
+		for i := range l.lastComb {
+			if l.lastComb[i] != c.currComb[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// scrolled moves what was last displayed up by n rows, after the display
+// has been scrolled, leaving the rows at the bottom dirty.
+func (cb *CellBuffer) scrolled(n int) {
+	for y := 0; y < cb.h; y++ {
+		for x := 0; x < cb.w; x++ {
+			c := &cb.cells[y*cb.w+x]
+			if y+n < cb.h {
+				l := &cb.cells[(y+n)*cb.w+x]
+				c.lastMain, c.lastComb, c.lastStyle = l.lastMain, l.lastComb, l.lastStyle
+			} else {
+				c.lastMain = rune(0)
+			}
+		}
+	}
+}
+
 // LockCell locks a cell from being drawn, effectively marking it "clean" until
 // the lock is removed. This can be used to prevent tcell from drawing a given
 // cell, even if the underlying content has changed. For example, when drawing a
@@ -207,6 +274,7 @@ func (cb *CellBuffer) Resize(w, h int) {
 			nc.currComb = oc.currComb
 			nc.currStyle = oc.currStyle
 			nc.width = oc.width
+			nc.tag = oc.tag
 			nc.lastMain = rune(0)
 		}
 	}
@@ -215,6 +283,43 @@ func (cb *CellBuffer) Resize(w, h int) {
 	cb.w = w
 }
 
+// setClusters changes whether the width of each cell is that of the whole
+// grapheme cluster it holds, or just of its primary rune.  All the cells
+// need to be drawn again after this, as the terminal's idea of their
+// widths changes too.
+func (cb *CellBuffer) setClusters(on bool) {
+	if on == cb.clusters {
+		return
+	}
+	cb.clusters = on
+	cb.updateWidths()
+}
+
+// setAmbiguousWide changes whether characters of ambiguous width (see
+// runewidth's EastAsianWidth) are taken to be wide, for this buffer only.
+// As with setClusters, all the cells need to be drawn again after this.
+func (cb *CellBuffer) setAmbiguousWide(on bool) {
+	cb.cond = nil
+	if on {
+		cb.cond = runewidth.NewCondition()
+		cb.cond.EastAsianWidth = true
+	}
+	cb.updateWidths()
+}
+
+// updateWidths works out the widths of all the cells again.
+func (cb *CellBuffer) updateWidths() {
+	for i := range cb.cells {
+		c := &cb.cells[i]
+		if cb.clusters {
+			c.width = clusterWidth(cb.cond, c.currMain, c.currComb)
+		} else {
+			c.width = runeWidth(cb.cond, c.currMain)
+		}
+	}
+	cb.Invalidate()
+}
+
 // Fill fills the entire cell buffer array with the specified character
 // and style.  Normally choose ' ' to clear the screen.  This API doesn't
 // support combining characters, or characters with a width larger than one.
@@ -225,6 +330,7 @@ func (cb *CellBuffer) Fill(r rune, style Style) {
 		c := &cb.cells[i]
 		c.currMain = r
 		c.currComb = nil
+		c.tag = nil
 		cs := style
 		if cs.fg == ColorNone {
 			cs.fg = c.currStyle.fg
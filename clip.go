@@ -0,0 +1,129 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// Rect is a rectangular region of cells.
+type Rect struct {
+	X, Y          int
+	Width, Height int
+}
+
+// intersect returns the part of r that is also in r2.  If they do not
+// overlap, the result has zero width or height.
+func (r Rect) intersect(r2 Rect) Rect {
+	x0, y0 := r.X, r.Y
+	if r2.X > x0 {
+		x0 = r2.X
+	}
+	if r2.Y > y0 {
+		y0 = r2.Y
+	}
+	x1, y1 := r.X+r.Width, r.Y+r.Height
+	if r2.X+r2.Width < x1 {
+		x1 = r2.X + r2.Width
+	}
+	if r2.Y+r2.Height < y1 {
+		y1 = r2.Y + r2.Height
+	}
+	if x1 < x0 {
+		x1 = x0
+	}
+	if y1 < y0 {
+		y1 = y0
+	}
+	return Rect{X: x0, Y: y0, Width: x1 - x0, Height: y1 - y0}
+}
+
+// DrawTarget is a surface that can be drawn on, either a Screen, or a
+// region of one returned by Clip.
+type DrawTarget interface {
+	// SetContent sets the contents of the given cell, as for
+	// Screen.SetContent.
+	SetContent(x int, y int, primary rune, combining []rune, style Style)
+
+	// GetContent returns the contents of the given cell, as for
+	// Screen.GetContent.
+	GetContent(x, y int) (primary rune, combining []rune, style Style, width int)
+
+	// Size returns the size of the surface.
+	Size() (width, height int)
+
+	// Fill fills the surface with the given character and style.
+	Fill(rune, Style)
+
+	// Clear fills the surface with blanks in the default style.
+	Clear()
+
+	// Clip returns a surface for a region of this one.
+	Clip(Rect) DrawTarget
+}
+
+// clipTarget draws on a region of a screen.
+type clipTarget struct {
+	screen DrawTarget
+	rect   Rect // position on the screen
+}
+
+func (b *baseScreen) Clip(r Rect) DrawTarget {
+	return &clipTarget{screen: b, rect: r}
+}
+
+func (c *clipTarget) inside(x, y int) bool {
+	return x >= 0 && y >= 0 && x < c.rect.Width && y < c.rect.Height
+}
+
+func (c *clipTarget) SetContent(x, y int, mainc rune, combc []rune, style Style) {
+	if !c.inside(x, y) {
+		return
+	}
+	if x+runewidth.RuneWidth(mainc) > c.rect.Width {
+		// a wide character would spill over the right edge
+		mainc, combc = ' ', nil
+	}
+	c.screen.SetContent(c.rect.X+x, c.rect.Y+y, mainc, combc, style)
+}
+
+func (c *clipTarget) GetContent(x, y int) (rune, []rune, Style, int) {
+	if !c.inside(x, y) {
+		return 0, nil, StyleDefault, 0
+	}
+	return c.screen.GetContent(c.rect.X+x, c.rect.Y+y)
+}
+
+func (c *clipTarget) Size() (int, int) {
+	return c.rect.Width, c.rect.Height
+}
+
+func (c *clipTarget) Fill(r rune, style Style) {
+	for y := 0; y < c.rect.Height; y++ {
+		for x := 0; x < c.rect.Width; x++ {
+			c.SetContent(x, y, r, nil, style)
+		}
+	}
+}
+
+func (c *clipTarget) Clear() {
+	c.Fill(' ', StyleDefault)
+}
+
+func (c *clipTarget) Clip(r Rect) DrawTarget {
+	r.X += c.rect.X
+	r.Y += c.rect.Y
+	return &clipTarget{screen: c.screen, rect: r.intersect(c.rect)}
+}
@@ -0,0 +1,41 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+// copySystemClipboard puts data on the system clipboard, as a fallback
+// for terminals that cannot set the clipboard themselves.  This is done in
+// the background, as it may run an external program; a failure is posted
+// as an EventError.
+func copySystemClipboard(data []byte, opts DisplayOptions, post func(Event)) {
+	data = append([]byte(nil), data...)
+	go func() {
+		if err := setSystemClipboard(data, !opts.NoClipboardCommands); err != nil {
+			post(NewEventError(err))
+		}
+	}()
+}
+
+// pasteSystemClipboard posts the contents of the system clipboard as an
+// EventClipboard, or an EventError if it cannot be read.
+func pasteSystemClipboard(opts DisplayOptions, post func(Event)) {
+	go func() {
+		data, err := getSystemClipboard(!opts.NoClipboardCommands)
+		if err != nil {
+			post(NewEventError(err))
+			return
+		}
+		post(NewEventClipboard(data))
+	}()
+}
@@ -0,0 +1,26 @@
+//go:build plan9 || nacl
+// +build plan9 nacl
+
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+func setSystemClipboard([]byte, bool) error {
+	return ErrNoClipboard
+}
+
+func getSystemClipboard(bool) ([]byte, error) {
+	return nil, ErrNoClipboard
+}
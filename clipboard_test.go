@@ -0,0 +1,43 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSystemClipboardNoCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows uses the clipboard API, not commands")
+	}
+	evs := make(chan Event, 2)
+	post := func(ev Event) { evs <- ev }
+	opts := DisplayOptions{SystemClipboard: true, NoClipboardCommands: true}
+
+	copySystemClipboard([]byte("text"), opts, post)
+	pasteSystemClipboard(opts, post)
+	for i := 0; i < 2; i++ {
+		select {
+		case ev := <-evs:
+			if ev, ok := ev.(*EventError); !ok || ev.Error() != ErrNoClipboard.Error() {
+				t.Errorf("wrong event: %#v", ev)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no event")
+		}
+	}
+}
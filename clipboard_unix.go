@@ -0,0 +1,87 @@
+//go:build !windows && !nacl && !plan9
+// +build !windows,!nacl,!plan9
+
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// clipboardTool is a program that can copy to and paste from the system
+// clipboard.
+type clipboardTool struct {
+	copy  []string
+	paste []string
+}
+
+// clipboardTools returns the programs that might work in this environment,
+// in order of preference.
+func clipboardTools() []clipboardTool {
+	var tools []clipboardTool
+	if runtime.GOOS == "darwin" {
+		tools = append(tools, clipboardTool{
+			copy:  []string{"pbcopy"},
+			paste: []string{"pbpaste"},
+		})
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		tools = append(tools, clipboardTool{
+			copy:  []string{"wl-copy"},
+			paste: []string{"wl-paste", "--no-newline"},
+		})
+	}
+	if os.Getenv("DISPLAY") != "" {
+		tools = append(tools, clipboardTool{
+			copy:  []string{"xclip", "-selection", "clipboard", "-in"},
+			paste: []string{"xclip", "-selection", "clipboard", "-out"},
+		}, clipboardTool{
+			copy:  []string{"xsel", "--clipboard", "--input"},
+			paste: []string{"xsel", "--clipboard", "--output"},
+		})
+	}
+	return tools
+}
+
+func findClipboardTool() *clipboardTool {
+	for _, tool := range clipboardTools() {
+		if _, err := exec.LookPath(tool.copy[0]); err == nil {
+			return &tool
+		}
+	}
+	return nil
+}
+
+func setSystemClipboard(data []byte, allowExec bool) error {
+	tool := findClipboardTool()
+	if !allowExec || tool == nil {
+		return ErrNoClipboard
+	}
+	cmd := exec.Command(tool.copy[0], tool.copy[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	return cmd.Run()
+}
+
+func getSystemClipboard(allowExec bool) ([]byte, error) {
+	tool := findClipboardTool()
+	if !allowExec || tool == nil {
+		return nil, ErrNoClipboard
+	}
+	return exec.Command(tool.paste[0], tool.paste[1:]...).Output()
+}
@@ -0,0 +1,114 @@
+//go:build windows
+// +build windows
+
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	procOpenClipboard    = u32.NewProc("OpenClipboard")
+	procCloseClipboard   = u32.NewProc("CloseClipboard")
+	procEmptyClipboard   = u32.NewProc("EmptyClipboard")
+	procGetClipboardData = u32.NewProc("GetClipboardData")
+	procSetClipboardData = u32.NewProc("SetClipboardData")
+	procGlobalAlloc      = k32.NewProc("GlobalAlloc")
+	procGlobalFree       = k32.NewProc("GlobalFree")
+	procGlobalLock       = k32.NewProc("GlobalLock")
+	procGlobalUnlock     = k32.NewProc("GlobalUnlock")
+	procGlobalSize       = k32.NewProc("GlobalSize")
+	procRtlMoveMemory    = k32.NewProc("RtlMoveMemory")
+)
+
+const (
+	cfUnicodeText = 13
+	gmemMoveable  = 0x0002
+)
+
+// openClipboard opens the clipboard, which must be done from the same
+// thread until it is closed.  The caller must call closeClipboard.
+func openClipboard() error {
+	runtime.LockOSThread()
+	if rv, _, err := procOpenClipboard.Call(0); rv == 0 {
+		runtime.UnlockOSThread()
+		return err
+	}
+	return nil
+}
+
+func closeClipboard() {
+	_, _, _ = procCloseClipboard.Call()
+	runtime.UnlockOSThread()
+}
+
+// setSystemClipboard uses the Windows clipboard API, so it never needs to
+// run an external program.
+func setSystemClipboard(data []byte, _ bool) error {
+	text, err := syscall.UTF16FromString(string(data))
+	if err != nil {
+		return err
+	}
+	if err := openClipboard(); err != nil {
+		return err
+	}
+	defer closeClipboard()
+	_, _, _ = procEmptyClipboard.Call()
+
+	size := uintptr(len(text) * 2)
+	h, _, err := procGlobalAlloc.Call(gmemMoveable, size)
+	if h == 0 {
+		return err
+	}
+	p, _, err := procGlobalLock.Call(h)
+	if p == 0 {
+		_, _, _ = procGlobalFree.Call(h)
+		return err
+	}
+	_, _, _ = procRtlMoveMemory.Call(p, uintptr(unsafe.Pointer(&text[0])), size)
+	_, _, _ = procGlobalUnlock.Call(h)
+	if rv, _, err := procSetClipboardData.Call(cfUnicodeText, h); rv == 0 {
+		_, _, _ = procGlobalFree.Call(h)
+		return err
+	}
+	return nil // the clipboard owns the memory now
+}
+
+func getSystemClipboard(_ bool) ([]byte, error) {
+	if err := openClipboard(); err != nil {
+		return nil, err
+	}
+	defer closeClipboard()
+
+	h, _, err := procGetClipboardData.Call(cfUnicodeText)
+	if h == 0 {
+		return nil, err
+	}
+	size, _, _ := procGlobalSize.Call(h)
+	p, _, err := procGlobalLock.Call(h)
+	if p == 0 {
+		return nil, err
+	}
+	text := make([]uint16, size/2+1)
+	if size >= 2 {
+		_, _, _ = procRtlMoveMemory.Call(uintptr(unsafe.Pointer(&text[0])), p, size)
+	}
+	_, _, _ = procGlobalUnlock.Call(h)
+	return []byte(syscall.UTF16ToString(text)), nil
+}
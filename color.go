@@ -37,6 +37,9 @@ type Color uint64
 const (
 	// ColorDefault is used to leave the Color unchanged from whatever
 	// system or terminal default may exist.  It's also the zero value.
+	// When drawing, it selects the color of the screen's default style
+	// (see Screen.SetStyle), which is the terminal's default color
+	// unless that style sets one.
 	ColorDefault Color = 0
 
 	// ColorValid is used to indicate the color value is actually
@@ -838,6 +841,8 @@ var ColorValues = map[Color]int32{
 const (
 	// ColorReset is used to indicate that the color should use the
 	// vanilla terminal colors.  (Basically go back to the defaults.)
+	// Unlike ColorDefault, this is the terminal's default foreground or
+	// background even if the screen's default style has other colors.
 	ColorReset = ColorSpecial | iota
 
 	// ColorNone indicates that we should not change the color from
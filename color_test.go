@@ -76,6 +76,14 @@ func TestColorFitting(t *testing.T) {
 		t.Errorf("Imperfect color fit")
 	}
 
+	// Excluding the best fit, for colors that must stay distinct
+	dark := GetColor("#121212")
+	if FindColor(dark, pal[:16]) != ColorBlack {
+		t.Errorf("Dark gray does not fit to black")
+	}
+	if c := findColorExcept(dark, pal[:16], ColorBlack); c == ColorBlack || c == ColorDefault {
+		t.Errorf("Excluded color returned: %v", c)
+	}
 }
 
 func TestColorNameLookup(t *testing.T) {
@@ -24,6 +24,12 @@ import (
 // from the palette given.  This is an expensive operation, so results should
 // be cached by the caller.
 func FindColor(c Color, palette []Color) Color {
+	return findColorExcept(c, palette, ColorDefault)
+}
+
+// findColorExcept is like FindColor, but never returns the excluded color,
+// for when the best match would make text invisible on its background.
+func findColorExcept(c Color, palette []Color, except Color) Color {
 	match := ColorDefault
 	dist := float64(0)
 	r, g, b := c.RGB()
@@ -33,6 +39,9 @@ func FindColor(c Color, palette []Color) Color {
 		B: float64(b) / 255.0,
 	}
 	for _, d := range palette {
+		if d == except && except != ColorDefault {
+			continue
+		}
 		r, g, b = d.RGB()
 		c2 := colorful.Color{
 			R: float64(r) / 255.0,
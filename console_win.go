@@ -20,6 +20,7 @@ package tcell
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"sync"
@@ -40,9 +41,15 @@ type cScreen struct {
 	fini       bool
 	vten       bool
 	truecolor  bool
+	vtIn       bool            // VT input mode, with win32-input-mode
+	vtInput    *consoleVtInput // decodes input in VT input mode
 	running    bool
 	disableAlt bool // disable the alternate screen
+	envNoAlt   bool // TCELL_ALTSCREEN disables the alternate screen
 	title      string
+	restoreW   io.Writer
+	restoreOK  bool
+	opts       DisplayOptions
 
 	w int
 	h int
@@ -55,12 +62,13 @@ type cScreen struct {
 	oomode      uint32
 	cells       CellBuffer
 	focusEnable bool
+	modifiers   bool // report modifier keys, for InputOptions.ModifierKeys
 
-	mouseEnabled bool
-	wg           sync.WaitGroup
-	eventQ       chan Event
-	stopQ        chan struct{}
-	finiOnce     sync.Once
+	mouseFlags MouseFlags
+	wg         sync.WaitGroup
+	eventQ     chan Event
+	stopQ      chan struct{}
+	life       lifecycle
 
 	sync.Mutex
 }
@@ -117,23 +125,24 @@ var (
 // characters (Unicode) are in use.  The documentation refers to them
 // without this suffix, as the resolution is made via preprocessor.
 var (
-	procReadConsoleInput            = k32.NewProc("ReadConsoleInputW")
-	procWaitForMultipleObjects      = k32.NewProc("WaitForMultipleObjects")
-	procCreateEvent                 = k32.NewProc("CreateEventW")
-	procSetEvent                    = k32.NewProc("SetEvent")
-	procGetConsoleCursorInfo        = k32.NewProc("GetConsoleCursorInfo")
-	procSetConsoleCursorInfo        = k32.NewProc("SetConsoleCursorInfo")
-	procSetConsoleCursorPosition    = k32.NewProc("SetConsoleCursorPosition")
-	procSetConsoleMode              = k32.NewProc("SetConsoleMode")
-	procGetConsoleMode              = k32.NewProc("GetConsoleMode")
-	procGetConsoleScreenBufferInfo  = k32.NewProc("GetConsoleScreenBufferInfo")
-	procFillConsoleOutputAttribute  = k32.NewProc("FillConsoleOutputAttribute")
-	procFillConsoleOutputCharacter  = k32.NewProc("FillConsoleOutputCharacterW")
-	procSetConsoleWindowInfo        = k32.NewProc("SetConsoleWindowInfo")
-	procSetConsoleScreenBufferSize  = k32.NewProc("SetConsoleScreenBufferSize")
-	procSetConsoleTextAttribute     = k32.NewProc("SetConsoleTextAttribute")
-	procGetLargestConsoleWindowSize = k32.NewProc("GetLargestConsoleWindowSize")
-	procMessageBeep                 = u32.NewProc("MessageBeep")
+	procReadConsoleInput              = k32.NewProc("ReadConsoleInputW")
+	procGetNumberOfConsoleInputEvents = k32.NewProc("GetNumberOfConsoleInputEvents")
+	procWaitForMultipleObjects        = k32.NewProc("WaitForMultipleObjects")
+	procCreateEvent                   = k32.NewProc("CreateEventW")
+	procSetEvent                      = k32.NewProc("SetEvent")
+	procGetConsoleCursorInfo          = k32.NewProc("GetConsoleCursorInfo")
+	procSetConsoleCursorInfo          = k32.NewProc("SetConsoleCursorInfo")
+	procSetConsoleCursorPosition      = k32.NewProc("SetConsoleCursorPosition")
+	procSetConsoleMode                = k32.NewProc("SetConsoleMode")
+	procGetConsoleMode                = k32.NewProc("GetConsoleMode")
+	procGetConsoleScreenBufferInfo    = k32.NewProc("GetConsoleScreenBufferInfo")
+	procFillConsoleOutputAttribute    = k32.NewProc("FillConsoleOutputAttribute")
+	procFillConsoleOutputCharacter    = k32.NewProc("FillConsoleOutputCharacterW")
+	procSetConsoleWindowInfo          = k32.NewProc("SetConsoleWindowInfo")
+	procSetConsoleScreenBufferSize    = k32.NewProc("SetConsoleScreenBufferSize")
+	procSetConsoleTextAttribute       = k32.NewProc("SetConsoleTextAttribute")
+	procGetLargestConsoleWindowSize   = k32.NewProc("GetLargestConsoleWindowSize")
+	procMessageBeep                   = u32.NewProc("MessageBeep")
 )
 
 const (
@@ -148,7 +157,6 @@ const (
 	vtCursorPos               = "\x1b[%d;%dH" // Note that it is Y then X
 	vtSgr0                    = "\x1b[0m"
 	vtBold                    = "\x1b[1m"
-	vtUnderline               = "\x1b[4m"
 	vtBlink                   = "\x1b[5m" // Not sure if this is processed
 	vtReverse                 = "\x1b[7m"
 	vtSetFg                   = "\x1b[38;5;%dm"
@@ -162,17 +170,12 @@ const (
 	vtCursorSteadyUnderline   = "\x1b[4 q"
 	vtCursorBlinkingBar       = "\x1b[5 q"
 	vtCursorSteadyBar         = "\x1b[6 q"
+	vtCursorBlink             = "\x1b[?12h"
+	vtCursorSteady            = "\x1b[?12l"
 	vtDisableAm               = "\x1b[?7l"
 	vtEnableAm                = "\x1b[?7h"
 	vtEnterCA                 = "\x1b[?1049h\x1b[22;0;0t"
 	vtExitCA                  = "\x1b[?1049l\x1b[23;0;0t"
-	vtDoubleUnderline         = "\x1b[4:2m"
-	vtCurlyUnderline          = "\x1b[4:3m"
-	vtDottedUnderline         = "\x1b[4:4m"
-	vtDashedUnderline         = "\x1b[4:5m"
-	vtUnderColor              = "\x1b[58:5:%dm"
-	vtUnderColorRGB           = "\x1b[58:2::%d:%d:%dm"
-	vtUnderColorReset         = "\x1b[59m"
 	vtEnterUrl                = "\x1b]8;%s;%s\x1b\\" // NB arg 1 is id, arg 2 is url
 	vtExitUrl                 = "\x1b]8;;\x1b\\"
 	vtCursorColorRGB          = "\x1b]12;#%02x%02x%02x\007"
@@ -190,6 +193,8 @@ var vtCursorStyles = map[CursorStyle]string{
 	CursorStyleSteadyUnderline:   vtCursorSteadyUnderline,
 	CursorStyleBlinkingBar:       vtCursorBlinkingBar,
 	CursorStyleSteadyBar:         vtCursorSteadyBar,
+	CursorStyleBlinkingDefault:   vtCursorDefault + vtCursorBlink,
+	CursorStyleSteadyDefault:     vtCursorDefault + vtCursorSteady,
 }
 
 // NewConsoleScreen returns a Screen for the Windows console associated
@@ -200,6 +205,10 @@ func NewConsoleScreen() (Screen, error) {
 }
 
 func (s *cScreen) Init() error {
+	return s.life.init(s.setup)
+}
+
+func (s *cScreen) setup() error {
 	s.eventQ = make(chan Event, 10)
 	s.quit = make(chan struct{})
 	s.scandone = make(chan struct{})
@@ -260,17 +269,20 @@ func (s *cScreen) Init() error {
 	}
 	switch os.Getenv("TCELL_ALTSCREEN") {
 	case "enable":
-		s.disableAlt = false // also the default
+		s.envNoAlt = false // also the default
 	case "disable":
-		s.disableAlt = true
+		s.envNoAlt = true
 	}
+	s.setDisableAlt()
 	if tryVt {
 		s.setOutMode(modeVtOutput | modeNoAutoNL | modeCookedOut | modeUnderline)
 		var om uint32
 		s.getOutMode(&om)
 		if om&modeVtOutput == modeVtOutput {
 			s.vten = true
+			s.vtIn = s.tryVtInput()
 		} else {
+			logDebug("console has no virtual terminal output", "mode", om)
 			s.truecolor = false
 			s.setOutMode(0)
 		}
@@ -288,26 +300,58 @@ func (s *cScreen) CharacterSet() string {
 	return "UTF-16LE"
 }
 
-func (s *cScreen) EnableMouse(...MouseFlags) {
+func (s *cScreen) EnableMouse(flags ...MouseFlags) {
+	var f MouseFlags
+	for _, flag := range flags {
+		f |= flag
+	}
+	if len(flags) == 0 {
+		f = MouseMotionEvents | MouseDragEvents | MouseButtonEvents
+	}
 	s.Lock()
-	s.mouseEnabled = true
-	s.enableMouse(true)
+	s.mouseFlags = f
+	s.enableMouse(f != 0)
 	s.Unlock()
 }
 
 func (s *cScreen) DisableMouse() {
 	s.Lock()
-	s.mouseEnabled = false
+	s.mouseFlags = 0
 	s.enableMouse(false)
 	s.Unlock()
 }
 
 func (s *cScreen) enableMouse(on bool) {
+	mode := uint32(modeResizeEn | modeExtendFlg)
 	if on {
-		s.setInMode(modeResizeEn | modeMouseEn | modeExtendFlg)
-	} else {
-		s.setInMode(modeResizeEn | modeExtendFlg)
+		mode |= modeMouseEn
+	}
+	if s.vtIn {
+		mode |= modeVtInput
+	}
+	s.setInMode(mode)
+}
+
+// tryVtInput checks whether the console supports VT input mode, in which
+// keys are reported as escape sequences that we ask to be in the
+// win32-input-mode form, so that nothing is lost compared to the key
+// records.  Consoles that do not know win32-input-mode send the usual
+// sequences, which are decoded as from a terminal.  This can be disabled
+// by setting TCELL_VTINPUT to disable.
+func (s *cScreen) tryVtInput() bool {
+	if os.Getenv("TCELL_VTINPUT") == "disable" {
+		return false
+	}
+	if s.vtInput == nil {
+		if s.vtInput = newConsoleVtInput(); s.vtInput == nil {
+			return false
+		}
 	}
+	var im uint32
+	s.setInMode(modeResizeEn | modeExtendFlg | modeVtInput)
+	s.getInMode(&im)
+	s.setInMode(modeResizeEn | modeExtendFlg)
+	return im&modeVtInput != 0
 }
 
 // Windows lacks bracketed paste (for now)
@@ -329,12 +373,21 @@ func (s *cScreen) DisableFocus() {
 }
 
 func (s *cScreen) Fini() {
-	s.finiOnce.Do(func() {
-		close(s.quit)
-		s.disengage()
+	s.life.fini(func() {
+		s.Lock()
+		s.fini = true
+		s.Unlock()
+		if s.quit != nil {
+			close(s.quit)
+			s.disengage()
+		}
 	})
 }
 
+func (s *cScreen) Done() <-chan struct{} {
+	return s.life.doneQ()
+}
+
 func (s *cScreen) disengage() {
 	s.Lock()
 	if !s.running {
@@ -353,6 +406,10 @@ func (s *cScreen) disengage() {
 		s.emitVtString(vtCursorStyles[CursorStyleDefault])
 		s.emitVtString(vtCursorColorReset)
 		s.emitVtString(vtEnableAm)
+		if s.vtIn {
+			s.emitVtString(disableWin32Input)
+			s.vtInput.reset()
+		}
 		if !s.disableAlt {
 			s.emitVtString(vtRestoreTitle)
 			s.emitVtString(vtExitCA)
@@ -387,7 +444,7 @@ func (s *cScreen) engage() error {
 	}
 	s.running = true
 	s.cancelflag = syscall.Handle(cf)
-	s.enableMouse(s.mouseEnabled)
+	s.enableMouse(s.mouseFlags != 0)
 
 	if s.vten {
 		s.setOutMode(modeVtOutput | modeNoAutoNL | modeCookedOut | modeUnderline)
@@ -396,14 +453,20 @@ func (s *cScreen) engage() error {
 			s.emitVtString(vtEnterCA)
 		}
 		s.emitVtString(vtDisableAm)
+		if s.vtIn {
+			s.emitVtString(enableWin32Input)
+		}
 		if s.title != "" {
 			s.emitVtString(fmt.Sprintf(vtSetTitle, s.title))
 		}
+		s.writeRestore()
 	} else {
 		s.setOutMode(0)
 	}
 
-	s.clearScreen(s.style, s.vten)
+	if !s.opts.NoClear && s.opts.InlineRows == 0 {
+		s.clearScreen(s.style, s.vten)
+	}
 	s.hideCursor()
 
 	s.cells.Invalidate()
@@ -441,7 +504,9 @@ type rect struct {
 
 func (s *cScreen) emitVtString(vs string) {
 	esc := utf16.Encode([]rune(vs))
-	_ = syscall.WriteConsole(s.out, &esc[0], uint32(len(esc)), nil, nil)
+	if e := syscall.WriteConsole(s.out, &esc[0], uint32(len(esc)), nil, nil); e != nil {
+		logDebug("cannot write to console", "error", e)
+	}
 }
 
 func (s *cScreen) showCursor() {
@@ -504,6 +569,14 @@ func (s *cScreen) HideCursor() {
 	s.ShowCursor(-1, -1)
 }
 
+func (s *cScreen) getCursor() (int, int, CursorStyle, Color) {
+	return s.curx, s.cury, s.cursorStyle, s.cursorColor
+}
+
+func (s *cScreen) getStyle() Style {
+	return s.style
+}
+
 type inputRecord struct {
 	typ  uint16
 	_    uint16
@@ -534,7 +607,7 @@ const (
 	mouseHWheeled uint32 = 0x8
 	mouseVWheeled uint32 = 0x4
 	// mouseDoubleClick uint32 = 0x2
-	// mouseMoved       uint32 = 0x1
+	mouseMoved uint32 = 0x1
 )
 
 type resizeRecord struct {
@@ -551,104 +624,6 @@ type keyRecord struct {
 	mod    uint32
 }
 
-const (
-	// Constants per Microsoft.  We don't put the modifiers
-	// here.
-	vkCancel = 0x03
-	vkBack   = 0x08 // Backspace
-	vkTab    = 0x09
-	vkClear  = 0x0c
-	vkReturn = 0x0d
-	vkPause  = 0x13
-	vkEscape = 0x1b
-	vkSpace  = 0x20
-	vkPrior  = 0x21 // PgUp
-	vkNext   = 0x22 // PgDn
-	vkEnd    = 0x23
-	vkHome   = 0x24
-	vkLeft   = 0x25
-	vkUp     = 0x26
-	vkRight  = 0x27
-	vkDown   = 0x28
-	vkPrint  = 0x2a
-	vkPrtScr = 0x2c
-	vkInsert = 0x2d
-	vkDelete = 0x2e
-	vkHelp   = 0x2f
-	vkF1     = 0x70
-	vkF2     = 0x71
-	vkF3     = 0x72
-	vkF4     = 0x73
-	vkF5     = 0x74
-	vkF6     = 0x75
-	vkF7     = 0x76
-	vkF8     = 0x77
-	vkF9     = 0x78
-	vkF10    = 0x79
-	vkF11    = 0x7a
-	vkF12    = 0x7b
-	vkF13    = 0x7c
-	vkF14    = 0x7d
-	vkF15    = 0x7e
-	vkF16    = 0x7f
-	vkF17    = 0x80
-	vkF18    = 0x81
-	vkF19    = 0x82
-	vkF20    = 0x83
-	vkF21    = 0x84
-	vkF22    = 0x85
-	vkF23    = 0x86
-	vkF24    = 0x87
-)
-
-var vkKeys = map[uint16]Key{
-	vkCancel: KeyCancel,
-	vkBack:   KeyBackspace,
-	vkTab:    KeyTab,
-	vkClear:  KeyClear,
-	vkPause:  KeyPause,
-	vkPrint:  KeyPrint,
-	vkPrtScr: KeyPrint,
-	vkPrior:  KeyPgUp,
-	vkNext:   KeyPgDn,
-	vkReturn: KeyEnter,
-	vkEnd:    KeyEnd,
-	vkHome:   KeyHome,
-	vkLeft:   KeyLeft,
-	vkUp:     KeyUp,
-	vkRight:  KeyRight,
-	vkDown:   KeyDown,
-	vkInsert: KeyInsert,
-	vkDelete: KeyDelete,
-	vkHelp:   KeyHelp,
-	vkEscape: KeyEscape,
-	vkSpace:  ' ',
-	vkF1:     KeyF1,
-	vkF2:     KeyF2,
-	vkF3:     KeyF3,
-	vkF4:     KeyF4,
-	vkF5:     KeyF5,
-	vkF6:     KeyF6,
-	vkF7:     KeyF7,
-	vkF8:     KeyF8,
-	vkF9:     KeyF9,
-	vkF10:    KeyF10,
-	vkF11:    KeyF11,
-	vkF12:    KeyF12,
-	vkF13:    KeyF13,
-	vkF14:    KeyF14,
-	vkF15:    KeyF15,
-	vkF16:    KeyF16,
-	vkF17:    KeyF17,
-	vkF18:    KeyF18,
-	vkF19:    KeyF19,
-	vkF20:    KeyF20,
-	vkF21:    KeyF21,
-	vkF22:    KeyF22,
-	vkF23:    KeyF23,
-	vkF24:    KeyF24,
-}
-
 // NB: All Windows platforms are little endian.  We assume this
 // never, ever change.  The following code is endian safe. and does
 // not use unsafe pointers.
@@ -665,29 +640,6 @@ func geti16(v []byte) int16 {
 	return int16(getu16(v))
 }
 
-// Convert windows dwControlKeyState to modifier mask
-func mod2mask(cks uint32) ModMask {
-	mm := ModNone
-	// Left or right control
-	ctrl := (cks & (0x0008 | 0x0004)) != 0
-	// Left or right alt
-	alt := (cks & (0x0002 | 0x0001)) != 0
-	// Filter out ctrl+alt (it means AltGr)
-	if !(ctrl && alt) {
-		if ctrl {
-			mm |= ModCtrl
-		}
-		if alt {
-			mm |= ModAlt
-		}
-	}
-	// Any shift
-	if (cks & 0x0010) != 0 {
-		mm |= ModShift
-	}
-	return mm
-}
-
 func mrec2btns(mbtns, flags uint32) ButtonMask {
 	btns := ButtonNone
 	if mbtns&0x1 != 0 {
@@ -780,31 +732,19 @@ func (s *cScreen) getConsoleInput() error {
 			krec.ch = getu16(rec.data[10:])
 			krec.mod = getu32(rec.data[12:])
 
-			if krec.isdown == 0 || krec.repeat < 1 {
-				// it's a key release event, ignore it
-				return nil
-			}
-			if krec.ch != 0 {
-				// synthesized key code
-				for krec.repeat > 0 {
-					// convert shift+tab to backtab
-					if mod2mask(krec.mod) == ModShift && krec.ch == vkTab {
-						s.postEvent(NewEventKey(KeyBacktab, 0, ModNone))
-					} else {
-						s.postEvent(NewEventKey(KeyRune, rune(krec.ch), mod2mask(krec.mod)))
-					}
-					krec.repeat--
+			if s.vtIn && krec.kcode == 0 {
+				// part of an escape sequence in VT input mode
+				if krec.isdown != 0 && krec.ch != 0 {
+					s.scanVtInput(rune(krec.ch), s.inputWaiting())
 				}
 				return nil
 			}
-			key := KeyNUL // impossible on Windows
-			ok := false
-			if key, ok = vkKeys[krec.kcode]; !ok {
-				return nil
-			}
-			for krec.repeat > 0 {
-				s.postEvent(NewEventKey(key, rune(krec.ch), mod2mask(krec.mod)))
-				krec.repeat--
+			s.Lock()
+			modifiers := s.modifiers
+			s.Unlock()
+			evs := win32KeyEvents(krec.isdown != 0, int(krec.repeat), krec.kcode, krec.ch, krec.mod, modifiers)
+			for _, ev := range evs {
+				s.postEvent(ev)
 			}
 
 		case mouseEvent:
@@ -815,6 +755,15 @@ func (s *cScreen) getConsoleInput() error {
 			mrec.mod = getu32(rec.data[8:])
 			mrec.flags = getu32(rec.data[12:])
 			btns := mrec2btns(mrec.btns, mrec.flags)
+			if mrec.flags&mouseMoved != 0 {
+				// the console reports all motion, so we keep to the flags
+				s.Lock()
+				f := s.mouseFlags
+				s.Unlock()
+				if !mouseMotionWanted(f, btns) {
+					return nil
+				}
+			}
 			// we ignore double click, events are delivered normally
 			s.postEvent(NewEventMouse(int(mrec.x), int(mrec.y), btns, mod2mask(mrec.mod)))
 
@@ -843,6 +792,27 @@ func (s *cScreen) getConsoleInput() error {
 	return nil
 }
 
+// scanVtInput adds a character received in VT input mode to the input,
+// and posts the events for anything complete.  If more is false, there is
+// no more input waiting, so incomplete sequences are not kept.
+func (s *cScreen) scanVtInput(r rune, more bool) {
+	s.Lock()
+	modifiers := s.modifiers
+	s.Unlock()
+	for _, ev := range s.vtInput.scan(r, more, modifiers) {
+		s.postEvent(ev)
+	}
+}
+
+// inputWaiting returns true if there are more input records to read.
+func (s *cScreen) inputWaiting() bool {
+	var n uint32
+	rv, _, _ := procGetNumberOfConsoleInputEvents.Call(
+		uintptr(s.in),
+		uintptr(unsafe.Pointer(&n)))
+	return rv != 0 && n > 0
+}
+
 func (s *cScreen) scanInput(stopQ chan struct{}) {
 	defer s.wg.Done()
 	for {
@@ -852,6 +822,7 @@ func (s *cScreen) scanInput(stopQ chan struct{}) {
 		default:
 		}
 		if e := s.getConsoleInput(); e != nil {
+			logDebug("console input stopped", "error", e)
 			return
 		}
 	}
@@ -902,6 +873,31 @@ func mapColor2RGB(c Color) uint16 {
 	return 0
 }
 
+// mapColor2RGBExcept maps a color that should differ from the given
+// console color to the closest other one.  This keeps text readable when
+// 256 or 24-bit colors are reduced to the 16 console colors, as two colors
+// that are distinct (such as dark gray on black) might otherwise become the
+// same.  The results are not cached, as this is rarely needed.
+func mapColor2RGBExcept(c Color, except uint16) uint16 {
+	for pc, vc := range vgaColors {
+		if vc == except {
+			return vgaColors[findColorExcept(c, winPalette, pc)]
+		}
+	}
+	return mapColor2RGB(c)
+}
+
+// consoleColor returns the color of the console attribute, for comparing
+// default colors with the colors in a style.
+func consoleColor(attr uint16) Color {
+	for pc, vc := range vgaColors {
+		if vc == attr {
+			return pc
+		}
+	}
+	return ColorDefault
+}
+
 // Map a tcell style to Windows attributes
 func (s *cScreen) mapStyle(style Style) uint16 {
 	f, b, a := style.fg, style.bg, style.attrs
@@ -910,9 +906,26 @@ func (s *cScreen) mapStyle(style Style) uint16 {
 	if f != ColorDefault && f != ColorReset {
 		fa = mapColor2RGB(f)
 	}
-	if b != ColorDefault && b != ColorReset {
+	if b != ColorDefault && b != ColorReset && a&AttrTransparent == 0 {
 		ba = mapColor2RGB(b)
 	}
+	if fa == ba {
+		// keep the foreground visible, unless it really is the same color
+		fc, bc := f, b
+		if fc == ColorDefault || fc == ColorReset {
+			fc = consoleColor(fa)
+		}
+		if bc == ColorDefault || bc == ColorReset || a&AttrTransparent != 0 {
+			bc = consoleColor(ba)
+		}
+		if fc.TrueColor() != bc.TrueColor() {
+			if f != ColorDefault && f != ColorReset {
+				fa = mapColor2RGBExcept(f, ba)
+			} else {
+				ba = mapColor2RGBExcept(b, fa)
+			}
+		}
+	}
 	var attr uint16
 	// We simulate reverse by doing the color swap ourselves.
 	// Apparently windows cannot really do this except in DBCS
@@ -943,6 +956,9 @@ func (s *cScreen) sendVtStyle(style Style) {
 
 	fg, bg, attrs := style.fg, style.bg, style.attrs
 	us, uc := style.ulStyle, style.ulColor
+	if attrs&AttrTransparent != 0 {
+		bg = ColorDefault
+	}
 
 	esc.WriteString(vtSgr0)
 	if attrs&(AttrBold|AttrDim) == AttrBold {
@@ -951,31 +967,7 @@ func (s *cScreen) sendVtStyle(style Style) {
 	if attrs&AttrBlink != 0 {
 		esc.WriteString(vtBlink)
 	}
-	if us != UnderlineStyleNone {
-		if uc == ColorReset {
-			esc.WriteString(vtUnderColorReset)
-		} else if uc.IsRGB() {
-			r, g, b := uc.RGB()
-			_, _ = fmt.Fprintf(esc, vtUnderColorRGB, int(r), int(g), int(b))
-		} else if uc.Valid() {
-			_, _ = fmt.Fprintf(esc, vtUnderColor, uc&0xff)
-		}
-
-		esc.WriteString(vtUnderline)
-		// legacy ConHost does not understand these but Terminal does
-		switch us {
-		case UnderlineStyleSolid:
-		case UnderlineStyleDouble:
-			esc.WriteString(vtDoubleUnderline)
-		case UnderlineStyleCurly:
-			esc.WriteString(vtCurlyUnderline)
-		case UnderlineStyleDotted:
-			esc.WriteString(vtDottedUnderline)
-		case UnderlineStyleDashed:
-			esc.WriteString(vtDashedUnderline)
-		}
-	}
-
+	esc.WriteString(vtUnderlineString(us, uc))
 	if attrs&AttrReverse != 0 {
 		esc.WriteString(vtReverse)
 	}
@@ -993,7 +985,11 @@ func (s *cScreen) sendVtStyle(style Style) {
 	}
 	// URL string can be long, so don't send it unless we really need to
 	if style.url != "" {
-		_, _ = fmt.Fprintf(esc, vtEnterUrl, style.urlId, style.url)
+		id := style.urlId
+		if id == "" {
+			id = autoUrlId(style.url)
+		}
+		_, _ = fmt.Fprintf(esc, vtEnterUrl, id, style.url)
 	} else {
 		esc.WriteString(vtExitUrl)
 	}
@@ -1032,9 +1028,7 @@ func (s *cScreen) draw() {
 		for x := 0; x < s.w; x++ {
 			mainc, combc, style, width := s.cells.GetContent(x, y)
 			dirty := s.cells.Dirty(x, y)
-			if style == StyleDefault {
-				style = s.style
-			}
+			style = style.inherit(s.style)
 
 			if !dirty || style != lstyle {
 				// write out any data queued thus far
@@ -1206,6 +1200,12 @@ func (s *cScreen) resize() {
 	}
 }
 
+// showCells shows the whole screen, as the console is drawn a row at a
+// time anyway.
+func (s *cScreen) showCells(int, int, int) {
+	s.Show()
+}
+
 func (s *cScreen) clearScreen(style Style, vtEnable bool) {
 	if vtEnable {
 		s.sendVtStyle(style)
@@ -1243,8 +1243,8 @@ const (
 	modeExtendFlg uint32 = 0x0080
 	modeMouseEn          = 0x0010
 	modeResizeEn         = 0x0008
+	modeVtInput          = 0x0200
 	// modeCooked          = 0x0001
-	// modeVtInput         = 0x0200
 
 	// Output modes
 	modeCookedOut uint32 = 0x0001
@@ -1255,15 +1255,19 @@ const (
 )
 
 func (s *cScreen) setInMode(mode uint32) {
-	_, _, _ = procSetConsoleMode.Call(
+	if rv, _, e := procSetConsoleMode.Call(
 		uintptr(s.in),
-		uintptr(mode))
+		uintptr(mode)); rv == 0 {
+		logDebug("cannot set console input mode", "mode", mode, "error", e)
+	}
 }
 
 func (s *cScreen) setOutMode(mode uint32) {
-	_, _, _ = procSetConsoleMode.Call(
+	if rv, _, e := procSetConsoleMode.Call(
 		uintptr(s.out),
-		uintptr(mode))
+		uintptr(mode)); rv == 0 {
+		logDebug("cannot set console output mode", "mode", mode, "error", e)
+	}
 }
 
 func (s *cScreen) getInMode(v *uint32) {
@@ -1293,6 +1297,90 @@ func (s *cScreen) SetTitle(title string) {
 	s.Unlock()
 }
 
+// SetTabColor has no effect, as iTerm2 is not available on Windows.
+func (s *cScreen) SetTabColor(Color) {}
+
+// SetBadge has no effect, as iTerm2 is not available on Windows.
+func (s *cScreen) SetBadge(string) {}
+
+// SetDisplayOptions only supports NoAltScreen, NoClear, and SystemClipboard
+// on Windows.
+func (s *cScreen) SetDisplayOptions(opts DisplayOptions) {
+	s.Lock()
+	s.opts = opts
+	s.setDisableAlt()
+	s.Unlock()
+}
+
+// setDisableAlt works out whether the alternate screen is used, from the
+// environment and the display options.
+func (s *cScreen) setDisableAlt() {
+	s.disableAlt = s.envNoAlt || s.opts.NoAltScreen || s.opts.InlineRows > 0
+}
+
+// SetInputOptions only uses ModifierKeys on Windows, as the console
+// delivers keys as events rather than escape sequences.
+func (s *cScreen) SetInputOptions(opts InputOptions) {
+	s.Lock()
+	s.modifiers = opts.ModifierKeys
+	s.Unlock()
+}
+
+// AddSemanticMark is not supported on the Windows console.
+func (s *cScreen) AddSemanticMark(int, int, SemanticMark, int) {}
+
+// TerminalID is not supported on the Windows console.
+func (s *cScreen) TerminalID() TerminalIdentity {
+	return TerminalIdentity{}
+}
+
+// ProbeColors does nothing, as the colors of the console are known when
+// the screen is started.
+func (s *cScreen) ProbeColors() {}
+
+func (s *cScreen) Err() error {
+	return nil
+}
+
+func (s *cScreen) FeatureReport() map[Feature]Support {
+	s.Lock()
+	defer s.Unlock()
+	report := make(map[Feature]Support)
+	colorSupport(report, s.Colors())
+	report[FeatureTrueColor] = supportIf(s.vten && s.truecolor)
+	report[FeatureMouse] = SupportAssumed
+	report[FeatureBracketedPaste] = SupportNone
+	report[FeatureFocus] = SupportAssumed
+	report[FeatureTitle] = SupportAssumed
+	report[FeatureCursorStyle] = supportIf(s.vten)
+	return report
+}
+
+func (s *cScreen) SetRestoreWriter(w io.Writer) {
+	s.Lock()
+	s.restoreW = w
+	s.restoreOK = false
+	if s.running && s.vten {
+		s.writeRestore()
+	}
+	s.Unlock()
+}
+
+// writeRestore writes the VT sequences needed to restore the console.
+// The console modes themselves cannot be restored this way, so this is
+// only useful when VT output is in use.
+func (s *cScreen) writeRestore() {
+	if s.restoreW == nil || s.restoreOK {
+		return
+	}
+	s.restoreOK = true
+	str := vtShowCursor + vtCursorStyles[CursorStyleDefault] + vtCursorColorReset + vtSgr0 + vtExitUrl + vtEnableAm
+	if !s.disableAlt {
+		str += vtRestoreTitle + vtExitCA
+	}
+	_, _ = io.WriteString(s.restoreW, str)
+}
+
 // No fallback rune support, since we have Unicode.  Yay!
 
 func (s *cScreen) RegisterRuneFallback(_ rune, _ string) {
@@ -1312,10 +1400,32 @@ func (s *cScreen) HasMouse() bool {
 	return true
 }
 
-func (s *cScreen) SetClipboard(_ []byte) {
+// SetClipboard and GetClipboard only work with DisplayOptions.SystemClipboard,
+// as the console has no clipboard support of its own.
+func (s *cScreen) SetClipboard(data []byte) {
+	s.Lock()
+	if s.opts.SystemClipboard {
+		copySystemClipboard(data, s.opts, s.postEvent)
+	}
+	s.Unlock()
 }
 
 func (s *cScreen) GetClipboard() {
+	s.Lock()
+	if s.opts.SystemClipboard {
+		pasteSystemClipboard(s.opts, s.postEvent)
+	}
+	s.Unlock()
+}
+
+// SendOSC only works when virtual terminal output is enabled, and replies
+// are not received.
+func (s *cScreen) SendOSC(code int, data string) {
+	s.Lock()
+	if s.vten {
+		s.emitVtString(oscString(code, data))
+	}
+	s.Unlock()
 }
 
 func (s *cScreen) Resize(int, int, int, int) {}
@@ -1373,12 +1483,14 @@ func (s *cScreen) Beep() error {
 }
 
 func (s *cScreen) Suspend() error {
-	s.disengage()
-	return nil
+	return s.life.suspend(func() error {
+		s.disengage()
+		return nil
+	})
 }
 
 func (s *cScreen) Resume() error {
-	return s.engage()
+	return s.life.resume(s.engage)
 }
 
 func (s *cScreen) Tty() (Tty, bool) {
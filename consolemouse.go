@@ -0,0 +1,219 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+)
+
+// consoleMouse reports the mouse on the Linux console, which has no mouse
+// support of its own, using the gpm daemon if it is running, or else the
+// mouse device (/dev/input/mice) directly.
+type consoleMouse struct {
+	rd    io.ReadCloser
+	gpm   bool             // rd is a gpm connection, rather than the device
+	order binary.ByteOrder // of gpm events, which are native structs
+	size  func() (int, int)
+	post  func(Event)
+
+	l     sync.Mutex
+	flags MouseFlags
+	btns  ButtonMask // held down
+	x, y  int        // position of the pointer, for the device
+	fx    int        // motion not yet a whole cell, for the device
+	fy    int
+}
+
+// gpm event types and buttons, from gpm.h
+const (
+	gpmMove = 1
+	gpmDrag = 2
+	gpmDown = 4
+	gpmUp   = 8
+	gpmHard = 256 // also let gpm handle the event
+
+	gpmRight     = 1
+	gpmMiddle    = 2
+	gpmLeft      = 4
+	gpmWheelUp   = 16
+	gpmWheelDown = 32
+)
+
+// gpmEventSize is the size of a Gpm_Event.
+const gpmEventSize = 28
+
+// miceCellWidth and miceCellHeight are the motion of the mouse device, in
+// its own units, that moves the pointer by a cell.
+const (
+	miceCellWidth  = 8
+	miceCellHeight = 16
+)
+
+func (cm *consoleMouse) setFlags(f MouseFlags) {
+	cm.l.Lock()
+	cm.flags = f
+	cm.l.Unlock()
+}
+
+// report posts a mouse event, if the flags ask for it.  Motion without a
+// button held down is only reported with MouseMotionEvents, and motion
+// with one only with MouseDragEvents (or MouseMotionEvents).
+func (cm *consoleMouse) report(x, y int, btn ButtonMask, mod ModMask, moved bool) {
+	cm.l.Lock()
+	f := cm.flags
+	cm.l.Unlock()
+	if f == 0 || (moved && !mouseMotionWanted(f, btn)) {
+		return
+	}
+	cm.post(NewEventMouse(x, y, btn, mod))
+}
+
+// gpmEvent handles a Gpm_Event from the gpm daemon.
+func (cm *consoleMouse) gpmEvent(b []byte) {
+	var btn ButtonMask
+	if b[0]&gpmLeft != 0 {
+		btn |= Button1
+	}
+	if b[0]&gpmRight != 0 {
+		btn |= Button2
+	}
+	if b[0]&gpmMiddle != 0 {
+		btn |= Button3
+	}
+	var mod ModMask
+	if b[1]&1 != 0 {
+		mod |= ModShift
+	}
+	if b[1]&4 != 0 {
+		mod |= ModCtrl
+	}
+	if b[1]&(2|8) != 0 {
+		mod |= ModAlt
+	}
+	x := int(int16(cm.order.Uint16(b[8:]))) - 1
+	y := int(int16(cm.order.Uint16(b[10:]))) - 1
+	wdy := int16(cm.order.Uint16(b[26:]))
+	typ := cm.order.Uint32(b[12:]) & 0xf
+
+	moved := false
+	switch typ {
+	case gpmDown:
+		cm.btns |= btn
+	case gpmUp:
+		cm.btns &^= btn
+	case gpmDrag:
+		cm.btns = btn
+		moved = true
+	case gpmMove:
+		cm.btns = 0
+		moved = true
+	}
+	wheel := ButtonNone
+	switch {
+	case b[0]&gpmWheelUp != 0 || wdy > 0:
+		wheel = WheelUp
+	case b[0]&gpmWheelDown != 0 || wdy < 0:
+		wheel = WheelDown
+	}
+	if wheel != ButtonNone {
+		cm.report(x, y, cm.btns|wheel, mod, false)
+		return
+	}
+	cm.report(x, y, cm.btns, mod, moved)
+}
+
+// miceEvent handles a packet from the mouse device, which uses the PS/2
+// protocol, reporting relative motion.
+func (cm *consoleMouse) miceEvent(b []byte) bool {
+	if b[0]&0x08 == 0 {
+		return false // not the start of a packet
+	}
+	var btn ButtonMask
+	if b[0]&1 != 0 {
+		btn |= Button1
+	}
+	if b[0]&2 != 0 {
+		btn |= Button2
+	}
+	if b[0]&4 != 0 {
+		btn |= Button3
+	}
+	dx, dy := int(b[1]), int(b[2])
+	if b[0]&0x10 != 0 {
+		dx -= 256
+	}
+	if b[0]&0x20 != 0 {
+		dy -= 256
+	}
+	w, h := cm.size()
+	x, y := cm.x, cm.y
+	cm.fx += dx
+	cm.fy -= dy // the device counts upwards
+	x += cm.fx / miceCellWidth
+	y += cm.fy / miceCellHeight
+	cm.fx %= miceCellWidth
+	cm.fy %= miceCellHeight
+	if x >= w {
+		x = w - 1
+	}
+	if y >= h {
+		y = h - 1
+	}
+	if x < 0 {
+		x = 0
+	}
+	if y < 0 {
+		y = 0
+	}
+	moved := x != cm.x || y != cm.y
+	changed := btn != cm.btns
+	if moved || changed {
+		cm.x, cm.y, cm.btns = x, y, btn
+		cm.report(x, y, btn, ModNone, !changed)
+	}
+	return true
+}
+
+// run reads events until the mouse is closed.
+func (cm *consoleMouse) run() {
+	if cm.gpm {
+		b := make([]byte, gpmEventSize)
+		for {
+			if _, err := io.ReadFull(cm.rd, b); err != nil {
+				return
+			}
+			cm.gpmEvent(b)
+		}
+	}
+	b := make([]byte, 3)
+	for {
+		if _, err := io.ReadFull(cm.rd, b); err != nil {
+			return
+		}
+		for !cm.miceEvent(b) {
+			// resynchronize, a byte at a time
+			copy(b, b[1:])
+			if _, err := io.ReadFull(cm.rd, b[2:]); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (cm *consoleMouse) close() {
+	_ = cm.rd.Close()
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package tcell
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// gpmSocket is where the gpm daemon listens for clients.
+const gpmSocket = "/dev/gpmctl"
+
+// miceDevice is the device that merges all mice, which usually only root
+// can read.
+const miceDevice = "/dev/input/mice"
+
+// nativeOrder returns the byte order of this machine, which gpm uses.
+func nativeOrder() binary.ByteOrder {
+	v := uint16(1)
+	if *(*byte)(unsafe.Pointer(&v)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// virtualConsole returns the number of the virtual console that the tty
+// is on, which gpm needs to know, or zero if it is not known.
+func virtualConsole(tty Tty) int {
+	dev := ""
+	if td, ok := tty.(ttyDevice); ok {
+		dev = td.device()
+	}
+	if dev == "" || dev == "/dev/tty" {
+		dev, _ = os.Readlink("/proc/self/fd/0")
+	}
+	base := filepath.Base(dev)
+	if !strings.HasPrefix(base, "tty") {
+		return 0
+	}
+	n, _ := strconv.Atoi(base[3:])
+	return n
+}
+
+// openConsoleMouse connects to gpm, or failing that, opens the mouse
+// device.
+func openConsoleMouse(tty Tty) (*consoleMouse, error) {
+	order := nativeOrder()
+	if conn, err := net.Dial("unix", gpmSocket); err == nil {
+		// Gpm_Connect: we want all events, and let gpm draw the pointer
+		// as it moves, for any modifiers
+		b := make([]byte, 16)
+		order.PutUint16(b[0:], 0xffff)
+		order.PutUint16(b[2:], gpmMove|gpmHard)
+		order.PutUint16(b[4:], 0)
+		order.PutUint16(b[6:], 0xffff)
+		order.PutUint32(b[8:], uint32(os.Getpid()))
+		order.PutUint32(b[12:], uint32(virtualConsole(tty)))
+		if _, err = conn.Write(b); err == nil {
+			return &consoleMouse{rd: conn, gpm: true, order: order}, nil
+		}
+		_ = conn.Close()
+	}
+	f, err := os.Open(miceDevice)
+	if err != nil {
+		return nil, err
+	}
+	return &consoleMouse{rd: f, order: order}, nil
+}
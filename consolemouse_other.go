@@ -0,0 +1,27 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+// +build !linux
+
+package tcell
+
+import (
+	"errors"
+)
+
+// openConsoleMouse fails, as only Linux has a console mouse that we know.
+func openConsoleMouse(Tty) (*consoleMouse, error) {
+	return nil, errors.New("no console mouse on this platform")
+}
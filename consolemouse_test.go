@@ -0,0 +1,73 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func gpmPacket(typ uint32, buttons, mods byte, x, y int) []byte {
+	b := make([]byte, gpmEventSize)
+	b[0], b[1] = buttons, mods
+	binary.LittleEndian.PutUint16(b[8:], uint16(x+1))
+	binary.LittleEndian.PutUint16(b[10:], uint16(y+1))
+	binary.LittleEndian.PutUint32(b[12:], typ)
+	return b
+}
+
+func TestConsoleMouse(t *testing.T) {
+	var evs []*EventMouse
+	cm := &consoleMouse{
+		order: binary.LittleEndian,
+		size:  func() (int, int) { return 80, 25 },
+		post:  func(ev Event) { evs = append(evs, ev.(*EventMouse)) },
+		flags: MouseButtonEvents | MouseDragEvents,
+	}
+	expect := func(x, y int, btn ButtonMask, mod ModMask) {
+		t.Helper()
+		if len(evs) != 1 {
+			t.Fatalf("Expected one event, got %d", len(evs))
+		}
+		ev := evs[0]
+		evs = nil
+		if ex, ey := ev.Position(); ex != x || ey != y || ev.Buttons() != btn || ev.Modifiers() != mod {
+			t.Errorf("Wrong event %d,%d %v %v", ex, ey, ev.Buttons(), ev.Modifiers())
+		}
+	}
+
+	cm.gpmEvent(gpmPacket(gpmMove, 0, 0, 3, 4)) // no motion events
+	if len(evs) != 0 {
+		t.Errorf("Motion reported without MouseMotionEvents")
+	}
+	cm.gpmEvent(gpmPacket(gpmDown, gpmLeft, 4, 3, 4))
+	expect(3, 4, Button1, ModCtrl)
+	cm.gpmEvent(gpmPacket(gpmDrag, gpmLeft, 0, 5, 4))
+	expect(5, 4, Button1, ModNone)
+	cm.gpmEvent(gpmPacket(gpmUp, gpmLeft, 0, 5, 4))
+	expect(5, 4, ButtonNone, ModNone)
+	cm.gpmEvent(gpmPacket(gpmDown, gpmWheelUp, 0, 1, 1))
+	expect(1, 1, WheelUp, ModNone)
+
+	// the mouse device reports relative motion, in packets of 3 bytes
+	cm.x, cm.y = 10, 10
+	if cm.miceEvent([]byte{0x00, 0, 0}) {
+		t.Errorf("Packet without sync bit accepted")
+	}
+	cm.miceEvent([]byte{0x09, miceCellWidth * 2, 0})
+	expect(12, 10, Button1, ModNone)
+	cm.miceEvent([]byte{0x08 | 0x10 | 0x20, 256 - miceCellWidth*20, 256 - miceCellHeight})
+	expect(0, 11, ButtonNone, ModNone)
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !(js && wasm)
+// +build !js !wasm
+
+package tcell
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// consoleVtInput decodes the characters that the Windows console sends in
+// VT input mode.  Consoles that honour win32-input-mode send each key
+// record as an escape sequence, but older ones (such as the conhost of
+// earlier Windows 10 releases) send the usual xterm sequences instead, so
+// the input is decoded as it would be from a terminal, which understands
+// both.  It is kept portable, so that it can be tested anywhere.
+type consoleVtInput struct {
+	dec *InputDecoder
+}
+
+// newConsoleVtInput returns a consoleVtInput, or nil if the xterm terminal
+// description is not available, in which case VT input cannot be used.
+func newConsoleVtInput() *consoleVtInput {
+	dec, err := NewInputDecoder(InputDecoderOptions{})
+	if err != nil {
+		return nil
+	}
+	return &consoleVtInput{dec: dec}
+}
+
+// scan adds a character to the input, and returns the events for what is
+// complete.  If more is false, no more input is waiting, so anything that
+// is incomplete (such as a lone ESC) is decoded as it is.  If modifiers is
+// true, presses of the modifier keys are reported.
+func (v *consoleVtInput) scan(r rune, more bool, modifiers bool) []Event {
+	var b [utf8.UTFMax]byte
+	n := utf8.EncodeRune(b[:], r)
+	v.dec.t.inputOpts.ModifierKeys = modifiers
+	evs := v.dec.Decode(b[:n])
+	if !more && v.dec.Pending() > 0 {
+		evs = append(evs, v.dec.Flush()...)
+	}
+	return evs
+}
+
+// reset discards any incomplete input.
+func (v *consoleVtInput) reset() {
+	v.dec.buf.Reset()
+}
+
+// VT escapes for underlines, as sent to the console.  The styled
+// underlines need the colon form, which older consoles discard; each is
+// sent after the plain underline, so that it is still shown there.
+// Double underline and the underline colors have semicolon forms, which
+// every console that knows them accepts, so those are used instead.
+const (
+	vtUnderline       = "\x1b[4m"
+	vtDoubleUnderline = "\x1b[21m"
+	vtCurlyUnderline  = "\x1b[4:3m"
+	vtDottedUnderline = "\x1b[4:4m"
+	vtDashedUnderline = "\x1b[4:5m"
+	vtUnderColor      = "\x1b[58;5;%dm"
+	vtUnderColorRGB   = "\x1b[58;2;%d;%d;%dm"
+	vtUnderColorReset = "\x1b[59m"
+)
+
+// vtUnderlineString returns the escapes that set the underline style us
+// and color uc, after the attributes have been reset.
+func vtUnderlineString(us UnderlineStyle, uc Color) string {
+	if us == UnderlineStyleNone {
+		return ""
+	}
+	s := ""
+	if uc == ColorReset {
+		s += vtUnderColorReset
+	} else if uc.IsRGB() {
+		r, g, b := uc.RGB()
+		s += fmt.Sprintf(vtUnderColorRGB, int(r), int(g), int(b))
+	} else if uc.Valid() {
+		s += fmt.Sprintf(vtUnderColor, uc&0xff)
+	}
+
+	s += vtUnderline
+	switch us {
+	case UnderlineStyleDouble:
+		s += vtDoubleUnderline
+	case UnderlineStyleCurly:
+		s += vtCurlyUnderline
+	case UnderlineStyleDotted:
+		s += vtDottedUnderline
+	case UnderlineStyleDashed:
+		s += vtDashedUnderline
+	}
+	return s
+}
@@ -0,0 +1,209 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Diagnosis is the report written by Diagnose, as JSON.
+type Diagnosis struct {
+	Term     string            `json:"term"`
+	Charset  string            `json:"charset"`
+	Identity TerminalIdentity  `json:"identity"`
+	Width    int               `json:"width"`
+	Height   int               `json:"height"`
+	Colors   int               `json:"colors"`
+	Features map[string]string `json:"features"`
+	Widths   []WidthProbe      `json:"widths"`
+	Keys     []string          `json:"keys"`
+	Mouse    []MouseProbe      `json:"mouse"`
+	Unknown  []string          `json:"unknown"`
+	Errors   []string          `json:"errors,omitempty"`
+}
+
+// WidthProbe compares the width that tcell expects for some text with the
+// width the terminal actually used.  Measured is -1 if the terminal could
+// not be asked.
+type WidthProbe struct {
+	Text     string `json:"text"`
+	Expected int    `json:"expected"`
+	Measured int    `json:"measured"`
+}
+
+// MouseProbe is a mouse button press seen while diagnosing.
+type MouseProbe struct {
+	X       int        `json:"x"`
+	Y       int        `json:"y"`
+	Buttons ButtonMask `json:"buttons"`
+	Mod     ModMask    `json:"mod"`
+}
+
+// widthSamples are the strings whose width is probed, chosen as the cases
+// that terminals most often disagree about.
+var widthSamples = []string{
+	"A",                    // narrow
+	"\u4e16",               // wide CJK
+	"e\u0301",              // combining accent
+	"\u00b1",               // East Asian ambiguous
+	"\U0001F600",           // emoji
+	"\u2764\uFE0F",         // text symbol with emoji presentation
+	"\U0001F1FA\U0001F1F8", // regional indicator flag
+	"\U0001F468\u200D\U0001F469\u200D\U0001F467", // ZWJ sequence
+}
+
+// widthProber is implemented by screens that can ask the terminal how
+// wide text really is.
+type widthProber interface {
+	probeWidth(s string) int
+}
+
+// Diagnose probes the terminal, and writes a report of the results to w
+// as JSON.  It takes over the terminal while it runs, first measuring how
+// the terminal displays some difficult characters, and then asking the
+// user to press keys and click the mouse, recording what was received.
+// It finishes when the user presses Enter or Ctrl-C.  Applications can
+// offer it to users (for example with a debugging flag) to gather
+// information for bug reports; it must be called while no other screen is
+// active.
+func Diagnose(w io.Writer) error {
+	s, err := NewScreen()
+	if err != nil {
+		return err
+	}
+	if err := s.Init(); err != nil {
+		return err
+	}
+	d := diagnose(s)
+	s.Fini()
+	d.Term = os.Getenv("TERM")
+	return d.write(w)
+}
+
+// diagnoser holds the state of a diagnosis in progress.
+type diagnoser struct {
+	Diagnosis
+	screen Screen
+	done   bool
+}
+
+// diagnose runs the probes on an initialized screen.
+func diagnose(s Screen) *Diagnosis {
+	d := &diagnoser{
+		Diagnosis: Diagnosis{
+			Charset:  s.CharacterSet(),
+			Identity: s.TerminalID(),
+			Colors:   s.Colors(),
+			Features: make(map[string]string),
+		},
+		screen: s,
+	}
+	for f, sup := range s.FeatureReport() {
+		d.Features[f.String()] = sup.String()
+	}
+	d.probeWidths()
+
+	s.SetInputOptions(InputOptions{RawSequences: true})
+	s.EnableMouse()
+	s.Clear()
+	d.Width, d.Height = s.Size()
+	d.draw()
+	for !d.done {
+		ev := s.PollEvent()
+		if ev == nil {
+			break
+		}
+		d.record(ev)
+		d.draw()
+	}
+	s.DisableMouse()
+	return &d.Diagnosis
+}
+
+func (d *diagnoser) probeWidths() {
+	var prober widthProber
+	if b, ok := d.screen.(*baseScreen); ok {
+		prober, _ = b.screenImpl.(widthProber)
+	}
+	for _, text := range widthSamples {
+		p := WidthProbe{Text: text, Expected: StringWidth(text), Measured: -1}
+		if prober != nil {
+			p.Measured = prober.probeWidth(text)
+		}
+		d.Widths = append(d.Widths, p)
+	}
+	d.screen.Sync()
+}
+
+func (d *diagnoser) record(ev Event) {
+	switch ev := ev.(type) {
+	case *EventKey:
+		d.Keys = append(d.Keys, ev.Name())
+		if ev.Key() == KeyEnter || ev.Key() == KeyCtrlC {
+			d.done = true
+		}
+	case *EventMouse:
+		if ev.Buttons()&^(WheelUp|WheelDown|WheelLeft|WheelRight) == ButtonNone {
+			return // motion and releases are too noisy to record
+		}
+		x, y := ev.Position()
+		d.Mouse = append(d.Mouse, MouseProbe{X: x, Y: y, Buttons: ev.Buttons(), Mod: ev.Modifiers()})
+	case *EventRaw:
+		d.Unknown = append(d.Unknown, fmt.Sprintf("%q", ev.Data()))
+	case *EventResize:
+		d.Width, d.Height = ev.Size()
+		d.screen.Sync()
+	case *EventError:
+		d.Errors = append(d.Errors, ev.Error())
+	}
+}
+
+func (d *diagnoser) draw() {
+	s := d.screen
+	s.Clear()
+	text := "Terminal diagnostics.  Press keys and click the mouse to test " +
+		"them.  Press Enter or Ctrl-C to finish.\n\n"
+	if n := len(d.Keys); n > 0 {
+		text += "Last key: " + d.Keys[n-1] + "\n"
+	}
+	if n := len(d.Mouse); n > 0 {
+		m := d.Mouse[n-1]
+		text += fmt.Sprintf("Last click: %d,%d\n", m.X, m.Y)
+	}
+	if n := len(d.Unknown); n > 0 {
+		text += "Last unknown sequence: " + d.Unknown[n-1] + "\n"
+	}
+	w, h := s.Size()
+	lines := LayoutText([]TextSpan{{Text: text, Style: StyleDefault}}, LayoutOptions{Width: w})
+	for y, line := range lines {
+		if y >= h {
+			break
+		}
+		line.Draw(s, 0, y)
+	}
+	s.Show()
+}
+
+func (d *Diagnosis) write(w io.Writer) error {
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
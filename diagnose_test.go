@@ -0,0 +1,52 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestDiagnose(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+
+	s.InjectKey(KeyRune, 'x', ModAlt)
+	s.InjectMouse(3, 4, Button1, ModNone)
+	s.InjectKey(KeyEnter, 0, ModNone)
+	d := diagnose(s)
+
+	buf := &bytes.Buffer{}
+	if err := d.write(buf); err != nil {
+		t.Fatalf("failed to write report: %v", err)
+	}
+	var report Diagnosis
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+	if len(report.Keys) != 2 || report.Keys[0] != "Alt+Rune[x]" {
+		t.Errorf("wrong keys: %v", report.Keys)
+	}
+	if len(report.Mouse) != 1 || report.Mouse[0] != (MouseProbe{X: 3, Y: 4, Buttons: Button1}) {
+		t.Errorf("wrong mouse: %v", report.Mouse)
+	}
+	if len(report.Widths) != len(widthSamples) || report.Widths[1].Expected != 2 || report.Widths[1].Measured != -1 {
+		t.Errorf("wrong widths: %v", report.Widths)
+	}
+	if report.Features["256Color"] != "assumed" {
+		t.Errorf("wrong features: %v", report.Features)
+	}
+}
@@ -44,4 +44,15 @@
 //
 // A rich set of key codes is supported, with support for up to 65 function
 // keys, and various other special keys.
+//
+// A program may use several screens at the same time, for example a server
+// giving each of its SSH or telnet clients its own screen, created with
+// NewTerminfoScreenFromTty and a Tty for the connection.  Each screen has
+// its own Tty, input goroutines, and state, and screens do not affect one
+// another.  A Tty that implements TtyEnviron supplies the environment
+// of the remote terminal, so that variables such as $TERM and $LANG of
+// the server process are not applied to it.  Fini restores and closes the
+// Tty of that screen only, and waits for its goroutines to exit; other
+// screens keep running.  Only one screen should use the terminal of the
+// process itself (/dev/tty or the Windows console) at a time.
 package tcell
@@ -0,0 +1,144 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"sync"
+	"time"
+)
+
+// flashRegion is a region of the screen shown in reverse video until
+// the deadline.
+type flashRegion struct {
+	x, y, w, h int
+	until      time.Time
+}
+
+// flashing is the state of the flash effect.  Like the selection, it is
+// applied to the cells only while the screen is drawn, so the application
+// never sees it in the cell contents, and only the cells that change need
+// to be drawn when it starts and ends.
+type flashing struct {
+	regions []flashRegion
+	saved   map[int]Style
+	savedW  int
+	l       sync.Mutex
+}
+
+func (b *baseScreen) Flash(x, y, width, height int, d time.Duration) {
+	b.flash.l.Lock()
+	b.flash.regions = append(b.flash.regions, flashRegion{
+		x: x, y: y, w: width, h: height, until: time.Now().Add(d),
+	})
+	b.flash.l.Unlock()
+	b.Show()
+
+	b.tick.l.Lock()
+	ticking := b.tick.stop != nil
+	b.tick.l.Unlock()
+	if ticking {
+		return // the next frame after the deadline ends it
+	}
+	stopQ := b.StopQ()
+	time.AfterFunc(d, func() {
+		select {
+		case <-stopQ:
+		default:
+			b.Show()
+		}
+	})
+}
+
+// applyFlash reverses the video of the cells in the regions being
+// flashed, remembering the original styles so that they can be restored
+// afterwards by restoreFlash.  Regions whose time is up are dropped.  It
+// returns false if there is nothing to do, or it is already applied.
+func (b *baseScreen) applyFlash() bool {
+	b.flash.l.Lock()
+	defer b.flash.l.Unlock()
+	if b.flash.saved != nil {
+		return false
+	}
+	now := time.Now()
+	live := b.flash.regions[:0]
+	for _, r := range b.flash.regions {
+		if now.Before(r.until) {
+			live = append(live, r)
+		}
+	}
+	b.flash.regions = live
+	if len(live) == 0 {
+		return false
+	}
+
+	cells := b.GetCells()
+	b.Lock()
+	defer b.Unlock()
+	w, h := cells.Size()
+	b.flash.saved = make(map[int]Style)
+	b.flash.savedW = w
+	for _, r := range live {
+		x1, y1 := r.x+r.w, r.y+r.h
+		if r.w <= 0 {
+			x1 = w
+		}
+		if r.h <= 0 {
+			y1 = h
+		}
+		for y := r.y; y < y1 && y < h; y++ {
+			for x := r.x; x < x1 && x < w; x++ {
+				if x < 0 || y < 0 {
+					continue
+				}
+				if _, done := b.flash.saved[y*w+x]; done {
+					continue // regions overlap
+				}
+				mainc, combc, style, _ := cells.GetContent(x, y)
+				b.flash.saved[y*w+x] = style
+				_, _, attrs := style.Decompose()
+				style = style.Reverse(attrs&AttrReverse == 0)
+				cells.SetContentWithTag(x, y, mainc, combc, style, cells.GetTag(x, y))
+			}
+		}
+	}
+	return true
+}
+
+func (b *baseScreen) restoreFlash() {
+	b.flash.l.Lock()
+	saved, savedW := b.flash.saved, b.flash.savedW
+	b.flash.saved = nil
+	b.flash.l.Unlock()
+	if saved == nil {
+		return
+	}
+	cells := b.GetCells()
+	b.Lock()
+	defer b.Unlock()
+	w, _ := cells.Size()
+	if w != savedW {
+		// resized while drawing; the contents are redrawn anyway
+		return
+	}
+	for i, orig := range saved {
+		x, y := i%w, i/w
+		mainc, combc, cur, _ := cells.GetContent(x, y)
+		_, _, attrs := orig.Decompose()
+		// if the application changed the cell meanwhile, leave it alone
+		if cur == orig.Reverse(attrs&AttrReverse == 0) {
+			cells.SetContentWithTag(x, y, mainc, combc, orig, cells.GetTag(x, y))
+		}
+	}
+}
@@ -120,6 +120,14 @@ func GetEncoding(charset string) encoding.Encoding {
 	if enc, ok := encodings[charset]; ok {
 		return enc
 	}
+	// Locales often spell the name differently, for example "koi8r" or
+	// "eucJP", so try again ignoring dashes and underscores.
+	name := normalizeCharset(charset)
+	for cs, enc := range encodings {
+		if normalizeCharset(cs) == name {
+			return enc
+		}
+	}
 	switch encodingFallback {
 	case EncodingFallbackASCII:
 		return gencoding.ASCII
@@ -129,6 +137,84 @@ func GetEncoding(charset string) encoding.Encoding {
 	return nil
 }
 
+// normalizeCharset returns the name of a character set in lower case,
+// without dashes or underscores.
+func normalizeCharset(charset string) string {
+	charset = strings.ToLower(charset)
+	charset = strings.Replace(charset, "-", "", -1)
+	return strings.Replace(charset, "_", "", -1)
+}
+
+// doubleByteCharsets are the East Asian character sets in which the
+// characters other than ASCII (and half width katakana) are two bytes,
+// and terminals display them two cells wide, including the ones that
+// Unicode gives an ambiguous width, such as box drawing characters.
+var doubleByteCharsets = map[string]bool{
+	"gb18030":   true,
+	"gbk":       true,
+	"gb2312":    true,
+	"euccn":     true,
+	"cp936":     true,
+	"big5":      true,
+	"big5hkscs": true,
+	"cp950":     true,
+	"shiftjis":  true,
+	"sjis":      true,
+	"cp932":     true,
+	"eucjp":     true,
+	"iso2022jp": true,
+	"2022jp":    true,
+	"euckr":     true,
+	"cp949":     true,
+}
+
+// doubleByteCharset returns true if charset is one of doubleByteCharsets.
+func doubleByteCharset(charset string) bool {
+	return doubleByteCharsets[normalizeCharset(charset)]
+}
+
+// encodedWidth returns the number of cells a terminal using a double byte
+// character set takes to display b, which is one character so encoded.
+func encodedWidth(b []byte) int {
+	switch {
+	case len(b) < 2:
+		return 1
+	case b[0] == 0x8e && len(b) == 2:
+		return 1 // EUC-JP half width katakana
+	case b[0] == 0x1b && len(b) > 2 && b[1] == '(':
+		return 1 // ISO-2022-JP single byte set, such as katakana
+	}
+	return 2
+}
+
+// localeCharset determines the character set from the locale variables
+// looked up with getenv.
+func localeCharset(getenv func(string) string) string {
+	// Per POSIX, we search for LC_ALL first, then LC_CTYPE, and
+	// finally LANG.  First one set wins.
+	locale := ""
+	if locale = getenv("LC_ALL"); locale == "" {
+		if locale = getenv("LC_CTYPE"); locale == "" {
+			locale = getenv("LANG")
+		}
+	}
+	if locale == "POSIX" || locale == "C" {
+		return "US-ASCII"
+	}
+	if i := strings.IndexRune(locale, '@'); i >= 0 {
+		locale = locale[:i]
+	}
+	if i := strings.IndexRune(locale, '.'); i >= 0 {
+		locale = locale[i+1:]
+	} else {
+		// Default assumption, and on Linux we can see LC_ALL
+		// without a character set, which we assume implies UTF-8.
+		return "UTF-8"
+	}
+	// XXX: add support for aliases
+	return locale
+}
+
 func init() {
 	// We always support UTF-8 and ASCII.
 	encodings = make(map[string]encoding.Encoding)
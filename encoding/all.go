@@ -68,7 +68,10 @@ func registerAll() {
 	tcell.RegisterEncoding("EUC-KR", korean.EUCKR)
 
 	tcell.RegisterEncoding("GB18030", simplifiedchinese.GB18030)
-	tcell.RegisterEncoding("GB2312", simplifiedchinese.HZGB2312)
+	// GB2312 in a locale is EUC-CN, which GBK extends, rather than the
+	// 7-bit HZ-GB-2312 used in mail.
+	tcell.RegisterEncoding("GB2312", simplifiedchinese.GBK)
+	tcell.RegisterEncoding("HZ-GB-2312", simplifiedchinese.HZGB2312)
 	tcell.RegisterEncoding("GBK", simplifiedchinese.GBK)
 
 	tcell.RegisterEncoding("Big5", traditionalchinese.Big5)
@@ -108,6 +111,13 @@ func registerAll() {
 		"ISO-2022-JP": "ISO2022JP",
 
 		"EUCKR": "EUC-KR",
+		"CP949": "EUC-KR",
+
+		"EUC-CN":     "GB2312",
+		"CP936":      "GBK",
+		"CP950":      "Big5",
+		"BIG5-HKSCS": "Big5",
+		"CP932":      "Shift_JIS",
 
 		// ISO646 isn't quite exactly ASCII, but the 1991 IRV
 		// (international reference version) is so.  This helps
@@ -0,0 +1,118 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// localeTty is a tty in a locale, which keeps what is written to it.
+type localeTty struct {
+	lang string
+	out  bytes.Buffer
+	l    sync.Mutex
+}
+
+func (tty *localeTty) Start() error        { return nil }
+func (tty *localeTty) Stop() error         { return nil }
+func (tty *localeTty) Drain() error        { return nil }
+func (tty *localeTty) Close() error        { return nil }
+func (tty *localeTty) NotifyResize(func()) {}
+func (tty *localeTty) WindowSize() (tcell.WindowSize, error) {
+	return tcell.WindowSize{Width: 20, Height: 2}, nil
+}
+
+func (tty *localeTty) Read(b []byte) (int, error) {
+	time.Sleep(10 * time.Millisecond)
+	return 0, nil
+}
+
+func (tty *localeTty) Write(b []byte) (int, error) {
+	tty.l.Lock()
+	defer tty.l.Unlock()
+	return tty.out.Write(b)
+}
+
+func (tty *localeTty) Getenv(name string) string {
+	if name == "LANG" {
+		return tty.lang
+	}
+	return ""
+}
+
+func TestLegacyOutput(t *testing.T) {
+	ti, err := tcell.LookupTerminfo("xterm")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	// double byte character sets make ambiguous characters wide, but
+	// only on their own screen
+	if runewidth.RuneWidth('─') != 1 {
+		t.Skip("ambiguous characters are wide (RUNEWIDTH_EASTASIAN)")
+	}
+
+	cases := []struct {
+		lang string
+		text string
+		wide bool // double byte, so box drawing is wide
+	}{
+		{"ru_RU.koi8r", "Жук", false},
+		{"zh_CN.GB18030", "中文", true},
+		{"zh_CN.gb2312", "中文", true},
+		{"zh_TW.Big5", "中文", true},
+		{"ja_JP.SJIS", "日本", true},
+		{"ja_JP.eucJP", "日本", true},
+	}
+	for _, c := range cases {
+		tty := &localeTty{lang: c.lang}
+		s, err := tcell.NewTerminfoScreenFromTtyTerminfo(tty, ti)
+		if err != nil {
+			t.Fatalf("%s: failed to create screen: %v", c.lang, err)
+		}
+		if err := s.Init(); err != nil {
+			t.Fatalf("%s: failed to initialize screen: %v", c.lang, err)
+		}
+		s.SetContent(0, 1, '─', nil, tcell.StyleDefault)
+		if _, _, _, w := s.GetContent(0, 1); (w == 2) != c.wide {
+			t.Errorf("%s: box drawing is %d wide", c.lang, w)
+		}
+		tcell.DrawSafeString(s, 0, 0, c.text, tcell.StyleDefault, tcell.SanitizeStrip)
+		s.Show()
+		s.Fini()
+		if runewidth.RuneWidth('─') != 1 {
+			t.Errorf("%s: widths changed for other screens", c.lang)
+		}
+
+		enc := tcell.GetEncoding(s.CharacterSet())
+		tty.l.Lock()
+		out, err := enc.NewDecoder().Bytes(tty.out.Bytes())
+		tty.l.Unlock()
+		if err != nil {
+			t.Errorf("%s: output does not decode: %v", c.lang, err)
+		}
+		for _, r := range c.text {
+			if !strings.ContainsRune(string(out), r) {
+				t.Errorf("%s: %q not in output %q", c.lang, r, out)
+			}
+		}
+	}
+}
@@ -15,6 +15,7 @@ package tcell
 
 import (
 	"fmt"
+	"testing"
 
 	"golang.org/x/text/encoding/simplifiedchinese"
 )
@@ -26,3 +27,35 @@ func ExampleRegisterEncoding() {
 	fmt.Println(string(glyph))
 	// Output: 倀
 }
+
+func TestLegacyCharsetNames(t *testing.T) {
+	RegisterEncoding("KOI8-R", simplifiedchinese.GBK) // any will do
+	defer delete(encodings, "koi8-r")
+	for _, name := range []string{"KOI8-R", "koi8r", "Koi8_R"} {
+		if GetEncoding(name) == nil {
+			t.Errorf("No encoding for %s", name)
+		}
+	}
+	for name, double := range map[string]bool{
+		"GB18030": true, "gb2312": true, "Big5-HKSCS": true, "Shift_JIS": true,
+		"eucJP": true, "EUC-KR": true, "KOI8-R": false, "UTF-8": false,
+	} {
+		if doubleByteCharset(name) != double {
+			t.Errorf("%s: double byte should be %v", name, double)
+		}
+	}
+	for _, c := range []struct {
+		b []byte
+		w int
+	}{
+		{[]byte("a"), 1},
+		{[]byte{0xd6, 0xd0}, 2},                 // GB18030
+		{[]byte{0x8e, 0xb1}, 1},                 // EUC-JP half width katakana
+		{[]byte{0x1b, '(', 'I', 0x31}, 1},       // ISO-2022-JP katakana
+		{[]byte{0x1b, '$', 'B', 0x46, 0x7c}, 2}, // ISO-2022-JP kanji
+	} {
+		if w := encodedWidth(c.b); w != c.w {
+			t.Errorf("%x: width %d, expected %d", c.b, w, c.w)
+		}
+	}
+}
@@ -48,6 +48,34 @@ var (
 	// ErrEventQFull indicates that the event queue is full, and
 	// cannot accept more events.
 	ErrEventQFull = errors.New("event queue full")
+
+	// ErrScreenClosed indicates that the screen has been finalized
+	// with Fini, and can no longer be used.
+	ErrScreenClosed = errors.New("screen is finalized")
+
+	// ErrNoClipboard indicates that the system clipboard could not be
+	// used, as there is no way to reach it (or the only way is to run an
+	// external program, and that was not allowed).
+	ErrNoClipboard = errors.New("no system clipboard available")
+
+	// ErrHangup indicates that the terminal has gone away, for example
+	// because the connection to it was closed.  Reading from a terminal
+	// on UNIX systems fails with EIO when this happens.
+	ErrHangup = errors.New("terminal hung up")
+
+	// ErrInputOverflow indicates that the terminal sent an escape
+	// sequence too long to be one that could be understood, so the
+	// input was discarded.
+	ErrInputOverflow = errors.New("input sequence too long")
+
+	// ErrStringTooLong indicates that the terminal sent a string (an OSC,
+	// DCS or APC sequence) longer than InputOptions.MaxString, so it was
+	// discarded, up to its end.
+	ErrStringTooLong = errors.New("input string too long")
+
+	// ErrInvalidString indicates that the terminal sent a string that
+	// was not valid UTF-8, so it was discarded.
+	ErrInvalidString = errors.New("input string not valid UTF-8")
 )
 
 // An EventError is an event representing some sort of error, and carries
@@ -67,6 +95,12 @@ func (ev *EventError) Error() string {
 	return ev.err.Error()
 }
 
+// Unwrap returns the error, so that errors.Is and errors.As can be used
+// with the event.
+func (ev *EventError) Unwrap() error {
+	return ev.err
+}
+
 // NewEventError creates an ErrorEvent with the given error payload.
 func NewEventError(err error) *EventError {
 	return &EventError{t: time.Now(), err: err}
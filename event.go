@@ -46,6 +46,24 @@ func (e *EventTime) SetEventNow() {
 	e.SetEventTime(time.Now())
 }
 
+// stampEvent sets the time of an input event to when, which is normally
+// when its bytes were read from the terminal, rather than when the event
+// was parsed.  Other events are left alone.
+func stampEvent(ev Event, when time.Time) {
+	switch ev := ev.(type) {
+	case *EventKey:
+		ev.t = when
+	case *EventMouse:
+		ev.t = when
+	case *EventPaste:
+		ev.t = when
+	case *EventRaw:
+		ev.t = when
+	case *EventFocus:
+		ev.EventTime = &EventTime{when: when}
+	}
+}
+
 // EventHandler is anything that handles events.  If the handler has
 // consumed the event, it should return true.  False otherwise.
 type EventHandler interface {
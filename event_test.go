@@ -15,6 +15,17 @@
 package tcell
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -69,6 +80,172 @@ func TestMouseEvents(t *testing.T) {
 	}
 }
 
+func TestMouseLevels(t *testing.T) {
+	ti, err := LookupTerminfo("xterm")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	tty := newRenderTty(10, 2)
+	s, err := NewTerminfoScreenFromTtyTerminfo(tty, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	tty.output()
+
+	steps := []struct {
+		name   string
+		change func()
+		output string
+	}{
+		{"clicks", func() { s.EnableMouse(MouseButtonEvents) }, "\x1b[?1000h\x1b[?1006h"},
+		{"drags", func() { s.EnableMouse(MouseDragEvents) }, "\x1b[?1000l\x1b[?1002h"},
+		{"same", func() { s.EnableMouse(MouseDragEvents | MouseButtonEvents) }, ""},
+		{"all", func() { s.EnableMouse() }, "\x1b[?1002l\x1b[?1003h"},
+		{"clicks again", func() { s.EnableMouse(MouseButtonEvents) }, "\x1b[?1003l\x1b[?1000h"},
+		{"off", func() { s.DisableMouse() }, "\x1b[?1000l\x1b[?1006l"},
+		{"off again", func() { s.DisableMouse() }, ""},
+		{"flags", func() { s.EnableMouse(0) }, ""},
+		{"drags from off", func() { s.EnableMouse(MouseDragEvents) }, "\x1b[?1002h\x1b[?1006h"},
+	}
+	for _, step := range steps {
+		step.change()
+		if out := tty.output(); out != step.output {
+			t.Errorf("%s: got %q, expected %q", step.name, out, step.output)
+		}
+	}
+
+	// on resuming, we don't know what was left on, so everything is reset
+	if err := s.Suspend(); err != nil {
+		t.Fatalf("Failed to suspend: %v", err)
+	}
+	if out := tty.output(); !strings.Contains(out, "\x1b[?1002l\x1b[?1006l") {
+		t.Errorf("Mouse not disabled on suspend: %q", out)
+	}
+	if err := s.Resume(); err != nil {
+		t.Fatalf("Failed to resume: %v", err)
+	}
+	if out := tty.output(); !strings.Contains(out, "\x1b[?1000l\x1b[?1002l\x1b[?1003l\x1b[?1006l\x1b[?1002h\x1b[?1006h") {
+		t.Errorf("Mouse not restored on resume: %q", out)
+	}
+}
+
+func TestMouseMotionWanted(t *testing.T) {
+	cases := []struct {
+		flags MouseFlags
+		btn   ButtonMask
+		want  bool
+	}{
+		{MouseButtonEvents, ButtonNone, false},
+		{MouseButtonEvents, Button1, false},
+		{MouseDragEvents, ButtonNone, false},
+		{MouseDragEvents, Button2, true},
+		{MouseDragEvents, WheelUp, false},
+		{MouseMotionEvents, ButtonNone, true},
+		{MouseMotionEvents, Button3, true},
+	}
+	for _, c := range cases {
+		if got := mouseMotionWanted(c.flags, c.btn); got != c.want {
+			t.Errorf("flags %d buttons %d: got %v", c.flags, c.btn, got)
+		}
+	}
+}
+
+func TestLocator(t *testing.T) {
+	ti, err := LookupTerminfo("xterm")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	tty := newRenderTty(10, 2)
+	s, err := NewTerminfoScreenFromTtyTerminfo(tty, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	s.EnableMouse(MouseButtonEvents)
+	tty.output()
+
+	steps := []struct {
+		name   string
+		change func()
+		output string
+	}{
+		{"locator", func() { s.SetInputOptions(InputOptions{Locator: true}) }, "\x1b[?1000l\x1b[?1006l\x1b[1;2'z\x1b[1;3'{"},
+		{"drags", func() { s.EnableMouse(MouseDragEvents) }, ""},
+		{"off", func() { s.DisableMouse() }, "\x1b[0'z"},
+		{"on", func() { s.EnableMouse() }, "\x1b[1;2'z\x1b[1;3'{"},
+		{"tracking", func() { s.SetInputOptions(InputOptions{}) }, "\x1b[0'z\x1b[?1003h\x1b[?1006h"},
+	}
+	for _, step := range steps {
+		step.change()
+		if out := tty.output(); out != step.output {
+			t.Errorf("%s: got %q, expected %q", step.name, out, step.output)
+		}
+	}
+}
+
+func TestParseLocator(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	ts, err := newTScreen(nil, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	ts.cells.Resize(80, 24)
+
+	cases := []struct {
+		seq    string
+		x, y   int
+		button ButtonMask
+	}{
+		{"\x1b[2;4;5;10&w", 9, 4, Button1},       // left pressed
+		{"\x1b[3;0;5;10&w", 9, 4, ButtonNone},    // left released
+		{"\x1b[6;1;1;1&w", 0, 0, Button2},        // right pressed
+		{"\x1b[4;2;1;1&w", 0, 0, Button3},        // middle pressed
+		{"\x1b[2;4;30;100;0&w", 79, 23, Button1}, // clipped, with a page
+	}
+	for _, c := range cases {
+		var evs []Event
+		buf := bytes.NewBufferString(c.seq)
+		if _, comp := ts.parseLocator(buf, &evs); !comp || buf.Len() != 0 {
+			t.Errorf("%q: not parsed", c.seq)
+			continue
+		}
+		if len(evs) != 1 {
+			t.Errorf("%q: wrong number of events: %d", c.seq, len(evs))
+			continue
+		}
+		ev, ok := evs[0].(*EventMouse)
+		if !ok {
+			t.Errorf("%q: not a mouse event: %#v", c.seq, evs[0])
+			continue
+		}
+		if x, y := ev.Position(); x != c.x || y != c.y || ev.Buttons() != c.button {
+			t.Errorf("%q: got %d,%d %v, expected %d,%d %v", c.seq, x, y, ev.Buttons(), c.x, c.y, c.button)
+		}
+	}
+
+	// the locator being unavailable, or a partial report, gives no event
+	var evs []Event
+	if _, comp := ts.parseLocator(bytes.NewBufferString("\x1b[0&w"), &evs); !comp || len(evs) != 0 {
+		t.Errorf("unavailable locator: %v %v", comp, evs)
+	}
+	if part, comp := ts.parseLocator(bytes.NewBufferString("\x1b[2;4;5"), &evs); !part || comp {
+		t.Errorf("partial report: %v %v", part, comp)
+	}
+	if part, _ := ts.parseLocator(bytes.NewBufferString("\x1b[2;4;5H"), &evs); part {
+		t.Errorf("other sequence taken as a report")
+	}
+}
+
 func TestChannelMouseEvents(t *testing.T) {
 
 	s := mkTestScreen(t, "")
@@ -107,3 +284,1040 @@ loop:
 		t.Errorf("Modifiers should be control")
 	}
 }
+
+func TestKeyDelay(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	ts, err := newTScreen(nil, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+
+	const (
+		def = 50 * time.Millisecond
+		esc = 20 * time.Millisecond
+		seq = 300 * time.Millisecond
+	)
+	partials := []string{"\x1b[", "\x1b[1;5", "\x1b]52;c;YWJj", "\x1bP>|XTerm", "\xe4\xb8"}
+	cases := []struct {
+		name string
+		opts InputOptions
+		esc  time.Duration // for a lone ESC
+		part time.Duration // for the partial sequences
+	}{
+		{"default", InputOptions{}, def, def},
+		{"escape", InputOptions{EscapeDelay: esc}, esc, esc},
+		{"sequence", InputOptions{SequenceDelay: seq}, def, seq},
+		{"both", InputOptions{EscapeDelay: esc, SequenceDelay: seq}, esc, seq},
+		{"complete", InputOptions{WaitComplete: true}, def, 0},
+		{"complete escape", InputOptions{EscapeDelay: esc, SequenceDelay: seq, WaitComplete: true}, esc, 0},
+	}
+	for _, c := range cases {
+		ts.inputOpts = c.opts
+		if d := ts.keyDelay(bytes.NewBufferString("\x1b")); d != c.esc {
+			t.Errorf("%s: ESC: got %v, expected %v", c.name, d, c.esc)
+		}
+		for _, p := range partials {
+			if d := ts.keyDelay(bytes.NewBufferString(p)); d != c.part {
+				t.Errorf("%s: %q: got %v, expected %v", c.name, p, d, c.part)
+			}
+		}
+	}
+}
+
+func TestKeyMatching(t *testing.T) {
+	ts := &tScreen{}
+	parse := func(seq string) *EventKey {
+		var evs []Event
+		buf := bytes.NewBufferString(seq)
+		if _, comp := ts.parseKittyKey(buf, &evs); !comp || buf.Len() != 0 {
+			t.Fatalf("sequence %q not parsed", seq)
+		}
+		if len(evs) != 1 {
+			t.Fatalf("sequence %q gave %d events", seq, len(evs))
+		}
+		return evs[0].(*EventKey)
+	}
+
+	// Ctrl+Z on a QWERTY layout
+	ev := parse("\x1b[122;5u")
+	if ev.Key() != KeyCtrlZ || ev.BaseRune() != 'z' {
+		t.Errorf("wrong key %v base %q", ev.Name(), ev.BaseRune())
+	}
+	if !ev.MatchesRune('z', ModCtrl) || !ev.MatchesPhysical('z', ModCtrl) {
+		t.Errorf("Ctrl+Z did not match")
+	}
+
+	// Ctrl+Я on a Russian layout, which is the Z key
+	ev = parse("\x1b[1103::122;5u")
+	if ev.Key() != KeyRune || ev.Rune() != 'я' || ev.BaseRune() != 'z' {
+		t.Errorf("wrong key %v base %q", ev.Name(), ev.BaseRune())
+	}
+	if ev.MatchesRune('z', ModCtrl) || !ev.MatchesRune('я', ModCtrl) {
+		t.Errorf("wrong character match")
+	}
+	if !ev.MatchesPhysical('z', ModCtrl) || ev.MatchesPhysical('z', ModCtrl|ModShift) {
+		t.Errorf("wrong physical match")
+	}
+
+	// Alt+Shift+1 reports the shifted character
+	ev = parse("\x1b[49:33;4u")
+	if ev.Rune() != '!' || ev.Modifiers() != ModAlt|ModShift {
+		t.Errorf("wrong key %v", ev.Name())
+	}
+	if !ev.MatchesRune('!', ModAlt) || !ev.MatchesPhysical('1', ModAlt|ModShift) {
+		t.Errorf("Alt+! did not match")
+	}
+
+	// the Esc key, and a key release (which is not reported)
+	if ev = parse("\x1b[27u"); ev.Key() != KeyEsc {
+		t.Errorf("wrong key %v", ev.Name())
+	}
+	var evs []Event
+	if _, comp := ts.parseKittyKey(bytes.NewBufferString("\x1b[97;1:3u"), &evs); !comp || len(evs) != 0 {
+		t.Errorf("key release not discarded")
+	}
+
+	// without the physical key, matching uses the character
+	ev = NewEventKey(KeyRune, 1, ModNone)
+	if !ev.MatchesPhysical('a', ModCtrl) || ev.MatchesPhysical('b', ModCtrl) {
+		t.Errorf("legacy Ctrl+A did not match")
+	}
+}
+
+func TestRawSequences(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	ts, err := newTScreen(nil, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	ts.inputOpts.RawSequences = true
+
+	buf := bytes.NewBufferString("\x1b[?99x\x1b]99;hi\aa\x1bP1$r0m\x1b\\\x1b[A\x1b[12")
+	evs := ts.collectEventsFromInput(buf, false)
+	if len(evs) != 5 {
+		t.Fatalf("wrong number of events: %d", len(evs))
+	}
+	raws := map[int]string{0: "\x1b[?99x", 1: "\x1b]99;hi\a", 3: "\x1bP1$r0m\x1b\\"}
+	for i, want := range raws {
+		if ev, ok := evs[i].(*EventRaw); !ok || string(ev.Data()) != want || ev.Kind() != want[1] {
+			t.Errorf("event %d wrong: %#v", i, evs[i])
+		}
+	}
+	if ev, ok := evs[2].(*EventKey); !ok || ev.Rune() != 'a' {
+		t.Errorf("wrong key: %#v", evs[2])
+	}
+	if ev, ok := evs[4].(*EventKey); !ok || ev.Key() != KeyUp {
+		t.Errorf("wrong key: %#v", evs[4])
+	}
+	if buf.String() != "\x1b[12" {
+		t.Errorf("incomplete sequence not kept: %q", buf.String())
+	}
+}
+
+func TestFeatureReport(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	ts, err := newTScreen(nil, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	report := ts.FeatureReport()
+	if report[Feature256Color] != SupportAssumed {
+		t.Errorf("256 colors not assumed: %v", report[Feature256Color])
+	}
+	if report[FeatureKittyKeyboard] != SupportNone || report[FeatureGraphics] != SupportNone {
+		t.Errorf("unconfirmed features reported: %v", report)
+	}
+
+	buf := bytes.NewBufferString("\x1b[?1u\x1b[?62;4;22c")
+	if evs := ts.collectEventsFromInput(buf, false); len(evs) != 0 {
+		t.Errorf("replies reported as events: %v", evs)
+	}
+	report = ts.FeatureReport()
+	for _, f := range []Feature{FeatureKittyKeyboard, FeatureGraphics, FeatureColor} {
+		if report[f] != SupportConfirmed {
+			t.Errorf("%v not confirmed: %v", f, report[f])
+		}
+	}
+}
+
+func TestCursorStyleReply(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	ts, err := newTScreen(nil, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	ts.prepareCursorStyles()
+
+	buf := bytes.NewBufferString("\x1bP1$r6 q\x1b\\")
+	if evs := ts.collectEventsFromInput(buf, false); len(evs) != 0 {
+		t.Errorf("reply reported as events: %v", evs)
+	}
+	if ts.initCursor != CursorStyleSteadyBar {
+		t.Errorf("wrong initial cursor: %v", ts.initCursor)
+	}
+	if !strings.Contains(ts.restoreString(), "\x1b[6 q") {
+		t.Errorf("initial cursor not restored: %q", ts.restoreString())
+	}
+	if ts.cursorStyles[CursorStyleSteadyDefault] != "\x1b[0 q\x1b[?12l" {
+		t.Errorf("wrong steady cursor: %q", ts.cursorStyles[CursorStyleSteadyDefault])
+	}
+}
+
+func TestOSCEvents(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	ts, err := newTScreen(nil, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	ts.inputOpts.OSC = []int{1337, 7}
+
+	buf := bytes.NewBufferString("\x1b]1337;File=a;b\x1b\\\x1b]7\a\x1b]9;no\ax")
+	evs := ts.collectEventsFromInput(buf, true)
+	if len(evs) < 2 {
+		t.Fatalf("too few events: %v", evs)
+	}
+	if ev, ok := evs[0].(*EventOSC); !ok || ev.Code() != 1337 || ev.Data() != "File=a;b" {
+		t.Errorf("wrong first event: %#v", evs[0])
+	}
+	if ev, ok := evs[1].(*EventOSC); !ok || ev.Code() != 7 || ev.Data() != "" {
+		t.Errorf("wrong second event: %#v", evs[1])
+	}
+	for _, ev := range evs[2:] {
+		if _, ok := ev.(*EventOSC); ok {
+			t.Errorf("unrequested command reported: %#v", ev)
+		}
+	}
+
+	// an incomplete command waits for the rest
+	buf = bytes.NewBufferString("\x1b]13")
+	if evs := ts.collectEventsFromInput(buf, false); len(evs) != 0 {
+		t.Errorf("incomplete command reported: %v", evs)
+	}
+
+	if s := oscString(1337, "a\x1b]0;x\a"); s != "\x1b]1337;a]0;x\x1b\\" {
+		t.Errorf("wrong command string: %q", s)
+	}
+}
+
+func TestXTGetTCap(t *testing.T) {
+	if q := xtgettcapQuery([]string{"RGB", "Ms"}); q != "\x1bP+q524742;4d73\x1b\\" {
+		t.Errorf("wrong query: %q", q)
+	}
+	ti, err := LookupTerminfo("xterm")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	ts, err := newTScreen(nil, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	ts.ident.Capabilities = make(map[string]string)
+
+	// RGB as a boolean, Smulx with a value, and an unknown capability
+	in := "\x1bP1+r524742;536d756c78=1b5b343a25703125646d\x1b\\\x1bP0+r4d73\x1b\\"
+	for i := 1; i < strings.Index(in, "\x1b\\"); i++ {
+		// a partial reply must wait for the rest
+		buf := bytes.NewBufferString(in[:i])
+		if evs := ts.collectEventsFromInput(buf, false); len(evs) != 0 {
+			t.Fatalf("partial reply %q reported as events: %v", in[:i], evs)
+		}
+	}
+	buf := bytes.NewBufferString(in)
+	if evs := ts.collectEventsFromInput(buf, false); len(evs) != 0 {
+		t.Errorf("reply reported as events: %v", evs)
+	}
+	caps := ts.TerminalID().Capabilities
+	if v, ok := caps["RGB"]; !ok || v != "" {
+		t.Errorf("RGB not reported: %v", caps)
+	}
+	if caps["Smulx"] != "\x1b[4:%p1%dm" {
+		t.Errorf("wrong Smulx: %q", caps["Smulx"])
+	}
+	if _, ok := caps["Ms"]; ok {
+		t.Errorf("unknown capability reported: %v", caps)
+	}
+
+	ts.applyTermCaps()
+	if !ts.truecolor {
+		t.Errorf("true color not enabled")
+	}
+	if ts.curlyUnder != "\x1b[4:3m" {
+		t.Errorf("wrong curly underline: %q", ts.curlyUnder)
+	}
+}
+
+func TestClusterMode(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	ts, err := newTScreen(nil, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	tty := newRenderTty(10, 1)
+	ts.tty = tty
+	ts.cells.Resize(10, 1)
+
+	buf := bytes.NewBufferString("\x1b[?2027;2$y")
+	if evs := ts.collectEventsFromInput(buf, false); len(evs) != 0 {
+		t.Errorf("reply reported as events: %v", evs)
+	}
+	if ts.clusterMode != 2 {
+		t.Fatalf("wrong mode: %d", ts.clusterMode)
+	}
+	ts.enableClusters()
+	if !ts.setClusters || !ts.cells.clusters || tty.output() != enableClusters {
+		t.Errorf("clustering not enabled")
+	}
+	if !strings.Contains(ts.restoreString(), disableClusters) {
+		t.Errorf("clustering not restored: %q", ts.restoreString())
+	}
+	if ts.FeatureReport()[FeatureGraphemeClusters] != SupportConfirmed {
+		t.Errorf("clustering not reported")
+	}
+}
+
+func TestConsoleTerms(t *testing.T) {
+	cases := []struct {
+		term string
+		seq  string
+		key  Key
+	}{
+		{"cons25", "\x1b[M", KeyF1},
+		{"cons25", "\x1b[G", KeyPgDn},
+		{"wsvt25", "\x1b[11~", KeyF1},
+		{"wsvt25m", "\x1b[7~", KeyHome},
+		{"cons25", "\x1b[Y", KeyF13},
+		{"cons25", "\x1b[Z", KeyBacktab},
+		{"cons25", "\x1b[k", KeyF25},
+		{"pccon", "\x1b[8~", KeyEnd},
+	}
+	for _, c := range cases {
+		ti, err := LookupTerminfo(c.term)
+		if err != nil {
+			t.Fatalf("No terminfo for %s: %v", c.term, err)
+		}
+		ts, err := newTScreen(nil, ti)
+		if err != nil {
+			t.Fatalf("Failed to create screen: %v", err)
+		}
+		tty := newRenderTty(10, 1)
+		ts.tty = &envTty{tty, nil} // not in tmux
+		ts.identify()
+		if out := tty.output(); out != "" {
+			t.Errorf("%s: console queried: %q", c.term, out)
+		}
+		evs := ts.collectEventsFromInput(bytes.NewBufferString(c.seq), true)
+		if len(evs) != 1 {
+			t.Errorf("%s: expected one event for %q, got %v", c.term, c.seq, evs)
+		} else if ev, ok := evs[0].(*EventKey); !ok || ev.Key() != c.key {
+			t.Errorf("%s: wrong event for %q: %v", c.term, c.seq, evs[0])
+		}
+		if ts.hyperlinks() {
+			t.Errorf("%s: hyperlinks enabled", c.term)
+		}
+	}
+}
+
+func TestKeyVariants(t *testing.T) {
+	cases := []struct {
+		term string
+		seq  string
+		key  Key
+	}{
+		{"vt220", "\x1b[11~", KeyF1},
+		{"vt220", "\x1bOP", KeyF1},
+		{"vt220", "\x1b[15~", KeyF5},
+		{"vt220", "\x1b[7~", KeyHome},
+		{"vt220", "\x1b[4~", KeyEnd},
+		{"xterm", "\x1bOF", KeyEnd},
+		{"xterm", "\x1b[1~", KeyHome},
+	}
+	for _, c := range cases {
+		ti, err := LookupTerminfo(c.term)
+		if err != nil {
+			t.Fatalf("No terminfo for %s: %v", c.term, err)
+		}
+		ts, err := newTScreen(nil, ti)
+		if err != nil {
+			t.Fatalf("Failed to create screen: %v", err)
+		}
+		evs := ts.collectEventsFromInput(bytes.NewBufferString(c.seq), true)
+		if len(evs) != 1 {
+			t.Errorf("%s: expected one event for %q, got %v", c.term, c.seq, evs)
+		} else if ev, ok := evs[0].(*EventKey); !ok || ev.Key() != c.key {
+			t.Errorf("%s: wrong event for %q: %v", c.term, c.seq, evs[0])
+		}
+	}
+}
+
+// envTty is a renderTty with its own environment.
+type envTty struct {
+	*renderTty
+	env map[string]string
+}
+
+func (tty *envTty) Getenv(name string) string { return tty.env[name] }
+
+func TestGuessTerminfo(t *testing.T) {
+	tty := &envTty{newRenderTty(10, 1), map[string]string{"TERM": "no-such-term"}}
+
+	StrictTerminfo = true
+	_, err := newTScreen(tty, nil)
+	StrictTerminfo = false
+	if err == nil {
+		t.Fatalf("Strict lookup of unknown terminal succeeded")
+	}
+
+	ts, err := newTScreen(tty, nil)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	if !ts.guessed || ts.ti.Name != "no-such-term" || ts.ti.Colors != 8 {
+		t.Fatalf("Wrong guessed terminfo: %v %q %d", ts.guessed, ts.ti.Name, ts.ti.Colors)
+	}
+	if ti, _ := LookupTerminfo("xterm"); ti.Name != "xterm" {
+		t.Errorf("Database entry was changed: %q", ti.Name)
+	}
+
+	ts.identify()
+	if out := tty.output(); !strings.Contains(out, xtgettcapQuery(append(termCaps, guessCaps...))) {
+		t.Errorf("Colors not asked for: %q", out)
+	}
+	ts.ident.Capabilities["Co"] = "256"
+	ts.refineGuess()
+	if ts.Colors() != 256 || len(ts.palette) != 256 {
+		t.Errorf("Wrong colors after refining: %d %d", ts.Colors(), len(ts.palette))
+	}
+	if seq := ts.ti.TParm(ts.ti.SetFg, 100); seq != "\x1b[38;5;100m" {
+		t.Errorf("Wrong color sequence: %q", seq)
+	}
+
+	if guessTerminfo("dumb", tty.Getenv) != nil {
+		t.Errorf("Guessed terminfo for dumb terminal")
+	}
+}
+
+func TestProbeColors(t *testing.T) {
+	ti, err := LookupTerminfo("xterm")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	tty := &envTty{newRenderTty(10, 1), map[string]string{}}
+	ts, err := newTScreen(tty, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	ts.running = true
+	ts.quit = make(chan struct{})
+	ts.eventQ = make(chan Event, 10)
+	ts.ident.Capabilities = make(map[string]string)
+	if n := ts.Colors(); n != 8 {
+		t.Fatalf("Wrong colors at first: %d", n)
+	}
+
+	ts.ProbeColors()
+	if out := tty.output(); !strings.Contains(out, xtgettcapQuery([]string{"RGB"})+queryDA1) {
+		t.Errorf("Terminal not asked: %q", out)
+	}
+	in := bytes.NewBufferString("\x1bP1+r524742\x1b\\\x1b[?62;22c")
+	if evs := ts.collectEventsFromInput(in, false); len(evs) != 0 {
+		t.Errorf("Replies reported as events: %v", evs)
+	}
+	select {
+	case ev := <-ts.eventQ:
+		if ev, ok := ev.(*EventColors); !ok || ev.Colors() != 1<<24 {
+			t.Errorf("Wrong event: %v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("No event for change of colors")
+	}
+	if n := ts.Colors(); n != 1<<24 {
+		t.Errorf("Wrong colors after probe: %d", n)
+	}
+}
+
+// failTty is a renderTty that can be made to fail to write.
+type failTty struct {
+	*renderTty
+	fail int32 // accessed atomically
+}
+
+var errTtyGone = errors.New("tty gone")
+
+func (tty *failTty) Write(b []byte) (int, error) {
+	if atomic.LoadInt32(&tty.fail) != 0 {
+		return 0, errTtyGone
+	}
+	return tty.renderTty.Write(b)
+}
+
+func TestTtyFailure(t *testing.T) {
+	ti, err := LookupTerminfo("xterm")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	for _, retry := range []bool{false, true} {
+		tty := &failTty{renderTty: newRenderTty(10, 2)}
+		s, err := NewTerminfoScreenFromTtyTerminfo(tty, ti)
+		if err != nil {
+			t.Fatalf("Failed to create screen: %v", err)
+		}
+		s.SetDisplayOptions(DisplayOptions{Recover: retry})
+		if err := s.Init(); err != nil {
+			t.Fatalf("Failed to initialize screen: %v", err)
+		}
+		if err := s.Err(); err != nil {
+			t.Fatalf("Error at start: %v", err)
+		}
+
+		atomic.StoreInt32(&tty.fail, 1)
+		s.SetContent(0, 0, 'x', nil, StyleDefault)
+		s.Show()
+		if err := s.Err(); err != errTtyGone {
+			t.Errorf("Wrong error: %v", err)
+		}
+		atomic.StoreInt32(&tty.fail, 0)
+		tty.output()
+		s.Show()
+		if !retry && tty.output() != "" {
+			t.Errorf("Written to after failure")
+		}
+		for {
+			ev := s.PollEvent()
+			if ev, ok := ev.(*EventError); ok {
+				if !errors.Is(ev, errTtyGone) {
+					t.Errorf("Wrong error event: %v", ev)
+				}
+				break
+			}
+		}
+
+		if retry {
+			deadline := time.Now().Add(2 * time.Second)
+			for s.Err() != nil && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if err := s.Err(); err != nil {
+				t.Errorf("Not recovered: %v", err)
+			}
+		} else if s.Err() == nil {
+			t.Errorf("Recovered without the option")
+		}
+		s.Fini()
+	}
+
+	if err := readError(syscall.EIO); !errors.Is(err, ErrHangup) {
+		t.Errorf("EIO is not a hangup: %v", err)
+	}
+}
+
+func TestKeySpec(t *testing.T) {
+	cases := []struct {
+		spec string
+		key  Key
+		ch   rune
+		mod  ModMask
+	}{
+		{"Ctrl+Shift+F5", KeyF5, 0, ModCtrl | ModShift},
+		{"ctrl+a", KeyCtrlA, 0, ModCtrl},
+		{"Alt+x", KeyRune, 'x', ModAlt},
+		{"Alt+Rune[X]", KeyRune, 'X', ModAlt},
+		{"Ctrl++", KeyRune, '+', ModCtrl},
+		{"+", KeyRune, '+', ModNone},
+		{"PgDn", KeyPgDn, 0, ModNone},
+		{"Ctrl-Space", KeyCtrlSpace, 0, ModNone},
+		{"Meta+Key[1000,5]", Key(1000), 5, ModMeta},
+	}
+	for _, c := range cases {
+		k, ch, mod, err := ParseKeySpec(c.spec)
+		if err != nil {
+			t.Errorf("%q: %v", c.spec, err)
+			continue
+		}
+		if k != c.key || ch != c.ch || mod != c.mod {
+			t.Errorf("%q: got %d %q %d", c.spec, k, ch, mod)
+		}
+	}
+	for _, spec := range []string{"", "Ctrl+", "Hyper+A", "F99", "Rune[ab]"} {
+		if _, _, _, err := ParseKeySpec(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+
+	// the names of events can be parsed back
+	for k := range KeyNames {
+		for _, mod := range []ModMask{ModNone, ModCtrl, ModAlt | ModShift} {
+			name := FormatKeySpec(k, 0, mod)
+			if name != NewEventKey(k, 0, mod).Name() {
+				t.Errorf("%s: does not match event name", name)
+			}
+			if k2, _, mod2, err := ParseKeySpec(name); err != nil || k2 != k || mod2 != mod {
+				t.Errorf("%s: parsed as %d %d (%v)", name, k2, mod2, err)
+			}
+		}
+	}
+}
+
+func TestWin32Input(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	ts, err := newTScreen(nil, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+
+	// 'a' down and up, Shift+Tab, Up with Ctrl twice, and a lone Shift
+	buf := bytes.NewBufferString("\x1b[65;30;97;1;0;1_\x1b[65;30;97;0;0;1_" +
+		"\x1b[9;15;9;1;16;1_\x1b[38;72;0;1;8;2_\x1b[16;42;0;1;16_\x1b[65;30")
+	evs := ts.collectEventsFromInput(buf, false)
+	if len(evs) != 4 {
+		t.Fatalf("wrong number of events: %d", len(evs))
+	}
+	if ev, ok := evs[0].(*EventKey); !ok || ev.Key() != KeyRune || ev.Rune() != 'a' || ev.BaseRune() != 'a' {
+		t.Errorf("wrong key: %#v", evs[0])
+	}
+	if ev, ok := evs[1].(*EventKey); !ok || ev.Key() != KeyBacktab {
+		t.Errorf("wrong key: %#v", evs[1])
+	}
+	for _, e := range evs[2:] {
+		if ev, ok := e.(*EventKey); !ok || ev.Key() != KeyUp || ev.Modifiers() != ModCtrl {
+			t.Errorf("wrong key: %#v", e)
+		}
+	}
+	if buf.String() != "\x1b[65;30" {
+		t.Errorf("incomplete sequence not kept: %q", buf.String())
+	}
+}
+
+// testLogger keeps the messages logged.
+type testLogger struct {
+	msgs []string
+	args [][]interface{}
+	l    sync.Mutex
+}
+
+func (tl *testLogger) Debug(msg string, args ...interface{}) {
+	tl.l.Lock()
+	defer tl.l.Unlock()
+	tl.msgs = append(tl.msgs, msg)
+	tl.args = append(tl.args, args)
+}
+
+func (tl *testLogger) find(msg string) []interface{} {
+	tl.l.Lock()
+	defer tl.l.Unlock()
+	for i, m := range tl.msgs {
+		if m == msg {
+			return tl.args[i]
+		}
+	}
+	return nil
+}
+
+func TestLogger(t *testing.T) {
+	tl := &testLogger{}
+	SetLogger(tl)
+	defer SetLogger(nil)
+
+	tty := &envTty{newRenderTty(10, 1), map[string]string{"TERM": "no-such-term"}}
+	ts, err := newTScreen(tty, nil)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	if args := tl.find("terminfo not found, guessing"); len(args) < 2 || args[1] != "no-such-term" {
+		t.Errorf("Guess not logged: %v", args)
+	}
+
+	ts.charset = "US-ASCII"
+	if b := ts.encodeRune('中', nil); string(b) != "?" {
+		t.Errorf("Wrong encoding: %q", b)
+	}
+	if args := tl.find("cannot display character"); len(args) < 4 || args[1] != '中' || args[3] != "US-ASCII" {
+		t.Errorf("Missing character not logged: %v", args)
+	}
+
+	SetLogger(nil)
+	n := len(tl.msgs)
+	ts.encodeRune('中', nil)
+	if len(tl.msgs) != n {
+		t.Errorf("Logged after logger removed: %v", tl.msgs[n:])
+	}
+}
+
+func TestPostEventFunc(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+
+	// the source is held back until its events are taken
+	produced := make(chan int, 10)
+	n := 0
+	s.PostEventFunc(func() Event {
+		if n == 3 {
+			return nil
+		}
+		n++
+		produced <- n
+		return NewEventInterrupt(n)
+	})
+	if n := <-produced; n != 1 {
+		t.Errorf("wrong first event produced: %d", n)
+	}
+
+	// input is delivered before the events of sources; the key is
+	// queued before InjectKey returns
+	s.InjectKey(KeyRune, 'a', ModNone)
+	var got []interface{}
+	for i := 0; i < 4; i++ {
+		switch ev := s.PollEvent().(type) {
+		case *EventKey:
+			got = append(got, ev.Rune())
+			// nothing has been taken from the source yet
+			if len(produced) != 0 {
+				t.Errorf("source not held back: %d more events produced", len(produced))
+			}
+		case *EventInterrupt:
+			got = append(got, ev.Data())
+		}
+	}
+	if !reflect.DeepEqual(got, []interface{}{'a', 1, 2, 3}) {
+		t.Errorf("wrong events: %v", got)
+	}
+
+	// events that come too late are discarded
+	release := make(chan struct{})
+	s.PostEventFunc(func() Event {
+		<-release
+		return NewEventInterrupt(nil)
+	})
+	s.Fini()
+	close(release)
+	if ev := s.PollEvent(); ev != nil {
+		t.Errorf("event after Fini: %v", ev)
+	}
+}
+
+func TestTabExtras(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	for _, prog := range []string{"iTerm.app", "Apple_Terminal"} {
+		tty := &envTty{newRenderTty(10, 1), map[string]string{"TERM_PROGRAM": prog}}
+		s, err := NewTerminfoScreenFromTtyTerminfo(tty, ti)
+		if err != nil {
+			t.Fatalf("Failed to create screen: %v", err)
+		}
+		if err := s.Init(); err != nil {
+			t.Fatalf("Failed to initialize screen: %v", err)
+		}
+		tty.output()
+		iterm := prog == "iTerm.app"
+		if sup := s.FeatureReport()[FeatureTabColor]; (sup != SupportNone) != iterm {
+			t.Errorf("%s: wrong tab color support: %v", prog, sup)
+		}
+
+		s.SetTabColor(NewRGBColor(0x10, 0x20, 0x30))
+		s.SetBadge(`a\b`)
+		out := tty.output()
+		expected := "\x1b]6;1;bg;red;brightness;16\x1b\\" +
+			"\x1b]6;1;bg;green;brightness;32\x1b\\" +
+			"\x1b]6;1;bg;blue;brightness;48\x1b\\" +
+			"\x1b]1337;SetBadgeFormat=YVxcYg==\x1b\\"
+		if !iterm {
+			expected = ""
+		}
+		if out != expected {
+			t.Errorf("%s: wrong output: %q", prog, out)
+		}
+
+		s.Fini()
+		out = tty.output()
+		reset := strings.Contains(out, "\x1b]6;1;bg;*;default\x1b\\") &&
+			strings.Contains(out, "\x1b]1337;SetBadgeFormat=\x1b\\")
+		if reset != iterm {
+			t.Errorf("%s: wrong reset on exit: %q", prog, out)
+		}
+	}
+}
+
+func TestConsoleVtInput(t *testing.T) {
+	v := newConsoleVtInput()
+	if v == nil {
+		t.Skipf("No terminfo for xterm")
+	}
+	scan := func(input string) []string {
+		var evs []Event
+		runes := []rune(input)
+		for i, r := range runes {
+			evs = append(evs, v.scan(r, i < len(runes)-1, false)...)
+		}
+		return describeEvents(evs)
+	}
+	// consoles that do not know win32-input-mode send the usual sequences
+	if evs := scan("\x1b[A\x1bOQx"); !reflect.DeepEqual(evs, []string{"Up", "F2", "Rune[x]"}) {
+		t.Errorf("wrong legacy keys: %v", evs)
+	}
+	if evs := scan("\x1b[65;30;97;1;0;1_"); !reflect.DeepEqual(evs, []string{"Rune[a]"}) {
+		t.Errorf("wrong win32-input-mode key: %v", evs)
+	}
+	// a lone ESC, with nothing after it, is the Esc key
+	if evs := scan("\x1b"); !reflect.DeepEqual(evs, []string{"Esc"}) {
+		t.Errorf("wrong lone ESC: %v", evs)
+	}
+}
+
+func TestParseCursorPosition(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	ts, err := newTScreen(nil, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+
+	q := make(chan cursorReport, 1)
+	ts.cprQ = q
+	if part, comp := ts.parseCursorPosition(bytes.NewBufferString("\x1b[12;3")); !part || comp {
+		t.Errorf("partial report: %v %v", part, comp)
+	}
+	if part, _ := ts.parseCursorPosition(bytes.NewBufferString("\x1b[12;3H")); part {
+		t.Errorf("other sequence taken as a report")
+	}
+	buf := bytes.NewBufferString("\x1b[12;3Rx")
+	if _, comp := ts.parseCursorPosition(buf); !comp || buf.String() != "x" {
+		t.Fatalf("report not parsed: %v %q", comp, buf.String())
+	}
+	select {
+	case cr := <-q:
+		if cr.x != 2 || cr.y != 11 {
+			t.Errorf("wrong position: %d,%d", cr.x, cr.y)
+		}
+	default:
+		t.Errorf("position not reported")
+	}
+	if ts.cprQ != nil {
+		t.Errorf("report still wanted")
+	}
+}
+
+// cprTty is a renderTty that also answers cursor position queries.
+type cprTty struct {
+	*renderTty
+}
+
+func (tty cprTty) Write(b []byte) (int, error) {
+	n, err := tty.renderTty.Write(b)
+	if bytes.Contains(b, []byte(queryCursor)) {
+		tty.l.Lock()
+		x, y := tty.cx, tty.cy
+		tty.l.Unlock()
+		tty.input <- []byte(fmt.Sprintf("\x1b[%d;%dR", y+1, x+1))
+	}
+	return n, err
+}
+
+func TestInlineAnchor(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	cases := []struct {
+		name  string
+		lines int // lines of output before the screen starts
+		yoff  int // where the region starts
+		first string
+	}{
+		{"room", 2, 2, "line 1"},
+		{"scrolled", 5, 3, "line 3"},
+	}
+	for _, c := range cases {
+		tty := cprTty{newRenderTty(10, 6)}
+		for i := 1; i <= c.lines; i++ {
+			_, _ = tty.renderTty.Write([]byte(fmt.Sprintf("line %d\r\n", i)))
+		}
+		ts, err := newTScreen(tty, ti)
+		if err != nil {
+			t.Fatalf("Failed to create screen: %v", err)
+		}
+		ts.opts.InlineRows = 3
+		ts.anchored = true
+		s := &baseScreen{screenImpl: ts}
+		if err := s.Init(); err != nil {
+			t.Fatalf("Failed to initialize screen: %v", err)
+		}
+		s.SetContent(0, 0, 'X', nil, StyleDefault)
+		s.Show()
+		ts.Lock()
+		yoff := ts.yoff
+		ts.Unlock()
+		lines := tty.contents()
+		s.Fini()
+
+		if yoff != c.yoff {
+			t.Errorf("%s: region at %d, expected %d", c.name, yoff, c.yoff)
+		}
+		if !strings.HasPrefix(lines[c.yoff], "X") {
+			t.Errorf("%s: not drawn at the region: %q", c.name, lines)
+		}
+		if strings.TrimSpace(lines[0]) != c.first {
+			t.Errorf("%s: earlier output moved: %q", c.name, lines)
+		}
+	}
+}
+
+// silentTty is a renderTty that does not answer the device attributes
+// query, as for a terminal that is slow, or does not answer at all.
+type silentTty struct {
+	*renderTty
+}
+
+func (tty silentTty) Write(b []byte) (int, error) {
+	_, err := tty.renderTty.Write(bytes.Replace(b, []byte(queryDA1), nil, -1))
+	return len(b), err
+}
+
+func TestIdentifyLate(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	tty := silentTty{newRenderTty(10, 2)}
+	s, err := NewTerminfoScreenFromTtyTerminfo(tty, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	start := time.Now()
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	if d := time.Since(start); d >= 200*time.Millisecond {
+		t.Errorf("Init waited for the terminal: %v", d)
+	}
+	if id := s.TerminalID(); id.Name != "" {
+		t.Errorf("identified without a reply: %+v", id)
+	}
+
+	// the replies are still used when they come
+	tty.input <- []byte("\x1bP>|XTerm(388)\x1b\\\x1b[?64;22c")
+	deadline := time.After(2 * time.Second)
+	for s.TerminalID().Class != 64 {
+		select {
+		case <-deadline:
+			t.Fatalf("late reply not used: %+v", s.TerminalID())
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	if id := s.TerminalID(); id.Name != "XTerm" || id.Version != "388" {
+		t.Errorf("wrong identity: %+v", id)
+	}
+}
+
+func TestIdentifyLateColors(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	tty := silentTty{newRenderTty(10, 2)}
+	s, err := NewTerminfoScreenFromTtyTerminfo(tty, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	if s.Colors() == 1<<24 {
+		t.Skip("truecolor from the environment")
+	}
+	s.SetContent(0, 0, 'X', nil, StyleDefault.Foreground(NewRGBColor(1, 2, 3)))
+	s.Show()
+	tty.output()
+
+	// a late reply that the terminal has truecolor redraws what is there
+	tty.input <- []byte("\x1bP1+r524742\x1b\\\x1b[?64;22c")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for {
+		ev := s.PollEventContext(ctx)
+		if ev == nil {
+			t.Fatalf("no colors event")
+		}
+		if _, ok := ev.(*EventColors); ok {
+			break
+		}
+	}
+	s.Show()
+	if out := tty.output(); !strings.Contains(out, "38;2;1;2;3") {
+		t.Errorf("not redrawn in truecolor: %q", out)
+	}
+}
+
+func TestRestoreWriter(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	f, err := ioutil.TempFile("", "restore")
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	restore := func() string {
+		b, _ := ioutil.ReadFile(f.Name())
+		return string(b)
+	}
+
+	tty := newRenderTty(10, 2)
+	s, err := NewTerminfoScreenFromTtyTerminfo(tty, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	s.SetRestoreWriter(f)
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	if r := restore(); !strings.Contains(r, "\x1b[?1049l") || strings.Contains(r, "\x1b[<u") {
+		t.Errorf("wrong initial restore: %q", r)
+	}
+
+	// modes turned on later are turned off too
+	s.SetInputOptions(InputOptions{AlternateKeys: true, Locator: true})
+	s.EnableMouse()
+	r := restore()
+	for _, seq := range []string{"\x1b[<u", "\x1b[0'z"} {
+		if !strings.Contains(r, seq) {
+			t.Errorf("%q not in restore: %q", seq, r)
+		}
+	}
+	if strings.Count(r, "\x1b[?1049l") != 1 {
+		t.Errorf("restore not rewritten: %q", r)
+	}
+
+	s.SetInputOptions(InputOptions{})
+	if r := restore(); strings.Contains(r, "\x1b[<u") || !strings.Contains(r, "\x1b[?1003l") {
+		t.Errorf("restore not updated: %q", r)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+)
+
+// ttyDevice is implemented by ttys that are opened from a device, so
+// that it can be opened again for a subprocess.
+type ttyDevice interface {
+	device() string
+}
+
+func (b *baseScreen) Exec(cmd *exec.Cmd) error {
+	dev := ""
+	if tty, ok := b.Tty(); ok {
+		if td, ok := tty.(ttyDevice); ok {
+			dev = td.device()
+		}
+	}
+	if err := b.Suspend(); err != nil {
+		return err
+	}
+	err := runAttached(cmd, dev)
+	if rerr := b.Resume(); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// runAttached runs cmd with the terminal device dev (or the standard
+// files, if there is no device) for any of its standard files that are
+// not set, and waits for it to finish.  While it runs, signals that the
+// terminal sends to the whole foreground process group (such as SIGINT
+// for Ctrl-C) are left for the command to act on, rather than stopping
+// this program, and signals sent only to this program (such as SIGTERM)
+// are passed on to the command.
+func runAttached(cmd *exec.Cmd, dev string) error {
+	if dev != "" && (cmd.Stdin == nil || cmd.Stdout == nil || cmd.Stderr == nil) {
+		if f, err := os.OpenFile(dev, os.O_RDWR, 0); err == nil {
+			defer f.Close()
+			if cmd.Stdin == nil {
+				cmd.Stdin = f
+			}
+			if cmd.Stdout == nil {
+				cmd.Stdout = f
+			}
+			if cmd.Stderr == nil {
+				cmd.Stderr = f
+			}
+		}
+	}
+	if cmd.Stdin == nil {
+		cmd.Stdin = os.Stdin
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+
+	sigs := make(chan os.Signal, 4)
+	signal.Notify(sigs, execSignals...)
+	defer signal.Stop(sigs)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				if forwardSignal(sig) {
+					_ = cmd.Process.Signal(sig)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	err := cmd.Wait()
+	close(done)
+	return err
+}
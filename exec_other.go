@@ -0,0 +1,31 @@
+//go:build windows || nacl || plan9 || js
+// +build windows nacl plan9 js
+
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"os"
+)
+
+// execSignals are the signals caught while running a command with Exec.
+// The console delivers Ctrl-C to the command as well, so there is no need
+// to pass it on.
+var execSignals = []os.Signal{os.Interrupt}
+
+func forwardSignal(os.Signal) bool {
+	return false
+}
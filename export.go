@@ -0,0 +1,163 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// sgrColor returns the SGR parameters for a color, using base for the
+// parameter of the first eight palette colors (30 for foreground, 40
+// for background, or 0 for the underline color, which has no short form).
+func sgrColor(c Color, base int) string {
+	if !c.Valid() || c&ColorSpecial != 0 {
+		return ""
+	}
+	ext := base + 8
+	if base == 0 {
+		ext = 58
+	}
+	if c.IsRGB() {
+		r, g, b := c.RGB()
+		return fmt.Sprintf("%d;2;%d;%d;%d", ext, r, g, b)
+	}
+	idx := int(c &^ ColorValid)
+	switch {
+	case base != 0 && idx < 8:
+		return fmt.Sprintf("%d", base+idx)
+	case base != 0 && idx < 16:
+		return fmt.Sprintf("%d", base+60+idx-8)
+	}
+	return fmt.Sprintf("%d;5;%d", ext, idx)
+}
+
+// sgrString returns a complete SGR sequence for the style, starting
+// with a reset so that it does not depend on the previous style.
+func sgrString(s Style) string {
+	params := []string{"0"}
+	attrs := []struct {
+		mask  AttrMask
+		param string
+	}{
+		{AttrBold, "1"},
+		{AttrDim, "2"},
+		{AttrItalic, "3"},
+		{AttrBlink, "5"},
+		{AttrReverse, "7"},
+		{AttrStrikeThrough, "9"},
+	}
+	for _, a := range attrs {
+		if s.attrs&a.mask != 0 {
+			params = append(params, a.param)
+		}
+	}
+	switch s.ulStyle {
+	case UnderlineStyleNone:
+		if s.attrs&AttrUnderline != 0 {
+			params = append(params, "4")
+		}
+	case UnderlineStyleSolid:
+		params = append(params, "4")
+	default:
+		params = append(params, fmt.Sprintf("4:%d", s.ulStyle))
+	}
+	bg := s.bg
+	if s.attrs&AttrTransparent != 0 {
+		bg = ColorDefault
+	}
+	for _, c := range []string{sgrColor(s.fg, 30), sgrColor(bg, 40), sgrColor(s.ulColor, 0)} {
+		if c != "" {
+			params = append(params, c)
+		}
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+func (b *baseScreen) ExportRegion(x, y, width, height int) []byte {
+	cells := b.GetCells()
+	b.Lock()
+	defer b.Unlock()
+
+	// only the part of the region on the screen is exported
+	sw, sh := cells.Size()
+	x1, y1 := x+width, y+height
+	if x < 0 {
+		x = 0
+	}
+	if y < 0 {
+		y = 0
+	}
+	if x1 > sw {
+		x1 = sw
+	}
+	if y1 > sh {
+		y1 = sh
+	}
+
+	out := &bytes.Buffer{}
+	style := StyleDefault
+	url := ""
+	for row := y; row < y1; row++ {
+		if row > y {
+			out.WriteByte('\n')
+		}
+		// trailing blanks in the default style are not needed
+		end := x1
+		for end > x {
+			mainc, combc, st, _ := cells.GetContent(end-1, row)
+			if mainc != ' ' || len(combc) != 0 || st != StyleDefault {
+				break
+			}
+			end--
+		}
+		for col := x; col < end; {
+			mainc, combc, st, w := cells.GetContent(col, row)
+			if st.url != url {
+				if st.url == "" {
+					out.WriteString("\x1b]8;;\x1b\\")
+				} else {
+					id := st.urlId
+					if id == "" {
+						id = autoUrlId(st.url)
+					}
+					fmt.Fprintf(out, "\x1b]8;%s;%s\x1b\\", id, st.url)
+				}
+				url = st.url
+			}
+			st.url, st.urlId = "", ""
+			if st != style {
+				out.WriteString(sgrString(st))
+				style = st
+			}
+			out.WriteRune(mainc)
+			for _, r := range combc {
+				out.WriteRune(r)
+			}
+			if w < 1 {
+				w = 1
+			}
+			col += w
+		}
+	}
+	if url != "" {
+		out.WriteString("\x1b]8;;\x1b\\")
+	}
+	if style != StyleDefault {
+		out.WriteString("\x1b[0m")
+	}
+	return out.Bytes()
+}
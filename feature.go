@@ -0,0 +1,142 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"fmt"
+	"time"
+)
+
+// Feature is an optional capability of a terminal, as reported by
+// Screen.FeatureReport.
+type Feature int
+
+const (
+	FeatureColor            Feature = iota // at least 8 colors
+	Feature256Color                        // at least 256 colors
+	FeatureTrueColor                       // 24-bit RGB colors
+	FeatureMouse                           // mouse reporting
+	FeatureBracketedPaste                  // pastes are distinguished from typing
+	FeatureFocus                           // focus reporting
+	FeatureKittyKeyboard                   // the kitty keyboard protocol
+	FeatureGraphics                        // sixel graphics
+	FeatureHyperlinks                      // OSC 8 hyperlinks
+	FeatureClipboard                       // setting the clipboard with OSC 52
+	FeatureTitle                           // setting the window title
+	FeatureCursorStyle                     // changing the cursor shape
+	FeatureStyledUnderline                 // curly, dotted, and other underlines
+	FeatureUnderlineColor                  // colored underlines
+	FeatureGraphemeClusters                // grapheme cluster widths (mode 2027)
+	FeatureTabColor                        // setting the tab color (iTerm2)
+	FeatureBadge                           // setting the badge (iTerm2)
+)
+
+var featureNames = map[Feature]string{
+	FeatureColor:            "Color",
+	Feature256Color:         "256Color",
+	FeatureTrueColor:        "TrueColor",
+	FeatureMouse:            "Mouse",
+	FeatureBracketedPaste:   "BracketedPaste",
+	FeatureFocus:            "Focus",
+	FeatureKittyKeyboard:    "KittyKeyboard",
+	FeatureGraphics:         "Graphics",
+	FeatureHyperlinks:       "Hyperlinks",
+	FeatureClipboard:        "Clipboard",
+	FeatureTitle:            "Title",
+	FeatureCursorStyle:      "CursorStyle",
+	FeatureStyledUnderline:  "StyledUnderline",
+	FeatureUnderlineColor:   "UnderlineColor",
+	FeatureGraphemeClusters: "GraphemeClusters",
+	FeatureTabColor:         "TabColor",
+	FeatureBadge:            "Badge",
+}
+
+func (f Feature) String() string {
+	if name, ok := featureNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("Feature%d", int(f))
+}
+
+// Support is how certain it is that a feature works.
+type Support int
+
+const (
+	// SupportNone means that the feature is not available.
+	SupportNone Support = iota
+
+	// SupportAssumed means that the feature is expected to work, based on
+	// the terminfo entry or the environment, but the terminal has not
+	// confirmed it.  Terminals often ignore sequences they do not
+	// understand, so it is usually safe to try such features.
+	SupportAssumed
+
+	// SupportConfirmed means that the terminal reported that it has the
+	// feature, in reply to a query.
+	SupportConfirmed
+)
+
+func (s Support) String() string {
+	switch s {
+	case SupportNone:
+		return "none"
+	case SupportAssumed:
+		return "assumed"
+	case SupportConfirmed:
+		return "confirmed"
+	}
+	return fmt.Sprintf("Support%d", int(s))
+}
+
+// supportIf returns SupportAssumed if the condition is true, for
+// building feature reports.
+func supportIf(cond bool) Support {
+	if cond {
+		return SupportAssumed
+	}
+	return SupportNone
+}
+
+// colorSupport adds the color features for a screen with the given
+// number of colors to the report.
+func colorSupport(report map[Feature]Support, colors int) {
+	report[FeatureColor] = supportIf(colors >= 8)
+	report[Feature256Color] = supportIf(colors >= 256)
+	report[FeatureTrueColor] = supportIf(colors >= 1<<24)
+}
+
+// EventColors is posted when the number of colors of the screen changes
+// after Screen.ProbeColors.  Applications that pick their colors based on
+// Screen.Colors can use it to pick them again.
+type EventColors struct {
+	t      time.Time
+	colors int
+}
+
+// NewEventColors returns an EventColors for a screen now with the given
+// number of colors.
+func NewEventColors(colors int) *EventColors {
+	return &EventColors{t: time.Now(), colors: colors}
+}
+
+// When returns the time when the Event was created.
+func (ev *EventColors) When() time.Time {
+	return ev.t
+}
+
+// Colors returns the number of colors, as Screen.Colors does.
+func (ev *EventColors) Colors() int {
+	return ev.colors
+}
@@ -0,0 +1,183 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+// GlyphSet is a set of characters used to draw gauges, such as progress
+// bars and sparklines.  Sets that divide cells more finely draw smoother
+// gauges, but need Unicode support in the terminal and its font.
+type GlyphSet int
+
+const (
+	// GlyphsASCII uses only ASCII characters, such as '#'.
+	GlyphsASCII = GlyphSet(iota)
+
+	// GlyphsBlocks uses the block elements, which divide a cell into
+	// eighths, horizontally or vertically.
+	GlyphsBlocks
+
+	// GlyphsBraille also uses the braille patterns, which divide a cell
+	// into a grid of two by four dots, to fit two values of a sparkline
+	// in each cell.  Progress bars use block elements.
+	GlyphsBraille
+)
+
+// DetectGlyphs returns the best glyph set that the screen can display.
+func DetectGlyphs(s Screen) GlyphSet {
+	blocks := s.CanDisplay('▏', false) && s.CanDisplay('▁', false) && s.CanDisplay(RuneBlock, false)
+	switch {
+	case blocks && s.CanDisplay('⣿', false):
+		return GlyphsBraille
+	case blocks:
+		return GlyphsBlocks
+	}
+	return GlyphsASCII
+}
+
+// ProgressRunes returns the characters of a progress bar that is width
+// cells wide, filled to the fraction (which is between 0 and 1).  Block
+// elements fill the last cell partially, to an eighth of a cell.
+func ProgressRunes(width int, fraction float64, glyphs GlyphSet) []rune {
+	if width <= 0 {
+		return nil
+	}
+	if fraction < 0 {
+		fraction = 0
+	} else if fraction > 1 {
+		fraction = 1
+	}
+	runes := make([]rune, width)
+	per := 8
+	if glyphs == GlyphsASCII {
+		per = 1
+	}
+	filled := int(fraction*float64(width*per) + 0.5)
+	for i := range runes {
+		switch n := filled - i*per; {
+		case n >= per && glyphs == GlyphsASCII:
+			runes[i] = '#'
+		case n >= per:
+			runes[i] = RuneBlock
+		case n > 0:
+			runes[i] = rune(0x2590 - n) // left eighths, '▏' through '▉'
+		default:
+			runes[i] = ' '
+		}
+	}
+	return runes
+}
+
+// braille dots for a bar of a sparkline, from the bottom up, in the left
+// and right columns of a cell
+var (
+	brailleLeft  = [...]rune{0x40, 0x04, 0x02, 0x01}
+	brailleRight = [...]rune{0x80, 0x20, 0x10, 0x08}
+)
+
+// SparklineRunes returns the characters of a sparkline, a small bar chart
+// of the values, that is width cells wide and height rows tall.  The rows
+// are returned from the top down.  Bars reach the top at the value max, or
+// at the largest of the values if max is zero (or less).  If there are
+// more values than fit, the last ones are shown.  Braille patterns fit two
+// values in each cell; other glyph sets fit one.
+func SparklineRunes(width, height int, values []float64, max float64, glyphs GlyphSet) [][]rune {
+	if width <= 0 || height <= 0 {
+		return nil
+	}
+	slots, per := width, 8
+	switch glyphs {
+	case GlyphsASCII:
+		per = 2
+	case GlyphsBraille:
+		slots, per = width*2, 4
+	}
+	if len(values) > slots {
+		values = values[len(values)-slots:]
+	}
+	if max <= 0 {
+		for _, v := range values {
+			if v > max {
+				max = v
+			}
+		}
+	}
+	levels := make([]int, len(values))
+	for i, v := range values {
+		if max > 0 && v > 0 {
+			levels[i] = int(v/max*float64(height*per) + 0.5)
+		}
+	}
+
+	rows := make([][]rune, height)
+	for row := range rows {
+		rows[row] = make([]rune, width)
+		base := (height - 1 - row) * per // level at the bottom of the row
+		for col := range rows[row] {
+			r := ' '
+			switch glyphs {
+			case GlyphsBraille:
+				var dots rune
+				for side, cols := range [][4]rune{brailleLeft, brailleRight} {
+					if i := col*2 + side; i < len(levels) {
+						for d := 0; d < levels[i]-base && d < per; d++ {
+							dots |= cols[d]
+						}
+					}
+				}
+				if dots != 0 {
+					r = 0x2800 | dots
+				}
+			case GlyphsASCII:
+				if col < len(levels) {
+					switch n := levels[col] - base; {
+					case n >= per:
+						r = '|'
+					case n > 0:
+						r = '.'
+					}
+				}
+			default:
+				if col < len(levels) {
+					switch n := levels[col] - base; {
+					case n >= per:
+						r = RuneBlock
+					case n > 0:
+						r = rune(0x2580 + n) // lower eighths, '▁' through '▇'
+					}
+				}
+			}
+			rows[row][col] = r
+		}
+	}
+	return rows
+}
+
+// DrawProgress draws a progress bar (see ProgressRunes) at the given
+// position.  The unfilled part of the bar is drawn with spaces, so the
+// background of the style shows the track of the bar.
+func DrawProgress(t DrawTarget, x, y, width int, fraction float64, style Style, glyphs GlyphSet) {
+	for i, r := range ProgressRunes(width, fraction, glyphs) {
+		t.SetContent(x+i, y, r, nil, style)
+	}
+}
+
+// DrawSparkline draws a sparkline (see SparklineRunes) with its top left
+// corner at the given position.
+func DrawSparkline(t DrawTarget, x, y, width, height int, values []float64, max float64, style Style, glyphs GlyphSet) {
+	for row, runes := range SparklineRunes(width, height, values, max, glyphs) {
+		for col, r := range runes {
+			t.SetContent(x+col, y+row, r, nil, style)
+		}
+	}
+}
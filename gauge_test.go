@@ -0,0 +1,85 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"testing"
+)
+
+func TestProgressRunes(t *testing.T) {
+	cases := []struct {
+		width    int
+		fraction float64
+		glyphs   GlyphSet
+		expect   string
+	}{
+		{4, 0, GlyphsBlocks, "    "},
+		{4, 0.5, GlyphsBlocks, "██  "},
+		{4, 0.5 + 3.0/32, GlyphsBlocks, "██▍ "},
+		{4, 2, GlyphsBraille, "████"},
+		{4, 0.6, GlyphsASCII, "##  "},
+		{0, 0.5, GlyphsASCII, ""},
+	}
+	for _, c := range cases {
+		if got := string(ProgressRunes(c.width, c.fraction, c.glyphs)); got != c.expect {
+			t.Errorf("progress %d %v %v: got %q, expected %q", c.width, c.fraction, c.glyphs, got, c.expect)
+		}
+	}
+}
+
+func TestSparklineRunes(t *testing.T) {
+	values := []float64{0, 1, 2, 4, 8, 16}
+	check := func(rows [][]rune, expect ...string) {
+		t.Helper()
+		if len(rows) != len(expect) {
+			t.Fatalf("got %d rows, expected %d", len(rows), len(expect))
+		}
+		for i, row := range rows {
+			if string(row) != expect[i] {
+				t.Errorf("row %d: got %q, expected %q", i, string(row), expect[i])
+			}
+		}
+	}
+	check(SparklineRunes(6, 1, values, 0, GlyphsBlocks), " ▁▁▂▄█")
+	check(SparklineRunes(4, 2, values, 16, GlyphsBlocks), "   █", "▂▄██")
+	check(SparklineRunes(3, 1, values, 0, GlyphsBraille), " ⣀⣼")
+	check(SparklineRunes(3, 1, values, 0, GlyphsASCII), "..|")
+	check(SparklineRunes(3, 1, nil, 0, GlyphsBlocks), "   ")
+}
+
+func TestDetectGlyphs(t *testing.T) {
+	s := NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	if g := DetectGlyphs(s); g != GlyphsBraille {
+		t.Errorf("UTF-8 screen should show braille, got %v", g)
+	}
+	s.SetSize(10, 2)
+	DrawProgress(s, 1, 0, 4, 1, StyleDefault, GlyphsBlocks)
+	if r, _, _, _ := s.GetContent(4, 0); r != RuneBlock {
+		t.Errorf("progress not drawn: %q", r)
+	}
+
+	a := NewSimulationScreen("US-ASCII")
+	if err := a.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer a.Fini()
+	if g := DetectGlyphs(a); g != GlyphsASCII {
+		t.Errorf("ASCII screen should use ASCII, got %v", g)
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"time"
+)
+
+// EventClick is a higher level mouse event, synthesized by a GestureDetector
+// when a mouse button is pressed and released without moving (much).
+// Successive clicks in quick succession at the same place are counted, so
+// that a double click has a count of two, a triple click three, and so on.
+type EventClick struct {
+	t     time.Time
+	btn   ButtonMask
+	mod   ModMask
+	x     int
+	y     int
+	count int
+}
+
+// NewEventClick returns an EventClick, for example to simulate clicks in
+// tests.
+func NewEventClick(x, y int, btn ButtonMask, mod ModMask, count int) *EventClick {
+	return &EventClick{t: time.Now(), btn: btn, mod: mod, x: x, y: y, count: count}
+}
+
+// When returns the time when the button was released.
+func (ev *EventClick) When() time.Time {
+	return ev.t
+}
+
+// Button returns the button that was clicked.
+func (ev *EventClick) Button() ButtonMask {
+	return ev.btn
+}
+
+// Modifiers returns the keyboard modifiers that were pressed with the button.
+func (ev *EventClick) Modifiers() ModMask {
+	return ev.mod
+}
+
+// Position returns the position of the click in character cells.
+func (ev *EventClick) Position() (int, int) {
+	return ev.x, ev.y
+}
+
+// Count returns the number of clicks in this sequence; 1 for a single
+// click, 2 for a double click, 3 for a triple click, and so forth.
+func (ev *EventClick) Count() int {
+	return ev.count
+}
+
+// DragPhase indicates which part of a drag gesture an EventDrag represents.
+type DragPhase int
+
+const (
+	DragStart = DragPhase(iota) // The pointer has moved with a button held down.
+	DragMove                    // The pointer moved further during the drag.
+	DragEnd                     // The button was released, ending the drag.
+)
+
+// EventDrag is a higher level mouse event, synthesized by a GestureDetector
+// when the mouse is moved with a button held down.
+type EventDrag struct {
+	t      time.Time
+	btn    ButtonMask
+	mod    ModMask
+	x      int
+	y      int
+	startX int
+	startY int
+	phase  DragPhase
+}
+
+// When returns the time of the underlying mouse event.
+func (ev *EventDrag) When() time.Time {
+	return ev.t
+}
+
+// Button returns the button held down for the drag.
+func (ev *EventDrag) Button() ButtonMask {
+	return ev.btn
+}
+
+// Modifiers returns the keyboard modifiers that were pressed when the
+// button was first pressed.
+func (ev *EventDrag) Modifiers() ModMask {
+	return ev.mod
+}
+
+// Position returns the current position of the pointer.
+func (ev *EventDrag) Position() (int, int) {
+	return ev.x, ev.y
+}
+
+// StartPosition returns the position where the button was first pressed.
+func (ev *EventDrag) StartPosition() (int, int) {
+	return ev.startX, ev.startY
+}
+
+// Phase returns which part of the drag this event represents.
+func (ev *EventDrag) Phase() DragPhase {
+	return ev.phase
+}
+
+// GestureDetector converts raw mouse events into click and drag gestures.
+// It is optional; applications feed it the events they receive, and it
+// returns any gestures that those events complete:
+//
+//	g := tcell.NewGestureDetector()
+//	for {
+//		ev := screen.PollEvent()
+//		for _, gev := range g.Process(ev) {
+//			// handle *EventClick and *EventDrag
+//		}
+//		// handle ev as usual
+//	}
+//
+// Note that drag events can only be detected if motion reporting is enabled
+// (see MouseDragEvents).  A GestureDetector is not safe for concurrent use.
+type GestureDetector struct {
+	// ClickInterval is the maximum time between successive clicks
+	// for them to be counted as a multiple click.
+	ClickInterval time.Duration
+
+	// Slop is the distance in cells that the pointer may move while
+	// the button is down, and still be considered a click rather than
+	// a drag.  It also applies between the clicks of a multiple click.
+	Slop int
+
+	down     bool
+	dragging bool
+	btn      ButtonMask
+	mod      ModMask
+	downX    int
+	downY    int
+	lastX    int
+	lastY    int
+
+	clicks    int
+	clickBtn  ButtonMask
+	clickX    int
+	clickY    int
+	clickTime time.Time
+}
+
+// NewGestureDetector returns a GestureDetector with default settings;
+// a click interval of 400 milliseconds, and no slop.
+func NewGestureDetector() *GestureDetector {
+	return &GestureDetector{ClickInterval: time.Millisecond * 400}
+}
+
+func (g *GestureDetector) near(x0, y0, x1, y1 int) bool {
+	dx, dy := x1-x0, y1-y0
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	return dx <= g.Slop && dy <= g.Slop
+}
+
+// Process examines an event, and returns any gesture events that it
+// generates.  Events other than EventMouse are ignored, as are wheel
+// motions.  The original event is never included in the result.
+func (g *GestureDetector) Process(ev Event) []Event {
+	mev, ok := ev.(*EventMouse)
+	if !ok {
+		return nil
+	}
+	btn := mev.Buttons() &^ (WheelUp | WheelDown | WheelLeft | WheelRight)
+	x, y := mev.Position()
+
+	switch {
+	case !g.down && btn != ButtonNone:
+		g.down = true
+		g.dragging = false
+		g.btn = btn
+		g.mod = mev.Modifiers()
+		g.downX, g.downY = x, y
+		g.lastX, g.lastY = x, y
+		return nil
+
+	case g.down && btn != ButtonNone:
+		var evs []Event
+		if !g.dragging {
+			if g.near(g.downX, g.downY, x, y) {
+				return nil
+			}
+			g.dragging = true
+			evs = append(evs, g.drag(mev, g.downX, g.downY, DragStart))
+		}
+		if x != g.lastX || y != g.lastY {
+			evs = append(evs, g.drag(mev, x, y, DragMove))
+		}
+		g.lastX, g.lastY = x, y
+		return evs
+
+	case g.down:
+		g.down = false
+		if g.dragging {
+			g.dragging = false
+			g.clicks = 0
+			return []Event{g.drag(mev, x, y, DragEnd)}
+		}
+		when := mev.When()
+		if g.clicks > 0 && g.clickBtn == g.btn &&
+			when.Sub(g.clickTime) <= g.ClickInterval &&
+			g.near(g.clickX, g.clickY, g.downX, g.downY) {
+			g.clicks++
+		} else {
+			g.clicks = 1
+		}
+		g.clickBtn = g.btn
+		g.clickX, g.clickY = g.downX, g.downY
+		g.clickTime = when
+		return []Event{&EventClick{
+			t:     when,
+			btn:   g.btn,
+			mod:   g.mod,
+			x:     g.downX,
+			y:     g.downY,
+			count: g.clicks,
+		}}
+	}
+	return nil
+}
+
+func (g *GestureDetector) drag(mev *EventMouse, x, y int, phase DragPhase) *EventDrag {
+	return &EventDrag{
+		t:      mev.When(),
+		btn:    g.btn,
+		mod:    g.mod,
+		x:      x,
+		y:      y,
+		startX: g.downX,
+		startY: g.downY,
+		phase:  phase,
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"testing"
+	"time"
+)
+
+func mouseAt(when time.Time, x, y int, btn ButtonMask) *EventMouse {
+	return &EventMouse{t: when, x: x, y: y, btn: btn}
+}
+
+func TestGestureClicks(t *testing.T) {
+	g := NewGestureDetector()
+	now := time.Now()
+	counts := []int{}
+	for i := 0; i < 3; i++ {
+		now = now.Add(time.Millisecond * 100)
+		if evs := g.Process(mouseAt(now, 5, 5, Button1)); len(evs) != 0 {
+			t.Fatalf("Unexpected events on press: %v", evs)
+		}
+		now = now.Add(time.Millisecond * 50)
+		evs := g.Process(mouseAt(now, 5, 5, ButtonNone))
+		if len(evs) != 1 {
+			t.Fatalf("Expected one event on release, got %d", len(evs))
+		}
+		ev, ok := evs[0].(*EventClick)
+		if !ok {
+			t.Fatalf("Expected click event, got %T", evs[0])
+		}
+		counts = append(counts, ev.Count())
+	}
+	if counts[0] != 1 || counts[1] != 2 || counts[2] != 3 {
+		t.Errorf("Wrong click counts: %v", counts)
+	}
+
+	// too slow for a double click
+	now = now.Add(time.Second)
+	g.Process(mouseAt(now, 5, 5, Button1))
+	evs := g.Process(mouseAt(now, 5, 5, ButtonNone))
+	if ev := evs[0].(*EventClick); ev.Count() != 1 {
+		t.Errorf("Expected single click, got %d", ev.Count())
+	}
+}
+
+func TestGestureDrag(t *testing.T) {
+	g := NewGestureDetector()
+	g.Slop = 1
+	now := time.Now()
+	g.Process(mouseAt(now, 1, 1, Button1))
+	if evs := g.Process(mouseAt(now, 2, 1, Button1)); len(evs) != 0 {
+		t.Errorf("Movement within slop should not drag")
+	}
+	evs := g.Process(mouseAt(now, 4, 2, Button1))
+	if len(evs) != 2 {
+		t.Fatalf("Expected start and move, got %d events", len(evs))
+	}
+	if ev := evs[0].(*EventDrag); ev.Phase() != DragStart {
+		t.Errorf("Expected drag start")
+	}
+	if ev := evs[1].(*EventDrag); ev.Phase() != DragMove {
+		t.Errorf("Expected drag move")
+	} else if x, y := ev.Position(); x != 4 || y != 2 {
+		t.Errorf("Wrong drag position %d,%d", x, y)
+	}
+	evs = g.Process(mouseAt(now, 6, 3, ButtonNone))
+	if len(evs) != 1 {
+		t.Fatalf("Expected drag end")
+	}
+	ev := evs[0].(*EventDrag)
+	if sx, sy := ev.StartPosition(); ev.Phase() != DragEnd || sx != 1 || sy != 1 {
+		t.Errorf("Bad drag end: %v", ev)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !(js && wasm)
+// +build !js !wasm
+
+package tcell
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2/terminfo"
+)
+
+// StrictTerminfo makes the terminfo screen fail with an error (normally
+// ErrTermNotFound) when there is no terminfo entry for $TERM.  Otherwise,
+// which is the default, the screen starts with a best-effort entry for an
+// XTerm-like terminal, which is refined with the answers the terminal
+// gives to the queries sent when it starts.  This is often needed in
+// containers, where the terminal database is missing or incomplete.
+var StrictTerminfo = false
+
+// guessCaps are asked for with XTGETTCAP, in addition to termCaps, when
+// the terminfo entry was guessed.
+var guessCaps = []string{
+	"Co", // number of colors
+}
+
+// guessTerminfo returns an entry for a terminal that is not in the
+// terminal database, or nil if it should not be guessed.  Nearly every
+// terminal emulator in use today understands the XTerm sequences, so
+// that is what we assume, with 256 colors only if the name suggests it.
+// The entry is a copy, named for the terminal, and is not added to the
+// database.
+func guessTerminfo(name string, getenv func(string) string) *terminfo.Terminfo {
+	if StrictTerminfo || name == "dumb" {
+		return nil
+	}
+	base := "xterm"
+	if strings.Contains(name, "256color") {
+		base = "xterm-256color"
+	}
+	ti, e := terminfo.LookupTerminfoEnv(base, getenv)
+	if e != nil {
+		return nil
+	}
+	c := *ti
+	c.Name = name
+	if c.Name == "" {
+		c.Name = "unknown"
+	}
+	c.Aliases = nil
+	return &c
+}
+
+// refineGuess improves a guessed terminfo entry with what the terminal
+// reported about itself.  A terminal that reports ANSI color in its
+// primary device attributes has at least 8 colors, and one that reports
+// a larger number of colors with XTGETTCAP gets the 256 color sequences.
+// Direct color is handled by applyTermCaps.  This is called with the lock
+// held.
+func (t *tScreen) refineGuess() {
+	colors := 0
+	if t.ident.HasFeature(22) {
+		colors = 8
+	}
+	if n, e := strconv.Atoi(t.ident.Capabilities["Co"]); e == nil && n > colors {
+		colors = n
+	}
+	if colors <= t.ti.Colors {
+		return
+	}
+	if colors >= 256 {
+		colors = 256
+		t.ti.SetFg = "\x1b[%?%p1%{8}%<%t3%p1%d%e%p1%{16}%<%t9%p1%{8}%-%d%e38;5;%p1%d%;m"
+		t.ti.SetBg = "\x1b[%?%p1%{8}%<%t4%p1%d%e%p1%{16}%<%t10%p1%{8}%-%d%e48;5;%p1%d%;m"
+		t.ti.SetFgBg = "\x1b[%?%p1%{8}%<%t3%p1%d%e%p1%{16}%<%t9%p1%{8}%-%d%e38;5;%p1%d%;;%?%p2%{8}%<%t4%p2%d%e%p2%{16}%<%t10%p2%{8}%-%d%e48;5;%p2%d%;m"
+	} else if colors > 8 {
+		// the bright colors, as the sequences for more than 16
+		// colors are not agreed upon
+		colors = 16
+		t.ti.SetFg = "\x1b[%?%p1%{8}%<%t3%p1%d%e9%p1%{8}%-%d%;m"
+		t.ti.SetBg = "\x1b[%?%p1%{8}%<%t4%p1%d%e10%p1%{8}%-%d%;m"
+		t.ti.SetFgBg = "\x1b[%?%p1%{8}%<%t3%p1%d%e9%p1%{8}%-%d%;;%?%p2%{8}%<%t4%p2%d%e10%p2%{8}%-%d%;m"
+	}
+	t.ti.Colors = colors
+	t.buildPalette()
+}
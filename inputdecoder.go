@@ -0,0 +1,136 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !(js && wasm)
+// +build !js !wasm
+
+package tcell
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/gdamore/tcell/v2/terminfo"
+)
+
+// InputDecoderOptions configure an InputDecoder.
+type InputDecoderOptions struct {
+	// Terminfo describes the terminal that sent the input, which
+	// determines the sequences for keys, and whether mouse reports are
+	// understood.  If nil, the entry for "xterm" is used.
+	Terminfo *terminfo.Terminfo
+
+	// Width and Height are the size of the screen, to which mouse
+	// positions are clipped.  If either is zero, it is 80 by 24.
+	Width, Height int
+
+	// Charset is the character set of the input, such as "UTF-8" (the
+	// default) or "GB18030".  Character sets other than UTF-8 and
+	// US-ASCII must be registered, usually by importing tcell/encoding.
+	Charset string
+
+	// Input are the input options of the screen.  RawSequences and OSC
+	// change how input is decoded.  The delays are not used, as the
+	// decoder has no timers; see InputDecoder.Flush.
+	Input InputOptions
+
+	// KittyKeys decodes the key reports of the kitty keyboard protocol,
+	// as the screen does after enabling it for AlternateKeys.
+	KittyKeys bool
+}
+
+// InputDecoder converts the bytes a terminal sends into events, exactly
+// as the terminfo screen does, but without a terminal or a screen.  As it
+// has no timers, the result depends only on the bytes given to it, which
+// makes it suitable for fuzzing, and for replaying recorded input in
+// tests.  Replies to the queries the screen sends (such as the device
+// attributes) are consumed without producing events.  The events are
+// stamped with the time they were decoded.
+//
+// An InputDecoder must not be used by several goroutines at once.
+type InputDecoder struct {
+	t   *tScreen
+	buf bytes.Buffer
+}
+
+// NewInputDecoder returns an InputDecoder with the given options.  It
+// fails with ErrTermNotFound if opts.Terminfo is nil and there is no
+// entry for xterm, or ErrNoCharset if the character set is unknown.
+func NewInputDecoder(opts InputDecoderOptions) (*InputDecoder, error) {
+	ti := opts.Terminfo
+	if ti == nil {
+		var e error
+		if ti, e = LookupTerminfo("xterm"); e != nil {
+			return nil, e
+		}
+	}
+	charset := opts.Charset
+	if charset == "" {
+		charset = "UTF-8"
+	}
+	enc := GetEncoding(charset)
+	if enc == nil {
+		return nil, ErrNoCharset
+	}
+	t, e := newTScreen(nil, ti)
+	if e != nil {
+		return nil, e
+	}
+	w, h := opts.Width, opts.Height
+	if w <= 0 || h <= 0 {
+		w, h = 80, 24
+	}
+	t.cells.Resize(w, h)
+	t.charset = charset
+	t.encoder = enc.NewEncoder()
+	t.decoder = enc.NewDecoder()
+	t.inputOpts = opts.Input
+	t.kittyKeys = opts.KittyKeys
+	// clipboard contents are only ever sent when asked for
+	t.setClipboard = "\x1b]52;c;%p1%s\x1b\\"
+	return &InputDecoder{t: t}, nil
+}
+
+// Decode decodes b, following any input that was not yet decoded, and
+// returns the events.  Input that could be the start of an escape
+// sequence is kept until more input arrives, or Flush is called.
+func (d *InputDecoder) Decode(b []byte) []Event {
+	d.buf.Write(b)
+	return d.collect(false)
+}
+
+// Flush decodes the input that was kept waiting for the rest of an escape
+// sequence, as the screen does when the escape delay expires.  A lone ESC
+// becomes the Esc key, for example.
+func (d *InputDecoder) Flush() []Event {
+	return d.collect(true)
+}
+
+// Pending returns the number of bytes kept waiting for more input.
+func (d *InputDecoder) Pending() int {
+	return d.buf.Len()
+}
+
+func (d *InputDecoder) collect(expire bool) []Event {
+	now := time.Now()
+	evs := append([]Event(nil), d.t.collectEventsFromInput(&d.buf, expire)...)
+	for _, ev := range evs {
+		stampEvent(ev, now)
+	}
+	if d.buf.Len() > maxInputLength {
+		d.buf.Reset()
+		evs = append(evs, NewEventError(ErrInputOverflow))
+	}
+	return evs
+}
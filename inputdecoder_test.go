@@ -0,0 +1,294 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// describeEvents returns a description of each event, for comparisons.
+func describeEvents(evs []Event) []string {
+	var res []string
+	for _, ev := range evs {
+		switch ev := ev.(type) {
+		case *EventKey:
+			res = append(res, ev.Name())
+		case *EventMouse:
+			x, y := ev.Position()
+			res = append(res, fmt.Sprintf("Mouse(%d,%d,%d)", x, y, ev.Buttons()))
+		case *EventPaste:
+			res = append(res, fmt.Sprintf("Paste(%v)", ev.Start()))
+		default:
+			res = append(res, fmt.Sprintf("%T", ev))
+		}
+	}
+	return res
+}
+
+func TestInputDecoder(t *testing.T) {
+	d, err := NewInputDecoder(InputDecoderOptions{})
+	if err != nil {
+		t.Skipf("No decoder: %v", err)
+	}
+	cases := []struct {
+		input   string
+		events  []string
+		pending int
+	}{
+		{"ab", []string{"Rune[a]", "Rune[b]"}, 0},
+		{"\x1b[A", []string{"Up"}, 0},
+		{"\x1b[<0;3;4M", []string{"Mouse(2,3,1)"}, 0},
+		{"\x1b[200~x\x1b[201~", []string{"Paste(true)", "Rune[x]", "Paste(false)"}, 0},
+		{"é", []string{"Rune[é]"}, 0},
+		{"\x1b[<0;200;100M", []string{"Mouse(79,23,1)"}, 0},
+		{"\x1b[?62;22c", nil, 0}, // device attributes are consumed
+		{"\x1b", nil, 1},
+		{"[B", []string{"Down"}, 0},
+		{"\xc3", nil, 1},
+	}
+	for _, c := range cases {
+		evs := describeEvents(d.Decode([]byte(c.input)))
+		if !reflect.DeepEqual(evs, c.events) {
+			t.Errorf("%q: got %v, expected %v", c.input, evs, c.events)
+		}
+		if d.Pending() != c.pending {
+			t.Errorf("%q: %d bytes pending", c.input, d.Pending())
+		}
+	}
+	if evs := describeEvents(d.Decode([]byte("\xa9"))); !reflect.DeepEqual(evs, []string{"Rune[é]"}) {
+		t.Errorf("Split character: %v", evs)
+	}
+
+	d.Decode([]byte("\x1b"))
+	if evs := describeEvents(d.Flush()); !reflect.DeepEqual(evs, []string{"Esc"}) {
+		t.Errorf("Flushed ESC: %v", evs)
+	}
+	if d.Pending() != 0 {
+		t.Errorf("%d bytes pending after flush", d.Pending())
+	}
+
+	if _, err := NewInputDecoder(InputDecoderOptions{Charset: "no-such-charset"}); err != ErrNoCharset {
+		t.Errorf("Unknown charset: %v", err)
+	}
+}
+
+// TestInputDecoderReplay checks that the events do not depend on how the
+// input is split up, as it arrives from the terminal.
+func TestInputDecoderReplay(t *testing.T) {
+	pieces := []string{
+		"a", "\x1b[A", "\x1b[1;5C", "\x1b[<0;3;4M", "\x1b[<0;3;4m",
+		"\x1b[M !!", "\x1b[200~", "\x1b[201~", "é", "中", "\x1bOP",
+		"\x1b[15~", "\x1b[?62;22c", "\x1b[I", "\x1b[O", "\t", "\x7f",
+	}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		var input []byte
+		for j := 0; j < 20; j++ {
+			input = append(input, pieces[rng.Intn(len(pieces))]...)
+		}
+		whole, err := NewInputDecoder(InputDecoderOptions{})
+		if err != nil {
+			t.Skipf("No decoder: %v", err)
+		}
+		split, _ := NewInputDecoder(InputDecoderOptions{})
+		expect := describeEvents(append(whole.Decode(input), whole.Flush()...))
+		var got []Event
+		for rest := input; len(rest) > 0; {
+			n := 1 + rng.Intn(len(rest))
+			got = append(got, split.Decode(rest[:n])...)
+			rest = rest[n:]
+		}
+		got = append(got, split.Flush()...)
+		if evs := describeEvents(got); !reflect.DeepEqual(evs, expect) {
+			t.Fatalf("%q: split gives %v, expected %v", input, evs, expect)
+		}
+	}
+}
+
+func TestKeypadKeys(t *testing.T) {
+	cases := []struct {
+		input    string
+		keypad   bool
+		expected []string
+	}{
+		{"\x1bOM\x1bOp\x1bOk", false, []string{"Enter", "Rune[0]", "Rune[+]"}},
+		{"\x1bOM\x1bOp\x1bOk", true, []string{"KPEnter", "KP0", "KPAdd"}},
+		{"\x1b[57414u\x1b[57399u\x1b[57416;5u", false, []string{"Enter", "Rune[0]", "Ctrl+Rune[,]"}},
+		{"\x1b[57414u\x1b[57399u\x1b[57416;5u", true, []string{"KPEnter", "KP0", "Ctrl+KPSeparator"}},
+		{"\x1b[13u", true, []string{"Enter"}},
+	}
+	for _, c := range cases {
+		d, err := NewInputDecoder(InputDecoderOptions{KittyKeys: true, Input: InputOptions{KeypadKeys: c.keypad}})
+		if err != nil {
+			t.Skipf("No decoder: %v", err)
+		}
+		if evs := describeEvents(d.Decode([]byte(c.input))); !reflect.DeepEqual(evs, c.expected) {
+			t.Errorf("%q %v: got %v, expected %v", c.input, c.keypad, evs, c.expected)
+		}
+	}
+	if k, _, _, err := ParseKeySpec("Shift+KPEnter"); err != nil || k != KeyKPEnter {
+		t.Errorf("Keypad key not parsed: %v %v", k, err)
+	}
+}
+
+func TestKeypadMode(t *testing.T) {
+	ti, err := LookupTerminfo("linux")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	tty := newRenderTty(10, 2)
+	s, err := NewTerminfoScreenFromTtyTerminfo(tty, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	tty.output()
+	s.SetInputOptions(InputOptions{KeypadKeys: true})
+	if out := tty.output(); out != "\x1b=" {
+		t.Errorf("Wrong output for application mode: %q", out)
+	}
+	s.SetInputOptions(InputOptions{})
+	if out := tty.output(); out != "\x1b>" {
+		t.Errorf("Wrong output for numeric mode: %q", out)
+	}
+	s.Fini()
+}
+
+func TestModifierKeys(t *testing.T) {
+	cases := []struct {
+		input    string
+		mods     bool
+		expected []string
+		actions  []KeyAction
+	}{
+		{"\x1b[57441;2u\x1b[97;2:2u\x1b[57441;1:3u", true,
+			[]string{"Shift+Shift", "Shift+Rune[a]", "Shift"},
+			[]KeyAction{KeyActionPress, KeyActionRepeat, KeyActionRelease}},
+		{"\x1b[57441;2u\x1b[57441;1:3u\x1b[97u", false, []string{"Rune[a]"}, []KeyAction{KeyActionPress}},
+		{"\x1b[57448;5u\x1b[57444;9u", true, []string{"Ctrl+Ctrl", "Meta+Meta"}, []KeyAction{KeyActionPress, KeyActionPress}},
+		{"\x1b[97;1:3u\x1b[97;65u", true, []string{"Rune[A]"}, []KeyAction{KeyActionPress}},
+		{"\x1b[1;1:2A\x1b[1;1:3A\x1b[3;5:1~\x1b[15;1:3~", true,
+			[]string{"Up", "Ctrl+Delete"}, []KeyAction{KeyActionRepeat, KeyActionPress}},
+		// win32-input-mode, Shift down and up, then a
+		{"\x1b[16;42;0;1;16;1_\x1b[16;42;0;0;0;1_\x1b[65;30;97;1;0;1_", true,
+			[]string{"Shift+Shift", "Shift", "Rune[a]"},
+			[]KeyAction{KeyActionPress, KeyActionRelease, KeyActionPress}},
+		{"\x1b[16;42;0;1;16;1_\x1b[16;42;0;0;0;1_\x1b[65;30;97;1;0;1_", false,
+			[]string{"Rune[a]"}, []KeyAction{KeyActionPress}},
+	}
+	for _, c := range cases {
+		d, err := NewInputDecoder(InputDecoderOptions{KittyKeys: true, Input: InputOptions{ModifierKeys: c.mods}})
+		if err != nil {
+			t.Skipf("No decoder: %v", err)
+		}
+		evs := d.Decode([]byte(c.input))
+		if names := describeEvents(evs); !reflect.DeepEqual(names, c.expected) {
+			t.Errorf("%q %v: got %v, expected %v", c.input, c.mods, names, c.expected)
+			continue
+		}
+		for i, ev := range evs {
+			if a := ev.(*EventKey).Action(); a != c.actions[i] {
+				t.Errorf("%q %v: event %d has action %d", c.input, c.mods, i, a)
+			}
+		}
+	}
+}
+
+func TestModifierKeysEnable(t *testing.T) {
+	ti, err := LookupTerminfo("xterm")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	tty := newRenderTty(10, 2)
+	s, err := NewTerminfoScreenFromTtyTerminfo(tty, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	tty.output()
+	s.SetInputOptions(InputOptions{AlternateKeys: true})
+	if out := tty.output(); out != "\x1b[>5u" {
+		t.Errorf("Wrong output for alternate keys: %q", out)
+	}
+	s.SetInputOptions(InputOptions{ModifierKeys: true})
+	if out := tty.output(); out != "\x1b[<u\x1b[>15u" {
+		t.Errorf("Wrong output for modifier keys: %q", out)
+	}
+	s.SetInputOptions(InputOptions{})
+	if out := tty.output(); out != "\x1b[<u" {
+		t.Errorf("Wrong output without modifier keys: %q", out)
+	}
+}
+
+func TestStringLimits(t *testing.T) {
+	d, err := NewInputDecoder(InputDecoderOptions{
+		Input: InputOptions{MaxString: 8, OSC: []int{7}},
+	})
+	if err != nil {
+		t.Skipf("No decoder: %v", err)
+	}
+	describe := func(evs []Event) []string {
+		var res []string
+		for _, ev := range evs {
+			switch ev := ev.(type) {
+			case *EventError:
+				res = append(res, ev.Error())
+			case *EventOSC:
+				res = append(res, fmt.Sprintf("OSC(%d,%q)", ev.Code(), ev.Data()))
+			default:
+				res = append(res, describeEvents([]Event{ev})...)
+			}
+		}
+		return res
+	}
+	cases := []struct {
+		input   string
+		events  []string
+		pending int
+	}{
+		{"\x1b]7;short\a", []string{`OSC(7,"short")`}, 0},
+		{"\x1b]7;0123", nil, 8},
+		{"456789", []string{"input string too long"}, 0},
+		// the rest of the string is discarded, not taken as keys
+		{"abcdef", nil, 0},
+		{"ghi\x1b", nil, 0},
+		{"\\x", []string{"Rune[x]"}, 0},
+		{"\x1bPtoo long for it\x1b\\y", []string{"input string too long", "Rune[y]"}, 0},
+		// a cancelled string ends at the ESC that starts the next
+		{"\x1b]7;0123456789\x1b[Az", []string{"input string too long", "Up", "Rune[z]"}, 0},
+		// as does one cancelled by an ESC at the end of a read
+		{"\x1b]7;0123456789", []string{"input string too long"}, 0},
+		{"abc\x1b", nil, 0},
+		{"[A", []string{"Up"}, 0},
+		{"\x1b]7;\xff\xfe\a", []string{"input string not valid UTF-8"}, 0},
+	}
+	for _, tc := range cases {
+		evs := describe(d.Decode([]byte(tc.input)))
+		if !reflect.DeepEqual(evs, tc.events) {
+			t.Errorf("%q: got %v, expected %v", tc.input, evs, tc.events)
+		}
+		if n := d.Pending(); n != tc.pending {
+			t.Errorf("%q: %d bytes pending, expected %d", tc.input, n, tc.pending)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !(js && wasm)
+// +build !js !wasm
+
+package tcell
+
+import (
+	"encoding/base64"
+	"strconv"
+)
+
+// iterm returns true if the terminal is iTerm2, which has proprietary
+// sequences for the tab color and badge.  Other terminals (including
+// Terminal.app) may show them as garbage, so they are only sent to it.
+// iTerm2 sets $LC_TERMINAL, which unlike $TERM_PROGRAM is usually
+// passed on by ssh, and identifies itself when asked.
+func (t *tScreen) iterm() bool {
+	return t.ident.Name == "iTerm2" ||
+		t.getenv("TERM_PROGRAM") == "iTerm.app" ||
+		t.getenv("LC_TERMINAL") == "iTerm2"
+}
+
+// tabColorStrings returns the OSC 6 sequences that set the tab color to
+// c, or reset it if c has no RGB value.
+func tabColorStrings(c Color) []string {
+	r, g, b := c.RGB()
+	if r < 0 {
+		return []string{oscString(6, "1;bg;*;default")}
+	}
+	return []string{
+		oscString(6, "1;bg;red;brightness;"+strconv.Itoa(int(r))),
+		oscString(6, "1;bg;green;brightness;"+strconv.Itoa(int(g))),
+		oscString(6, "1;bg;blue;brightness;"+strconv.Itoa(int(b))),
+	}
+}
+
+// badgeString returns the OSC 1337 sequence that sets the badge.  The
+// text is a format, in which \(name) is replaced by the session
+// variable, so backslashes are doubled to show them as they are.
+func badgeString(text string) string {
+	format := []byte{}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' {
+			format = append(format, '\\')
+		}
+		format = append(format, text[i])
+	}
+	return oscString(1337, "SetBadgeFormat="+base64.StdEncoding.EncodeToString(format))
+}
+
+// putOSC sends an OSC sequence, also passing it through tmux if needed.
+func (t *tScreen) putOSC(seq string) {
+	t.TPuts(seq)
+	if t.passthrough {
+		t.TPuts(tmuxWrap(seq))
+	}
+}
+
+// sendTabExtras sends the tab color and badge, if they were set, when
+// the screen is engaged, or resets them when it is disengaged.
+func (t *tScreen) sendTabExtras(reset bool) {
+	if !t.iterm() {
+		return
+	}
+	if t.tabColor != ColorDefault {
+		c := t.tabColor
+		if reset {
+			c = ColorDefault
+		}
+		for _, seq := range tabColorStrings(c) {
+			t.putOSC(seq)
+		}
+	}
+	if t.badge != "" {
+		text := t.badge
+		if reset {
+			text = ""
+		}
+		t.putOSC(badgeString(text))
+	}
+}
+
+func (t *tScreen) SetTabColor(c Color) {
+	t.Lock()
+	defer t.Unlock()
+	if c == ColorReset {
+		c = ColorDefault
+	}
+	if c == t.tabColor {
+		return
+	}
+	t.tabColor = c
+	if t.running && t.iterm() {
+		for _, seq := range tabColorStrings(c) {
+			t.putOSC(seq)
+		}
+	}
+}
+
+func (t *tScreen) SetBadge(text string) {
+	t.Lock()
+	defer t.Unlock()
+	if text == t.badge {
+		return
+	}
+	t.badge = text
+	if t.running && t.iterm() {
+		t.putOSC(badgeString(text))
+	}
+}
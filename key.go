@@ -43,10 +43,12 @@ import (
 // overly much on availability of modifiers, or the availability of any
 // specific keys.
 type EventKey struct {
-	t   time.Time
-	mod ModMask
-	key Key
-	ch  rune
+	t      time.Time
+	mod    ModMask
+	key    Key
+	ch     rune
+	base   rune
+	action KeyAction
 }
 
 // When returns the time when this Event was created, which should closely
@@ -55,6 +57,23 @@ func (ev *EventKey) When() time.Time {
 	return ev.t
 }
 
+// KeyAction is what happened to a key.
+type KeyAction int
+
+const (
+	KeyActionPress   = KeyAction(iota) // The key was pressed.
+	KeyActionRepeat                    // The key is held down, and repeats.
+	KeyActionRelease                   // The key was released.
+)
+
+// Action returns what happened to the key.  Most terminals only report
+// key presses.  Releases are only reported for the modifier keys (such as
+// KeyShift), with the ModifierKeys input option; repeats only where the
+// terminal reports them, which is with the kitty keyboard protocol.
+func (ev *EventKey) Action() KeyAction {
+	return ev.action
+}
+
 // Rune returns the rune corresponding to the key press, if it makes sense.
 // The result is only defined if the value of Key() is KeyRune.
 func (ev *EventKey) Rune() rune {
@@ -78,6 +97,54 @@ func (ev *EventKey) Modifiers() ModMask {
 	return ev.mod
 }
 
+// BaseRune returns the character that the key would produce on a US
+// (QWERTY) keyboard layout, without Shift, or zero if that is not known.
+// This identifies the physical key, regardless of the layout in use, which
+// is useful for shortcuts that should stay in the same place on the
+// keyboard (such as Ctrl+Z), even when the active layout is, for example,
+// Cyrillic or Greek.
+//
+// This is only known for terminals using the kitty keyboard protocol, when
+// enabled with InputOptions.AlternateKeys, and for the Windows console.
+// The kitty protocol reports this for keys pressed together with Ctrl or
+// Alt, and for other keys that do not produce text.  On Windows, it is
+// derived from the virtual key code, which for letters follows the layout
+// when that layout uses Latin letters.
+func (ev *EventKey) BaseRune() rune {
+	return ev.base
+}
+
+// MatchesRune returns true if the key press produced the given character,
+// with the given modifiers.  Since the Shift key is (usually) reflected in
+// the character itself, ModShift is not compared.  Control characters
+// reported as KeyCtrlA through KeyCtrlZ match the corresponding lower case
+// letter with ModCtrl, so for example MatchesRune('s', ModCtrl) matches
+// Ctrl+S regardless of how the terminal reported it.
+func (ev *EventKey) MatchesRune(r rune, mod ModMask) bool {
+	ch := ev.ch
+	switch {
+	case ev.key >= KeyCtrlA && ev.key <= KeyCtrlZ && ev.mod&ModCtrl != 0:
+		ch = rune(ev.key-KeyCtrlA) + 'a'
+	case ev.key != KeyRune:
+		return false
+	}
+	return ch == r && ev.mod&^ModShift == mod&^ModShift
+}
+
+// MatchesPhysical returns true if the key press was of the key that
+// produces the given character on a US layout (see BaseRune), with
+// exactly the given modifiers.  For example MatchesPhysical('z', ModCtrl)
+// matches Ctrl together with the key labeled Z on a QWERTY keyboard, even
+// if the active layout produces some other character for it.  If the
+// physical key is not known, this is the same as MatchesRune, which gives
+// the same result on US-like layouts.
+func (ev *EventKey) MatchesPhysical(r rune, mod ModMask) bool {
+	if ev.base == 0 {
+		return ev.MatchesRune(r, mod)
+	}
+	return ev.base == r && ev.mod == mod
+}
+
 // KeyNames holds the written names of special keys. Useful to echo back a key
 // name, or to look up a key from a string value.
 var KeyNames = map[Key]string{
@@ -171,6 +238,28 @@ var KeyNames = map[Key]string{
 	KeyF62:            "F62",
 	KeyF63:            "F63",
 	KeyF64:            "F64",
+	KeyKP0:            "KP0",
+	KeyKP1:            "KP1",
+	KeyKP2:            "KP2",
+	KeyKP3:            "KP3",
+	KeyKP4:            "KP4",
+	KeyKP5:            "KP5",
+	KeyKP6:            "KP6",
+	KeyKP7:            "KP7",
+	KeyKP8:            "KP8",
+	KeyKP9:            "KP9",
+	KeyKPDecimal:      "KPDecimal",
+	KeyKPDivide:       "KPDivide",
+	KeyKPMultiply:     "KPMultiply",
+	KeyKPSubtract:     "KPSubtract",
+	KeyKPAdd:          "KPAdd",
+	KeyKPEnter:        "KPEnter",
+	KeyKPEqual:        "KPEqual",
+	KeyKPSeparator:    "KPSeparator",
+	KeyShift:          "Shift",
+	KeyCtrl:           "Ctrl",
+	KeyAlt:            "Alt",
+	KeyMeta:           "Meta",
 	KeyCtrlA:          "Ctrl-A",
 	KeyCtrlB:          "Ctrl-B",
 	KeyCtrlC:          "Ctrl-C",
@@ -202,33 +291,46 @@ var KeyNames = map[Key]string{
 }
 
 // Name returns a printable value or the key stroke.  This can be used
-// when printing the event, for example.
+// when printing the event, for example.  The result can be converted back
+// with ParseKeySpec.
 func (ev *EventKey) Name() string {
+	return FormatKeySpec(ev.key, ev.ch, ev.mod)
+}
+
+// keyModNames are the names of the modifiers, in the order that they
+// are written.
+var keyModNames = []struct {
+	mod  ModMask
+	name string
+}{
+	{ModShift, "Shift"},
+	{ModAlt, "Alt"},
+	{ModMeta, "Meta"},
+	{ModCtrl, "Ctrl"},
+}
+
+// FormatKeySpec returns the name of a key stroke, in the same form as
+// EventKey.Name, for example "Shift+Ctrl+F5" or "Alt+Rune[x]".  The
+// rune is only used for KeyRune.
+func FormatKeySpec(k Key, ch rune, mod ModMask) string {
 	s := ""
 	m := []string{}
-	if ev.mod&ModShift != 0 {
-		m = append(m, "Shift")
-	}
-	if ev.mod&ModAlt != 0 {
-		m = append(m, "Alt")
-	}
-	if ev.mod&ModMeta != 0 {
-		m = append(m, "Meta")
-	}
-	if ev.mod&ModCtrl != 0 {
-		m = append(m, "Ctrl")
+	for _, mn := range keyModNames {
+		if mod&mn.mod != 0 {
+			m = append(m, mn.name)
+		}
 	}
 
 	ok := false
-	if s, ok = KeyNames[ev.key]; !ok {
-		if ev.key == KeyRune {
-			s = "Rune[" + string(ev.ch) + "]"
+	if s, ok = KeyNames[k]; !ok {
+		if k == KeyRune {
+			s = "Rune[" + string(ch) + "]"
 		} else {
-			s = fmt.Sprintf("Key[%d,%d]", ev.key, int(ev.ch))
+			s = fmt.Sprintf("Key[%d,%d]", k, int(ch))
 		}
 	}
 	if len(m) != 0 {
-		if ev.mod&ModCtrl != 0 && strings.HasPrefix(s, "Ctrl-") {
+		if mod&ModCtrl != 0 && strings.HasPrefix(s, "Ctrl-") {
 			s = s[5:]
 		}
 		return fmt.Sprintf("%s+%s", strings.Join(m, "+"), s)
@@ -236,6 +338,72 @@ func (ev *EventKey) Name() string {
 	return s
 }
 
+// ParseKeySpec parses the name of a key stroke, such as "Ctrl+Shift+F5",
+// as found in key binding configuration.  The modifiers (Shift, Alt, Meta
+// and Ctrl) may be given in any order, followed by the name of the key
+// as found in KeyNames, or a single character.  Names are not case
+// sensitive, except for single characters.  The names returned by
+// EventKey.Name (and FormatKeySpec) are understood, including the forms
+// "Rune[x]" and "Key[n,n]".
+//
+// As with key events, Ctrl together with a letter is the corresponding
+// control key, so "Ctrl+A" gives KeyCtrlA (with ModCtrl).  For other
+// named keys, the rune returned is zero.
+func ParseKeySpec(spec string) (Key, rune, ModMask, error) {
+	mod := ModNone
+	rest := spec
+	for {
+		// a trailing + is the key itself, as in "Ctrl++"
+		i := strings.IndexByte(rest, '+')
+		if i <= 0 || i == len(rest)-1 {
+			break
+		}
+		found := false
+		for _, mn := range keyModNames {
+			if strings.EqualFold(rest[:i], mn.name) {
+				mod |= mn.mod
+				found = true
+			}
+		}
+		if !found {
+			break
+		}
+		rest = rest[i+1:]
+	}
+
+	if strings.HasPrefix(rest, "Rune[") && strings.HasSuffix(rest, "]") {
+		if r := []rune(rest[5 : len(rest)-1]); len(r) == 1 {
+			return KeyRune, r[0], mod, nil
+		}
+	}
+	var k, ch int
+	if n, _ := fmt.Sscanf(rest, "Key[%d,%d]", &k, &ch); n == 2 {
+		return Key(k), rune(ch), mod, nil
+	}
+	if mod&ModCtrl != 0 {
+		if k, ok := lookupKeyName("Ctrl-" + rest); ok {
+			return k, 0, mod, nil
+		}
+	}
+	if k, ok := lookupKeyName(rest); ok {
+		return k, 0, mod, nil
+	}
+	if r := []rune(rest); len(r) == 1 {
+		return KeyRune, r[0], mod, nil
+	}
+	return KeyNUL, 0, ModNone, fmt.Errorf("unknown key %q", spec)
+}
+
+// lookupKeyName finds a key by its name in KeyNames, ignoring case.
+func lookupKeyName(name string) (Key, bool) {
+	for k, n := range KeyNames {
+		if strings.EqualFold(n, name) {
+			return k, true
+		}
+	}
+	return KeyNUL, false
+}
+
 // NewEventKey attempts to create a suitable event.  It parses the various
 // ASCII control sequences if KeyRune is passed for Key, but if the caller
 // has more precise information it should set that specifically.  Callers
@@ -375,6 +543,42 @@ const (
 	KeyF64
 )
 
+// These are the keys of the numeric keypad, which are only reported when
+// the KeypadKeys input option is set.  Otherwise they are reported as the
+// characters they produce, with keypad Enter as KeyEnter.
+const (
+	KeyKP0 Key = iota + 512
+	KeyKP1
+	KeyKP2
+	KeyKP3
+	KeyKP4
+	KeyKP5
+	KeyKP6
+	KeyKP7
+	KeyKP8
+	KeyKP9
+	KeyKPDecimal
+	KeyKPDivide
+	KeyKPMultiply
+	KeyKPSubtract
+	KeyKPAdd
+	KeyKPEnter
+	KeyKPEqual
+	KeyKPSeparator
+)
+
+// These are the modifier keys themselves, which are only reported when
+// the ModifierKeys input option is set, both when pressed and released
+// (see EventKey.Action).  Either of a pair of keys (such as the left and
+// right Shift keys) is the same key.  KeyMeta is also the Super (Windows
+// or Command) and Hyper keys.
+const (
+	KeyShift Key = iota + 576
+	KeyCtrl
+	KeyAlt
+	KeyMeta
+)
+
 const (
 	// These key codes are used internally, and will never appear to applications.
 	keyPasteStart Key = iota + 16384
@@ -0,0 +1,329 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"strings"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// TextSpan is a run of text in a single style, for LayoutText.
+type TextSpan struct {
+	Text  string
+	Style Style
+}
+
+// WrapMode determines where LayoutText breaks lines that are too long.
+type WrapMode int
+
+const (
+	// WrapWord breaks lines at spaces (see LayoutOptions.WordSpace).
+	// Words that are too long for a line of their own are broken between
+	// characters (or hyphenated, see LayoutOptions.Hyphenate).
+	WrapWord WrapMode = iota
+
+	// WrapChar breaks lines between any two characters.
+	WrapChar
+
+	// WrapNone does not break lines, other than at newlines.
+	WrapNone
+)
+
+// LayoutOptions control how LayoutText arranges text.
+type LayoutOptions struct {
+	// Width is the number of cells available for each line.  If it is
+	// zero or negative, lines are only broken at newlines.
+	Width int
+
+	// Wrap determines where lines are broken.
+	Wrap WrapMode
+
+	// Indent is the hanging indent, the number of cells that lines after
+	// the first one of each paragraph are indented by.  It is ignored if
+	// it leaves no room for text.
+	Indent int
+
+	// TabWidth is the distance between tab stops.  Zero means 8, and a
+	// negative value replaces each tab with a single space.
+	TabWidth int
+
+	// Hyphenate, if not nil, is called with a word that does not fit on
+	// the rest of a line, and returns the byte offsets in the word at
+	// which it may be broken with a hyphen.  The longest part that fits
+	// (together with the hyphen) is kept on the line.
+	Hyphenate func(word string) []int
+
+	// WordSpace, if not nil, reports which characters separate words, in
+	// place of IsWordSpace.  They are treated as spaces: lines may be
+	// broken at them, and they are dropped at the break.  Use the same
+	// rule for selecting words (as views.CellView does) so that wrapping
+	// and selection agree.
+	WordSpace func(r rune) bool
+}
+
+// IsWordSpace is the default rule for where words end, both for word
+// wrapping by LayoutText and for selecting words with a double click in
+// the views package.  It reports true for spaces and tabs.
+func IsWordSpace(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+// TextLine is a line of text arranged by LayoutText.  The cells are
+// consecutive, starting after the indent.
+type TextLine struct {
+	Indent int
+	Cells  []StyledCell
+}
+
+// Width returns the number of cells occupied by the line, including the
+// indent.
+func (l TextLine) Width() int {
+	w := l.Indent
+	for _, c := range l.Cells {
+		w += c.Width
+	}
+	return w
+}
+
+// Draw draws the line at the given position, and returns the number of
+// cells used, including the indent.  Cells of the indent are not drawn.
+func (l TextLine) Draw(t DrawTarget, x, y int) int {
+	col := x + l.Indent
+	for _, c := range l.Cells {
+		t.SetContent(col, y, c.Main, c.Comb, c.Style)
+		col += c.Width
+	}
+	return col - x
+}
+
+// layoutItem is a character (and any combining characters) to lay out.
+type layoutItem struct {
+	cell  StyledCell
+	tab   bool
+	space bool
+}
+
+// LayoutText arranges styled text into lines that fit in the width given
+// by the options, ready to be drawn.  Each newline starts a new paragraph.
+// Characters are measured as the screen does, so wide characters take two
+// cells, and are never split, and combining characters stay with the
+// preceding character.  Control characters other than tab and newline
+// are discarded.
+func LayoutText(spans []TextSpan, opts LayoutOptions) []TextLine {
+	var lines []TextLine
+	var para []layoutItem
+	started := false
+	isSpace := opts.WordSpace
+	if isSpace == nil {
+		isSpace = IsWordSpace
+	}
+	for _, sp := range spans {
+		for _, r := range sp.Text {
+			started = true
+			switch {
+			case r == '\n':
+				lines = append(lines, layoutParagraph(para, opts)...)
+				para = nil
+				started = false
+				continue
+			case r == '\t':
+				para = append(para, layoutItem{
+					cell: StyledCell{Main: ' ', Style: sp.Style, Width: 1},
+					tab:  true, space: true,
+				})
+				continue
+			case r < ' ' || (r >= 0x7f && r < 0xa0):
+				continue
+			}
+			w := runewidth.RuneWidth(r)
+			if w == 0 {
+				if n := len(para); n > 0 && !para[n-1].tab {
+					para[n-1].cell.Comb = append(para[n-1].cell.Comb, r)
+					continue
+				}
+				// a leading combining character gets a space to sit on
+				para = append(para, layoutItem{
+					cell: StyledCell{Main: ' ', Comb: []rune{r}, Style: sp.Style, Width: 1},
+				})
+				continue
+			}
+			para = append(para, layoutItem{
+				cell:  StyledCell{Main: r, Style: sp.Style, Width: w},
+				space: isSpace(r),
+			})
+		}
+	}
+	if started {
+		lines = append(lines, layoutParagraph(para, opts)...)
+	}
+	return lines
+}
+
+// layout holds the state of the paragraph being arranged.
+type layout struct {
+	opts   LayoutOptions
+	tab    int
+	indent int
+	lines  []TextLine
+	line   TextLine
+	col    int
+}
+
+func (lo *layout) width(it layoutItem) int {
+	if it.tab && lo.tab > 0 {
+		return lo.tab - lo.col%lo.tab
+	}
+	return it.cell.Width
+}
+
+func (lo *layout) fits(w int) bool {
+	return lo.col+w <= lo.opts.Width
+}
+
+func (lo *layout) place(it layoutItem) {
+	n := lo.width(it)
+	if it.tab {
+		for i := 0; i < n; i++ {
+			lo.line.Cells = append(lo.line.Cells, it.cell)
+		}
+	} else {
+		lo.line.Cells = append(lo.line.Cells, it.cell)
+	}
+	lo.col += n
+}
+
+// newLine starts a new line, after removing trailing spaces if the
+// line is broken between words.
+func (lo *layout) newLine(trim bool) {
+	if trim {
+		cells := lo.line.Cells
+		for len(cells) > 0 && cells[len(cells)-1].Main == ' ' && len(cells[len(cells)-1].Comb) == 0 {
+			cells = cells[:len(cells)-1]
+		}
+		lo.line.Cells = cells
+	}
+	lo.lines = append(lo.lines, lo.line)
+	lo.line = TextLine{Indent: lo.indent}
+	lo.col = lo.indent
+}
+
+// hyphenate returns the number of items of the word to place before a
+// hyphen, so that they and the hyphen fit on the line, or zero.
+func (lo *layout) hyphenate(word []layoutItem) int {
+	if lo.opts.Hyphenate == nil {
+		return 0
+	}
+	sb := &strings.Builder{}
+	offsets := make(map[int]int) // byte offset to item index
+	for k, it := range word {
+		offsets[sb.Len()] = k
+		sb.WriteRune(it.cell.Main)
+		for _, r := range it.cell.Comb {
+			sb.WriteRune(r)
+		}
+	}
+	best := 0
+	for _, p := range lo.opts.Hyphenate(sb.String()) {
+		k, ok := offsets[p]
+		if !ok || k == 0 || k <= best {
+			continue
+		}
+		w := 1 // the hyphen
+		for _, it := range word[:k] {
+			w += it.cell.Width
+		}
+		if lo.fits(w) {
+			best = k
+		}
+	}
+	return best
+}
+
+func layoutParagraph(items []layoutItem, opts LayoutOptions) []TextLine {
+	lo := &layout{opts: opts, tab: opts.TabWidth}
+	if lo.tab == 0 {
+		lo.tab = 8
+	}
+	wrap := opts.Wrap
+	if opts.Width <= 0 {
+		wrap = WrapNone
+	}
+	if opts.Indent > 0 && opts.Indent < opts.Width {
+		lo.indent = opts.Indent
+	}
+
+	for i := 0; i < len(items); {
+		it := items[i]
+		switch {
+		case wrap == WrapNone:
+			lo.place(it)
+			i++
+
+		case wrap == WrapChar || it.space:
+			if !lo.fits(lo.width(it)) && len(lo.line.Cells) > 0 {
+				lo.newLine(wrap == WrapWord)
+				if wrap == WrapWord {
+					// spaces at the break are dropped
+					for i < len(items) && items[i].space {
+						i++
+					}
+					continue
+				}
+			}
+			lo.place(it)
+			i++
+
+		default:
+			j := i
+			w := 0
+			for j < len(items) && !items[j].space {
+				w += items[j].cell.Width
+				j++
+			}
+			if lo.fits(w) {
+				for ; i < j; i++ {
+					lo.place(items[i])
+				}
+				continue
+			}
+			if k := lo.hyphenate(items[i:j]); k > 0 {
+				for end := i + k; i < end; i++ {
+					lo.place(items[i])
+				}
+				lo.place(layoutItem{cell: StyledCell{Main: '-', Style: items[i-1].cell.Style, Width: 1}})
+				lo.newLine(false)
+				continue
+			}
+			if len(lo.line.Cells) > 0 {
+				lo.newLine(true)
+				continue
+			}
+			// too long for a line of its own, so break it anywhere,
+			// placing at least one character to make progress
+			n := 0
+			for i < j && (n == 0 || lo.fits(items[i].cell.Width)) {
+				lo.place(items[i])
+				i++
+				n++
+			}
+			if i < j {
+				lo.newLine(false)
+			}
+		}
+	}
+	lo.lines = append(lo.lines, lo.line)
+	return lo.lines
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"strings"
+	"testing"
+)
+
+func layoutStrings(lines []TextLine) []string {
+	var res []string
+	for _, l := range lines {
+		res = append(res, strings.Repeat(" ", l.Indent)+ansiText(l.Cells))
+	}
+	return res
+}
+
+func TestLayoutText(t *testing.T) {
+	cases := []struct {
+		text string
+		opts LayoutOptions
+		want []string
+	}{
+		{"the quick brown fox", LayoutOptions{Width: 10}, []string{"the quick", "brown fox"}},
+		{"the quick brown fox", LayoutOptions{Width: 10, Wrap: WrapChar}, []string{"the quick ", "brown fox"}},
+		{"the quick brown fox", LayoutOptions{Width: 6, Wrap: WrapNone}, []string{"the quick brown fox"}},
+		{"the quick brown fox", LayoutOptions{Width: 10, Indent: 2}, []string{"the quick", "  brown", "  fox"}},
+		{"abcdefghij xy", LayoutOptions{Width: 4}, []string{"abcd", "efgh", "ij", "xy"}},
+		{"日本語の文章", LayoutOptions{Width: 5}, []string{"日本", "語の", "文章"}},
+		{"a\tb\n\ncd", LayoutOptions{Width: 20, TabWidth: 4}, []string{"a   b", "", "cd"}},
+		{"one  two   three", LayoutOptions{Width: 8}, []string{"one  two", "three"}},
+		{"été long", LayoutOptions{Width: 4}, []string{"été", "long"}},
+		{"", LayoutOptions{Width: 4}, nil},
+		{"x\n", LayoutOptions{Width: 4}, []string{"x"}},
+		{"a/path/to/it", LayoutOptions{Width: 8, WordSpace: func(r rune) bool { return r == '/' }}, []string{"a/path/", "to/it"}},
+	}
+	for _, c := range cases {
+		got := layoutStrings(LayoutText([]TextSpan{{Text: c.text}}, c.opts))
+		if strings.Join(got, "|") != strings.Join(c.want, "|") || len(got) != len(c.want) {
+			t.Errorf("%q: got %q, expected %q", c.text, got, c.want)
+		}
+	}
+}
+
+func TestLayoutSpans(t *testing.T) {
+	red := StyleDefault.Foreground(ColorRed)
+	hyphenate := func(word string) []int {
+		if word == "wonderful" {
+			return []int{3, 6}
+		}
+		return nil
+	}
+	lines := LayoutText([]TextSpan{
+		{Text: "a ", Style: StyleDefault},
+		{Text: "wonderful", Style: red},
+		{Text: " day"},
+	}, LayoutOptions{Width: 9, Hyphenate: hyphenate})
+	got := layoutStrings(lines)
+	if want := []string{"a wonder-", "ful day"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, expected %q", got, want)
+	}
+	if lines[0].Cells[0].Style != StyleDefault || lines[0].Cells[8].Style != red || lines[1].Cells[0].Style != red {
+		t.Errorf("wrong styles")
+	}
+	if lines[0].Width() != 9 {
+		t.Errorf("wrong width: %d", lines[0].Width())
+	}
+
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+	if n := lines[1].Draw(s, 1, 0); n != 7 {
+		t.Errorf("wrong width drawn: %d", n)
+	}
+	s.Show()
+	cells, _, _ := s.GetContents()
+	if string(cells[1].Runes) != "f" || cells[1].Style != red || string(cells[7].Runes) != "y" {
+		t.Errorf("wrong content drawn")
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// screen lifecycle states
+const (
+	stateNew       int32 = iota // Init not called yet
+	stateActive                 // initialized, and using the terminal
+	stateSuspended              // suspended with Suspend
+	stateClosed                 // finalized with Fini
+)
+
+// lifecycle tracks the state of a screen from creation to Fini.  The
+// transitions are serialized, so that Init, Fini, Suspend, and Resume
+// may be called from any goroutine, and each one runs the implementation
+// only when it makes sense in the current state.  Fini in particular
+// runs the implementation exactly once, even if called before Init or
+// from several goroutines at once.
+type lifecycle struct {
+	state    int32 // accessed atomically, so it can be read without l
+	done     chan struct{}
+	doneOnce sync.Once
+	l        sync.Mutex
+}
+
+func (lc *lifecycle) init(fn func() error) error {
+	lc.l.Lock()
+	defer lc.l.Unlock()
+	switch atomic.LoadInt32(&lc.state) {
+	case stateClosed:
+		return ErrScreenClosed
+	case stateNew:
+		if err := fn(); err != nil {
+			return err
+		}
+		atomic.StoreInt32(&lc.state, stateActive)
+	}
+	return nil
+}
+
+func (lc *lifecycle) fini(fn func()) {
+	lc.l.Lock()
+	defer lc.l.Unlock()
+	if atomic.LoadInt32(&lc.state) == stateClosed {
+		return
+	}
+	atomic.StoreInt32(&lc.state, stateClosed)
+	fn()
+	close(lc.doneQ())
+}
+
+func (lc *lifecycle) suspend(fn func() error) error {
+	lc.l.Lock()
+	defer lc.l.Unlock()
+	switch atomic.LoadInt32(&lc.state) {
+	case stateClosed:
+		return ErrScreenClosed
+	case stateActive:
+		if err := fn(); err != nil {
+			return err
+		}
+		atomic.StoreInt32(&lc.state, stateSuspended)
+	}
+	return nil
+}
+
+func (lc *lifecycle) resume(fn func() error) error {
+	lc.l.Lock()
+	defer lc.l.Unlock()
+	switch atomic.LoadInt32(&lc.state) {
+	case stateClosed:
+		return ErrScreenClosed
+	case stateSuspended:
+		if err := fn(); err != nil {
+			return err
+		}
+		atomic.StoreInt32(&lc.state, stateActive)
+	}
+	return nil
+}
+
+// restart runs stop and then start if the screen is active, to recover
+// from a failure of the terminal.  If start fails, the screen is left
+// suspended, so that Resume can try again.
+func (lc *lifecycle) restart(stop func(), start func() error) error {
+	lc.l.Lock()
+	defer lc.l.Unlock()
+	switch atomic.LoadInt32(&lc.state) {
+	case stateClosed:
+		return ErrScreenClosed
+	case stateActive:
+		stop()
+		if err := start(); err != nil {
+			atomic.StoreInt32(&lc.state, stateSuspended)
+			return err
+		}
+	}
+	return nil
+}
+
+// closed returns true once Fini has been called, even if it has not
+// finished yet.
+func (lc *lifecycle) closed() bool {
+	return atomic.LoadInt32(&lc.state) == stateClosed
+}
+
+// doneQ returns the channel that is closed when Fini has finished.
+func (lc *lifecycle) doneQ() chan struct{} {
+	lc.doneOnce.Do(func() {
+		lc.done = make(chan struct{})
+	})
+	return lc.done
+}
@@ -0,0 +1,211 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"sync"
+	"time"
+)
+
+// OverflowPolicy determines which events are discarded when input arrives
+// faster than the limit set with Screen.SetInputLimits.
+type OverflowPolicy int
+
+const (
+	// OverflowCoalesceMouse discards mouse events that do not change the
+	// buttons held down (that is, motion), when more events are already
+	// waiting, so that only the most recent position is reported.
+	OverflowCoalesceMouse OverflowPolicy = 1 << iota
+
+	// OverflowDropRepeats discards key events that repeat the previous
+	// key, as happens when a key is held down.
+	OverflowDropRepeats
+)
+
+// InputLimits control how fast input events are delivered to the
+// application.  A terminal can send events much faster than some
+// applications can process them, for example when the mouse is moved
+// quickly, or when a large amount of text is pasted, leaving the
+// application unresponsive until it catches up.  Only key and mouse events
+// are limited; other events are always delivered.
+//
+// The zero value disables limiting.
+type InputLimits struct {
+	// Rate is the number of events per second that are delivered without
+	// restriction.  Beyond that, events are discarded according to the
+	// Overflow policy.  Zero disables rate limiting.
+	Rate int
+
+	// Burst is the number of events that may be delivered at once, before
+	// Rate applies.  Zero means the same as Rate.
+	Burst int
+
+	// Overflow is the set of events to discard when over the limit.
+	// Zero means OverflowCoalesceMouse | OverflowDropRepeats.
+	Overflow OverflowPolicy
+
+	// MaxPaste is the maximum number of keys delivered for a single
+	// bracketed paste (see EnablePaste).  The remainder of the paste is
+	// discarded.  Keys that are part of a paste are not otherwise limited,
+	// since discarding some of them would corrupt the pasted text.  Zero
+	// means no maximum.
+	MaxPaste int
+}
+
+// InputStats counts the events discarded due to the InputLimits, and
+// measures the latency of input events, from when the input was received
+// from the terminal until the event was delivered to the application.
+// A large latency indicates that the application is slow to process
+// events.
+type InputStats struct {
+	Coalesced    uint64 // mouse events coalesced
+	Dropped      uint64 // repeated keys dropped
+	PasteDropped uint64 // keys dropped from pastes that were too long
+
+	Delivered    uint64        // key, mouse, and paste events delivered
+	TotalLatency time.Duration // sum of the latencies of delivered events
+	MaxLatency   time.Duration // the largest latency seen
+}
+
+// MeanLatency returns the average latency of the delivered events.
+func (st InputStats) MeanLatency() time.Duration {
+	if st.Delivered == 0 {
+		return 0
+	}
+	return st.TotalLatency / time.Duration(st.Delivered)
+}
+
+// limiter is the state of input rate limiting.
+type limiter struct {
+	limits  InputLimits
+	stats   InputStats
+	tokens  float64
+	last    time.Time
+	buttons ButtonMask // buttons of the last mouse event
+	key     Key        // the last key event
+	ch      rune
+	mod     ModMask
+	pasting bool
+	pasted  int
+	l       sync.Mutex
+}
+
+func (b *baseScreen) SetInputLimits(limits InputLimits) {
+	b.limit.l.Lock()
+	b.limit.limits = limits
+	b.limit.tokens = float64(limits.Burst)
+	if limits.Burst <= 0 {
+		b.limit.tokens = float64(limits.Rate)
+	}
+	b.limit.last = time.Now()
+	b.limit.l.Unlock()
+}
+
+func (b *baseScreen) InputStats() InputStats {
+	b.limit.l.Lock()
+	defer b.limit.l.Unlock()
+	return b.limit.stats
+}
+
+// admitEvent returns false if the event should be discarded because of
+// the input limits.
+func (b *baseScreen) admitEvent(ev Event) bool {
+	lm := &b.limit
+	lm.l.Lock()
+	defer lm.l.Unlock()
+
+	switch ev := ev.(type) {
+	case *EventPaste:
+		lm.pasting = ev.Start()
+		lm.pasted = 0
+	case *EventKey:
+		if lm.pasting {
+			lm.pasted++
+			if lm.limits.MaxPaste > 0 && lm.pasted > lm.limits.MaxPaste {
+				lm.stats.PasteDropped++
+				return false
+			}
+			return true
+		}
+	}
+
+	limits := lm.limits
+	if limits.Rate <= 0 {
+		return true
+	}
+	burst := limits.Burst
+	if burst <= 0 {
+		burst = limits.Rate
+	}
+	policy := limits.Overflow
+	if policy == 0 {
+		policy = OverflowCoalesceMouse | OverflowDropRepeats
+	}
+
+	var over bool
+	switch ev.(type) {
+	case *EventKey, *EventMouse:
+		now := time.Now()
+		lm.tokens += now.Sub(lm.last).Seconds() * float64(limits.Rate)
+		if lm.tokens > float64(burst) {
+			lm.tokens = float64(burst)
+		}
+		lm.last = now
+		if lm.tokens >= 1 {
+			lm.tokens--
+		} else {
+			over = true
+		}
+	}
+
+	switch ev := ev.(type) {
+	case *EventMouse:
+		if over && policy&OverflowCoalesceMouse != 0 &&
+			ev.Buttons() == lm.buttons && b.HasPendingEvent() {
+			lm.stats.Coalesced++
+			return false
+		}
+		lm.buttons = ev.Buttons()
+	case *EventKey:
+		if over && policy&OverflowDropRepeats != 0 &&
+			ev.Key() == lm.key && ev.Rune() == lm.ch && ev.Modifiers() == lm.mod {
+			lm.stats.Dropped++
+			return false
+		}
+		lm.key, lm.ch, lm.mod = ev.Key(), ev.Rune(), ev.Modifiers()
+	}
+	return true
+}
+
+// noteDelivery records the latency of an input event that is being
+// delivered to the application.
+func (lm *limiter) noteDelivery(ev Event) {
+	switch ev.(type) {
+	case *EventKey, *EventMouse, *EventPaste:
+	default:
+		return
+	}
+	latency := time.Since(ev.When())
+	if latency < 0 {
+		latency = 0
+	}
+	lm.l.Lock()
+	lm.stats.Delivered++
+	lm.stats.TotalLatency += latency
+	if latency > lm.stats.MaxLatency {
+		lm.stats.MaxLatency = latency
+	}
+	lm.l.Unlock()
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"sync/atomic"
+)
+
+// Logger receives the debugging traces of tcell, such as what the terminal
+// reported about itself when the screen started, input that could not be
+// understood, characters that could not be displayed, and failures that
+// tcell otherwise works around silently.  The arguments after the message
+// are alternating keys and values.
+//
+// A *slog.Logger from log/slog is a Logger, so it can be passed directly
+// to SetLogger.  The traces are only meant for people, and their messages
+// and keys may change from one release to the next.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+}
+
+// loggerHolder wraps the Logger, as an atomic.Value must always hold the
+// same type.
+type loggerHolder struct {
+	Logger
+}
+
+var logger atomic.Value
+
+// SetLogger sets the Logger for debugging traces, for all screens.  If l
+// is nil, which is the default, nothing is logged.
+func SetLogger(l Logger) {
+	logger.Store(loggerHolder{l})
+}
+
+// logDebug sends a debugging trace to the Logger, if there is one.
+func logDebug(msg string, args ...interface{}) {
+	if h, ok := logger.Load().(loggerHolder); ok && h.Logger != nil {
+		h.Debug(msg, args...)
+	}
+}
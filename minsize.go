@@ -0,0 +1,129 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"fmt"
+	"sync"
+)
+
+// savedCell is the content of a cell hidden by the "too small" message.
+type savedCell struct {
+	mainc rune
+	combc []rune
+	style Style
+	tag   interface{}
+}
+
+// minSize is the smallest size the application can work with.  When the
+// screen is smaller, a message saying so is shown in place of the cells
+// while the screen is drawn, much like the selection, so the application
+// never sees it in the cell contents.
+type minSize struct {
+	w, h    int
+	saved   []savedCell
+	message []rune // what was shown in place of each saved cell
+	l       sync.Mutex
+}
+
+func (b *baseScreen) SetMinSize(width, height int) {
+	b.minSize.l.Lock()
+	b.minSize.w, b.minSize.h = width, height
+	b.minSize.l.Unlock()
+}
+
+// tooSmallMessage returns the lines of the message for a screen of
+// width by height that should be at least minW by minH.
+func tooSmallMessage(width, height, minW, minH int) []string {
+	return []string{
+		"Terminal too small",
+		fmt.Sprintf("%dx%d, needs %dx%d", width, height, minW, minH),
+	}
+}
+
+// applyMinSize replaces the contents of the screen with a message, if it
+// is smaller than the minimum size, remembering the contents so that they
+// can be restored afterwards by restoreMinSize.  It returns false if there
+// is nothing to do, or it is already applied.
+func (b *baseScreen) applyMinSize() bool {
+	b.minSize.l.Lock()
+	defer b.minSize.l.Unlock()
+	if b.minSize.saved != nil {
+		return false
+	}
+	cells := b.GetCells()
+	b.Lock()
+	defer b.Unlock()
+	w, h := cells.Size()
+	if (w >= b.minSize.w && h >= b.minSize.h) || w == 0 || h == 0 {
+		return false
+	}
+
+	message := make([]rune, w*h)
+	for i := range message {
+		message[i] = ' '
+	}
+	lines := tooSmallMessage(w, h, b.minSize.w, b.minSize.h)
+	top := (h - len(lines)) / 2
+	for i, line := range lines {
+		y := top + i
+		if y < 0 || y >= h {
+			continue
+		}
+		text := []rune(line)
+		if len(text) > w {
+			text = text[:w]
+		}
+		left := (w - len(text)) / 2
+		copy(message[y*w+left:], text)
+	}
+
+	saved := make([]savedCell, w*h)
+	for i := range saved {
+		x, y := i%w, i/w
+		mainc, combc, style, _ := cells.GetContent(x, y)
+		saved[i] = savedCell{mainc, combc, style, cells.GetTag(x, y)}
+		cells.SetContentWithTag(x, y, message[i], nil, StyleDefault, nil)
+	}
+	b.minSize.saved = saved
+	b.minSize.message = message
+	return true
+}
+
+func (b *baseScreen) restoreMinSize() {
+	b.minSize.l.Lock()
+	saved, message := b.minSize.saved, b.minSize.message
+	b.minSize.saved, b.minSize.message = nil, nil
+	b.minSize.l.Unlock()
+	if saved == nil {
+		return
+	}
+	cells := b.GetCells()
+	b.Lock()
+	defer b.Unlock()
+	w, h := cells.Size()
+	if w*h != len(saved) {
+		// resized while drawing; the contents are redrawn anyway
+		return
+	}
+	for i, orig := range saved {
+		x, y := i%w, i/w
+		mainc, _, style, _ := cells.GetContent(x, y)
+		// if the application changed the cell meanwhile, leave it alone
+		if mainc == message[i] && style == StyleDefault {
+			cells.SetContentWithTag(x, y, orig.mainc, orig.combc, orig.style, orig.tag)
+		}
+	}
+}
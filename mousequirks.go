@@ -0,0 +1,82 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"strings"
+)
+
+// MouseQuirks are the ways in which a terminal's mouse reports differ from
+// the XTerm protocols, or extend them, that tcell allows for.  They are
+// chosen from the terminfo name and the identity the terminal reports, and
+// can be seen in TerminalIdentity for debugging.
+type MouseQuirks int
+
+const (
+	// MouseQuirkMotionButton is for terminals that report motion with
+	// button 1 held, when no button is held.  Such motion is taken to be
+	// without buttons until a button is pressed.
+	MouseQuirkMotionButton = MouseQuirks(1 << iota)
+
+	// MouseQuirkWheelMotion is for terminals (rxvt-unicode) that set the
+	// motion bit in wheel reports while a button is held, which would
+	// otherwise make the wheel look like a drag.
+	MouseQuirkWheelMotion
+
+	// MouseQuirkURXVT is for terminals (rxvt-unicode) that may not have
+	// SGR mouse reports, so we also ask for, and understand, the decimal
+	// reports of mode 1015, which are not limited to 223 columns.
+	MouseQuirkURXVT
+
+	// MouseQuirkLocator is for terminals that may send DEC locator
+	// reports, which are understood as mouse events.
+	MouseQuirkLocator
+)
+
+// mouseQuirkNames are the names of the quirks, in order, for String.
+var mouseQuirkNames = []string{"MotionButton", "WheelMotion", "URXVT", "Locator"}
+
+// String returns the names of the quirks, separated by "|", for debugging.
+func (q MouseQuirks) String() string {
+	var names []string
+	for i, name := range mouseQuirkNames {
+		if q&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "None"
+	}
+	return strings.Join(names, "|")
+}
+
+// mouseQuirksFor returns the mouse quirks for the terminal with the given
+// terminfo name and reported identity (which may be empty, before the
+// terminal has been identified).  Terminals we know nothing about get the
+// quirks that do no harm to the others.
+func mouseQuirksFor(term string, id TerminalIdentity) MouseQuirks {
+	name := strings.ToLower(id.Name)
+	switch {
+	case strings.HasPrefix(term, "rxvt") || id.Type == 85:
+		// rxvt-unicode reports 85 in its secondary device attributes
+		return MouseQuirkWheelMotion | MouseQuirkURXVT
+	case strings.HasPrefix(name, "xterm"):
+		return MouseQuirkLocator
+	case strings.HasPrefix(name, "kitty"), strings.HasPrefix(name, "foot"),
+		strings.HasPrefix(name, "wezterm"), strings.HasPrefix(name, "ghostty"):
+		return 0
+	}
+	return MouseQuirkMotionButton | MouseQuirkLocator
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMouseQuirksFor(t *testing.T) {
+	cases := []struct {
+		term   string
+		id     TerminalIdentity
+		quirks MouseQuirks
+	}{
+		{"xterm", TerminalIdentity{}, MouseQuirkMotionButton | MouseQuirkLocator},
+		{"xterm", TerminalIdentity{Name: "XTerm", Version: "388"}, MouseQuirkLocator},
+		{"xterm-kitty", TerminalIdentity{Name: "kitty"}, 0},
+		{"rxvt-unicode-256color", TerminalIdentity{}, MouseQuirkWheelMotion | MouseQuirkURXVT},
+		{"xterm", TerminalIdentity{Type: 85}, MouseQuirkWheelMotion | MouseQuirkURXVT},
+	}
+	for _, c := range cases {
+		if q := mouseQuirksFor(c.term, c.id); q != c.quirks {
+			t.Errorf("%s %v: got %v, expected %v", c.term, c.id.Name, q, c.quirks)
+		}
+	}
+	if s := (MouseQuirkWheelMotion | MouseQuirkLocator).String(); s != "WheelMotion|Locator" {
+		t.Errorf("Wrong name: %q", s)
+	}
+	if s := MouseQuirks(0).String(); s != "None" {
+		t.Errorf("Wrong name: %q", s)
+	}
+}
+
+func TestMouseQuirks(t *testing.T) {
+	rxvt, err := LookupTerminfo("rxvt-unicode")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	cases := []struct {
+		name   string
+		ti     string
+		input  string
+		events []string
+	}{
+		// motion with button 1 before any press is without buttons
+		{"motion", "xterm", "\x1b[<32;3;4M", []string{"Mouse(2,3,0)"}},
+		{"motion-rxvt", "rxvt", "\x1b[<32;3;4M", []string{"Mouse(2,3,1)"}},
+		// the wheel with the motion bit is still the wheel
+		{"wheel", "rxvt", "\x1b[<96;3;4M", []string{"Mouse(2,3,256)"}},
+		{"wheel-xterm", "xterm", "\x1b[<96;3;4M", []string{"Mouse(2,3,0)"}},
+		// decimal reports are only for rxvt
+		{"1015", "rxvt", "\x1b[32;300;4M\x1b[35;300;4M", []string{"Mouse(299,3,1)", "Mouse(299,3,0)"}},
+		{"locator", "xterm", "\x1b[2;4;5;6;0&w", []string{"Mouse(5,4,1)"}},
+		{"locator-rxvt", "rxvt", "\x1b[0&w", []string{"Alt+Rune[[]", "Rune[0]", "Rune[&]", "Rune[w]"}},
+	}
+	for _, c := range cases {
+		opts := InputDecoderOptions{Width: 400, Height: 50}
+		if c.ti == "rxvt" {
+			opts.Terminfo = rxvt
+		}
+		d, err := NewInputDecoder(opts)
+		if err != nil {
+			t.Fatalf("%s: no decoder: %v", c.name, err)
+		}
+		evs := describeEvents(append(d.Decode([]byte(c.input)), d.Flush()...))
+		if !reflect.DeepEqual(evs, c.events) {
+			t.Errorf("%s: got %v, expected %v", c.name, evs, c.events)
+		}
+	}
+}
+
+func TestMouseQuirksEnable(t *testing.T) {
+	ti, err := LookupTerminfo("rxvt-unicode")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	tty := newRenderTty(10, 2)
+	s, err := NewTerminfoScreenFromTtyTerminfo(tty, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	if q := s.TerminalID().MouseQuirks; q&MouseQuirkURXVT == 0 {
+		t.Errorf("Wrong quirks: %v", q)
+	}
+	s.EnableMouse()
+	if out := tty.output(); !strings.Contains(out, "\x1b[?1015h\x1b[?1006h") {
+		t.Errorf("Decimal reports not asked for: %q", out)
+	}
+}
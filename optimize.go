@@ -0,0 +1,90 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"strings"
+)
+
+// Optimizations are ways of reducing the output needed to update the
+// terminal, which rely on the terminal behaving as expected.  Some
+// terminals get them wrong, so they can be turned on or off with
+// DisplayOptions, or with the TCELL_OPTIMIZE environment variable, which
+// is a comma separated list of the names below, each prefixed with "-"
+// to turn it off.  For example, TCELL_OPTIMIZE=-scroll,wideskip.  The
+// environment variable takes precedence, so that users can work around
+// problems with their terminals.
+type Optimizations int
+
+const (
+	// OptimizeScroll scrolls the terminal when the content has moved up
+	// by whole lines, instead of drawing it all again.  It is on by
+	// default for terminals that identify themselves, and is never used
+	// for inline screens.
+	OptimizeScroll Optimizations = 1 << iota
+
+	// OptimizeRepeat uses REP to draw runs of the same character.  It is
+	// on by default for terminals that identify themselves, as those are
+	// modern enough to support it.
+	OptimizeRepeat
+
+	// OptimizeWideSkip trusts the terminal to move the cursor past both
+	// cells of a wide character, rather than moving it explicitly.  This
+	// is off by default, as terminals often disagree about the width of
+	// characters such as emoji.
+	OptimizeWideSkip
+
+	// OptimizeStyleStack uses XTPUSHSGR and XTPOPSGR to save the style
+	// before drawing a short run of cells in another style (such as a
+	// highlighted word) and to restore it afterwards, rather than sending
+	// the whole of it again.  It is on by default for XTerm, which has
+	// had them since patch 334.
+	OptimizeStyleStack
+)
+
+var optimizationNames = map[string]Optimizations{
+	"scroll":     OptimizeScroll,
+	"repeat":     OptimizeRepeat,
+	"wideskip":   OptimizeWideSkip,
+	"stylestack": OptimizeStyleStack,
+}
+
+// pushSGR and popSGR are XTPUSHSGR (saving all of the style) and
+// XTPOPSGR.
+const (
+	pushSGR = "\x1b[#{"
+	popSGR  = "\x1b[#}"
+)
+
+// repeatMin is the shortest run of characters worth sending with REP.
+const repeatMin = 8
+
+// adjustOptimizations applies a list of optimizations to turn on or off,
+// in the form used by TCELL_OPTIMIZE.  Unknown names are ignored.
+func adjustOptimizations(opt Optimizations, spec string) Optimizations {
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		off := strings.HasPrefix(name, "-")
+		name = strings.TrimLeft(name, "+-")
+		if o, ok := optimizationNames[name]; ok {
+			if off {
+				opt &^= o
+			} else {
+				opt |= o
+			}
+		}
+	}
+	return opt
+}
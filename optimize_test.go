@@ -0,0 +1,419 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// renderTty is a Tty that interprets enough of what is written to it to
+// check what a screen draws.  It answers the device attributes query, so
+// that screens start quickly.  Reads time out, as for a real terminal, so
+// that the screen can stop reading.
+type renderTty struct {
+	w, h    int
+	cells   [][]rune
+	cx, cy  int
+	wrap    bool
+	last    rune
+	pending []byte // incomplete output
+	written bytes.Buffer
+	input   chan []byte
+	l       sync.Mutex
+}
+
+func newRenderTty(w, h int) *renderTty {
+	tty := &renderTty{w: w, h: h, wrap: true, input: make(chan []byte, 10)}
+	tty.cells = make([][]rune, h)
+	for y := range tty.cells {
+		tty.cells[y] = []rune(strings.Repeat(" ", w))
+	}
+	return tty
+}
+
+func (tty *renderTty) Start() error        { return nil }
+func (tty *renderTty) Stop() error         { return nil }
+func (tty *renderTty) Drain() error        { return nil }
+func (tty *renderTty) NotifyResize(func()) {}
+func (tty *renderTty) Close() error        { return nil }
+func (tty *renderTty) WindowSize() (WindowSize, error) {
+	return WindowSize{Width: tty.w, Height: tty.h}, nil
+}
+
+func (tty *renderTty) Read(b []byte) (int, error) {
+	select {
+	case data := <-tty.input:
+		return copy(b, data), nil
+	case <-time.After(10 * time.Millisecond):
+		return 0, nil
+	}
+}
+
+func (tty *renderTty) Write(b []byte) (int, error) {
+	tty.l.Lock()
+	defer tty.l.Unlock()
+	tty.written.Write(b)
+	if bytes.Contains(b, []byte(queryDA1)) {
+		tty.input <- []byte("\x1b[?62;22c")
+	}
+	tty.pending = append(tty.pending, b...)
+	for len(tty.pending) > 0 {
+		n := tty.interpret(tty.pending)
+		if n == 0 {
+			break
+		}
+		tty.pending = tty.pending[n:]
+	}
+	return len(b), nil
+}
+
+// interpret handles the sequence or character at the start of b, and
+// returns its length, or zero if it is incomplete.
+func (tty *renderTty) interpret(b []byte) int {
+	switch b[0] {
+	case '\x1b':
+		if len(b) < 2 {
+			return 0
+		}
+		switch b[1] {
+		case '[':
+			for i := 2; i < len(b); i++ {
+				if b[i] >= 0x40 && b[i] <= 0x7e {
+					tty.csi(string(b[2:i]), b[i])
+					return i + 1
+				}
+			}
+			return 0
+		case ']', 'P':
+			for i := 2; i < len(b); i++ {
+				if b[i] == '\a' {
+					return i + 1
+				}
+				if b[i] == '\\' && b[i-1] == '\x1b' {
+					return i + 1
+				}
+			}
+			return 0
+		case '(', ')':
+			if len(b) < 3 {
+				return 0
+			}
+			return 3
+		}
+		return 2
+	case '\n':
+		tty.cy++
+		if tty.cy == tty.h {
+			tty.cells = append(tty.cells[1:], []rune(strings.Repeat(" ", tty.w)))
+			tty.cy--
+		}
+		return 1
+	case '\r':
+		tty.cx = 0
+		return 1
+	}
+	if b[0] < ' ' {
+		return 1
+	}
+	if !utf8.FullRune(b) {
+		return 0
+	}
+	r, n := utf8.DecodeRune(b)
+	tty.put(r)
+	return n
+}
+
+func (tty *renderTty) put(r rune) {
+	w := runewidth.RuneWidth(r)
+	if tty.cx+w > tty.w {
+		if !tty.wrap {
+			tty.cx = tty.w - w
+		} else {
+			tty.cx = 0
+			tty.cy++
+		}
+	}
+	if tty.cy >= tty.h {
+		return
+	}
+	tty.cells[tty.cy][tty.cx] = r
+	if w > 1 {
+		tty.cells[tty.cy][tty.cx+1] = 0
+	}
+	tty.cx += w
+	tty.last = r
+}
+
+func (tty *renderTty) csi(params string, final byte) {
+	if strings.HasPrefix(params, "?") {
+		switch params {
+		case "?7h":
+			tty.wrap = true
+		case "?7l":
+			tty.wrap = false
+		}
+		return
+	}
+	var args []int
+	for _, p := range strings.Split(params, ";") {
+		v, _ := strconv.Atoi(p)
+		args = append(args, v)
+	}
+	switch final {
+	case 'H':
+		tty.cy, tty.cx = 0, 0
+		if len(args) == 2 {
+			tty.cy, tty.cx = args[0]-1, args[1]-1
+		}
+	case 'J':
+		// only erasing below (the default) and all are used
+		for y := range tty.cells {
+			if args[0] == 2 || y > tty.cy {
+				tty.cells[y] = []rune(strings.Repeat(" ", tty.w))
+			} else if y == tty.cy {
+				copy(tty.cells[y][tty.cx:], []rune(strings.Repeat(" ", tty.w-tty.cx)))
+			}
+		}
+	case 'b':
+		for i := 0; i < args[0]; i++ {
+			tty.put(tty.last)
+		}
+	case '@':
+		n := args[0]
+		if n == 0 {
+			n = 1
+		}
+		row := tty.cells[tty.cy]
+		copy(row[tty.cx+n:], row[tty.cx:])
+		for i := 0; i < n; i++ {
+			row[tty.cx+i] = ' '
+		}
+	}
+}
+
+// contents returns the lines displayed.
+func (tty *renderTty) contents() []string {
+	tty.l.Lock()
+	defer tty.l.Unlock()
+	var lines []string
+	for _, row := range tty.cells {
+		lines = append(lines, strings.Replace(string(row), "\x00", "", -1))
+	}
+	return lines
+}
+
+func (tty *renderTty) output() string {
+	tty.l.Lock()
+	defer tty.l.Unlock()
+	s := tty.written.String()
+	tty.written.Reset()
+	return s
+}
+
+func drawLines(s Screen, lines []string) {
+	for y, line := range lines {
+		x := 0
+		for _, r := range line {
+			s.SetContent(x, y, r, nil, StyleDefault)
+			x += runewidth.RuneWidth(r)
+		}
+	}
+}
+
+func TestOptimizations(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	frame := []string{
+		"first line          ",
+		"====================",
+		"wide 世界 chars     ",
+		"aaaaaaaaaaaaaaaaaaaa",
+		"line 4              ",
+		"line 5              ",
+		"line 6              ",
+		"line 7              ",
+		"line 8              ",
+		"last line ----------",
+	}
+	cases := []struct {
+		name string
+		opt  Optimizations
+	}{
+		{"none", 0},
+		{"scroll", OptimizeScroll},
+		{"repeat", OptimizeRepeat},
+		{"wideskip", OptimizeWideSkip},
+		{"all", OptimizeScroll | OptimizeRepeat | OptimizeWideSkip},
+	}
+	for _, tc := range cases {
+		tty := newRenderTty(20, 10)
+		s, err := NewTerminfoScreenFromTtyTerminfo(tty, ti)
+		if err != nil {
+			t.Fatalf("Failed to create screen: %v", err)
+		}
+		s.SetDisplayOptions(DisplayOptions{Optimize: tc.opt, NoOptimize: ^tc.opt})
+		if err := s.Init(); err != nil {
+			t.Fatalf("Failed to initialize screen: %v", err)
+		}
+
+		drawLines(s, frame)
+		s.Show()
+		if got := tty.contents(); strings.Join(got, "\n") != strings.Join(frame, "\n") {
+			t.Errorf("%s: wrong first frame:\n%s", tc.name, strings.Join(got, "\n"))
+		}
+		out := tty.output()
+		if rep := strings.Contains(out, "\x1b[19b"); rep != (tc.opt&OptimizeRepeat != 0) {
+			t.Errorf("%s: REP used: %v", tc.name, rep)
+		}
+
+		scrolled := append(append([]string{}, frame[3:]...), "new 1               ", "new 2               ", "new 3               ")
+		drawLines(s, scrolled)
+		s.Show()
+		if got := tty.contents(); strings.Join(got, "\n") != strings.Join(scrolled, "\n") {
+			t.Errorf("%s: wrong scrolled frame:\n%s", tc.name, strings.Join(got, "\n"))
+		}
+		out = tty.output()
+		if sc := strings.Contains(out, "\n\n\n"); sc != (tc.opt&OptimizeScroll != 0) {
+			t.Errorf("%s: scrolled: %v", tc.name, sc)
+		}
+		s.Fini()
+	}
+}
+
+func TestDefaultOptimizations(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	ts, err := newTScreen(nil, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	if opt := ts.optimizations(); opt != 0 {
+		t.Errorf("Optimizations for an unidentified terminal: %v", opt)
+	}
+	ts.ident.Name, ts.ident.Version = "XTerm", "390"
+	if opt := ts.optimizations(); opt != OptimizeScroll|OptimizeRepeat|OptimizeStyleStack {
+		t.Errorf("Wrong optimizations for XTerm: %v", opt)
+	}
+}
+
+func TestAdjustOptimizations(t *testing.T) {
+	opt := adjustOptimizations(OptimizeScroll|OptimizeRepeat, "-scroll, WideSkip,bogus")
+	if opt != OptimizeRepeat|OptimizeWideSkip {
+		t.Errorf("wrong optimizations: %v", opt)
+	}
+}
+
+func TestShowCells(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	tty := newRenderTty(20, 3)
+	s, err := NewTerminfoScreenFromTtyTerminfo(tty, ti)
+	if err != nil {
+		t.Fatalf("Failed to create screen: %v", err)
+	}
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	s.Show()
+	tty.output()
+
+	drawLines(s, []string{"top", "typed", "bottom"})
+	s.ShowCells(0, 1, 5)
+	out := tty.output()
+	if !strings.Contains(out, "typed") || strings.Contains(out, "top") || strings.Contains(out, "bottom") {
+		t.Errorf("wrong cells shown: %q", out)
+	}
+	if got := tty.contents(); strings.TrimSpace(got[1]) != "typed" || strings.TrimSpace(got[0]) != "" {
+		t.Errorf("wrong contents: %q", got)
+	}
+
+	// the rest is sent by Show, but not the cells already shown
+	s.Show()
+	out = tty.output()
+	if strings.Contains(out, "typed") || !strings.Contains(out, "top") || !strings.Contains(out, "bottom") {
+		t.Errorf("wrong cells shown after: %q", out)
+	}
+
+	// with a selection drawn, it is the same as Show
+	s.EnableSelection(StyleDefault.Reverse(true), false)
+	s.(*baseScreen).sel.active = true
+	drawLines(s, []string{"TOP", "TYPED"})
+	s.ShowCells(0, 1, 5)
+	if got := tty.contents(); strings.TrimSpace(got[0]) != "TOP" {
+		t.Errorf("overlaid screen not shown whole: %q", got)
+	}
+}
+
+func TestStyleStack(t *testing.T) {
+	ti, err := LookupTerminfo("xterm-256color")
+	if err != nil {
+		t.Skipf("No terminfo: %v", err)
+	}
+	base := StyleDefault.Foreground(NewRGBColor(0x10, 0x20, 0x30)).Background(NewRGBColor(0xf0, 0xe0, 0xd0)).Underline(true)
+	mark := base.Reverse(true)
+	var sizes [2]int
+	for i, opt := range []Optimizations{0, OptimizeStyleStack} {
+		tty := newRenderTty(20, 2)
+		s, err := NewTerminfoScreenFromTtyTerminfo(tty, ti)
+		if err != nil {
+			t.Fatalf("Failed to create screen: %v", err)
+		}
+		s.SetDisplayOptions(DisplayOptions{Optimize: opt, NoOptimize: ^opt})
+		if err := s.Init(); err != nil {
+			t.Fatalf("Failed to initialize screen: %v", err)
+		}
+		s.Show()
+		tty.output()
+
+		for x, r := range "some marked text" {
+			style := base
+			if x >= 5 && x < 11 {
+				style = mark
+			}
+			s.SetContent(x, 0, r, nil, style)
+		}
+		s.SetContent(0, 1, 'z', nil, StyleDefault)
+		s.Show()
+		out := tty.output()
+		sizes[i] = len(out)
+		if got := tty.contents()[0]; got != "some marked text    " {
+			t.Errorf("%v: wrong contents: %q", opt, got)
+		}
+		pushes, pops := strings.Count(out, pushSGR), strings.Count(out, popSGR)
+		if expected := i; pushes != expected || pops != expected {
+			t.Errorf("%v: %d pushes, %d pops", opt, pushes, pops)
+		}
+		s.Fini()
+	}
+	if sizes[1] >= sizes[0] {
+		t.Errorf("style stack did not save output: %d >= %d", sizes[1], sizes[0])
+	}
+	t.Logf("output %d bytes, %d with the style stack", sizes[0], sizes[1])
+}
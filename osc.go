@@ -0,0 +1,129 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// EventOSC is an operating system command (OSC) received from the
+// terminal, for one of the numbers requested with InputOptions.OSC.  This
+// allows applications to use private or terminal specific commands,
+// typically replies to commands sent with Screen.SendOSC.
+type EventOSC struct {
+	t    time.Time
+	code int
+	data string
+}
+
+// NewEventOSC returns an EventOSC for the command number and data.
+func NewEventOSC(code int, data string) *EventOSC {
+	return &EventOSC{t: time.Now(), code: code, data: data}
+}
+
+// When returns the time when the command was received.
+func (ev *EventOSC) When() time.Time {
+	return ev.t
+}
+
+// Code returns the number of the command.
+func (ev *EventOSC) Code() int {
+	return ev.code
+}
+
+// Data returns the text after the number and the following semicolon,
+// without the terminator.  It is empty if there was no semicolon.
+func (ev *EventOSC) Data() string {
+	return ev.data
+}
+
+// oscString returns the OSC sequence for the number and data, terminated
+// by ST.  Control characters are removed from the data, so that it cannot
+// end the sequence early or start another one.
+func oscString(code int, data string) string {
+	data = strings.Map(func(r rune) rune {
+		if r < ' ' || (r >= 0x7f && r < 0xa0) {
+			return -1
+		}
+		return r
+	}, data)
+	return "\x1b]" + strconv.Itoa(code) + ";" + data + "\x1b\\"
+}
+
+// parseOSC reports an OSC sequence at the start of buf as an EventOSC, if
+// its number is one of codes.
+func parseOSC(buf *bytes.Buffer, evs *[]Event, codes []int) (bool, bool) {
+	b := buf.Bytes()
+	if len(b) < 3 {
+		return len(codes) > 0 && bytes.HasPrefix([]byte("\x1b]"), b), false
+	}
+	if b[0] != '\x1b' || b[1] != ']' {
+		return false, false
+	}
+	i := 2
+	for i < len(b) && b[i] >= '0' && b[i] <= '9' {
+		i++
+	}
+	if i == len(b) {
+		// the number may not be complete yet
+		return len(codes) > 0, false
+	}
+	if i == 2 {
+		return false, false
+	}
+	code, err := strconv.Atoi(string(b[2:i]))
+	if err != nil || !oscWanted(code, codes) {
+		return false, false
+	}
+	n, ok := rawSequenceLen(b)
+	if !ok {
+		return false, false
+	}
+	if n == 0 {
+		return true, false
+	}
+	data := b[i:n]
+	if bytes.HasSuffix(data, []byte("\x1b\\")) {
+		data = data[:len(data)-2]
+	} else {
+		data = data[:len(data)-1]
+	}
+	if len(data) > 0 {
+		if data[0] != ';' {
+			return false, false
+		}
+		data = data[1:]
+	}
+	if utf8.Valid(data) {
+		*evs = append(*evs, NewEventOSC(code, string(data)))
+	} else {
+		*evs = append(*evs, NewEventError(ErrInvalidString))
+	}
+	buf.Next(n)
+	return true, true
+}
+
+func oscWanted(code int, codes []int) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"encoding/binary"
+	"errors"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// CellPatch is a change to the contents of a Buffer.  Patches are made by
+// Buffer.Diff, and applied by Buffer.Apply, which allows the contents of
+// a screen to be mirrored elsewhere, such as over a network connection,
+// by sending only what has changed since the last frame.
+//
+// A patch either replaces a run of cells in a row, starting at X, Y, with
+// the characters in Cells (each a main rune followed by any combining
+// runes), all in Style; or, if Width and Height are not zero, it resizes
+// the buffer, leaving it blank.
+type CellPatch struct {
+	X, Y          int
+	Style         Style
+	Cells         []string
+	Width, Height int
+}
+
+// ErrBadPatch is returned by DecodePatches for malformed data.
+var ErrBadPatch = errors.New("malformed cell patch")
+
+// maxPatchSize is the largest width or height, and maxPatchCells the most
+// cells, that a patch may resize a buffer to, so that bad data cannot make
+// Apply allocate without limit.
+const (
+	maxPatchSize  = 1 << 12
+	maxPatchCells = 1 << 20
+)
+
+// validPatchSize returns true if a buffer may be resized to w by h.
+func validPatchSize(w, h int) bool {
+	return w >= 0 && h >= 0 && w <= maxPatchSize && h <= maxPatchSize && w*h <= maxPatchCells
+}
+
+// cellText returns the characters of a cell, as held in a CellPatch.
+// Cells that were never drawn are blank.
+func cellText(c *cell) string {
+	if c.currMain == 0 {
+		return " "
+	}
+	return string(c.currMain) + string(c.currComb)
+}
+
+// Diff returns the patches that change prev into buf.  Runs of changed
+// cells in a row that have the same style are sent together.  If prev is
+// nil, or has a different size, the patches start by resizing it, and
+// then draw each cell that is not blank.  The cursor and the default
+// style are not included.
+func (buf *Buffer) Diff(prev *Buffer) []CellPatch {
+	var patches []CellPatch
+	blank := &cell{currMain: ' '}
+	if prev == nil || prev.w != buf.w || prev.h != buf.h {
+		patches = append(patches, CellPatch{Width: buf.w, Height: buf.h})
+		prev = nil
+	}
+	for y := 0; y < buf.h; y++ {
+		var run *CellPatch
+		for x := 0; x < buf.w; x++ {
+			c := &buf.cells[y*buf.w+x]
+			old := blank
+			if prev != nil {
+				old = &prev.cells[y*buf.w+x]
+			}
+			text := cellText(c)
+			if text == cellText(old) && c.currStyle == old.currStyle {
+				run = nil
+				continue
+			}
+			if run == nil || run.Style != c.currStyle {
+				patches = append(patches, CellPatch{X: x, Y: y, Style: c.currStyle})
+				run = &patches[len(patches)-1]
+			}
+			run.Cells = append(run.Cells, text)
+		}
+	}
+	return patches
+}
+
+// Apply changes the contents of buf with the patches.  Cells outside the
+// buffer are ignored, as are patches that resize it to a negative size, or
+// to more than 4096 cells in either direction, or more than 1048576 cells
+// in all.  The zero value of Buffer is empty, so a mirror can
+// start with one, and apply patches made against a nil Buffer.
+func (buf *Buffer) Apply(patches []CellPatch) {
+	for _, p := range patches {
+		if p.Width != 0 || p.Height != 0 {
+			if !validPatchSize(p.Width, p.Height) {
+				continue
+			}
+			buf.w, buf.h = p.Width, p.Height
+			buf.cells = make([]cell, buf.w*buf.h)
+			for i := range buf.cells {
+				buf.cells[i] = cell{currMain: ' ', width: 1}
+			}
+			continue
+		}
+		if p.Y < 0 || p.Y >= buf.h {
+			continue
+		}
+		for i, text := range p.Cells {
+			x := p.X + i
+			if x < 0 || x >= buf.w {
+				continue
+			}
+			runes := []rune(text)
+			c := &buf.cells[p.Y*buf.w+x]
+			*c = cell{currMain: ' ', currStyle: p.Style, width: 1}
+			if len(runes) > 0 {
+				c.currMain = runes[0]
+				c.width = runewidth.RuneWidth(runes[0])
+			}
+			if len(runes) > 1 {
+				c.currComb = runes[1:]
+			}
+		}
+	}
+}
+
+// patch flags in the encoding
+const (
+	patchResize    = 1 << iota // the patch resizes the buffer
+	patchSameStyle             // the style is the same as that of the last patch
+)
+
+// EncodePatches returns a compact binary encoding of the patches, for
+// sending to a mirror, which decodes them with DecodePatches.  Numbers are
+// encoded as varints, and a style is only sent when it differs from the
+// one before it.
+func EncodePatches(patches []CellPatch) []byte {
+	var b []byte
+	var tmp [binary.MaxVarintLen64]byte
+	num := func(v uint64) {
+		b = append(b, tmp[:binary.PutUvarint(tmp[:], v)]...)
+	}
+	str := func(s string) {
+		num(uint64(len(s)))
+		b = append(b, s...)
+	}
+	last := StyleDefault
+	for _, p := range patches {
+		if p.Width != 0 || p.Height != 0 {
+			num(patchResize)
+			num(uint64(p.Width))
+			num(uint64(p.Height))
+			continue
+		}
+		if p.Style == last {
+			num(patchSameStyle)
+		} else {
+			num(0)
+		}
+		num(uint64(p.X))
+		num(uint64(p.Y))
+		if p.Style != last {
+			st := p.Style
+			num(uint64(st.fg))
+			num(uint64(st.bg))
+			num(uint64(st.ulColor))
+			num(uint64(st.ulStyle))
+			num(uint64(st.attrs))
+			str(st.url)
+			str(st.urlId)
+			last = st
+		}
+		num(uint64(len(p.Cells)))
+		for _, text := range p.Cells {
+			str(text)
+		}
+	}
+	return b
+}
+
+// DecodePatches decodes patches encoded by EncodePatches.
+func DecodePatches(b []byte) ([]CellPatch, error) {
+	var patches []CellPatch
+	bad := false
+	num := func() uint64 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			bad = true
+			b = nil
+			return 0
+		}
+		b = b[n:]
+		return v
+	}
+	str := func() string {
+		n := num()
+		if n > uint64(len(b)) {
+			bad = true
+			b = nil
+			return ""
+		}
+		s := string(b[:n])
+		b = b[n:]
+		return s
+	}
+	last := StyleDefault
+	for len(b) > 0 && !bad {
+		flags := num()
+		if flags&patchResize != 0 {
+			w, h := num(), num()
+			if w > maxPatchSize || h > maxPatchSize || !validPatchSize(int(w), int(h)) {
+				return nil, ErrBadPatch
+			}
+			patches = append(patches, CellPatch{Width: int(w), Height: int(h)})
+			continue
+		}
+		p := CellPatch{X: int(num()), Y: int(num()), Style: last}
+		if flags&patchSameStyle == 0 {
+			p.Style = Style{
+				fg:      Color(num()),
+				bg:      Color(num()),
+				ulColor: Color(num()),
+				ulStyle: UnderlineStyle(num()),
+				attrs:   AttrMask(num()),
+				url:     str(),
+				urlId:   str(),
+			}
+			last = p.Style
+		}
+		n := num()
+		if n > uint64(len(b)) {
+			return nil, ErrBadPatch // each cell takes at least a byte
+		}
+		for i := uint64(0); i < n; i++ {
+			p.Cells = append(p.Cells, str())
+		}
+		patches = append(patches, p)
+	}
+	if bad {
+		return nil, ErrBadPatch
+	}
+	return patches, nil
+}
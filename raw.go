@@ -0,0 +1,102 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"time"
+)
+
+// EventRaw is an escape sequence received from the terminal that tcell
+// does not understand.  These are only delivered if enabled with
+// InputOptions.RawSequences, and allow applications to handle sequences
+// that are specific to a particular terminal.  Only complete control
+// sequences (CSI), operating system commands (OSC), and device control
+// strings (DCS) are reported this way.
+type EventRaw struct {
+	t    time.Time
+	data []byte
+}
+
+// NewEventRaw returns an EventRaw for the sequence.
+func NewEventRaw(data []byte) *EventRaw {
+	return &EventRaw{t: time.Now(), data: data}
+}
+
+// When returns the time when the sequence was received.
+func (ev *EventRaw) When() time.Time {
+	return ev.t
+}
+
+// Data returns the complete sequence, including the leading ESC.
+func (ev *EventRaw) Data() []byte {
+	return ev.data
+}
+
+// Kind returns the character after the ESC that identifies the kind of
+// sequence: '[' for CSI, ']' for OSC, or 'P' for DCS.
+func (ev *EventRaw) Kind() byte {
+	if len(ev.data) < 2 {
+		return 0
+	}
+	return ev.data[1]
+}
+
+// rawSequenceLen returns the length of the CSI, OSC, or DCS sequence at
+// the start of b.  If b does not start with one, then ok is false.  If it
+// starts with an incomplete one, then n is zero.
+func rawSequenceLen(b []byte) (n int, ok bool) {
+	if len(b) < 2 {
+		return 0, len(b) == 1 && b[0] == '\x1b'
+	}
+	if b[0] != '\x1b' {
+		return 0, false
+	}
+	switch b[1] {
+	case '[':
+		// parameters, then intermediates, then a final byte
+		i := 2
+		for i < len(b) && b[i] >= 0x30 && b[i] <= 0x3f {
+			i++
+		}
+		for i < len(b) && b[i] >= 0x20 && b[i] <= 0x2f {
+			i++
+		}
+		if i == len(b) {
+			return 0, true
+		}
+		if b[i] < 0x40 || b[i] > 0x7e {
+			return 0, false
+		}
+		return i + 1, true
+	case ']', 'P':
+		// terminated by ST, or BEL for OSC
+		for i := 2; i < len(b); i++ {
+			switch {
+			case b[i] == '\a' && b[1] == ']':
+				return i + 1, true
+			case b[i] == '\x1b':
+				if i+1 == len(b) {
+					return 0, true
+				}
+				if b[i+1] != '\\' {
+					return 0, false
+				}
+				return i + 2, true
+			}
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,149 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"strings"
+	"sync"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// reflowLine is a logical line written with AppendLine.
+type reflowLine struct {
+	text  string
+	style Style
+}
+
+// reflow holds the logical lines for the line oriented mode, so that
+// the screen contents can be wrapped again when the screen is resized.
+type reflow struct {
+	enabled bool
+	lines   []reflowLine
+	l       sync.Mutex
+}
+
+// reflowCell is a single cell of a wrapped row.
+type reflowCell struct {
+	mainc rune
+	combc []rune
+	style Style
+	width int
+}
+
+// wrapLine breaks a logical line into rows no wider than w cells.
+// Wide characters that do not fit at the end of a row are moved to the
+// next row, and combining characters stay with the preceding character.
+// An empty line still occupies one row.  The widths of the runes are
+// according to cond (see runeWidth).
+func wrapLine(line reflowLine, w int, cond *runewidth.Condition) [][]reflowCell {
+	var rows [][]reflowCell
+	var row []reflowCell
+	col := 0
+	for _, r := range ShapeText([]rune(line.text)) {
+		width := runeWidth(cond, r)
+		if width == 0 {
+			if len(row) > 0 {
+				row[len(row)-1].combc = append(row[len(row)-1].combc, r)
+			}
+			continue
+		}
+		if col+width > w && col > 0 {
+			rows = append(rows, row)
+			row = nil
+			col = 0
+		}
+		row = append(row, reflowCell{mainc: r, style: line.style, width: width})
+		col += width
+	}
+	return append(rows, row)
+}
+
+func (b *baseScreen) EnableReflow() {
+	b.reflow.l.Lock()
+	b.reflow.enabled = true
+	b.reflow.l.Unlock()
+	b.drawLines()
+}
+
+func (b *baseScreen) DisableReflow() {
+	b.reflow.l.Lock()
+	b.reflow.enabled = false
+	b.reflow.lines = nil
+	b.reflow.l.Unlock()
+}
+
+func (b *baseScreen) AppendLine(text string, style Style) {
+	b.reflow.l.Lock()
+	if !b.reflow.enabled {
+		b.reflow.l.Unlock()
+		return
+	}
+	for _, s := range strings.Split(text, "\n") {
+		b.reflow.lines = append(b.reflow.lines, reflowLine{text: s, style: style})
+	}
+	b.reflow.l.Unlock()
+	b.drawLines()
+}
+
+func (b *baseScreen) ClearLines() {
+	b.reflow.l.Lock()
+	b.reflow.lines = nil
+	b.reflow.l.Unlock()
+	b.drawLines()
+}
+
+// drawLines wraps the logical lines to the current width, and fills the
+// cell buffer with them.  If they do not all fit, the oldest lines
+// are the ones left out.
+func (b *baseScreen) drawLines() {
+	b.reflow.l.Lock()
+	defer b.reflow.l.Unlock()
+	if !b.reflow.enabled {
+		return
+	}
+	cells := b.GetCells()
+	b.Lock()
+	defer b.Unlock()
+
+	w, h := cells.Size()
+	if w <= 0 || h <= 0 {
+		return
+	}
+	// only wrap as many lines (from the end) as can possibly be visible
+	var rows [][]reflowCell
+	for i := len(b.reflow.lines) - 1; i >= 0 && len(rows) < h; i-- {
+		rows = append(wrapLine(b.reflow.lines[i], w, cells.cond), rows...)
+	}
+	if len(rows) > h {
+		rows = rows[len(rows)-h:]
+	}
+
+	cells.Fill(' ', StyleDefault)
+	for y, row := range rows {
+		x := 0
+		for _, c := range row {
+			cells.SetContent(x, y, c.mainc, c.combc, c.style)
+			x += c.width
+		}
+	}
+}
+
+// handleReflow wraps the lines again when the screen is resized.
+func (b *baseScreen) handleReflow(ev Event) {
+	if _, ok := ev.(*EventResize); ok {
+		b.drawLines()
+	}
+}
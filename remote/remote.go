@@ -0,0 +1,262 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package remote serves a tcell Screen to web browsers, so that a terminal
+// application can be viewed and used in a browser tab, without building it
+// for WebAssembly.  The application draws on a screen as usual (typically
+// a SimulationScreen, so that it need not run in a terminal), and the
+// Server sends what changes to each connected browser over a WebSocket,
+// as patches made by tcell.Buffer.Diff.  Keys and mouse events from the
+// browser are posted to the screen as events.
+//
+//	s := tcell.NewSimulationScreen("")
+//	_ = s.Init()
+//	http.Handle("/", remote.NewServer(s))
+//	go http.ListenAndServe("localhost:8080", nil)
+//	// run the application on s as usual ...
+//
+// The viewer is a small page served by the Server itself.  With a
+// SimulationScreen, the screen is resized to fit the browser window (the
+// most recent viewer to resize wins, up to 1000 by 1000), and an
+// EventResize is posted.  Other screens keep their size.  The cursor is
+// not shown.
+package remote
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// maxSize is the largest width or height a viewer can resize the screen
+// to, so that a client cannot make it allocate without limit.
+const maxSize = 1000
+
+// Server is an http.Handler that serves the viewer page, and the WebSocket
+// that it connects to.
+type Server struct {
+	screen tcell.Screen
+
+	// Interval is how often the screen is checked for changes to send.
+	// The default is 50 milliseconds.
+	Interval time.Duration
+
+	// CheckOrigin returns true if the WebSocket request may be accepted.
+	// If it is nil, only requests from the same origin as the server are
+	// accepted (or without an Origin, as sent by clients other than
+	// browsers), so that other web pages the user visits cannot connect
+	// and send input to the application.
+	CheckOrigin func(r *http.Request) bool
+}
+
+// sameOrigin returns true if the request has no Origin header, or if its
+// host is the host of the request.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// NewServer returns a Server for the screen, which should already be
+// initialized.
+func NewServer(s tcell.Screen) *Server {
+	return &Server{screen: s, Interval: time.Millisecond * 50}
+}
+
+// ServeHTTP serves the viewer page, or the WebSocket if the request is to
+// upgrade to one.
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !isWebSocket(r) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(viewerPage))
+		return
+	}
+	check := srv.CheckOrigin
+	if check == nil {
+		check = sameOrigin
+	}
+	if !check(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	ws, err := upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			op, msg, err := ws.readMessage()
+			if err != nil {
+				return
+			}
+			if op == opText {
+				srv.handleMessage(msg)
+			}
+		}
+	}()
+
+	interval := srv.Interval
+	if interval <= 0 {
+		interval = time.Millisecond * 50
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var prev *tcell.Buffer
+	for {
+		buf := srv.screen.CaptureContents()
+		if patches := buf.Diff(prev); len(patches) > 0 {
+			if ws.writeFrame(opBinary, tcell.EncodePatches(patches)) != nil {
+				return
+			}
+		}
+		prev = buf
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// message is a message from the viewer.
+type message struct {
+	Type    string `json:"type"` // "key", "mouse", or "resize"
+	Key     string `json:"key"`  // KeyboardEvent.key
+	Buttons int    `json:"buttons"`
+	Wheel   int    `json:"wheel"` // negative is up, positive down
+	X       int    `json:"x"`
+	Y       int    `json:"y"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	Shift   bool   `json:"shift"`
+	Ctrl    bool   `json:"ctrl"`
+	Alt     bool   `json:"alt"`
+	Meta    bool   `json:"meta"`
+}
+
+// browserKeys maps the names of keys in KeyboardEvent.key to tcell keys.
+var browserKeys = map[string]tcell.Key{
+	"Enter":      tcell.KeyEnter,
+	"Tab":        tcell.KeyTab,
+	"Backspace":  tcell.KeyBackspace2,
+	"Escape":     tcell.KeyEscape,
+	"Delete":     tcell.KeyDelete,
+	"Insert":     tcell.KeyInsert,
+	"Home":       tcell.KeyHome,
+	"End":        tcell.KeyEnd,
+	"PageUp":     tcell.KeyPgUp,
+	"PageDown":   tcell.KeyPgDn,
+	"ArrowUp":    tcell.KeyUp,
+	"ArrowDown":  tcell.KeyDown,
+	"ArrowLeft":  tcell.KeyLeft,
+	"ArrowRight": tcell.KeyRight,
+	"F1":         tcell.KeyF1,
+	"F2":         tcell.KeyF2,
+	"F3":         tcell.KeyF3,
+	"F4":         tcell.KeyF4,
+	"F5":         tcell.KeyF5,
+	"F6":         tcell.KeyF6,
+	"F7":         tcell.KeyF7,
+	"F8":         tcell.KeyF8,
+	"F9":         tcell.KeyF9,
+	"F10":        tcell.KeyF10,
+	"F11":        tcell.KeyF11,
+	"F12":        tcell.KeyF12,
+}
+
+// event returns the event for a message, or nil if there is none.
+func (m *message) event() tcell.Event {
+	var mod tcell.ModMask
+	if m.Shift {
+		mod |= tcell.ModShift
+	}
+	if m.Ctrl {
+		mod |= tcell.ModCtrl
+	}
+	if m.Alt {
+		mod |= tcell.ModAlt
+	}
+	if m.Meta {
+		mod |= tcell.ModMeta
+	}
+	switch m.Type {
+	case "key":
+		if k, ok := browserKeys[m.Key]; ok {
+			if k == tcell.KeyTab && m.Shift {
+				return tcell.NewEventKey(tcell.KeyBacktab, 0, mod&^tcell.ModShift)
+			}
+			return tcell.NewEventKey(k, 0, mod)
+		}
+		runes := []rune(m.Key)
+		if len(runes) != 1 {
+			return nil // a modifier, or a key that we do not know
+		}
+		r := runes[0]
+		if m.Ctrl {
+			// as a terminal would send it, a control character
+			if lr := []rune(strings.ToLower(m.Key))[0]; lr >= 'a' && lr <= 'z' {
+				c := lr - 'a' + 1
+				return tcell.NewEventKey(tcell.Key(c), c, mod)
+			}
+		}
+		return tcell.NewEventKey(tcell.KeyRune, r, mod&^tcell.ModShift)
+	case "mouse":
+		btn := tcell.ButtonMask(m.Buttons) & (tcell.Button1 | tcell.Button2 | tcell.Button3)
+		switch {
+		case m.Wheel < 0:
+			btn |= tcell.WheelUp
+		case m.Wheel > 0:
+			btn |= tcell.WheelDown
+		}
+		return tcell.NewEventMouse(m.X, m.Y, btn, mod)
+	}
+	return nil
+}
+
+func (srv *Server) handleMessage(data []byte) {
+	var m message
+	if json.Unmarshal(data, &m) != nil {
+		return
+	}
+	if m.Type == "resize" {
+		if sim, ok := srv.screen.(tcell.SimulationScreen); ok && m.Width > 0 && m.Height > 0 {
+			if m.Width > maxSize {
+				m.Width = maxSize
+			}
+			if m.Height > maxSize {
+				m.Height = maxSize
+			}
+			sim.SetSize(m.Width, m.Height)
+			_ = srv.screen.PostEvent(tcell.NewEventResize(m.Width, m.Height))
+		}
+		return
+	}
+	if ev := m.event(); ev != nil {
+		_ = srv.screen.PostEvent(ev)
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// handshake starts a WebSocket connection to the server, from origin if
+// it is not empty, and returns the response.
+func handshake(t *testing.T, url, origin string) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	hdr := ""
+	if origin != "" {
+		hdr = "Origin: " + origin + "\r\n"
+	}
+	_, _ = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\n"+hdr+
+		"Upgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	rd := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(rd, nil)
+	if err != nil {
+		t.Fatalf("Failed to read handshake: %v", err)
+	}
+	return conn, rd, resp
+}
+
+// dial connects to the server as a WebSocket client.
+func dial(t *testing.T, url string) (net.Conn, *bufio.Reader) {
+	conn, rd, resp := handshake(t, url, "http://test")
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Bad handshake: %v %v", resp.Status, resp.Header)
+	}
+	return conn, rd
+}
+
+func readFrame(t *testing.T, rd *bufio.Reader) []byte {
+	var hdr [2]byte
+	if _, err := io.ReadFull(rd, hdr[:]); err != nil {
+		t.Fatalf("Failed to read frame: %v", err)
+	}
+	n := int(hdr[1] & 0x7f)
+	if n == 126 {
+		var ext [2]byte
+		_, _ = io.ReadFull(rd, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(rd, data); err != nil {
+		t.Fatalf("Failed to read frame: %v", err)
+	}
+	return data
+}
+
+// writeText sends a masked text frame, as clients must.
+func writeText(conn net.Conn, s string) {
+	mask := []byte{1, 2, 3, 4}
+	frame := append([]byte{0x81, 0x80 | byte(len(s))}, mask...)
+	for i := range s {
+		frame = append(frame, s[i]^mask[i%4])
+	}
+	_, _ = conn.Write(frame)
+}
+
+func TestServer(t *testing.T) {
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	s.SetSize(5, 2)
+	s.SetContent(1, 1, 'X', nil, tcell.StyleDefault.Bold(true))
+
+	srv := NewServer(s)
+	srv.Interval = time.Millisecond * 10
+	hs := httptest.NewServer(srv)
+	defer hs.Close()
+
+	resp, err := http.Get(hs.URL)
+	if err != nil {
+		t.Fatalf("Failed to get viewer: %v", err)
+	}
+	page, _ := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if !strings.Contains(string(page), "new WebSocket(") {
+		t.Errorf("Viewer page not served")
+	}
+
+	conn, rd := dial(t, hs.URL)
+	defer conn.Close()
+	patches, err := tcell.DecodePatches(readFrame(t, rd))
+	if err != nil {
+		t.Fatalf("Bad patches: %v", err)
+	}
+	mirror := &tcell.Buffer{}
+	mirror.Apply(patches)
+	if d := s.CaptureContents().Diff(mirror); len(d) != 0 {
+		t.Errorf("Mirror differs: %v", d)
+	}
+
+	s.SetContent(0, 0, 'Y', nil, tcell.StyleDefault)
+	patches, _ = tcell.DecodePatches(readFrame(t, rd))
+	if len(patches) != 1 || patches[0].Cells[0] != "Y" {
+		t.Errorf("Wrong change sent: %v", patches)
+	}
+
+	writeText(conn, `{"type":"key","key":"a","ctrl":true}`)
+	writeText(conn, `{"type":"key","key":"ArrowUp","shift":true}`)
+	writeText(conn, `{"type":"mouse","x":3,"y":1,"buttons":1}`)
+	writeText(conn, `{"type":"resize","width":20,"height":6}`)
+	if ev, ok := s.PollEvent().(*tcell.EventKey); !ok || !ev.MatchesRune('a', tcell.ModCtrl) {
+		t.Errorf("Ctrl-A not received: %v", ev)
+	}
+	if ev, ok := s.PollEvent().(*tcell.EventKey); !ok || ev.Key() != tcell.KeyUp || ev.Modifiers() != tcell.ModShift {
+		t.Errorf("Shift-Up not received: %v", ev)
+	}
+	if ev, ok := s.PollEvent().(*tcell.EventMouse); !ok || ev.Buttons() != tcell.Button1 {
+		t.Errorf("Mouse not received: %v", ev)
+	} else if x, y := ev.Position(); x != 3 || y != 1 {
+		t.Errorf("Wrong mouse position %d,%d", x, y)
+	}
+	if ev, ok := s.PollEvent().(*tcell.EventResize); !ok {
+		t.Errorf("Resize not received: %v", ev)
+	} else if w, h := ev.Size(); w != 20 || h != 6 {
+		t.Errorf("Wrong size %dx%d", w, h)
+	}
+}
+
+func TestServerResizeLimit(t *testing.T) {
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	srv := NewServer(s)
+	srv.handleMessage([]byte(`{"type":"resize","width":100000000,"height":100000000}`))
+	if w, h := s.Size(); w != maxSize || h != maxSize {
+		t.Errorf("Size not limited: %dx%d", w, h)
+	}
+	if ev, ok := s.PollEvent().(*tcell.EventResize); !ok {
+		t.Errorf("Resize not received: %v", ev)
+	} else if w, h := ev.Size(); w != maxSize || h != maxSize {
+		t.Errorf("Wrong size %dx%d", w, h)
+	}
+}
+
+func TestServerOrigin(t *testing.T) {
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	srv := NewServer(s)
+	hs := httptest.NewServer(srv)
+	defer hs.Close()
+
+	conn, _, resp := handshake(t, hs.URL, "http://evil.example")
+	conn.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Foreign origin accepted: %v", resp.Status)
+	}
+
+	srv.CheckOrigin = func(r *http.Request) bool {
+		return r.Header.Get("Origin") == "http://evil.example"
+	}
+	conn, _, resp = handshake(t, hs.URL, "http://evil.example")
+	conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("Origin allowed by CheckOrigin refused: %v", resp.Status)
+	}
+}
+
+func TestMessageTooLarge(t *testing.T) {
+	// a fragment, followed by a continuation whose length would wrap
+	// around when added to it
+	frames := []byte{0x01, 0x83, 1, 2, 3, 4, 'a' ^ 1, 'b' ^ 2, 'c' ^ 3}
+	frames = append(frames, 0x80, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	ws := &wsConn{rd: bufio.NewReader(bytes.NewReader(frames))}
+	if _, _, err := ws.readMessage(); err != errTooLarge {
+		t.Errorf("Oversized continuation not refused: %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+// viewerPage is the viewer, which connects back to the URL it was loaded
+// from.  It decodes the patches of tcell.EncodePatches, keeping a grid of
+// spans, one for each cell, and sends keys, mouse events, and the size of
+// the window as JSON messages.
+const viewerPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>tcell</title>
+<style>
+body { margin: 0; background: #000; overflow: hidden; }
+#screen { font-family: monospace; font-size: 16px; line-height: 1.2; color: #d0d0d0; white-space: pre; cursor: default; user-select: none; }
+#screen div { height: 1.2em; }
+</style>
+</head>
+<body>
+<div id="screen"></div>
+<script>
+"use strict";
+var screen = document.getElementById("screen");
+var cells = [], width = 0, height = 0, cellW = 8, cellH = 16;
+
+var palette = ["#000000", "#800000", "#008000", "#808000", "#000080", "#800080", "#008080", "#c0c0c0",
+	"#808080", "#ff0000", "#00ff00", "#ffff00", "#0000ff", "#ff00ff", "#00ffff", "#ffffff"];
+(function () {
+	var levels = [0, 95, 135, 175, 215, 255];
+	var hex = function (v) { return ("0" + v.toString(16)).slice(-2); };
+	for (var i = 0; i < 216; i++) {
+		palette.push("#" + hex(levels[Math.floor(i / 36)]) + hex(levels[Math.floor(i / 6) % 6]) + hex(levels[i % 6]));
+	}
+	for (var j = 0; j < 24; j++) {
+		palette.push("#" + hex(8 + j * 10) + hex(8 + j * 10) + hex(8 + j * 10));
+	}
+})();
+
+// colors are up to 35 bits, so this avoids the 32 bit operators
+function css(c) {
+	var bit = function (n) { return Math.floor(c / Math.pow(2, n)) % 2; };
+	if (c === 0 || !bit(32) || bit(34)) {
+		return "";
+	}
+	if (bit(33)) {
+		return "#" + ("00000" + (c % 0x1000000).toString(16)).slice(-6);
+	}
+	return palette[c - Math.pow(2, 32)] || "";
+}
+
+function resize(w, h) {
+	width = w;
+	height = h;
+	cells = [];
+	screen.textContent = "";
+	for (var y = 0; y < h; y++) {
+		var row = document.createElement("div");
+		for (var x = 0; x < w; x++) {
+			var span = document.createElement("span");
+			span.textContent = " ";
+			row.appendChild(span);
+			cells.push(span);
+		}
+		screen.appendChild(row);
+	}
+}
+
+function styleCSS(st) {
+	var fg = css(st.fg), bg = css(st.bg), s = "";
+	if (st.attrs & 4) {
+		var t = fg;
+		fg = bg || "#000";
+		bg = t || "#d0d0d0";
+	}
+	if (fg) { s += "color:" + fg + ";"; }
+	if (bg) { s += "background:" + bg + ";"; }
+	if (st.attrs & 1) { s += "font-weight:bold;"; }
+	if (st.attrs & 16) { s += "opacity:0.6;"; }
+	if (st.attrs & 32) { s += "font-style:italic;"; }
+	var lines = [];
+	if ((st.attrs & 8) || st.ul) { lines.push("underline"); }
+	if (st.attrs & 64) { lines.push("line-through"); }
+	if (lines.length) { s += "text-decoration:" + lines.join(" ") + ";"; }
+	return s;
+}
+
+function apply(data) {
+	var b = new Uint8Array(data), i = 0, dec = new TextDecoder();
+	var num = function () {
+		var v = 0, mul = 1, c;
+		do {
+			c = b[i++];
+			v += (c & 0x7f) * mul;
+			mul *= 128;
+		} while (c & 0x80);
+		return v;
+	};
+	var str = function () {
+		var n = num(), s = dec.decode(b.subarray(i, i + n));
+		i += n;
+		return s;
+	};
+	var style = "";
+	while (i < b.length) {
+		var flags = num();
+		if (flags & 1) {
+			resize(num(), num());
+			continue;
+		}
+		var x = num(), y = num();
+		if (!(flags & 2)) {
+			var st = { fg: num(), bg: num() };
+			num(); // underline color
+			st.ul = num();
+			st.attrs = num();
+			str(); // url
+			str(); // url id
+			style = styleCSS(st);
+		}
+		var n = num();
+		for (var k = 0; k < n; k++, x++) {
+			var text = str();
+			if (x < width && y < height) {
+				var span = cells[y * width + x];
+				span.textContent = text;
+				span.style.cssText = style;
+			}
+		}
+	}
+}
+
+var ws = new WebSocket((location.protocol === "https:" ? "wss:" : "ws:") + "//" + location.host + location.pathname + location.search);
+ws.binaryType = "arraybuffer";
+ws.onmessage = function (ev) { apply(ev.data); };
+ws.onopen = sendSize;
+ws.onclose = function () { document.title += " (disconnected)"; };
+
+function send(msg) {
+	if (ws.readyState === WebSocket.OPEN) {
+		ws.send(JSON.stringify(msg));
+	}
+}
+
+function sendSize() {
+	var probe = document.createElement("span");
+	probe.textContent = "X";
+	screen.appendChild(probe);
+	var r = probe.getBoundingClientRect();
+	screen.removeChild(probe);
+	cellW = r.width || cellW;
+	cellH = r.height || cellH;
+	send({ type: "resize", width: Math.floor(window.innerWidth / cellW), height: Math.floor(window.innerHeight / cellH) });
+}
+
+function mods(ev, msg) {
+	msg.shift = ev.shiftKey;
+	msg.ctrl = ev.ctrlKey;
+	msg.alt = ev.altKey;
+	msg.meta = ev.metaKey;
+	return msg;
+}
+
+function mouse(ev, wheel) {
+	var r = screen.getBoundingClientRect();
+	send(mods(ev, {
+		type: "mouse",
+		x: Math.floor((ev.clientX - r.left) / cellW),
+		y: Math.floor((ev.clientY - r.top) / cellH),
+		buttons: ev.buttons,
+		wheel: wheel
+	}));
+}
+
+window.addEventListener("resize", sendSize);
+document.addEventListener("keydown", function (ev) {
+	ev.preventDefault();
+	send(mods(ev, { type: "key", key: ev.key }));
+});
+screen.addEventListener("mousedown", function (ev) { mouse(ev, 0); });
+screen.addEventListener("mouseup", function (ev) { mouse(ev, 0); });
+screen.addEventListener("mousemove", function (ev) { mouse(ev, 0); });
+screen.addEventListener("wheel", function (ev) { ev.preventDefault(); mouse(ev, Math.sign(ev.deltaY)); });
+screen.addEventListener("contextmenu", function (ev) { ev.preventDefault(); });
+</script>
+</body>
+</html>
+`
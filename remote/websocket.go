@@ -0,0 +1,195 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// This is just enough of WebSocket (RFC 6455) for the viewer: the server
+// side of the handshake, and unfragmented messages in each direction.
+// Fragmented messages from the client are reassembled.
+
+// websocket opcodes
+const (
+	opContinue = 0x0
+	opText     = 0x1
+	opBinary   = 0x2
+	opClose    = 0x8
+	opPing     = 0x9
+	opPong     = 0xa
+)
+
+// maxMessage is the largest message accepted from the client.  The viewer
+// only sends small JSON messages.
+const maxMessage = 1 << 16
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var errNotWebSocket = errors.New("not a websocket request")
+var errTooLarge = errors.New("websocket message too large")
+
+// wsConn is a WebSocket connection, on the server side.
+type wsConn struct {
+	conn net.Conn
+	rd   *bufio.Reader
+	wl   sync.Mutex
+}
+
+// isWebSocket returns true if the request asks to upgrade to WebSocket.
+func isWebSocket(r *http.Request) bool {
+	return headerHas(r.Header, "Connection", "upgrade") && headerHas(r.Header, "Upgrade", "websocket")
+}
+
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// upgrade completes the handshake, and takes over the connection.
+func upgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !isWebSocket(r) || key == "" || r.Method != http.MethodGet {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return nil, errNotWebSocket
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, errNotWebSocket
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	_, err = conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"))
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, rd: brw.Reader}, nil
+}
+
+// writeFrame sends a single, unmasked frame.
+func (ws *wsConn) writeFrame(op byte, data []byte) error {
+	hdr := []byte{0x80 | op, 0}
+	switch n := len(data); {
+	case n < 126:
+		hdr[1] = byte(n)
+	case n <= 0xffff:
+		hdr[1] = 126
+		hdr = append(hdr, byte(n>>8), byte(n))
+	default:
+		hdr[1] = 127
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(n))
+		hdr = append(hdr, ext[:]...)
+	}
+	ws.wl.Lock()
+	defer ws.wl.Unlock()
+	if _, err := ws.conn.Write(append(hdr, data...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readMessage returns the next text or binary message, answering pings,
+// and returning io.EOF when the client closes the connection.
+func (ws *wsConn) readMessage() (byte, []byte, error) {
+	var msg []byte
+	var msgOp byte
+	for {
+		var hdr [2]byte
+		if _, err := io.ReadFull(ws.rd, hdr[:]); err != nil {
+			return 0, nil, err
+		}
+		fin, op := hdr[0]&0x80 != 0, hdr[0]&0x0f
+		n := uint64(hdr[1] & 0x7f)
+		switch n {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(ws.rd, ext[:]); err != nil {
+				return 0, nil, err
+			}
+			n = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(ws.rd, ext[:]); err != nil {
+				return 0, nil, err
+			}
+			n = binary.BigEndian.Uint64(ext[:])
+		}
+		// n is checked alone first, as the sum could overflow
+		if n > maxMessage || n+uint64(len(msg)) > maxMessage {
+			return 0, nil, errTooLarge
+		}
+		var mask [4]byte
+		if hdr[1]&0x80 != 0 {
+			if _, err := io.ReadFull(ws.rd, mask[:]); err != nil {
+				return 0, nil, err
+			}
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(ws.rd, data); err != nil {
+			return 0, nil, err
+		}
+		for i := range data {
+			data[i] ^= mask[i%4]
+		}
+
+		switch op {
+		case opClose:
+			_ = ws.writeFrame(opClose, nil)
+			return 0, nil, io.EOF
+		case opPing:
+			if err := ws.writeFrame(opPong, data); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opText, opBinary:
+			msgOp, msg = op, data
+		case opContinue:
+			msg = append(msg, data...)
+		}
+		if fin {
+			return msgOp, msg, nil
+		}
+	}
+}
+
+func (ws *wsConn) Close() error {
+	return ws.conn.Close()
+}
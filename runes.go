@@ -68,7 +68,9 @@ const (
 //
 // Programs that desire richer fallbacks may register additional ones,
 // or change or even remove these mappings with Screen.RegisterRuneFallback
-// Screen.UnregisterRuneFallback methods.
+// Screen.UnregisterRuneFallback methods.  Runes without a fallback here
+// use the fallbacks of the runes package, which has many more, and where
+// fallbacks can be registered for all screens, or for a character set.
 //
 // Note that Unicode is presumed to be able to display all glyphs.
 // This is a pretty poor assumption, but there is no easy way to
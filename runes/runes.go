@@ -0,0 +1,244 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package runes maps Unicode characters that a terminal cannot display to
+// fallbacks that it can, such as "+" for a box drawing corner.  The tcell
+// renderer uses it when the character set of the terminal (from the
+// locale) lacks a character, and the terminal has no alternate character
+// set glyph for it either.
+//
+// Every fallback is a single column wide, so that it takes the place of
+// the character without moving the rest of the line.  The general
+// fallbacks are ASCII.  Fallbacks for a particular character set can use
+// the other characters it has, for example a light box drawing corner in
+// place of a rounded one, for KOI8-R.
+package runes
+
+import (
+	"strings"
+	"sync"
+)
+
+// boxDrawing are the fallbacks for the Box Drawing block, U+2500 to U+257F.
+var boxDrawing = "" +
+	"--||--||--||" + // light and heavy lines, and dashes
+	strings.Repeat("+", 64) + // corners, tees, and crosses
+	"--||" + // double dashes
+	"=|" + strings.Repeat("+", 27) + // double lines
+	"++++" + // rounded corners
+	"/\\X" + // diagonals
+	"-|-|-|-|-|-|" // half lines
+
+// blockElements are the fallbacks for the Block Elements block, U+2580 to
+// U+259F.
+var blockElements = "" +
+	"#_" + "######" + "#" + "######" + "|" + "#" + // eighths and halves
+	"#:#" + // shades
+	"~|" + // upper and right eighths
+	"##########" // quadrants
+
+// general are the fallbacks for any character set, other than the boxes
+// and blocks.  The first ones are the VT100 alternate character set, as
+// the tcell RuneFallbacks.
+var general = map[rune]string{
+	'£': "f",
+	'↓': "v",
+	'←': "<",
+	'→': ">",
+	'↑': "^",
+	'·': "o",
+	'°': "\\",
+	'◆': "+",
+	'≥': ">",
+	'π': "*",
+	'§': "#",
+	'≤': "<",
+	'≠': "!",
+	'±': "#",
+	'⎺': "~",
+	'⎻': "-",
+	'⎼': "-",
+	'⎽': "_",
+
+	'‘': "'",
+	'’': "'",
+	'‚': ",",
+	'′': "'",
+	'“': "\"",
+	'”': "\"",
+	'„': "\"",
+	'″': "\"",
+	'‹': "<",
+	'›': ">",
+	'«': "<",
+	'»': ">",
+	'‐': "-",
+	'‑': "-",
+	'‒': "-",
+	'–': "-",
+	'—': "-",
+	'―': "-",
+	'−': "-",
+	'¦': "|",
+	'×': "x",
+	'÷': "/",
+	'≈': "~",
+	'•': "o",
+	'∙': "o",
+	'●': "o",
+	'○': "o",
+	'◇': "+",
+	'■': "#",
+	'□': "#",
+	'▪': "#",
+	'▫': "#",
+	'▲': "^",
+	'△': "^",
+	'▼': "v",
+	'▽': "v",
+	'◀': "<",
+	'◄': "<",
+	'▶': ">",
+	'►': ">",
+	'⇐': "<",
+	'⇒': ">",
+	'⇑': "^",
+	'⇓': "v",
+	'↔': "-",
+	'↕': "|",
+
+	'\u00a0': " ", // no-break space
+}
+
+// lightBoxes are fallbacks for character sets with the light (and perhaps
+// double) box drawing characters, but not the others.
+var lightBoxes = map[rune]string{
+	'━': "─", '┃': "│",
+	'┄': "─", '┅': "─", '┈': "─", '┉': "─", '╌': "─", '╍': "─",
+	'┆': "│", '┇': "│", '┊': "│", '┋': "│", '╎': "│", '╏': "│",
+	'┏': "┌", '┓': "┐", '┗': "└", '┛': "┘",
+	'┣': "├", '┫': "┤", '┳': "┬", '┻': "┴", '╋': "┼",
+	'╭': "┌", '╮': "┐", '╯': "┘", '╰': "└",
+	'╴': "─", '╶': "─", '╸': "─", '╺': "─", '╼': "─", '╾': "─",
+	'╵': "│", '╷': "│", '╹': "│", '╻': "│", '╽': "│", '╿': "│",
+}
+
+// latin1 are fallbacks for the ISO 8859-1 family of character sets.
+var latin1 = map[rune]string{
+	'•': "·",
+	'∙': "·",
+	'‹': "«",
+	'›': "»",
+	'−': "-",
+}
+
+var (
+	charsets = map[string]map[rune]string{
+		"koi8r":     lightBoxes,
+		"koi8u":     lightBoxes,
+		"iso88591":  latin1,
+		"88591":     latin1,
+		"iso885915": latin1,
+		"885915":    latin1,
+		"latin1":    latin1,
+	}
+	registered = map[string]map[rune]string{}
+	removed    = map[string]map[rune]bool{}
+	lock       sync.RWMutex
+)
+
+// normalize returns the name of a character set without case or dashes,
+// so that "ISO-8859-1" and "iso8859-1" are the same.
+func normalize(charset string) string {
+	charset = strings.ToLower(charset)
+	charset = strings.Replace(charset, "-", "", -1)
+	return strings.Replace(charset, "_", "", -1)
+}
+
+// builtin returns the built-in fallback for r, for the normalized charset
+// (or for all of them if it is empty).
+func builtin(charset string, r rune) string {
+	if charset != "" {
+		return charsets[charset][r]
+	}
+	switch {
+	case r >= 0x2500 && r < 0x2580:
+		return boxDrawing[r-0x2500 : r-0x2500+1]
+	case r >= 0x2580 && r < 0x25a0:
+		return blockElements[r-0x2580 : r-0x2580+1]
+	}
+	return general[r]
+}
+
+// lookup returns the fallback for r for the normalized charset only.
+func lookup(charset string, r rune) string {
+	if fb, ok := registered[charset][r]; ok {
+		return fb
+	}
+	if removed[charset][r] {
+		return ""
+	}
+	return builtin(charset, r)
+}
+
+// Fallback returns the ASCII fallback for r, or the empty string if there
+// is none.
+func Fallback(r rune) string {
+	lock.RLock()
+	defer lock.RUnlock()
+	return lookup("", r)
+}
+
+// FallbackFor returns the fallback for r on a terminal using charset, such
+// as "KOI8-R", or the empty string if there is none.  Fallbacks for the
+// character set are preferred to the general ones, but the result might
+// not be one the character set has, if none was registered for it.
+func FallbackFor(charset string, r rune) string {
+	lock.RLock()
+	defer lock.RUnlock()
+	if charset = normalize(charset); charset != "" {
+		if fb := lookup(charset, r); fb != "" {
+			return fb
+		}
+	}
+	return lookup("", r)
+}
+
+// Register sets the fallback for r, which replaces any built-in one.  If
+// charset is not empty, it is only for terminals using that character set,
+// otherwise it is for all of them.  The fallback should be a single column
+// wide.
+func Register(charset string, r rune, fallback string) {
+	lock.Lock()
+	defer lock.Unlock()
+	charset = normalize(charset)
+	if registered[charset] == nil {
+		registered[charset] = make(map[rune]string)
+	}
+	registered[charset][r] = fallback
+	delete(removed[charset], r)
+}
+
+// Unregister removes the fallback for r, for the character set (or the
+// general one, if charset is empty), including the built-in one.
+func Unregister(charset string, r rune) {
+	lock.Lock()
+	defer lock.Unlock()
+	charset = normalize(charset)
+	delete(registered[charset], r)
+	if removed[charset] == nil {
+		removed[charset] = make(map[rune]bool)
+	}
+	removed[charset][r] = true
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runes
+
+import (
+	"testing"
+)
+
+func TestTables(t *testing.T) {
+	if len(boxDrawing) != 0x80 {
+		t.Errorf("Wrong box drawing table length: %d", len(boxDrawing))
+	}
+	if len(blockElements) != 0x20 {
+		t.Errorf("Wrong block elements table length: %d", len(blockElements))
+	}
+	for r := rune(0x2500); r < 0x25a0; r++ {
+		if Fallback(r) == "" {
+			t.Errorf("No fallback for %q", r)
+		}
+	}
+}
+
+func TestFallback(t *testing.T) {
+	cases := []struct {
+		charset string
+		r       rune
+		fb      string
+	}{
+		{"", '─', "-"},
+		{"", '║', "|"},
+		{"", '╭', "+"},
+		{"", '═', "="},
+		{"", '╳', "X"},
+		{"", '▒', ":"},
+		{"", '▁', "_"},
+		{"", '“', "\""},
+		{"", '—', "-"},
+		{"", '€', ""},
+		{"US-ASCII", '╭', "+"},
+		{"KOI8-R", '╭', "┌"},
+		{"koi8-r", '━', "─"},
+		{"KOI8-R", '═', "="},
+		{"ISO-8859-1", '•', "·"},
+		{"ISO8859-1", '•', "·"},
+		{"ISO8859-1", '—', "-"},
+	}
+	for _, c := range cases {
+		if fb := FallbackFor(c.charset, c.r); fb != c.fb {
+			t.Errorf("%s %q: got %q, expected %q", c.charset, c.r, fb, c.fb)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("", '€', "E")
+	Register("ISO8859-15", '€', "¤")
+	if fb := Fallback('€'); fb != "E" {
+		t.Errorf("Wrong fallback: %q", fb)
+	}
+	if fb := FallbackFor("iso-8859-15", '€'); fb != "¤" {
+		t.Errorf("Wrong fallback for charset: %q", fb)
+	}
+	Unregister("ISO8859-15", '€')
+	if fb := FallbackFor("ISO8859-15", '€'); fb != "E" {
+		t.Errorf("General fallback not used: %q", fb)
+	}
+	Unregister("", '€')
+	if fb := Fallback('€'); fb != "" {
+		t.Errorf("Fallback not removed: %q", fb)
+	}
+
+	Unregister("", '┌')
+	if fb := Fallback('┌'); fb != "" {
+		t.Errorf("Built-in fallback not removed: %q", fb)
+	}
+	Register("", '┌', "+")
+	if fb := Fallback('┌'); fb != "+" {
+		t.Errorf("Built-in fallback not restored: %q", fb)
+	}
+}
@@ -93,3 +93,24 @@ func TestRuneFallbacks(t *testing.T) {
 		t.Errorf("Should not be able to display hline")
 	}
 }
+
+func TestRunesPackageFallbacks(t *testing.T) {
+	s := mkTestScreen(t, "US-ASCII")
+	defer s.Fini()
+
+	if !s.CanDisplay('╭', true) || s.CanDisplay('╭', false) {
+		t.Errorf("Rounded corner should only display with a fallback")
+	}
+	s.SetContent(0, 0, '╭', nil, StyleDefault)
+	s.SetContent(1, 0, '═', nil, StyleDefault)
+	s.Show()
+	cells, _, _ := s.GetContents()
+	if got := string(cells[0].Bytes) + string(cells[1].Bytes); got != "+=" {
+		t.Errorf("Wrong fallbacks drawn: %q", got)
+	}
+
+	s.UnregisterRuneFallback('╭')
+	if s.CanDisplay('╭', true) {
+		t.Errorf("Unregistered fallback still used")
+	}
+}
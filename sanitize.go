@@ -0,0 +1,136 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// SanitizePolicy determines what happens to unsafe characters when text
+// is sanitized.  Unsafe characters are the C0 and C1 control characters
+// (including ESC, which would otherwise allow text to inject escape
+// sequences), DEL, bidirectional embedding, override, isolate and mark
+// characters (which can make text display differently than it reads),
+// the line and paragraph separators, and the interlinear annotation
+// characters.  Invalid UTF-8 is always replaced with U+FFFD, except by
+// SanitizeStrip, which removes it.
+type SanitizePolicy int
+
+const (
+	// SanitizeEscape replaces unsafe characters with a visible form;
+	// control characters use caret notation (such as ^[ for ESC), and
+	// other characters are shown as <U+XXXX>.
+	SanitizeEscape = SanitizePolicy(iota)
+
+	// SanitizeStrip removes unsafe characters.
+	SanitizeStrip
+
+	// SanitizeReplace replaces each unsafe character with U+FFFD.
+	SanitizeReplace
+)
+
+// unsafeRune returns true if the rune is unsafe to display.
+func unsafeRune(r rune) bool {
+	switch {
+	case r < 0x20, r >= 0x7f && r < 0xa0:
+		return true
+	case r == 0x061c, r == 0x200e, r == 0x200f: // bidi marks
+		return true
+	case r >= 0x202a && r <= 0x202e: // bidi embeddings and overrides
+		return true
+	case r >= 0x2066 && r <= 0x2069: // bidi isolates
+		return true
+	case r == 0x2028, r == 0x2029: // line and paragraph separators
+		return true
+	case r >= 0xfff9 && r <= 0xfffb: // interlinear annotations
+		return true
+	}
+	return false
+}
+
+// escapeRune returns the visible form of an unsafe rune.
+func escapeRune(r rune) string {
+	switch {
+	case r < 0x20:
+		return "^" + string(r+'@')
+	case r == 0x7f:
+		return "^?"
+	}
+	return fmt.Sprintf("<U+%04X>", r)
+}
+
+// Sanitize returns the text with any unsafe characters handled according
+// to the policy.  The result is safe to place in cells, or to write to a
+// terminal.  Applications that display untrusted text, such as log viewers,
+// should use this (or DrawSafeString) to avoid escape sequence injection.
+func Sanitize(text string, policy SanitizePolicy) string {
+	sb := &strings.Builder{}
+	for len(text) > 0 {
+		r, n := utf8.DecodeRuneInString(text)
+		text = text[n:]
+		switch {
+		case r == utf8.RuneError && n <= 1:
+			if policy != SanitizeStrip {
+				sb.WriteRune(utf8.RuneError)
+			}
+		case !unsafeRune(r):
+			sb.WriteRune(r)
+		case policy == SanitizeEscape:
+			sb.WriteString(escapeRune(r))
+		case policy == SanitizeReplace:
+			sb.WriteRune(utf8.RuneError)
+		}
+	}
+	return sb.String()
+}
+
+// DrawSafeString sanitizes the text according to the policy, and then
+// draws it on a single row of the screen starting at the given position,
+// in the given style.  Wide characters take two cells, and combining
+// characters are attached to the preceding character.  Text that extends
+// past the right edge of the screen is not drawn.  The return value is
+// the number of cells used.
+func DrawSafeString(s Screen, x, y int, text string, style Style, policy SanitizePolicy) int {
+	w, _ := s.Size()
+	col := x
+	var mainc rune
+	var combc []rune
+	width := 0
+	flush := func() {
+		if width > 0 && col+width <= w {
+			s.SetContent(col, y, mainc, combc, style)
+		}
+		col += width
+	}
+	for _, r := range Sanitize(text, policy) {
+		rw := runewidth.RuneWidth(r)
+		if rw == 0 {
+			if width == 0 {
+				// a leading combining character gets a space to sit on
+				mainc, width = ' ', 1
+			}
+			combc = append(combc, r)
+			continue
+		}
+		flush()
+		mainc, combc, width = r, nil, rw
+	}
+	flush()
+	return col - x
+}
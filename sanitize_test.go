@@ -0,0 +1,63 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	cases := []struct {
+		text   string
+		policy SanitizePolicy
+		result string
+	}{
+		{"plain text", SanitizeEscape, "plain text"},
+		{"a\x1b[31mb", SanitizeEscape, "a^[[31mb"},
+		{"a\x1b[31mb", SanitizeStrip, "a[31mb"},
+		{"a\x1b[31mb", SanitizeReplace, "a�[31mb"},
+		{"del\x7f", SanitizeEscape, "del^?"},
+		{"c1\u009b", SanitizeEscape, "c1<U+009B>"},
+		{"abc‮def", SanitizeEscape, "abc<U+202E>def"},
+		{"abc⁦def", SanitizeStrip, "abcdef"},
+		{"bad\xffutf", SanitizeEscape, "bad�utf"},
+		{"bad\xffutf", SanitizeStrip, "badutf"},
+		{"日本 é", SanitizeStrip, "日本 é"},
+	}
+	for _, c := range cases {
+		if r := Sanitize(c.text, c.policy); r != c.result {
+			t.Errorf("%q: got %q expected %q", c.text, r, c.result)
+		}
+	}
+}
+
+func TestDrawSafeString(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+	s.SetSize(6, 1)
+
+	if n := DrawSafeString(s, 0, 0, "日\x1bx", StyleDefault, SanitizeEscape); n != 5 {
+		t.Errorf("Wrong width: %d", n)
+	}
+	expect := []rune{'日', ' ', '^', '[', 'x'}
+	for x, r := range expect {
+		if x == 1 {
+			continue
+		}
+		if c, _, _, _ := s.GetContent(x, 0); c != r {
+			t.Errorf("Cell %d: got %q expected %q", x, c, r)
+		}
+	}
+}
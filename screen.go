@@ -14,18 +14,35 @@
 
 package tcell
 
-import "sync"
+import (
+	"context"
+	"io"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 // Screen represents the physical (or emulated) screen.
 // This can be a terminal window or a physical console.  Platforms implement
 // this differently.
 type Screen interface {
-	// Init initializes the screen for use.
+	// Init initializes the screen for use.  Calling it again has no
+	// effect, and it returns ErrScreenClosed after Fini.
 	Init() error
 
-	// Fini finalizes the screen also releasing resources.
+	// Fini finalizes the screen also releasing resources.  It may be
+	// called from any goroutine, even while others are using the screen
+	// (for example, blocked in PollEvent or drawing with Show), and more
+	// than once.  Only the first call does anything, and it returns
+	// when the screen is finalized.  Afterwards PollEvent returns nil,
+	// and drawing has no effect.
 	Fini()
 
+	// Done returns a channel that is closed when Fini has finished
+	// releasing the screen's resources.
+	Done() <-chan struct{}
+
 	// Clear logically erases the screen.
 	// This is effectively a short-cut for Fill(' ', StyleDefault).
 	Clear()
@@ -63,9 +80,23 @@ type Screen interface {
 	// last column will be replaced with a single width space on output.
 	SetContent(x int, y int, primary rune, combining []rune, style Style)
 
+	// SetContentWithTag is like SetContent, but also attaches an
+	// application defined tag to the cell.  The tag is not displayed,
+	// but is kept with the cell until its content is replaced (by
+	// SetContent, Fill, Clear, and so forth), and can be retrieved with
+	// GetTag.  A typical use is to record which widget drew a cell, so
+	// that mouse events can be routed to it.
+	SetContentWithTag(x int, y int, primary rune, combining []rune, style Style, tag interface{})
+
+	// GetTag returns the tag set on the cell with SetContentWithTag, or nil
+	// if there is none, or the coordinates are out of range.
+	GetTag(x, y int) interface{}
+
 	// SetStyle sets the default style to use when clearing the screen
 	// or when StyleDefault is specified.  If it is also StyleDefault,
 	// then whatever system/terminal default is relevant will be used.
+	// Colors of other styles that are ColorDefault are also taken from
+	// this style; use ColorReset for the terminal's own default colors.
 	SetStyle(style Style)
 
 	// ShowCursor is used to display the cursor at a given location.
@@ -83,6 +114,12 @@ type Screen interface {
 	// and the terminal supports doing so.
 	SetCursorStyle(CursorStyle, ...Color)
 
+	// SetCursorShape sets the shape of the cursor, and whether it blinks,
+	// leaving its color alone.  CursorShapeDefault keeps the terminal's
+	// default shape, while still changing whether it blinks.  Use
+	// SetCursorStyle with CursorStyleDefault to restore both defaults.
+	SetCursorShape(shape CursorShape, blink bool)
+
 	// Size returns the screen size as width, height.  This changes in
 	// response to a call to Clear or Flush.
 	Size() (width, height int)
@@ -103,6 +140,18 @@ type Screen interface {
 	// Furthermore, this will return nil if the Screen is finalized.
 	PollEvent() Event
 
+	// PollEventContext is like PollEvent, but also returns nil if the
+	// context is canceled (or reaches its deadline) before an event arrives.
+	PollEventContext(ctx context.Context) Event
+
+	// EventChan returns a channel on which events are delivered, for use
+	// in select statements.  It must be called after Init.  The channel
+	// is closed when the screen is finalized.  The same channel is
+	// returned by every call.  Events are only delivered to one receiver,
+	// so once this has been called, PollEvent and ChannelEvents should not
+	// be used.
+	EventChan() <-chan Event
+
 	// HasPendingEvent returns true if PollEvent would return an event
 	// without blocking.  If the screen is stopped and PollEvent would
 	// return nil, then the return value from this function is unspecified.
@@ -128,9 +177,33 @@ type Screen interface {
 	// Goroutine is recommended to ensure no deadlock can occur.
 	PostEventWait(ev Event)
 
+	// InjectEvent injects an event into the same queue used for events
+	// received from the terminal, as if the user had generated it.  This is
+	// useful for keyboard macros, or for testing against the real backend.
+	// Events are delivered in the order they were injected.  Unlike
+	// PostEvent, this never fails because the queue is full, and unlike
+	// PostEventWait, it never blocks, so it is safe to call from any
+	// goroutine, including the one polling for events.  Injected events are
+	// discarded if the screen is finalized before they can be delivered.
+	InjectEvent(ev Event)
+
+	// PostEventFunc adds an event source, such as a timer or a network
+	// connection, whose events are delivered along with the others, so
+	// that the application can handle them all in one goroutine, without
+	// channels of its own.  It starts a goroutine that calls fn, and
+	// delivers the event that it returns, over and over until fn returns
+	// nil, or the screen is finalized.  Each event is delivered before fn
+	// is called again, so a source that produces events faster than the
+	// application takes them is held back, rather than filling the queue.
+	// Events from the terminal (and those posted with PostEvent) are
+	// delivered first, so that a busy source cannot hold up input.  An
+	// event that fn returns after the screen is finalized is discarded.
+	PostEventFunc(fn func() Event)
+
 	// EnableMouse enables the mouse.  (If your terminal supports it.)
 	// If no flags are specified, then all events are reported, if the
-	// terminal supports them.
+	// terminal supports them.  If the mouse is already enabled, only the
+	// events reported change.
 	EnableMouse(...MouseFlags)
 
 	// DisableMouse disables the mouse.
@@ -148,6 +221,14 @@ type Screen interface {
 	// DisableFocus disables reporting of focus events.
 	DisableFocus()
 
+	// HasFocus returns true unless the terminal has reported that it lost
+	// focus, and has not since reported that it regained it.  This is
+	// maintained from the EventFocus events received with PollEvent (or
+	// the other ways of receiving events), so it is only meaningful when
+	// focus reporting is enabled (see EnableFocus), and the platform
+	// supports it.
+	HasFocus() bool
+
 	// HasMouse returns true if the terminal (apparently) supports a
 	// mouse.  Note that the return value of true doesn't guarantee that
 	// a mouse/pointing device is present; a false return definitely
@@ -156,7 +237,9 @@ type Screen interface {
 
 	// Colors returns the number of colors.  All colors are assumed to
 	// use the ANSI color map.  If a terminal is monochrome, it will
-	// return 0.
+	// return 0.  Otherwise it is the size of the palette, normally 8,
+	// 16, 88, or 256, or 1<<24 if RGB colors are displayed as they are.
+	// This can change at runtime (see ProbeColors).
 	Colors() int
 
 	// Show makes all the content changes made using SetContent() visible
@@ -166,6 +249,18 @@ type Screen interface {
 	// manner possible.
 	Show()
 
+	// ShowCells sends the cells of row y, from column x for width columns,
+	// to the terminal at once, without looking at the rest of the screen.
+	// It is meant for cases where latency matters, such as echoing the
+	// key just typed in an editor: the application sets the cells as
+	// usual (for example with SetContent), and shows them with ShowCells
+	// before working out the rest of the changes, which are sent by the
+	// next Show.  The cells shown are no longer considered changed, so
+	// they are not sent again.  While something is drawn over the screen
+	// (a selection, a flash, or the message that the screen is too small),
+	// or the accessibility mode is enabled, it is the same as Show.
+	ShowCells(x, y, width int)
+
 	// Sync works like Show(), but it updates every visible cell on the
 	// physical display, assuming that it is not synchronized with any
 	// internal model.  This may be both expensive and visually jarring,
@@ -240,12 +335,25 @@ type Screen interface {
 
 	// Suspend pauses input and output processing.  It also restores the
 	// terminal settings to what they were when the application started.
-	// This can be used to, for example, run a sub-shell.
+	// This can be used to, for example, run a sub-shell.  It has no
+	// effect if the screen is already suspended, and returns
+	// ErrScreenClosed after Fini.
 	Suspend() error
 
-	// Resume resumes after Suspend().
+	// Resume resumes after Suspend().  It has no effect if the screen is
+	// not suspended, and returns ErrScreenClosed after Fini.
 	Resume() error
 
+	// Exec runs cmd with the terminal, suspending the screen while it
+	// runs, and resuming it afterwards, for example to run an editor.
+	// Any of the command's standard input, output, and error that are not
+	// set are connected to the terminal.  While the command runs, Ctrl-C
+	// (and similar keys) act on the command rather than the application,
+	// and termination signals sent to the application are passed on to
+	// the command.  The error is that from running the command, if any,
+	// or else from resuming the screen.
+	Exec(cmd *exec.Cmd) error
+
 	// Beep attempts to sound an OS-dependent audible alert and returns an error
 	// when unsuccessful.
 	Beep() error
@@ -273,6 +381,21 @@ type Screen interface {
 	// the results may vary.  Use of unicode characters may not be supported.
 	SetTitle(string)
 
+	// SetTabColor sets the color of the tab (or title bar) of the
+	// terminal window.  ColorDefault (or ColorReset) restores the usual
+	// color.  Only iTerm2 is known to support this (see FeatureTabColor),
+	// so on other terminals it has no effect.  The color is reset when
+	// the screen is finalized or suspended.
+	SetTabColor(Color)
+
+	// SetBadge sets the badge, text that the terminal shows large and
+	// faint in a corner of the window, such as the name of the host or
+	// project.  An empty string removes it.  Only iTerm2 is known to
+	// support this (see FeatureBadge), so on other terminals it has no
+	// effect.  The badge is removed when the screen is finalized or
+	// suspended.
+	SetBadge(string)
+
 	// SetClipboard is used to post arbitrary data to the system clipboard.
 	// This need not be UTF-8 string data.  It's up to the recipient to decode the
 	// data meaningfully.  Terminals may prevent this for security reasons.
@@ -283,6 +406,247 @@ type Screen interface {
 	// EventPaste with the clipboard content as the Data() field.  Terminals may
 	// prevent this for security reasons.
 	GetClipboard()
+
+	// SendOSC sends an operating system command (OSC) with the given
+	// number and data to the terminal.  This is meant for private or
+	// terminal specific commands that tcell has no API for; control
+	// characters are removed from the data.  Replies can be received by
+	// listing the number in InputOptions.OSC.  Terminals that do not
+	// understand the command ignore it, and screens that are not
+	// terminals do nothing.
+	SendOSC(code int, data string)
+
+	// SetRestoreWriter arranges for the escape sequences needed to restore
+	// the terminal to its normal state (cursor visible, main screen buffer,
+	// mouse and other reporting modes disabled, and so forth) to be written
+	// to w when the screen is initialized, or immediately if it already is.
+	// The sequences are written again whenever the modes to be turned off
+	// change.  If w has Truncate and Seek methods, as an *os.File does, it
+	// is rewritten from the start; otherwise the new sequences follow the
+	// old ones, which they supersede.  Typically w is a file, which an
+	// external wrapper or signal handler can write to the terminal if the
+	// application dies without calling Fini, for example because it was
+	// killed with SIGKILL.  Note that the tty line discipline (raw mode)
+	// cannot be restored this way; use something like "stty sane" for
+	// that.  Screens that are not terminals ignore this.
+	SetRestoreWriter(w io.Writer)
+
+	// SetDisplayOptions changes how the screen takes over the terminal
+	// display, for example to run on the primary screen buffer instead of
+	// the alternate one.  See DisplayOptions for details.  The options are
+	// applied when the screen is initialized or resumed, so they should be
+	// set before calling Init.
+	SetDisplayOptions(DisplayOptions)
+
+	// SetInputOptions changes how input from the terminal is split into
+	// keys, in particular how long to wait before deciding that an ESC
+	// is the Esc key rather than the start of an escape sequence.  See
+	// InputOptions for details.  Screens that do not receive input as
+	// escape sequences (such as the Windows console) ignore this.
+	SetInputOptions(InputOptions)
+
+	// CaptureContents takes a snapshot of the screen contents, including
+	// every cell, the cursor position and style, and the default style.
+	// The snapshot can be put back later with RestoreContents, for example
+	// to remove a modal overlay without having to redraw what was under it.
+	CaptureContents() *Buffer
+
+	// RestoreContents puts back the contents captured by CaptureContents.
+	// If the screen has been resized since, the contents are clipped, and
+	// any cells outside the captured area are cleared.  As usual, Show must
+	// be called to display the results.
+	RestoreContents(*Buffer)
+
+	// ExportRegion returns the contents of a region of the screen as text
+	// with ANSI escape sequences for the styles, colors and hyperlinks.
+	// Rows are separated by newlines, and trailing blanks are omitted.
+	// The result can be used to copy styled text, for logging, or for
+	// comparing screen contents in tests, and can be converted back to
+	// cells with ParseANSI.  As with GetContent, these are the logical
+	// contents, which may not have been shown yet.  Only the part of the
+	// region that is on the screen is exported.
+	ExportRegion(x, y, width, height int) []byte
+
+	// AddSemanticMark records a shell integration mark (OSC 133) at the
+	// given cell.  It is sent to the terminal at the next Show or Sync,
+	// with the cursor at that cell.  Terminals that support these marks
+	// use them to find prompts and command output, for example to let the
+	// user jump to the previous prompt, or select the output of a command.
+	// The status is the exit status of the command, and is only used with
+	// MarkCommandEnd; a negative value omits it.  Marks are ignored by
+	// screens that are not terminals, and terminals that are not known to
+	// support them.
+	AddSemanticMark(x, y int, mark SemanticMark, status int)
+
+	// TerminalID returns what the terminal reported about itself, in
+	// response to the device attribute (DA1 and DA2) and version (XTVERSION)
+	// queries made during Init.  Applications can use this to work around
+	// problems with specific terminals.  Terminals that are slow to respond
+	// may not have replied by the time Init returns; the result is updated
+	// if they reply later.  The zero value is returned for screens that are
+	// not terminals.
+	TerminalID() TerminalIdentity
+
+	// Ticker starts a frame clock, which updates the screen (as if by Show)
+	// fps times a second, and then delivers an EventTick.  Applications
+	// doing animation can draw the next frame when they receive the tick,
+	// without calling Show themselves; all of the changes made are then
+	// displayed together at the next frame.  If the application has not yet
+	// received the previous tick, the frame is skipped rather than letting
+	// ticks pile up.  A value of zero (or less) stops the clock.  It must
+	// be called after Init, and the clock stops when the screen is
+	// finalized.
+	Ticker(fps int)
+
+	// Flash shows a region of the screen in reverse video for the given
+	// duration, for example as a visual bell, or to draw attention to an
+	// error.  A width or height of zero (or less) extends the region to
+	// the edge of the screen, so Flash(0, 0, 0, 0, d) flashes the whole
+	// screen.  The screen is updated (as if by Show) at once, and again
+	// when the time is up, at the next frame if the frame clock (see
+	// Ticker) is running.  The cell contents are not changed, so the
+	// application can keep drawing while the flash is shown.
+	Flash(x, y, width, height int, d time.Duration)
+
+	// SetMinSize sets the smallest size the application can work with.
+	// When the screen is smaller than that, Show and Sync display a
+	// message saying that the terminal is too small, instead of the
+	// contents.  The application can keep drawing; its contents are
+	// shown again once the screen is large enough.  The default of zero
+	// for both means there is no minimum.
+	SetMinSize(width, height int)
+
+	// EnableAccessibility enables the accessibility mode, for use with
+	// screen readers and other speech tools.  Whenever the screen is shown
+	// (by Show or Sync), the logical text of each row is compared with what
+	// was shown before, and fn is called with an Announcement of the rows
+	// that changed, followed by one for the cursor, if it moved.  The
+	// logical text is read from left to right, with runs of spaces
+	// collapsed, and leaves out decorations such as box drawing and block
+	// characters, so that changes only to styles, borders or gauges are not
+	// announced.  The first Show after enabling announces the whole screen.
+	// The function is called by the goroutine calling Show, without the
+	// screen locked.
+	EnableAccessibility(fn func(Announcement))
+
+	// DisableAccessibility disables the accessibility mode.
+	DisableAccessibility()
+
+	// AccessibleText returns the logical text of the screen contents (see
+	// EnableAccessibility), with the rows in order, one per line.  Empty
+	// rows, and rows that are the same as the one above, are left out.
+	AccessibleText() string
+
+	// SetInputLimits limits the rate at which key and mouse events are
+	// delivered, discarding excess mouse motion and repeated keys, and
+	// optionally limits the size of pastes.  This keeps the application
+	// responsive when the terminal sends a flood of input.  See InputLimits
+	// for details.  The events discarded are counted, and can be retrieved
+	// with InputStats.
+	SetInputLimits(InputLimits)
+
+	// FeatureReport summarizes the optional features of the terminal,
+	// as far as they are known, and how certain that is.  Applications
+	// can use it to decide what to display, and it is useful for bug
+	// reports.  Features not in the report are not known to be supported.
+	// The terminal is identified in the background after Init returns,
+	// so the report may change once it has been (or after an EventColors
+	// is posted), as may the optimizations used for it.
+	FeatureReport() map[Feature]Support
+
+	// Err returns the error that stopped the screen from using the
+	// terminal, or nil.  This is a failure to write to the terminal, or
+	// to read from it, such as ErrHangup when the terminal has gone away.
+	// The error is also posted as an EventError.  Once it has happened,
+	// nothing more is sent to the terminal, so that drawing does not fail
+	// (or block) over and over, and long running programs can notice and
+	// shut down, or wait for the terminal to be started again (see the
+	// Recover display option).
+	Err() error
+
+	// ProbeColors works out again how many colors the terminal has, for
+	// example after the application has set $COLORTERM, or when the
+	// terminal or tmux configuration has changed.  The terminal is asked
+	// again with XTGETTCAP, if it was asked during Init, and the replies
+	// are waited for in the background.  If the result of Colors changes,
+	// an EventColors is posted, and the whole screen is redrawn by the
+	// next Show.
+	ProbeColors()
+
+	// InputStats returns the number of events discarded because of the
+	// limits set with SetInputLimits, and the latency of input events,
+	// which is the time from when the terminal sent them until they were
+	// delivered to the application.
+	InputStats() InputStats
+
+	// SetTheme changes the theme, which maps style roles (such as
+	// RoleSelection) to styles.  The default style of the screen (see
+	// SetStyle) is set to the style for RoleNormal, and an EventThemeChange
+	// is delivered, so that the application (and the widgets in the views
+	// package) can redraw using the new theme.  Switching between light and
+	// dark variants of a theme is done by calling this again.  It must be
+	// called after Init.
+	SetTheme(*Theme)
+
+	// Theme returns the current theme, or nil if none has been set.
+	// The Style method of a nil Theme returns StyleDefault for all roles.
+	Theme() *Theme
+
+	// Clip returns a surface for drawing on a region of the screen.  The
+	// coordinates used with it are relative to the top left corner of the
+	// region, and content outside of the region is discarded (a wide
+	// character that would extend past the right edge is replaced with a
+	// space).  Regions can be nested, by calling Clip on the result.  This
+	// lets a parent hand a child widget a restricted surface to draw on.
+	// The screen itself is also a DrawTarget.
+	Clip(Rect) DrawTarget
+
+	// EnableSelection enables the built-in selection mode.  When enabled,
+	// dragging the mouse with the primary button held selects text in the
+	// reading order, like a terminal emulator does, and the selected cells
+	// are displayed with the given style (StyleDefault means reverse video).
+	// The mouse events are still delivered to the application.  If autoCopy
+	// is true, the selected text is posted to the clipboard (see SetClipboard)
+	// when the button is released.  Mouse reporting must be enabled with
+	// EnableMouse, including drag events, for this to work.
+	EnableSelection(style Style, autoCopy bool)
+
+	// DisableSelection disables the built-in selection mode, and clears
+	// any current selection.
+	DisableSelection()
+
+	// GetSelection returns the currently selected text.  Trailing spaces are
+	// removed from each line, and lines are separated by newlines.  Wide
+	// characters and combining characters are handled correctly.
+	GetSelection() string
+
+	// EnableReflow enables the line oriented mode.  In this mode the
+	// screen keeps the logical lines added with AppendLine, and wraps them
+	// to the width of the screen, like a terminal does; when there are
+	// more lines than fit, the oldest ones scroll off the top.  When the
+	// screen is resized, the lines are wrapped again, rather than being
+	// truncated.  This is useful for chat or log style applications.
+	// Other content drawn on the screen is replaced whenever the lines are
+	// redrawn.  As usual, Show must be called to display the results.
+	EnableReflow()
+
+	// DisableReflow disables the line oriented mode, and discards the
+	// logical lines.  The screen contents are left as they are.
+	DisableReflow()
+
+	// AppendLine adds a logical line in the given style, when the line
+	// oriented mode is enabled (see EnableReflow).  Any newlines in the
+	// text start new logical lines.
+	AppendLine(text string, style Style)
+
+	// ClearLines discards all the logical lines added with AppendLine.
+	ClearLines()
+
+	// Protect runs the given function, and if it panics, finalizes the
+	// screen (restoring the terminal) before allowing the panic to continue.
+	// This ensures that the panic message is visible and legible, and that
+	// the user's terminal is left in a usable state.
+	Protect(func())
 }
 
 // NewScreen returns a default Screen suitable for the user's terminal
@@ -299,7 +663,11 @@ func NewScreen() (Screen, error) {
 }
 
 // MouseFlags are options to modify the handling of mouse events.
-// Actual events can be ORed together.
+// Actual events can be ORed together, but each level includes the ones
+// before it, so there is no need to.  Applications that only need clicks
+// should ask for MouseButtonEvents, as the terminal then need not report
+// each cell the mouse passes over.  EnableMouse can be called again at any
+// time to change the level, without disabling the mouse in between.
 type MouseFlags int
 
 const (
@@ -308,6 +676,217 @@ const (
 	MouseMotionEvents = MouseFlags(4) // All mouse events (includes click and drag events)
 )
 
+// mouseMotionWanted reports whether motion, with the buttons held, is
+// reported with the flags, for platforms where the filtering is not done
+// by the terminal.
+func mouseMotionWanted(f MouseFlags, btn ButtonMask) bool {
+	if f&MouseMotionEvents != 0 {
+		return true
+	}
+	return f&MouseDragEvents != 0 && btn&(Button1|Button2|Button3) != 0
+}
+
+// DisplayOptions control how the screen takes over the terminal display.
+// The zero value gives the default behavior, which is to use the alternate
+// screen buffer (if the terminal has one), clearing it at start, and to
+// restore the primary screen buffer on exit.
+type DisplayOptions struct {
+	// NoAltScreen runs on the primary screen buffer.  Content written
+	// to the screen will remain in the terminal's scroll back.
+	NoAltScreen bool
+
+	// NoClear avoids clearing the display at start.  Note that the
+	// first call to Show will still draw every cell.
+	NoClear bool
+
+	// KeepOnExit leaves the final frame visible on exit, with the cursor
+	// placed below it, instead of clearing it.  This has no effect when
+	// the alternate screen buffer is in use.
+	KeepOnExit bool
+
+	// InlineRows, if non-zero, preserves the existing content of the
+	// terminal, and confines the screen to a region of this many rows at
+	// the bottom of the display, scrolling the existing content up to make
+	// room.  The size reported for the screen is the size of the region.
+	// This implies NoAltScreen and NoClear.  This is useful for command
+	// line tools that present a small interface, such as a picker.
+	InlineRows int
+
+	// Optimize and NoOptimize turn output optimizations on and off,
+	// overriding the defaults for the terminal.  See Optimizations.
+	Optimize   Optimizations
+	NoOptimize Optimizations
+
+	// SystemClipboard makes SetClipboard and GetClipboard use the system
+	// clipboard when the terminal cannot set the clipboard itself (with
+	// OSC 52).  On Windows the clipboard API is used; elsewhere a program
+	// such as pbcopy, wl-copy, xclip, or xsel is run, if one is found.
+	// Note that when running remotely (over ssh, for example) this is
+	// the remote system's clipboard.
+	SystemClipboard bool
+
+	// NoClipboardCommands prevents SystemClipboard from running external
+	// programs, for applications where that is a security concern.
+	NoClipboardCommands bool
+
+	// Recover makes the screen try to start the terminal again after it
+	// fails (see Screen.Err), as Suspend and Resume would.  This works if
+	// the failure was temporary, or if the terminal (for /dev/tty, the
+	// controlling terminal of the process) can be opened again.  If it
+	// cannot, the screen is left suspended.
+	Recover bool
+}
+
+// InputOptions control how the bytes received from a terminal are
+// interpreted as keys.  Many keys are sent as escape sequences starting
+// with ESC, which is also what the Esc key sends, and what is sent before
+// a key pressed together with Alt (Meta).  When an ESC (or part of an
+// escape sequence) is received, the screen waits a little for the rest of
+// it before giving up and reporting what it has as separate keys.  Over
+// slow links, sequences can be split by longer delays, causing for example
+// Alt+x to be reported as Esc followed by x.
+//
+// The zero value gives the default behavior, which waits 50 milliseconds.
+type InputOptions struct {
+	// EscapeDelay is how long to wait after a lone ESC for more input,
+	// before reporting it as the Esc key.  Zero means the default.
+	EscapeDelay time.Duration
+
+	// SequenceDelay is how long to wait for the rest of an escape
+	// sequence (or a multibyte character) after part of it has been
+	// received.  Zero means the same as EscapeDelay.
+	SequenceDelay time.Duration
+
+	// WaitComplete makes the screen wait indefinitely for the rest of
+	// a partially received escape sequence, instead of timing out.  A lone
+	// ESC still times out according to EscapeDelay.  This avoids misreading
+	// sequences on high latency links, at the cost of a delay if the user
+	// types something that looks like the start of an escape sequence; in
+	// that case the input is reported when the next key arrives.
+	WaitComplete bool
+
+	// AlternateKeys asks the terminal to report keys using the kitty
+	// keyboard protocol, which includes the key on a US layout for keys
+	// pressed with Ctrl or Alt (see EventKey.BaseRune), so that shortcuts
+	// can be matched by physical key.  Keys reported this way also have
+	// accurate modifiers, including ModShift.  Terminals that do not
+	// support the protocol ignore the request.
+	AlternateKeys bool
+
+	// KeypadKeys puts the numeric keypad in application mode (DECKPAM),
+	// and reports its keys as KeyKP0 to KeyKP9, KeyKPEnter and so on,
+	// so that they can be told apart from the main keyboard.  The
+	// terminal must report keypad keys distinctly, in application mode
+	// or with the kitty keyboard protocol, which not all do; and some
+	// only do so with NumLock off.  Otherwise the keys are reported as
+	// the characters they produce, and keypad Enter as KeyEnter.
+	KeypadKeys bool
+
+	// ModifierKeys reports presses and releases of the modifier keys
+	// themselves, as KeyShift, KeyCtrl, KeyAlt and KeyMeta, for example
+	// to show hints while Shift is held.  This needs a terminal with the
+	// kitty keyboard protocol, which then also reports when other keys
+	// repeat, or the Windows console.
+	ModifierKeys bool
+
+	// RawSequences reports escape sequences that are not otherwise
+	// understood as EventRaw, instead of as individual keys.
+	RawSequences bool
+
+	// OSC lists operating system command numbers that are reported as
+	// EventOSC when received from the terminal, so that applications can
+	// receive replies to commands sent with SendOSC.  The commands that
+	// tcell understands itself (such as clipboard contents) are not
+	// reported this way.
+	OSC []int
+
+	// MaxString is the most bytes of a string (an OSC, DCS or APC
+	// sequence, such as the clipboard contents) received from the
+	// terminal that are kept while waiting for its end.  A longer string
+	// is discarded, through to its end, and reported with an EventError
+	// for ErrStringTooLong, so that a faulty or hostile terminal cannot
+	// use memory without limit, or have the rest of the string taken as
+	// keys.  Zero means 4 MiB.
+	MaxString int
+
+	// ConsoleMouse gets the mouse from the gpm daemon (or, failing that,
+	// from /dev/input/mice, which usually needs root) on the Linux
+	// console, where the terminal itself has no mouse support.  It only
+	// has an effect for terminals without mouse support, and on Linux.
+	// With the mouse device, no pointer is shown, so the application
+	// should draw one.
+	ConsoleMouse bool
+
+	// Locator uses the DEC locator protocol for the mouse, instead of
+	// XTerm mouse tracking, for terminals (such as the DEC VT models)
+	// that only have the locator.  It reports presses and releases of
+	// the buttons, but not motion, so there are no drags or hovering.
+	// Terminals without the locator ignore it.
+	Locator bool
+}
+
+// SemanticMark is a shell integration mark, which identifies the start
+// of a semantic zone (prompt, command, or output) for the terminal.
+type SemanticMark int
+
+const (
+	MarkPromptStart  = SemanticMark(iota) // The prompt starts here (OSC 133;A).
+	MarkCommandStart                      // The command input starts here (OSC 133;B).
+	MarkOutputStart                       // The command output starts here (OSC 133;C).
+	MarkCommandEnd                        // The command finished (OSC 133;D).
+)
+
+// TerminalIdentity is the identity of a terminal, as reported by itself.
+// Fields the terminal did not report are zero.  Note that many terminals
+// claim to be some other terminal, usually xterm or a DEC VT model, in
+// their device attributes, so the Name is the most reliable identification
+// when present.
+type TerminalIdentity struct {
+	// Name and Version are from the reply to XTVERSION, which for example
+	// is "XTerm(388)", "kitty(0.31.0)" or "tmux 3.4".  If the reply could
+	// not be split, the whole reply is the Name.
+	Name    string
+	Version string
+
+	// Class is the first parameter of the primary device attributes
+	// (DA1), which is the conformance level, for example 62 for a VT220,
+	// 64 for a VT420, or 1 for a VT100.
+	Class int
+
+	// Features are the remaining parameters of the primary device
+	// attributes, which list the optional features supported, such as
+	// 4 for sixel graphics, or 22 for ANSI color.
+	Features []int
+
+	// Type, Firmware and ROM are the parameters of the secondary device
+	// attributes (DA2).  Type identifies the terminal model, and Firmware
+	// is often the version of the terminal (xterm reports its patch level).
+	Type     int
+	Firmware int
+	ROM      int
+
+	// Capabilities are the terminfo capabilities that the terminal
+	// reported itself with XTGETTCAP, by name.  Boolean capabilities
+	// have an empty value.  Only a few capabilities are asked for, and
+	// most terminals do not answer at all.
+	Capabilities map[string]string
+
+	// MouseQuirks are not reported by the terminal, but are what tcell
+	// allows for in its mouse reports, chosen from the rest of this.
+	MouseQuirks MouseQuirks
+}
+
+// HasFeature returns true if the primary device attributes included the
+// given feature.
+func (ti TerminalIdentity) HasFeature(f int) bool {
+	for _, v := range ti.Features {
+		if v == f {
+			return true
+		}
+	}
+	return false
+}
+
 // CursorStyle represents a given cursor style, which can include the shape and
 // whether the cursor blinks or is solid.  Support for changing this is not universal.
 type CursorStyle int
@@ -320,13 +899,74 @@ const (
 	CursorStyleSteadyUnderline
 	CursorStyleBlinkingBar
 	CursorStyleSteadyBar
+	CursorStyleBlinkingDefault // The default shape, but blinking
+	CursorStyleSteadyDefault   // The default shape, but not blinking
+)
+
+// CursorShape is the shape of the cursor, without whether it blinks.
+type CursorShape int
+
+const (
+	CursorShapeDefault = CursorShape(iota) // The terminal's default
+	CursorShapeBlock
+	CursorShapeUnderline
+	CursorShapeBar
 )
 
+// NewCursorStyle returns the cursor style with the given shape, that
+// blinks or not.
+func NewCursorStyle(shape CursorShape, blink bool) CursorStyle {
+	var cs CursorStyle
+	switch shape {
+	case CursorShapeBlock:
+		cs = CursorStyleBlinkingBlock
+	case CursorShapeUnderline:
+		cs = CursorStyleBlinkingUnderline
+	case CursorShapeBar:
+		cs = CursorStyleBlinkingBar
+	default:
+		if blink {
+			return CursorStyleBlinkingDefault
+		}
+		return CursorStyleSteadyDefault
+	}
+	if !blink {
+		cs++ // the steady style follows the blinking one
+	}
+	return cs
+}
+
+// Shape returns the shape of the cursor for the style.
+func (cs CursorStyle) Shape() CursorShape {
+	switch cs {
+	case CursorStyleBlinkingBlock, CursorStyleSteadyBlock:
+		return CursorShapeBlock
+	case CursorStyleBlinkingUnderline, CursorStyleSteadyUnderline:
+		return CursorShapeUnderline
+	case CursorStyleBlinkingBar, CursorStyleSteadyBar:
+		return CursorShapeBar
+	}
+	return CursorShapeDefault
+}
+
+// Blinking returns true if the style makes the cursor blink.  For
+// CursorStyleDefault it returns false, although the terminal's default
+// cursor may blink.
+func (cs CursorStyle) Blinking() bool {
+	switch cs {
+	case CursorStyleBlinkingBlock, CursorStyleBlinkingUnderline,
+		CursorStyleBlinkingBar, CursorStyleBlinkingDefault:
+		return true
+	}
+	return false
+}
+
 // screenImpl is a subset of Screen that can be used with baseScreen to formulate
 // a complete implementation of Screen.  See Screen for doc comments about methods.
 type screenImpl interface {
 	Init() error
 	Fini()
+	Done() <-chan struct{}
 	SetStyle(style Style)
 	ShowCursor(x int, y int)
 	HideCursor()
@@ -353,9 +993,29 @@ type screenImpl interface {
 	Beep() error
 	SetSize(int, int)
 	SetTitle(string)
+	SetTabColor(Color)
+	SetBadge(string)
 	Tty() (Tty, bool)
 	SetClipboard([]byte)
 	GetClipboard()
+	SendOSC(int, string)
+	SetRestoreWriter(io.Writer)
+	SetDisplayOptions(DisplayOptions)
+	SetInputOptions(InputOptions)
+	AddSemanticMark(x, y int, mark SemanticMark, status int)
+	TerminalID() TerminalIdentity
+	FeatureReport() map[Feature]Support
+	ProbeColors()
+	Err() error
+
+	// showCells is Show for the cells of one row from x, for ShowCells.
+	showCells(x, y, width int)
+
+	// getCursor returns the cursor position (-1, -1 if hidden), shape and
+	// color, and getStyle returns the default style, for CaptureContents.
+	// They are called with the lock held.
+	getCursor() (int, int, CursorStyle, Color)
+	getStyle() Style
 
 	// Following methods are not part of the Screen api, but are used for interaction with
 	// the common layer code.
@@ -380,6 +1040,29 @@ type screenImpl interface {
 
 type baseScreen struct {
 	screenImpl
+
+	injectL   sync.Mutex
+	injected  []Event
+	injecting bool
+
+	srcQ    chan Event // events from event sources
+	srcOnce sync.Once
+
+	sel    selection
+	reflow reflow
+
+	evChan     chan Event
+	evChanOnce sync.Once
+
+	tick  ticker
+	limit limiter
+	theme theming
+	flash flashing
+
+	minSize minSize
+	access  accessibility
+
+	unfocused int32 // set atomically, non-zero when focus is lost
 }
 
 func (b *baseScreen) SetCell(x int, y int, style Style, ch ...rune) {
@@ -409,6 +1092,21 @@ func (b *baseScreen) SetContent(x, y int, mainc rune, combc []rune, st Style) {
 	b.Unlock()
 }
 
+func (b *baseScreen) SetContentWithTag(x, y int, mainc rune, combc []rune, st Style, tag interface{}) {
+	cells := b.GetCells()
+	b.Lock()
+	cells.SetContentWithTag(x, y, mainc, combc, st, tag)
+	b.Unlock()
+}
+
+func (b *baseScreen) GetTag(x, y int) interface{} {
+	cells := b.GetCells()
+	b.Lock()
+	tag := cells.GetTag(x, y)
+	b.Unlock()
+	return tag
+}
+
 func (b *baseScreen) GetContent(x, y int) (rune, []rune, Style, int) {
 	var primary rune
 	var combining []rune
@@ -437,35 +1135,97 @@ func (b *baseScreen) LockRegion(x, y, width, height int, lock bool) {
 	b.Unlock()
 }
 
-func (b *baseScreen) ChannelEvents(ch chan<- Event, quit <-chan struct{}) {
-	defer close(ch)
+// nextEvent waits for the next event, and lets the built-in modes see
+// it.  Events in the event queue (input, resizes, and posted events) are
+// preferred to those of event sources (see PostEventFunc), so that the
+// sources cannot hold up input.  It returns nil if quit is closed, or the
+// screen is stopped.
+func (b *baseScreen) nextEvent(quit <-chan struct{}) Event {
 	for {
 		select {
 		case <-quit:
-			return
+			return nil
 		case <-b.StopQ():
-			return
-		case ev := <-b.EventQ():
+			return nil
+		case <-b.Done():
+			return nil
+		default:
+		}
+		var ev Event
+		select {
+		case ev = <-b.EventQ():
+		default:
 			select {
 			case <-quit:
-				return
+				return nil
 			case <-b.StopQ():
-				return
-			case ch <- ev:
+				return nil
+			case <-b.Done():
+				return nil
+			case ev = <-b.EventQ():
+			case ev = <-b.sourceQ():
 			}
 		}
+		if b.admitEvent(ev) {
+			b.handleEvent(ev)
+			return ev
+		}
 	}
 }
 
-func (b *baseScreen) PollEvent() Event {
-	select {
-	case <-b.StopQ():
-		return nil
-	case ev := <-b.EventQ():
-		return ev
+func (b *baseScreen) ChannelEvents(ch chan<- Event, quit <-chan struct{}) {
+	defer close(ch)
+	for {
+		ev := b.nextEvent(quit)
+		if ev == nil {
+			return
+		}
+		select {
+		case <-quit:
+			return
+		case <-b.StopQ():
+			return
+		case ch <- ev:
+		}
+	}
+}
+
+func (b *baseScreen) PollEventContext(ctx context.Context) Event {
+	return b.nextEvent(ctx.Done())
+}
+
+func (b *baseScreen) EventChan() <-chan Event {
+	b.evChanOnce.Do(func() {
+		b.evChan = make(chan Event)
+		go b.ChannelEvents(b.evChan, nil)
+	})
+	return b.evChan
+}
+
+// handleEvent lets the built-in modes see events before they are
+// delivered to the application.
+func (b *baseScreen) handleEvent(ev Event) {
+	b.handleSelection(ev)
+	b.handleReflow(ev)
+	b.handleTick(ev)
+	b.limit.noteDelivery(ev)
+	if fev, ok := ev.(*EventFocus); ok {
+		lost := int32(1)
+		if fev.Focused {
+			lost = 0
+		}
+		atomic.StoreInt32(&b.unfocused, lost)
 	}
 }
 
+func (b *baseScreen) HasFocus() bool {
+	return atomic.LoadInt32(&b.unfocused) == 0
+}
+
+func (b *baseScreen) PollEvent() Event {
+	return b.nextEvent(nil)
+}
+
 func (b *baseScreen) HasPendingEvent() bool {
 	return len(b.EventQ()) > 0
 }
@@ -486,6 +1246,67 @@ func (b *baseScreen) PostEvent(ev Event) error {
 	}
 }
 
+func (b *baseScreen) InjectEvent(ev Event) {
+	b.injectL.Lock()
+	b.injected = append(b.injected, ev)
+	if !b.injecting {
+		b.injecting = true
+		go b.deliverInjected()
+	}
+	b.injectL.Unlock()
+}
+
+// sourceQ is the channel on which event sources deliver their events.  It
+// is unbuffered, so that each source waits until its event is taken.
+func (b *baseScreen) sourceQ() chan Event {
+	b.srcOnce.Do(func() {
+		b.srcQ = make(chan Event)
+	})
+	return b.srcQ
+}
+
+func (b *baseScreen) PostEventFunc(fn func() Event) {
+	go func() {
+		for {
+			ev := fn()
+			if ev == nil {
+				return
+			}
+			select {
+			case b.sourceQ() <- ev:
+			case <-b.Done():
+				return
+			}
+		}
+	}()
+}
+
+// deliverInjected delivers injected events to the event queue, waiting for
+// space as needed.  It runs until there are no more events to deliver.
+func (b *baseScreen) deliverInjected() {
+	for {
+		b.injectL.Lock()
+		if len(b.injected) == 0 {
+			b.injecting = false
+			b.injectL.Unlock()
+			return
+		}
+		ev := b.injected[0]
+		b.injected = b.injected[1:]
+		b.injectL.Unlock()
+
+		select {
+		case b.EventQ() <- ev:
+		case <-b.StopQ():
+			b.injectL.Lock()
+			b.injected = nil
+			b.injecting = false
+			b.injectL.Unlock()
+			return
+		}
+	}
+}
+
 func (b *baseScreen) SetCursorStyle(cs CursorStyle, ccs ...Color) {
 	if len(ccs) > 0 {
 		b.SetCursor(cs, ccs[0])
@@ -493,3 +1314,20 @@ func (b *baseScreen) SetCursorStyle(cs CursorStyle, ccs ...Color) {
 		b.SetCursor(cs, ColorNone)
 	}
 }
+
+func (b *baseScreen) SetCursorShape(shape CursorShape, blink bool) {
+	b.Lock()
+	_, _, _, cc := b.getCursor()
+	b.Unlock()
+	b.SetCursor(NewCursorStyle(shape, blink), cc)
+}
+
+func (b *baseScreen) Protect(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.Fini()
+			panic(r)
+		}
+	}()
+	fn()
+}
@@ -0,0 +1,302 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"strings"
+	"sync"
+)
+
+// selection tracks the state of the built-in selection mode.
+// The selection runs in reading order from the anchor to the end point,
+// the same way that terminal emulators select text.
+type selection struct {
+	enabled  bool
+	autoCopy bool
+	style    Style
+	down     bool // primary button is down
+	active   bool // there is a selected region
+	ax, ay   int  // anchor, where the button was pressed
+	ex, ey   int  // end point, where the pointer is now
+	saved    map[int]Style
+	savedW   int
+	l        sync.Mutex
+}
+
+// bounds returns the start and end points of the selection, in reading order.
+func (sel *selection) bounds() (int, int, int, int) {
+	x0, y0, x1, y1 := sel.ax, sel.ay, sel.ex, sel.ey
+	if y1 < y0 || (y1 == y0 && x1 < x0) {
+		x0, y0, x1, y1 = x1, y1, x0, y0
+	}
+	return x0, y0, x1, y1
+}
+
+// contains returns true if the cell is within the selection.
+func (sel *selection) contains(x, y int) bool {
+	if !sel.active {
+		return false
+	}
+	x0, y0, x1, y1 := sel.bounds()
+	if y < y0 || y > y1 {
+		return false
+	}
+	if y == y0 && x < x0 {
+		return false
+	}
+	if y == y1 && x > x1 {
+		return false
+	}
+	return true
+}
+
+func (b *baseScreen) EnableSelection(style Style, autoCopy bool) {
+	if style == StyleDefault {
+		style = style.Reverse(true)
+	}
+	b.sel.l.Lock()
+	b.sel.enabled = true
+	b.sel.style = style
+	b.sel.autoCopy = autoCopy
+	b.sel.l.Unlock()
+}
+
+func (b *baseScreen) DisableSelection() {
+	b.sel.l.Lock()
+	b.sel.enabled = false
+	b.sel.active = false
+	b.sel.down = false
+	b.sel.l.Unlock()
+}
+
+func (b *baseScreen) GetSelection() string {
+	b.sel.l.Lock()
+	defer b.sel.l.Unlock()
+	return b.selectedText()
+}
+
+// selectedText returns the selected text.  Trailing spaces are removed from
+// each line, and lines are separated by newlines.  The selection lock
+// must be held.
+func (b *baseScreen) selectedText() string {
+	if !b.sel.active {
+		return ""
+	}
+	cells := b.GetCells()
+	b.Lock()
+	defer b.Unlock()
+
+	w, _ := cells.Size()
+	x0, y0, x1, y1 := b.sel.bounds()
+	sb := &strings.Builder{}
+	for y := y0; y <= y1; y++ {
+		start, end := 0, w-1
+		if y == y0 {
+			start = x0
+		}
+		if y == y1 && x1 < end {
+			end = x1
+		}
+		line := &strings.Builder{}
+		for x := start; x <= end; x++ {
+			mainc, combc, _, width := cells.GetContent(x, y)
+			line.WriteRune(mainc)
+			for _, r := range combc {
+				line.WriteRune(r)
+			}
+			// skip the cell covered by the right half of a wide character
+			x += width - 1
+		}
+		sb.WriteString(strings.TrimRight(line.String(), " "))
+		if y != y1 {
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+// handleSelection updates the selection in response to mouse events.
+// The events are still delivered to the application.
+func (b *baseScreen) handleSelection(ev Event) {
+	mev, ok := ev.(*EventMouse)
+	if !ok {
+		return
+	}
+	b.sel.l.Lock()
+	defer b.sel.l.Unlock()
+	if !b.sel.enabled {
+		return
+	}
+	x, y := mev.Position()
+	btn := mev.Buttons()
+	switch {
+	case btn&ButtonPrimary != 0 && !b.sel.down:
+		// a new press clears any existing selection
+		b.sel.down = true
+		b.sel.active = false
+		b.sel.ax, b.sel.ay = x, y
+		b.sel.ex, b.sel.ey = x, y
+	case btn&ButtonPrimary != 0:
+		if x != b.sel.ex || y != b.sel.ey {
+			b.sel.active = true
+			b.sel.ex, b.sel.ey = x, y
+		}
+	case b.sel.down && btn&(WheelUp|WheelDown|WheelLeft|WheelRight) == 0:
+		b.sel.down = false
+		if b.sel.active && b.sel.autoCopy {
+			if text := b.selectedText(); text != "" {
+				b.SetClipboard([]byte(text))
+			}
+		}
+	}
+}
+
+// applySelection changes the style of the selected cells to the selection
+// style, remembering the original style so it can be restored afterwards
+// by restoreSelection.  This is done around the actual drawing so that
+// the application never sees the selection in the cell contents.
+// It returns false if there is nothing to do, or it is already applied.
+func (b *baseScreen) applySelection() bool {
+	b.sel.l.Lock()
+	if !b.sel.enabled || !b.sel.active || b.sel.saved != nil {
+		b.sel.l.Unlock()
+		return false
+	}
+	cells := b.GetCells()
+	b.Lock()
+	w, h := cells.Size()
+	b.sel.saved = make(map[int]Style)
+	b.sel.savedW = w
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !b.sel.contains(x, y) {
+				continue
+			}
+			mainc, combc, style, _ := cells.GetContent(x, y)
+			b.sel.saved[y*w+x] = style
+			cells.SetContentWithTag(x, y, mainc, combc, b.sel.style, cells.GetTag(x, y))
+		}
+	}
+	b.Unlock()
+	b.sel.l.Unlock()
+	return true
+}
+
+func (b *baseScreen) restoreSelection() {
+	b.sel.l.Lock()
+	saved, savedW := b.sel.saved, b.sel.savedW
+	b.sel.saved = nil
+	style := b.sel.style
+	b.sel.l.Unlock()
+	if saved == nil {
+		return
+	}
+	cells := b.GetCells()
+	b.Lock()
+	w, _ := cells.Size()
+	if w != savedW {
+		// resized while drawing; the contents are redrawn anyway
+		b.Unlock()
+		return
+	}
+	for i, orig := range saved {
+		x, y := i%w, i/w
+		mainc, combc, cur, _ := cells.GetContent(x, y)
+		// if the application changed the cell meanwhile, leave it alone
+		if cur == style {
+			cells.SetContentWithTag(x, y, mainc, combc, orig, cells.GetTag(x, y))
+		}
+	}
+	b.Unlock()
+}
+
+// drawOverlays displays the selection and any flash (or instead, if the
+// screen is smaller than its minimum size, a message saying so) for the
+// duration of a Show or Sync, including for implementations (like the
+// simulation screen) that are not called through baseScreen.  The returned
+// function restores the original contents, and makes the announcements of
+// the accessibility mode, and must be called after the screen lock is
+// released.
+func drawOverlays(s Screen) func() {
+	b, ok := s.(*baseScreen)
+	if !ok {
+		return func() {}
+	}
+	if b.applyMinSize() {
+		// the selection and flash are hidden by the message
+		announce := b.updateAccessibility()
+		return func() {
+			b.restoreMinSize()
+			announce()
+		}
+	}
+	sel := b.applySelection()
+	flash := b.applyFlash()
+	announce := b.updateAccessibility()
+	return func() {
+		if flash {
+			b.restoreFlash()
+		}
+		if sel {
+			b.restoreSelection()
+		}
+		announce()
+	}
+}
+
+func (b *baseScreen) Show() {
+	restore := drawOverlays(b)
+	b.screenImpl.Show()
+	restore()
+}
+
+func (b *baseScreen) Sync() {
+	restore := drawOverlays(b)
+	b.screenImpl.Sync()
+	restore()
+}
+
+// overlaid returns true if something would be drawn over the screen by
+// drawOverlays, or if the accessibility mode needs to see what is shown.
+func (b *baseScreen) overlaid() bool {
+	b.sel.l.Lock()
+	sel := b.sel.enabled && b.sel.active
+	b.sel.l.Unlock()
+	b.flash.l.Lock()
+	flash := len(b.flash.regions) > 0
+	b.flash.l.Unlock()
+	b.access.l.Lock()
+	access := b.access.fn != nil
+	b.access.l.Unlock()
+	if sel || flash || access {
+		return true
+	}
+	b.minSize.l.Lock()
+	minW, minH := b.minSize.w, b.minSize.h
+	b.minSize.l.Unlock()
+	cells := b.GetCells()
+	b.Lock()
+	w, h := cells.Size()
+	b.Unlock()
+	return w < minW || h < minH
+}
+
+func (b *baseScreen) ShowCells(x, y, width int) {
+	if b.overlaid() {
+		b.Show()
+		return
+	}
+	b.screenImpl.showCells(x, y, width)
+}
@@ -0,0 +1,175 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"sync"
+	"unicode"
+)
+
+// Shaper is a function that shapes a line of text, in logical order, before
+// it is placed into cells.  This is used for scripts where the form of a
+// character depends on its neighbors, such as Arabic, on terminals that
+// do not do their own shaping.  A Shaper must return the same number of
+// runes that it was given, so that positions (and styles) in the text are
+// preserved; if it does not, the result is ignored.  It must not modify
+// the slice it was given.
+type Shaper func(line []rune) []rune
+
+var shaper Shaper
+var shaperLk sync.Mutex
+
+// SetShaper registers a shaping function, which is used by the line
+// oriented mode of Screen (see AppendLine) and by the text widgets in the
+// views package.  Use nil (the default) to disable shaping.  For example,
+// to enable the built-in Arabic joining:
+//
+//	tcell.SetShaper(tcell.ShapeArabic)
+func SetShaper(s Shaper) {
+	shaperLk.Lock()
+	shaper = s
+	shaperLk.Unlock()
+}
+
+// ShapeText applies the registered Shaper (if any) to a line of text.
+// Applications that convert strings to cells themselves may call this
+// before doing so.  The result should be reordered for bidirectional text
+// (see NewBidiParagraph) after shaping, not before.
+func ShapeText(line []rune) []rune {
+	shaperLk.Lock()
+	s := shaper
+	shaperLk.Unlock()
+	if s == nil {
+		return line
+	}
+	if shaped := s(line); len(shaped) == len(line) {
+		return shaped
+	}
+	return line
+}
+
+// arabicForm describes the presentation forms of an Arabic letter.
+// Dual joining letters have isolated, final, initial, and medial forms
+// at consecutive code points starting at base.  Right joining letters
+// (which do not join to the following letter) only have isolated and
+// final forms.
+type arabicForm struct {
+	base rune
+	dual bool
+}
+
+var arabicForms = map[rune]arabicForm{
+	'ء': {0xFE80, false}, // hamza (does not actually join)
+	'آ': {0xFE81, false}, // alef with madda above
+	'أ': {0xFE83, false}, // alef with hamza above
+	'ؤ': {0xFE85, false}, // waw with hamza above
+	'إ': {0xFE87, false}, // alef with hamza below
+	'ئ': {0xFE89, true},  // yeh with hamza above
+	'ا': {0xFE8D, false}, // alef
+	'ب': {0xFE8F, true},  // beh
+	'ة': {0xFE93, false}, // teh marbuta
+	'ت': {0xFE95, true},  // teh
+	'ث': {0xFE99, true},  // theh
+	'ج': {0xFE9D, true},  // jeem
+	'ح': {0xFEA1, true},  // hah
+	'خ': {0xFEA5, true},  // khah
+	'د': {0xFEA9, false}, // dal
+	'ذ': {0xFEAB, false}, // thal
+	'ر': {0xFEAD, false}, // reh
+	'ز': {0xFEAF, false}, // zain
+	'س': {0xFEB1, true},  // seen
+	'ش': {0xFEB5, true},  // sheen
+	'ص': {0xFEB9, true},  // sad
+	'ض': {0xFEBD, true},  // dad
+	'ط': {0xFEC1, true},  // tah
+	'ظ': {0xFEC5, true},  // zah
+	'ع': {0xFEC9, true},  // ain
+	'غ': {0xFECD, true},  // ghain
+	'ف': {0xFED1, true},  // feh
+	'ق': {0xFED5, true},  // qaf
+	'ك': {0xFED9, true},  // kaf
+	'ل': {0xFEDD, true},  // lam
+	'م': {0xFEE1, true},  // meem
+	'ن': {0xFEE5, true},  // noon
+	'ه': {0xFEE9, true},  // heh
+	'و': {0xFEED, false}, // waw
+	'ى': {0xFEEF, false}, // alef maksura
+	'ي': {0xFEF1, true},  // yeh
+}
+
+const arabicTatweel = 'ـ'
+
+// arabicJoining returns whether the rune joins to the previous letter,
+// whether it joins to the following letter, and whether it is transparent
+// (a combining mark, which is skipped when looking for neighbors).
+func arabicJoining(r rune) (prev bool, next bool, transparent bool) {
+	if r == arabicTatweel {
+		return true, true, false
+	}
+	if f, ok := arabicForms[r]; ok {
+		if r == 'ء' {
+			return false, false, false
+		}
+		return true, f.dual, false
+	}
+	return false, false, unicode.Is(unicode.Mn, r)
+}
+
+// ShapeArabic is a basic Shaper for Arabic.  It replaces the Arabic letters
+// with the presentation form (isolated, initial, medial or final) that
+// corresponds to how the letter joins with its neighbors.  Ligatures
+// (such as lam with alef) are not formed, since that would change the
+// number of characters.
+func ShapeArabic(line []rune) []rune {
+	out := make([]rune, len(line))
+	copy(out, line)
+	for i, r := range line {
+		f, ok := arabicForms[r]
+		if !ok {
+			continue
+		}
+		joinPrev, joinNext, _ := arabicJoining(r)
+		before, after := false, false
+		if joinPrev {
+			for j := i - 1; j >= 0; j-- {
+				_, n, t := arabicJoining(line[j])
+				if !t {
+					before = n
+					break
+				}
+			}
+		}
+		if joinNext {
+			for j := i + 1; j < len(line); j++ {
+				p, _, t := arabicJoining(line[j])
+				if !t {
+					after = p
+					break
+				}
+			}
+		}
+		switch {
+		case before && after:
+			out[i] = f.base + 3
+		case after:
+			out[i] = f.base + 2
+		case before:
+			out[i] = f.base + 1
+		default:
+			out[i] = f.base
+		}
+	}
+	return out
+}
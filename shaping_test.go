@@ -0,0 +1,58 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"testing"
+)
+
+func TestShapeArabic(t *testing.T) {
+	cases := []struct {
+		text   string
+		shaped []rune
+	}{
+		// beh alone is isolated
+		{"ب", []rune{0xFE8F}},
+		// beh-beh-beh: initial, medial, final
+		{"ببب", []rune{0xFE91, 0xFE92, 0xFE90}},
+		// dal does not join to the next letter
+		{"بدب", []rune{0xFE91, 0xFEAA, 0xFE8F}},
+		// combining marks are transparent
+		{"بَب", []rune{0xFE91, 'َ', 0xFE90}},
+		// other text is untouched
+		{"a ب", []rune{'a', ' ', 0xFE8F}},
+	}
+	for _, c := range cases {
+		if got := ShapeArabic([]rune(c.text)); string(got) != string(c.shaped) {
+			t.Errorf("%q: got %U expected %U", c.text, got, c.shaped)
+		}
+	}
+}
+
+func TestShapeText(t *testing.T) {
+	defer SetShaper(nil)
+	line := []rune("ببب")
+	if string(ShapeText(line)) != string(line) {
+		t.Errorf("Text shaped without a shaper")
+	}
+	SetShaper(ShapeArabic)
+	if got := ShapeText(line); got[0] != 0xFE91 {
+		t.Errorf("Shaper not applied")
+	}
+	SetShaper(func([]rune) []rune { return nil })
+	if string(ShapeText(line)) != string(line) {
+		t.Errorf("Bad shaper result not ignored")
+	}
+}
@@ -15,7 +15,14 @@
 package tcell
 
 import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func mkTestScreen(t *testing.T, charset string) SimulationScreen {
@@ -160,3 +167,802 @@ func TestTitle(t *testing.T) {
 		t.Errorf("Title mismatched")
 	}
 }
+
+func TestProtect(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("Panic not propagated: %v", r)
+		}
+		if ev := s.PollEvent(); ev != nil {
+			t.Errorf("Screen not finalized")
+		}
+	}()
+	s.Protect(func() {
+		panic("boom")
+	})
+	t.Errorf("Should not be reached")
+}
+
+func TestInjectEvent(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+
+	// more events than the queue can hold
+	for i := 0; i < 30; i++ {
+		s.InjectEvent(NewEventKey(KeyRune, rune('A'+i), ModNone))
+	}
+	for i := 0; i < 30; i++ {
+		ev, ok := s.PollEvent().(*EventKey)
+		if !ok {
+			t.Fatalf("Expected key event")
+		}
+		if ev.Rune() != rune('A'+i) {
+			t.Errorf("Wrong order: got %c expected %c", ev.Rune(), rune('A'+i))
+		}
+	}
+}
+
+func TestSelection(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+	s.SetSize(10, 3)
+	for i, r := range "hello" {
+		s.SetContent(i, 0, r, nil, StyleDefault)
+	}
+	for i, r := range "world" {
+		s.SetContent(i, 1, r, nil, StyleDefault)
+	}
+	s.EnableSelection(StyleDefault, true)
+
+	s.InjectEvent(NewEventMouse(2, 0, Button1, ModNone))
+	s.InjectEvent(NewEventMouse(4, 0, Button1, ModNone))
+	s.InjectEvent(NewEventMouse(2, 1, Button1, ModNone))
+	s.InjectEvent(NewEventMouse(2, 1, ButtonNone, ModNone))
+	for i := 0; i < 4; i++ {
+		if _, ok := s.PollEvent().(*EventMouse); !ok {
+			t.Fatalf("Expected mouse event")
+		}
+	}
+	if text := s.GetSelection(); text != "llo\nwor" {
+		t.Errorf("Wrong selection: %q", text)
+	}
+	if data := string(s.GetClipboardData()); data != "llo\nwor" {
+		t.Errorf("Wrong clipboard: %q", data)
+	}
+
+	s.Show()
+	cells, _, _ := s.GetContents()
+	if cells[3].Style != StyleDefault.Reverse(true) {
+		t.Errorf("Selected cell not highlighted")
+	}
+	if _, _, style, _ := s.GetContent(3, 0); style != StyleDefault {
+		t.Errorf("Selection style leaked into cell contents")
+	}
+
+	s.DisableSelection()
+	if text := s.GetSelection(); text != "" {
+		t.Errorf("Selection not cleared: %q", text)
+	}
+}
+
+func TestReflow(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+	s.SetSize(10, 3)
+	s.EnableReflow()
+	s.AppendLine("first", StyleDefault)
+	s.AppendLine("the quick brown", StyleDefault.Bold(true))
+
+	row := func(y int) string {
+		sb := &strings.Builder{}
+		w, _ := s.Size()
+		for x := 0; x < w; x++ {
+			r, _, _, _ := s.GetContent(x, y)
+			sb.WriteRune(r)
+		}
+		return strings.TrimRight(sb.String(), " ")
+	}
+	if row(0) != "first" || row(1) != "the quick" || row(2) != "brown" {
+		t.Errorf("Wrong wrap: %q %q %q", row(0), row(1), row(2))
+	}
+	if _, _, style, _ := s.GetContent(0, 2); style != StyleDefault.Bold(true) {
+		t.Errorf("Wrong style")
+	}
+
+	// the simulation does not report resizes made with SetSize
+	s.SetSize(20, 3)
+	s.InjectEvent(NewEventResize(20, 3))
+	if _, ok := s.PollEvent().(*EventResize); !ok {
+		t.Fatalf("Expected resize event")
+	}
+	if row(0) != "first" || row(1) != "the quick brown" || row(2) != "" {
+		t.Errorf("Wrong reflow: %q %q %q", row(0), row(1), row(2))
+	}
+}
+
+func TestCellTags(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+	s.SetSize(10, 2)
+
+	type widget struct{ name string }
+	w := &widget{"button"}
+	s.SetContentWithTag(1, 1, 'X', nil, StyleDefault, w)
+	if tag, ok := s.GetTag(1, 1).(*widget); !ok || tag != w {
+		t.Errorf("Tag not returned")
+	}
+	if r, _, _, _ := s.GetContent(1, 1); r != 'X' {
+		t.Errorf("Content not set")
+	}
+	if s.GetTag(0, 0) != nil || s.GetTag(20, 20) != nil {
+		t.Errorf("Unexpected tag")
+	}
+
+	s.SetSize(12, 3)
+	if s.GetTag(1, 1) != w {
+		t.Errorf("Tag lost on resize")
+	}
+	s.SetContent(1, 1, 'Y', nil, StyleDefault)
+	if s.GetTag(1, 1) != nil {
+		t.Errorf("Tag not cleared by SetContent")
+	}
+	s.SetContentWithTag(2, 1, 'Z', nil, StyleDefault, 7)
+	s.Clear()
+	if s.GetTag(2, 1) != nil {
+		t.Errorf("Tag not cleared by Clear")
+	}
+}
+
+func TestCaptureContents(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+	s.SetSize(10, 3)
+
+	s.SetContent(0, 0, 'A', nil, StyleDefault.Bold(true))
+	s.SetContentWithTag(1, 0, 'é', []rune{'́'}, StyleDefault, "tag")
+	s.ShowCursor(2, 1)
+	buf := s.CaptureContents()
+	if w, h := buf.Size(); w != 10 || h != 3 {
+		t.Errorf("Wrong size %dx%d", w, h)
+	}
+
+	// draw an overlay
+	s.Fill('#', StyleDefault)
+	s.HideCursor()
+
+	s.RestoreContents(buf)
+	if r, _, style, _ := s.GetContent(0, 0); r != 'A' || style != StyleDefault.Bold(true) {
+		t.Errorf("Cell not restored")
+	}
+	if r, comb, _, _ := s.GetContent(1, 0); r != 'é' || len(comb) != 1 {
+		t.Errorf("Combining characters not restored")
+	}
+	if s.GetTag(1, 0) != "tag" {
+		t.Errorf("Tag not restored")
+	}
+	if r, _, _, _ := s.GetContent(5, 2); r != ' ' {
+		t.Errorf("Overlay not removed")
+	}
+	s.Show()
+	if x, y, visible := s.GetCursor(); x != 2 || y != 1 || !visible {
+		t.Errorf("Cursor not restored")
+	}
+}
+
+func TestPollEventContext(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if ev := s.PollEventContext(ctx); ev != nil {
+		t.Errorf("Expected nil on timeout, got %v", ev)
+	}
+
+	_ = s.PostEvent(NewEventInterrupt(nil))
+	if _, ok := s.PollEventContext(context.Background()).(*EventInterrupt); !ok {
+		t.Errorf("Expected interrupt event")
+	}
+}
+
+func TestEventChan(t *testing.T) {
+	s := mkTestScreen(t, "")
+
+	ch := s.EventChan()
+	if s.EventChan() != ch {
+		t.Errorf("Different channel returned")
+	}
+	_ = s.PostEvent(NewEventInterrupt(nil))
+	select {
+	case ev := <-ch:
+		if _, ok := ev.(*EventInterrupt); !ok {
+			t.Errorf("Expected interrupt event")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for event")
+	}
+
+	s.Fini()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Errorf("Channel not closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for close")
+	}
+}
+
+func TestTicker(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+	s.SetSize(4, 1)
+
+	s.Ticker(100)
+	s.SetContent(0, 0, 'X', nil, StyleDefault)
+	var last uint64
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		ev, ok := s.PollEventContext(ctx).(*EventTick)
+		cancel()
+		if !ok {
+			t.Fatalf("Expected tick event")
+		}
+		if ev.Frame() <= last {
+			t.Errorf("Frame numbers not increasing")
+		}
+		last = ev.Frame()
+	}
+	// the content was shown without calling Show
+	if cells, _, _ := s.GetContents(); cells[0].Runes[0] != 'X' {
+		t.Errorf("Content not shown")
+	}
+
+	s.Ticker(0)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	// drain any tick posted before the clock stopped
+	for s.PollEventContext(ctx) != nil {
+	}
+	ctx2, cancel2 := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel2()
+	if ev := s.PollEventContext(ctx2); ev != nil {
+		t.Errorf("Unexpected event after stopping: %v", ev)
+	}
+}
+
+func TestInputLimits(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+
+	// with a very low rate, only the first of the repeats gets through
+	s.SetInputLimits(InputLimits{Rate: 1, Burst: 1})
+	for i := 0; i < 5; i++ {
+		_ = s.PostEvent(NewEventKey(KeyRune, 'a', ModNone))
+	}
+	_ = s.PostEvent(NewEventKey(KeyRune, 'b', ModNone))
+	for _, r := range "ab" {
+		if ev, ok := s.PollEvent().(*EventKey); !ok || ev.Rune() != r {
+			t.Fatalf("Expected key %q", r)
+		}
+	}
+	if st := s.InputStats(); st.Dropped != 4 {
+		t.Errorf("Expected 4 dropped keys, got %d", st.Dropped)
+	}
+
+	// mouse motion is coalesced, keeping the final position
+	for i := 0; i < 3; i++ {
+		_ = s.PostEvent(NewEventMouse(i, 0, ButtonNone, ModNone))
+	}
+	if ev, ok := s.PollEvent().(*EventMouse); !ok {
+		t.Fatalf("Expected mouse event")
+	} else if x, _ := ev.Position(); x != 2 {
+		t.Errorf("Expected final position, got %d", x)
+	}
+	// but button changes are not
+	_ = s.PostEvent(NewEventMouse(3, 0, Button1, ModNone))
+	_ = s.PostEvent(NewEventMouse(3, 0, ButtonNone, ModNone))
+	for _, btn := range []ButtonMask{Button1, ButtonNone} {
+		if ev, ok := s.PollEvent().(*EventMouse); !ok || ev.Buttons() != btn {
+			t.Fatalf("Expected mouse button %v", btn)
+		}
+	}
+	if st := s.InputStats(); st.Coalesced != 2 {
+		t.Errorf("Expected 2 coalesced events, got %d", st.Coalesced)
+	}
+
+	// pastes are truncated, but not otherwise limited
+	s.SetInputLimits(InputLimits{Rate: 1, MaxPaste: 3})
+	_ = s.PostEvent(NewEventPaste(true))
+	for i := 0; i < 5; i++ {
+		_ = s.PostEvent(NewEventKey(KeyRune, 'x', ModNone))
+	}
+	_ = s.PostEvent(NewEventPaste(false))
+	keys := 0
+	for {
+		ev := s.PollEvent()
+		if _, ok := ev.(*EventKey); ok {
+			keys++
+		}
+		if ev, ok := ev.(*EventPaste); ok && ev.End() {
+			break
+		}
+	}
+	if keys != 3 {
+		t.Errorf("Expected 3 keys pasted, got %d", keys)
+	}
+	if st := s.InputStats(); st.PasteDropped != 2 {
+		t.Errorf("Expected 2 keys dropped from paste, got %d", st.PasteDropped)
+	}
+}
+
+func TestTheme(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+
+	if th := s.Theme(); th != nil || th.Style(RoleError) != StyleDefault {
+		t.Errorf("Expected no theme")
+	}
+	dark := NewDarkTheme()
+	s.SetTheme(dark)
+	ev, ok := s.PollEvent().(*EventThemeChange)
+	if !ok || ev.Theme() != dark || s.Theme() != dark {
+		t.Fatalf("Expected theme change event")
+	}
+	// roles not in the theme use the normal style
+	normal := dark.Style(RoleNormal)
+	if dark.Style(StyleRole("custom")) != normal {
+		t.Errorf("Unknown role did not use normal style")
+	}
+	if dark.Style(RoleError) == normal {
+		t.Errorf("Error style is the normal style")
+	}
+	// the screen default style follows the theme
+	s.Clear()
+	s.Show()
+	if cells, _, _ := s.GetContents(); cells[0].Style != normal {
+		t.Errorf("Screen style not set from theme")
+	}
+}
+
+func TestClip(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+	s.SetSize(10, 5)
+
+	var target DrawTarget = s
+	outer := target.Clip(Rect{X: 2, Y: 1, Width: 6, Height: 3})
+	if w, h := outer.Size(); w != 6 || h != 3 {
+		t.Errorf("Wrong size %dx%d", w, h)
+	}
+	outer.Fill('.', StyleDefault)
+	outer.SetContent(-1, 0, 'X', nil, StyleDefault) // outside
+	outer.SetContent(5, 0, '界', nil, StyleDefault)  // too wide for the last column
+	inner := outer.Clip(Rect{X: 4, Y: 1, Width: 5, Height: 5})
+	if w, h := inner.Size(); w != 2 || h != 2 {
+		t.Errorf("Wrong inner size %dx%d", w, h)
+	}
+	inner.SetContent(0, 0, 'A', nil, StyleDefault)
+	inner.SetContent(1, 1, 'B', nil, StyleDefault)
+	inner.SetContent(2, 0, 'C', nil, StyleDefault) // outside
+	if r, _, _, _ := inner.GetContent(1, 1); r != 'B' {
+		t.Errorf("Wrong content %q", r)
+	}
+	s.Show()
+
+	cells, _, _ := s.GetContents()
+	var rows []string
+	for y := 0; y < 5; y++ {
+		row := ""
+		for x := 0; x < 10; x++ {
+			row += string(cells[y*10+x].Runes)
+		}
+		rows = append(rows, row)
+	}
+	expect := []string{
+		"          ",
+		"  .....   ", // the wide character became a space
+		"  ....A.  ",
+		"  .....B  ",
+		"          ",
+	}
+	for y := range expect {
+		if rows[y] != expect[y] {
+			t.Errorf("Row %d: got %q, expected %q", y, rows[y], expect[y])
+		}
+	}
+}
+
+func TestShade(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+	s.SetSize(3, 1)
+
+	s.SetContent(0, 0, 'A', nil, StyleDefault.Foreground(NewRGBColor(200, 100, 0)).Background(ColorWhite))
+	s.SetContent(1, 0, 'B', nil, StyleDefault)
+	s.SetContent(2, 0, 'C', nil, StyleDefault.Foreground(ColorRed))
+	buf := s.CaptureContents()
+	buf.Shade(Rect{X: 0, Y: 0, Width: 2, Height: 5}, ColorBlack, 0.5)
+	s.RestoreContents(buf)
+
+	_, _, st, _ := s.GetContent(0, 0)
+	if st.fg != NewRGBColor(100, 50, 0) || st.bg != NewRGBColor(128, 128, 128) {
+		t.Errorf("Wrong colors %v %v", st.fg, st.bg)
+	}
+	// default colors are dimmed instead
+	if _, _, st, _ = s.GetContent(1, 0); st.attrs&AttrDim == 0 || st.bg != ColorDefault {
+		t.Errorf("Default colors not dimmed")
+	}
+	// outside the region
+	if _, _, st, _ = s.GetContent(2, 0); st.fg != ColorRed {
+		t.Errorf("Cell outside region changed")
+	}
+}
+
+func TestBufferPatches(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+	s.SetSize(6, 2)
+
+	bold := StyleDefault.Bold(true).Url("https://example.com")
+	s.SetContent(0, 0, 'A', nil, bold)
+	s.SetContent(1, 0, 'e', []rune{'\u0301'}, bold)
+	s.SetContent(4, 1, 'Z', nil, StyleDefault)
+	first := s.CaptureContents()
+
+	mirror := &Buffer{}
+	patches, err := DecodePatches(EncodePatches(first.Diff(nil)))
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if len(patches) != 3 {
+		t.Errorf("Expected resize and two runs, got %d patches", len(patches))
+	}
+	mirror.Apply(patches)
+
+	s.SetContent(1, 0, 'B', nil, bold)
+	s.SetContent(2, 0, 'C', nil, bold)
+	second := s.CaptureContents()
+	patches = second.Diff(first)
+	if len(patches) != 1 || patches[0].X != 1 || len(patches[0].Cells) != 2 {
+		t.Errorf("Wrong patches for the change: %v", patches)
+	}
+	data := EncodePatches(patches)
+	if patches, err = DecodePatches(data); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	mirror.Apply(patches)
+	if d := second.Diff(mirror); len(d) != 0 {
+		t.Errorf("Mirror differs: %v", d)
+	}
+
+	s.Clear()
+	s.RestoreContents(mirror)
+	if r, comb, style, _ := s.GetContent(1, 0); r != 'B' || len(comb) != 0 || style != bold {
+		t.Errorf("Mirror not restored")
+	}
+	if _, err := DecodePatches(data[:len(data)-1]); err != ErrBadPatch {
+		t.Errorf("Truncated data accepted")
+	}
+
+	// sizes that would allocate without limit are refused
+	huge := EncodePatches([]CellPatch{{Width: 100000, Height: 100000}})
+	if _, err := DecodePatches(huge); err != ErrBadPatch {
+		t.Errorf("Huge resize accepted")
+	}
+	mirror.Apply([]CellPatch{{Width: -5, Height: 2}, {Width: 4000, Height: 4000}})
+	if d := second.Diff(mirror); len(d) != 0 {
+		t.Errorf("Bad resize applied: %v", d)
+	}
+}
+
+func TestHasFocus(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+
+	if !s.HasFocus() {
+		t.Errorf("Screen should start focused")
+	}
+	s.EnableFocus()
+	_ = s.PostEvent(NewEventFocus(false))
+	s.PollEvent()
+	if s.HasFocus() {
+		t.Errorf("Focus loss not noticed")
+	}
+	_ = s.PostEvent(NewEventFocus(true))
+	s.PollEvent()
+	if !s.HasFocus() {
+		t.Errorf("Focus gain not noticed")
+	}
+}
+
+func TestFiniRace(t *testing.T) {
+	s := NewSimulationScreen("")
+	s.Fini() // before Init
+	select {
+	case <-s.Done():
+	default:
+		t.Errorf("not done after Fini")
+	}
+	if err := s.Init(); err != ErrScreenClosed {
+		t.Errorf("wrong Init error: %v", err)
+	}
+
+	s = mkTestScreen(t, "")
+	if err := s.Init(); err != nil {
+		t.Errorf("second Init failed: %v", err)
+	}
+	polled := make(chan struct{})
+	go func() {
+		for s.PollEvent() != nil {
+		}
+		close(polled)
+	}()
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			s.SetContent(0, 0, 'A', nil, StyleDefault)
+			s.Show()
+			_ = s.PostEvent(NewEventInterrupt(nil))
+		}
+	}()
+	defer close(stop)
+
+	time.Sleep(time.Millisecond * 10)
+	for i := 0; i < 4; i++ {
+		go s.Fini()
+	}
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("not done after Fini")
+	}
+	select {
+	case <-polled:
+	case <-time.After(time.Second):
+		t.Fatalf("PollEvent did not return")
+	}
+	s.Fini()
+	if err := s.Suspend(); err != ErrScreenClosed {
+		t.Errorf("wrong Suspend error: %v", err)
+	}
+	if err := s.Resume(); err != ErrScreenClosed {
+		t.Errorf("wrong Resume error: %v", err)
+	}
+}
+
+func TestInputLatency(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+
+	s.InjectKey(KeyRune, 'a', ModNone)
+	time.Sleep(time.Millisecond * 20)
+	if ev := s.PollEvent(); ev == nil {
+		t.Fatalf("no event")
+	}
+	_ = s.PostEvent(NewEventInterrupt(nil))
+	s.PollEvent()
+
+	st := s.InputStats()
+	if st.Delivered != 1 {
+		t.Errorf("wrong delivered count: %d", st.Delivered)
+	}
+	if st.MaxLatency < time.Millisecond*20 || st.MeanLatency() != st.MaxLatency {
+		t.Errorf("wrong latency: max %v mean %v", st.MaxLatency, st.MeanLatency())
+	}
+}
+
+func TestDefaultColors(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+
+	def := StyleDefault.Foreground(ColorWhite).Background(ColorBlue)
+	s.SetStyle(def)
+	s.SetContent(0, 0, 'A', nil, StyleDefault)
+	s.SetContent(1, 0, 'B', nil, StyleDefault.Foreground(ColorRed))
+	s.SetContent(2, 0, 'C', nil, StyleDefault.Foreground(ColorRed).Background(ColorReset))
+	s.Show()
+
+	cells, _, _ := s.GetContents()
+	want := []Style{
+		def,
+		def.Foreground(ColorRed),
+		StyleDefault.Foreground(ColorRed).Background(ColorReset),
+	}
+	for i, st := range want {
+		if cells[i].Style != st {
+			t.Errorf("cell %d wrong style: %v", i, cells[i].Style)
+		}
+	}
+}
+
+func TestCursorShape(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+
+	for _, shape := range []CursorShape{CursorShapeDefault, CursorShapeBlock, CursorShapeUnderline, CursorShapeBar} {
+		for _, blink := range []bool{false, true} {
+			s.SetCursorShape(shape, blink)
+			_, _, cs, _ := s.(*simscreen).getCursor()
+			if cs.Shape() != shape || cs.Blinking() != blink {
+				t.Errorf("shape %v blink %v: got style %v", shape, blink, cs)
+			}
+		}
+	}
+	if NewCursorStyle(CursorShapeBar, false) != CursorStyleSteadyBar {
+		t.Errorf("wrong style for steady bar")
+	}
+	if CursorStyleDefault.Blinking() || CursorStyleDefault.Shape() != CursorShapeDefault {
+		t.Errorf("default style has a shape or blinks")
+	}
+}
+
+func TestExec(t *testing.T) {
+	if os.Getenv("TCELL_EXEC_HELPER") != "" {
+		os.Stdout.WriteString("hello")
+		os.Exit(3)
+	}
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestExec$")
+	cmd.Env = append(os.Environ(), "TCELL_EXEC_HELPER=1")
+	out := &bytes.Buffer{}
+	cmd.Stdout = out
+	err := s.Exec(cmd)
+	if ee, ok := err.(*exec.ExitError); !ok || ee.ExitCode() != 3 {
+		t.Errorf("wrong error: %v", err)
+	}
+	if out.String() != "hello" {
+		t.Errorf("wrong output: %q", out.String())
+	}
+	if atomic.LoadInt32(&s.(*simscreen).life.state) != stateActive {
+		t.Errorf("screen not resumed")
+	}
+}
+
+func TestFlash(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+	s.SetSize(10, 3)
+	reversed := StyleDefault.Foreground(ColorRed).Reverse(true)
+	s.SetContent(1, 1, 'x', nil, reversed)
+
+	s.Flash(1, 1, 2, 0, time.Hour)
+	cells, w, _ := s.GetContents()
+	if cells[w+1].Style != reversed.Reverse(false) {
+		t.Errorf("Reversed cell not flashed: %v", cells[w+1].Style)
+	}
+	if cells[w+2].Style != StyleDefault.Reverse(true) {
+		t.Errorf("Cell not flashed: %v", cells[w+2].Style)
+	}
+	if cells[2*w+1].Style != StyleDefault.Reverse(true) {
+		t.Errorf("Region not extended to the bottom")
+	}
+	if cells[w+3].Style != StyleDefault || cells[1].Style != StyleDefault {
+		t.Errorf("Cell outside the region flashed")
+	}
+	if _, _, style, _ := s.GetContent(2, 1); style != StyleDefault {
+		t.Errorf("Flash style leaked into cell contents")
+	}
+
+	// drawing meanwhile keeps the flash
+	s.SetContent(2, 1, 'y', nil, StyleDefault)
+	s.Show()
+	cells, _, _ = s.GetContents()
+	if cells[w+2].Style != StyleDefault.Reverse(true) || string(cells[w+2].Runes) != "y" {
+		t.Errorf("Flash lost by drawing")
+	}
+
+	// pretend the time is up
+	b := s.(*simscreen).Screen.(*baseScreen)
+	b.flash.l.Lock()
+	b.flash.regions[0].until = time.Now()
+	b.flash.l.Unlock()
+	s.Show()
+	cells, _, _ = s.GetContents()
+	if cells[w+1].Style != reversed || cells[w+2].Style != StyleDefault {
+		t.Errorf("Flash not ended")
+	}
+}
+
+func TestSimColors(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+
+	s.SetColors(256)
+	s.SetColors(16)
+	ev, ok := s.PollEvent().(*EventColors)
+	if !ok || ev.Colors() != 16 {
+		t.Fatalf("Wrong event: %v", ev)
+	}
+	if n := s.Colors(); n != 16 {
+		t.Errorf("Wrong colors: %d", n)
+	}
+	if s.FeatureReport()[Feature256Color] != SupportNone {
+		t.Errorf("256 colors still reported")
+	}
+}
+
+func TestMinSize(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+
+	s.SetContent(0, 0, 'A', nil, StyleDefault.Bold(true))
+	s.SetMinSize(100, 10)
+	s.Show()
+	cells, w, _ := s.GetContents()
+	if string(cells[0].Runes) != " " {
+		t.Errorf("Contents shown when too small: %q", cells[0].Runes)
+	}
+	row := ""
+	for x := 0; x < w; x++ {
+		row += string(cells[12*w+x].Runes)
+	}
+	if strings.TrimSpace(row) != "80x25, needs 100x10" {
+		t.Errorf("Wrong message: %q", row)
+	}
+	if r, _, st, _ := s.GetContent(0, 0); r != 'A' || st != StyleDefault.Bold(true) {
+		t.Errorf("Contents lost: %q %v", r, st)
+	}
+
+	s.SetMinSize(80, 25)
+	s.Show()
+	cells, _, _ = s.GetContents()
+	if string(cells[0].Runes) != "A" {
+		t.Errorf("Contents not shown again: %q", cells[0].Runes)
+	}
+}
+
+func TestClipboardPolicy(t *testing.T) {
+	s := mkTestScreen(t, "")
+	defer s.Fini()
+
+	clipboard := func() string {
+		s.GetClipboard()
+		if !s.HasPendingEvent() {
+			return "<none>"
+		}
+		ev, ok := s.PollEvent().(*EventClipboard)
+		if !ok {
+			t.Fatalf("Expected clipboard event")
+		}
+		return string(ev.Data())
+	}
+	s.SetClipboard([]byte("one"))
+	if data := clipboard(); data != "one" {
+		t.Errorf("Wrong clipboard: %q", data)
+	}
+
+	s.SetClipboardPolicy(SimClipboardPolicy{DenyWrite: true})
+	s.SetClipboard([]byte("two"))
+	if data := clipboard(); data != "one" {
+		t.Errorf("Write not denied: %q", data)
+	}
+
+	s.SetClipboardPolicy(SimClipboardPolicy{DenyRead: true})
+	s.SetClipboard([]byte("three"))
+	if data := clipboard(); data != "<none>" {
+		t.Errorf("Read not denied: %q", data)
+	}
+	if data := string(s.GetClipboardData()); data != "three" {
+		t.Errorf("Wrong clipboard data: %q", data)
+	}
+
+	s.SetClipboardPolicy(SimClipboardPolicy{MaxBytes: 4})
+	s.SetClipboard([]byte("four"))
+	s.SetClipboard([]byte("too long"))
+	if data := clipboard(); data != "four" {
+		t.Errorf("Wrong clipboard after limit: %q", data)
+	}
+	s.SetClipboardData([]byte("copied elsewhere"))
+	if data := clipboard(); data != "<none>" {
+		t.Errorf("Data over limit reported: %q", data)
+	}
+}
@@ -15,10 +15,13 @@
 package tcell
 
 import (
+	"io"
 	"sync"
 	"unicode/utf8"
 
 	"golang.org/x/text/transform"
+
+	"github.com/gdamore/tcell/v2/runes"
 )
 
 // NewSimulationScreen returns a SimulationScreen.  Note that
@@ -27,7 +30,7 @@ func NewSimulationScreen(charset string) SimulationScreen {
 	if charset == "" {
 		charset = "UTF-8"
 	}
-	ss := &simscreen{charset: charset}
+	ss := &simscreen{charset: charset, colors: 256}
 	ss.Screen = &baseScreen{screenImpl: ss}
 	return ss
 }
@@ -66,6 +69,35 @@ type SimulationScreen interface {
 
 	// GetClipboardData gets the actual data for the clipboard.
 	GetClipboardData() []byte
+
+	// SetClipboardData sets the data on the clipboard, as if copied by
+	// another program, for GetClipboard to report.
+	SetClipboardData(data []byte)
+
+	// SetClipboardPolicy limits what the application can do with the
+	// clipboard, as terminals do, so that tests can check how it copes
+	// when clipboard access is denied.  The default allows everything.
+	SetClipboardPolicy(policy SimClipboardPolicy)
+
+	// SetColors changes the number of colors the screen has (256 at
+	// first), as if the terminal had changed, and posts an EventColors
+	// if it is different.
+	SetColors(colors int)
+}
+
+// SimClipboardPolicy is what the simulation screen lets the application
+// do with the clipboard.  Like most terminals, it ignores requests that
+// are not allowed, so the application gets no response to GetClipboard.
+type SimClipboardPolicy struct {
+	// DenyRead ignores GetClipboard.
+	DenyRead bool
+
+	// DenyWrite ignores SetClipboard.
+	DenyWrite bool
+
+	// MaxBytes, if not zero, is the most data that can be set or
+	// reported.  Larger data is ignored, rather than truncated.
+	MaxBytes int
 }
 
 // SimCell represents a simulated screen cell.  The purpose of this
@@ -106,12 +138,24 @@ type simscreen struct {
 	fallback  map[rune]string
 	title     string
 	clipboard []byte
+	clipPol   SimClipboardPolicy
+	colors    int
+	life      lifecycle
+
+	nofallback map[rune]bool // unregistered, so not from package runes
+
+	cursorStyle CursorStyle
+	cursorColor Color
 
 	Screen
 	sync.Mutex
 }
 
 func (s *simscreen) Init() error {
+	return s.life.init(s.setup)
+}
+
+func (s *simscreen) setup() error {
 	s.evch = make(chan Event, 10)
 	s.quit = make(chan struct{})
 	s.fillchar = 'X'
@@ -121,6 +165,7 @@ func (s *simscreen) Init() error {
 	s.physh = 25
 	s.cursorx = -1
 	s.cursory = -1
+	s.cursorColor = ColorNone
 	s.style = StyleDefault
 
 	if enc := GetEncoding(s.charset); enc != nil {
@@ -138,20 +183,29 @@ func (s *simscreen) Init() error {
 	for k, v := range RuneFallbacks {
 		s.fallback[k] = v
 	}
+	s.nofallback = make(map[rune]bool)
 	return nil
 }
 
 func (s *simscreen) Fini() {
+	s.life.fini(s.finish)
+}
+
+func (s *simscreen) Done() <-chan struct{} {
+	return s.life.doneQ()
+}
+
+func (s *simscreen) finish() {
 	s.Lock()
 	s.fini = true
 	s.back.Resize(0, 0)
+	s.physw = 0
+	s.physh = 0
+	s.front = nil
 	s.Unlock()
 	if s.quit != nil {
 		close(s.quit)
 	}
-	s.physw = 0
-	s.physh = 0
-	s.front = nil
 }
 
 func (s *simscreen) SetStyle(style Style) {
@@ -171,9 +225,7 @@ func (s *simscreen) drawCell(x, y int) int {
 	}
 	simc := &s.front[(y*s.physw)+x]
 
-	if style == StyleDefault {
-		style = s.style
-	}
+	style = style.inherit(s.style)
 	simc.Style = style
 	simc.Runes = append([]rune{mainc}, combc...)
 
@@ -207,6 +259,10 @@ func (s *simscreen) drawCell(x, y int) int {
 				simc.Bytes = append(simc.Bytes,
 					[]byte(subst)...)
 
+			} else if subst := runes.FallbackFor(s.charset, r); subst != "" && !s.nofallback[r] {
+				simc.Bytes = append(simc.Bytes,
+					[]byte(subst)...)
+
 			} else if r >= ' ' && r <= '~' {
 				simc.Bytes = append(simc.Bytes, byte(r))
 
@@ -247,13 +303,29 @@ func (s *simscreen) hideCursor() {
 	s.cursorvis = false
 }
 
-func (s *simscreen) SetCursor(CursorStyle, Color) {}
+func (s *simscreen) SetCursor(cs CursorStyle, cc Color) {
+	s.Lock()
+	s.cursorStyle, s.cursorColor = cs, cc
+	s.Unlock()
+}
+
+func (s *simscreen) getCursor() (int, int, CursorStyle, Color) {
+	return s.cursorx, s.cursory, s.cursorStyle, s.cursorColor
+}
+
+func (s *simscreen) getStyle() Style {
+	return s.style
+}
 
 func (s *simscreen) Show() {
+	restore := drawOverlays(s.Screen)
 	s.Lock()
-	s.resize()
-	s.draw()
+	if !s.fini {
+		s.resize()
+		s.draw()
+	}
 	s.Unlock()
+	restore()
 }
 
 func (s *simscreen) clearScreen() {
@@ -282,6 +354,32 @@ func (s *simscreen) draw() {
 	s.showCursor()
 }
 
+func (s *simscreen) showCells(x, y, width int) {
+	s.Lock()
+	defer s.Unlock()
+	if s.fini {
+		return
+	}
+	w, h := s.back.Size()
+	s.resize()
+	if nw, nh := s.back.Size(); s.clear || nw != w || nh != h {
+		s.draw()
+		return
+	}
+	if y < 0 || y >= h {
+		return
+	}
+	if x < 0 {
+		width += x
+		x = 0
+	}
+	s.hideCursor()
+	for end := x + width; x < end && x < w; x++ {
+		x += s.drawCell(x, y) - 1
+	}
+	s.showCursor()
+}
+
 func (s *simscreen) EnableMouse(...MouseFlags) {
 	s.mouse = true
 }
@@ -322,7 +420,25 @@ func (s *simscreen) resize() {
 }
 
 func (s *simscreen) Colors() int {
-	return 256
+	s.Lock()
+	defer s.Unlock()
+	return s.colors
+}
+
+func (s *simscreen) SetColors(colors int) {
+	s.Lock()
+	changed := colors != s.colors
+	s.colors = colors
+	s.Unlock()
+	if changed {
+		s.postEvent(NewEventColors(colors))
+	}
+}
+
+func (s *simscreen) ProbeColors() {}
+
+func (s *simscreen) Err() error {
+	return nil
 }
 
 func (s *simscreen) postEvent(ev Event) {
@@ -391,12 +507,16 @@ outer:
 }
 
 func (s *simscreen) Sync() {
+	restore := drawOverlays(s.Screen)
 	s.Lock()
-	s.clear = true
-	s.resize()
-	s.back.Invalidate()
-	s.draw()
+	if !s.fini {
+		s.clear = true
+		s.resize()
+		s.back.Invalidate()
+		s.draw()
+	}
 	s.Unlock()
+	restore()
 }
 
 func (s *simscreen) CharacterSet() string {
@@ -435,12 +555,14 @@ func (s *simscreen) GetCursor() (int, int, bool) {
 func (s *simscreen) RegisterRuneFallback(r rune, subst string) {
 	s.Lock()
 	s.fallback[r] = subst
+	delete(s.nofallback, r)
 	s.Unlock()
 }
 
 func (s *simscreen) UnregisterRuneFallback(r rune) {
 	s.Lock()
 	delete(s.fallback, r)
+	s.nofallback[r] = true
 	s.Unlock()
 }
 
@@ -463,7 +585,7 @@ func (s *simscreen) CanDisplay(r rune, checkFallbacks bool) bool {
 	if _, ok := s.fallback[r]; ok {
 		return true
 	}
-	return false
+	return runes.FallbackFor(s.charset, r) != "" && !s.nofallback[r]
 }
 
 func (s *simscreen) HasMouse() bool {
@@ -481,11 +603,11 @@ func (s *simscreen) Beep() error {
 }
 
 func (s *simscreen) Suspend() error {
-	return nil
+	return s.life.suspend(func() error { return nil })
 }
 
 func (s *simscreen) Resume() error {
-	return nil
+	return s.life.resume(func() error { return nil })
 }
 
 func (s *simscreen) Tty() (Tty, bool) {
@@ -508,21 +630,74 @@ func (s *simscreen) SetTitle(title string) {
 	s.title = title
 }
 
+func (s *simscreen) SetTabColor(Color) {}
+
+func (s *simscreen) SetBadge(string) {}
+
 func (s *simscreen) GetTitle() string {
 	return s.title
 }
 
 func (s *simscreen) SetClipboard(data []byte) {
+	s.Lock()
+	defer s.Unlock()
+	pol := s.clipPol
+	if pol.DenyWrite || (pol.MaxBytes > 0 && len(data) > pol.MaxBytes) {
+		return
+	}
 	s.clipboard = data
 }
 
 func (s *simscreen) GetClipboard() {
-	if s.clipboard != nil {
-		ev := NewEventClipboard(s.clipboard)
-		s.postEvent(ev)
+	s.Lock()
+	data, pol := s.clipboard, s.clipPol
+	s.Unlock()
+	if data == nil || pol.DenyRead || (pol.MaxBytes > 0 && len(data) > pol.MaxBytes) {
+		return
 	}
+	ev := NewEventClipboard(data)
+	s.postEvent(ev)
+}
+
+func (s *simscreen) SetClipboardData(data []byte) {
+	s.Lock()
+	s.clipboard = data
+	s.Unlock()
+}
+
+func (s *simscreen) SetClipboardPolicy(policy SimClipboardPolicy) {
+	s.Lock()
+	s.clipPol = policy
+	s.Unlock()
+}
+
+func (s *simscreen) SendOSC(int, string) {}
+
+func (s *simscreen) SetRestoreWriter(io.Writer) {}
+
+func (s *simscreen) SetDisplayOptions(DisplayOptions) {}
+
+func (s *simscreen) SetInputOptions(InputOptions) {}
+
+func (s *simscreen) AddSemanticMark(int, int, SemanticMark, int) {}
+
+func (s *simscreen) TerminalID() TerminalIdentity {
+	return TerminalIdentity{}
+}
+
+func (s *simscreen) FeatureReport() map[Feature]Support {
+	report := make(map[Feature]Support)
+	colorSupport(report, s.Colors())
+	report[FeatureMouse] = SupportAssumed
+	report[FeatureBracketedPaste] = SupportAssumed
+	report[FeatureFocus] = SupportAssumed
+	report[FeatureClipboard] = SupportAssumed
+	report[FeatureTitle] = SupportAssumed
+	return report
 }
 
 func (s *simscreen) GetClipboardData() []byte {
+	s.Lock()
+	defer s.Unlock()
 	return s.clipboard
 }
@@ -0,0 +1,95 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !(js && wasm)
+// +build !js !wasm
+
+package tcell
+
+import (
+	"bytes"
+)
+
+// defaultMaxString is the default for InputOptions.MaxString.  It is the
+// same as the limit for all pending input, so that large clipboard
+// contents can still be received.
+const defaultMaxString = maxInputLength
+
+// states of tScreen.skipping
+const (
+	skipNone = iota // not skipping
+	skipBody        // skipping the body of a string that is too long
+	skipEsc         // as skipBody, just after an ESC
+)
+
+// skipString discards strings (OSC, DCS and APC sequences) from the
+// terminal that are longer than InputOptions.MaxString.  Once the
+// start of one is found to be too long, the rest of it is discarded as
+// it arrives, until the BEL or ST that ends it, rather than being kept
+// waiting for the end.  It returns true if the input should be looked
+// at again.
+func (t *tScreen) skipString(buf *bytes.Buffer, evs *[]Event) bool {
+	b := buf.Bytes()
+	if t.skipping == skipNone {
+		if len(b) < 2 || b[0] != '\x1b' || (b[1] != ']' && b[1] != 'P' && b[1] != '_') {
+			return false
+		}
+		max := t.inputOpts.MaxString
+		if max <= 0 {
+			max = defaultMaxString
+		}
+		if len(b)-2 <= max {
+			return false
+		}
+		// any ESC ends the string (or at least starts the ST), so
+		// it is left to the other parsers
+		if bytes.IndexByte(b[2:max+3], '\x1b') >= 0 || bytes.IndexByte(b[2:max+3], '\a') >= 0 {
+			return false
+		}
+		logDebug("input string too long", "limit", max)
+		*evs = append(*evs, NewEventError(ErrStringTooLong))
+		t.skipping = skipBody
+		buf.Next(max + 2)
+		return true
+	}
+
+	for i, c := range b {
+		switch {
+		case t.skipping == skipEsc:
+			// ESC \ is ST, anything else is the start of the next
+			// sequence, after the string was cancelled
+			t.skipping = skipNone
+			if c == '\\' {
+				buf.Next(i + 1)
+			} else if i > 0 {
+				buf.Next(i - 1) // keep the ESC
+			} else {
+				// the ESC was at the end of earlier input, so put
+				// it back, for the sequence it starts
+				rest := append([]byte{'\x1b'}, b...)
+				buf.Reset()
+				buf.Write(rest)
+			}
+			return true
+		case c == '\a':
+			t.skipping = skipNone
+			buf.Next(i + 1)
+			return true
+		case c == '\x1b':
+			t.skipping = skipEsc
+		}
+	}
+	buf.Next(len(b))
+	return true
+}
@@ -14,6 +14,13 @@
 
 package tcell
 
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
 // Style represents a complete text style, including both foreground color,
 // background color, and additional attributes such as "bold" or "underline".
 //
@@ -39,6 +46,26 @@ var StyleDefault Style
 // styleInvalid is just an arbitrary invalid style used internally.
 var styleInvalid = Style{attrs: AttrInvalid}
 
+// inherit returns the style to draw s with on a screen whose default
+// style is def.  StyleDefault is replaced by def, and otherwise colors that
+// are ColorDefault are taken from def.  ColorReset is left alone, since it
+// asks for the terminal's own default color.
+func (s Style) inherit(def Style) Style {
+	if s == StyleDefault {
+		return def
+	}
+	if s.fg == ColorDefault {
+		s.fg = def.fg
+	}
+	if s.bg == ColorDefault {
+		s.bg = def.bg
+	}
+	if s.ulColor == ColorDefault {
+		s.ulColor = def.ulColor
+	}
+	return s
+}
+
 // Foreground returns a new style based on s, with the foreground color set
 // as requested.  ColorDefault can be used to select the global default.
 func (s Style) Foreground(c Color) Style {
@@ -117,6 +144,14 @@ func (s Style) StrikeThrough(on bool) Style {
 	return s.setAttrs(AttrStrikeThrough, on)
 }
 
+// Transparent returns a new style based on s, with the transparent
+// background attribute set as requested.  The background color of such
+// cells is never painted, so that the terminal's own background, which
+// may be an image or translucent, shows through instead.
+func (s Style) Transparent(on bool) Style {
+	return s.setAttrs(AttrTransparent, on)
+}
+
 // Underline style.  Modern terminals have the option of rendering the
 // underline using different styles, and even different colors.
 type UnderlineStyle int
@@ -181,6 +216,16 @@ func (s Style) Url(url string) Style {
 	return s2
 }
 
+// autoUrlId returns an id parameter for a Url that has no explicit id.
+// The id is derived from the Url itself, so that it is stable, and so that
+// all cells linking to the same Url are treated as one link by terminals
+// that highlight links on hover, even if they span multiple lines.
+func autoUrlId(url string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(url))
+	return fmt.Sprintf("id=tcell-%x", h.Sum64())
+}
+
 // UrlId returns a style with the UrlId set. If the provided UrlId is not empty,
 // any marked up Url with this style will be given the UrlId also. If the
 // terminal supports it, any text with the same UrlId will be grouped as if it
@@ -190,3 +235,152 @@ func (s Style) UrlId(id string) Style {
 	s2.urlId = "id=" + id
 	return s2
 }
+
+// styleAttrNames are the names of attributes used by ParseStyle and
+// FormatStyle, in the order that they are written.
+var styleAttrNames = []struct {
+	attr AttrMask
+	name string
+}{
+	{AttrBold, "bold"},
+	{AttrDim, "dim"},
+	{AttrItalic, "italic"},
+	{AttrBlink, "blink"},
+	{AttrReverse, "reverse"},
+	{AttrStrikeThrough, "strikethrough"},
+	{AttrTransparent, "transparent"},
+}
+
+var underlineStyleNames = []string{
+	UnderlineStyleSolid:  "solid",
+	UnderlineStyleDouble: "double",
+	UnderlineStyleCurly:  "curly",
+	UnderlineStyleDotted: "dotted",
+	UnderlineStyleDashed: "dashed",
+}
+
+// parseStyleColor parses a color for ParseStyle.  In addition to the
+// names and forms understood by GetColor, palette colors can be given
+// as colorN (for example color208).
+func parseStyleColor(name string) (Color, bool) {
+	name = strings.ToLower(name)
+	switch name {
+	case "default":
+		return ColorDefault, true
+	case "reset":
+		return ColorReset, true
+	case "none":
+		return ColorNone, true
+	}
+	if strings.HasPrefix(name, "color") {
+		if n, err := strconv.Atoi(name[5:]); err == nil && n >= 0 && n < 256 {
+			return PaletteColor(n), true
+		}
+	}
+	if c := GetColor(name); c != ColorDefault {
+		return c, true
+	}
+	return ColorDefault, false
+}
+
+// formatStyleColor is the inverse of parseStyleColor.
+func formatStyleColor(c Color) string {
+	if c.Valid() && !c.IsRGB() && c.Name() == "" {
+		return fmt.Sprintf("color%d", c&^ColorValid)
+	}
+	return c.String()
+}
+
+// ParseStyle parses a description of a style, as found in a theme or
+// configuration file.  The description is a list of words separated by
+// spaces, each of which is one of:
+//
+//   - an attribute: bold, dim, italic, blink, reverse, or strikethrough
+//   - underline, or underline:style where the style is one of solid,
+//     double, curly, dotted, or dashed
+//   - fg:color, bg:color, or ul:color, for the foreground, background, and
+//     underline colors
+//   - url:url, for a hyperlink
+//
+// Colors may be any of the names in ColorNames, "#rrggbb", "colorN" for
+// entry N of the palette, or "default".  Names are not case sensitive.
+// For example "bold underline fg:#ff8800 bg:navy".  The result is based
+// on StyleDefault, and an error is returned for words that are not
+// understood.  FormatStyle does the reverse.
+func ParseStyle(spec string) (Style, error) {
+	s := StyleDefault
+	for _, word := range strings.Fields(spec) {
+		key, val := word, ""
+		if i := strings.IndexByte(word, ':'); i >= 0 {
+			key, val = word[:i], word[i+1:]
+		}
+		key = strings.ToLower(key)
+		ok := false
+		switch key {
+		case "fg", "bg", "ul":
+			var c Color
+			if c, ok = parseStyleColor(val); ok {
+				switch key {
+				case "fg":
+					s = s.Foreground(c)
+				case "bg":
+					s = s.Background(c)
+				default:
+					s = s.Underline(c)
+				}
+			}
+		case "url":
+			s, ok = s.Url(val), val != ""
+		case "underline":
+			if val == "" {
+				s, ok = s.Underline(UnderlineStyleSolid), true
+				break
+			}
+			for i, name := range underlineStyleNames {
+				if i != 0 && strings.EqualFold(name, val) {
+					s, ok = s.Underline(UnderlineStyle(i)), true
+				}
+			}
+		default:
+			for _, an := range styleAttrNames {
+				if key == an.name && val == "" {
+					s, ok = s.setAttrs(an.attr, true), true
+				}
+			}
+		}
+		if !ok {
+			return StyleDefault, fmt.Errorf("bad style word %q", word)
+		}
+	}
+	return s, nil
+}
+
+// FormatStyle returns a description of the style in the form understood
+// by ParseStyle.  The URL id, if any, is not included.
+func FormatStyle(s Style) string {
+	var words []string
+	for _, an := range styleAttrNames {
+		if s.attrs&an.attr != 0 {
+			words = append(words, an.name)
+		}
+	}
+	switch {
+	case s.ulStyle > UnderlineStyleSolid && int(s.ulStyle) < len(underlineStyleNames):
+		words = append(words, "underline:"+underlineStyleNames[s.ulStyle])
+	case s.ulStyle != UnderlineStyleNone || s.attrs&AttrUnderline != 0:
+		words = append(words, "underline")
+	}
+	if s.fg != ColorDefault {
+		words = append(words, "fg:"+formatStyleColor(s.fg))
+	}
+	if s.bg != ColorDefault {
+		words = append(words, "bg:"+formatStyleColor(s.bg))
+	}
+	if s.ulColor != ColorDefault {
+		words = append(words, "ul:"+formatStyleColor(s.ulColor))
+	}
+	if s.url != "" {
+		words = append(words, "url:"+s.url)
+	}
+	return strings.Join(words, " ")
+}
@@ -39,3 +39,76 @@ func TestStyle(t *testing.T) {
 		t.Errorf("Bad custom style (%v, %v, %v)", fg, bg, attr)
 	}
 }
+
+func TestAutoUrlId(t *testing.T) {
+	a := autoUrlId("https://example.com/")
+	b := autoUrlId("https://example.com/")
+	c := autoUrlId("https://example.org/")
+	if a != b {
+		t.Errorf("Url ids not stable: %q != %q", a, b)
+	}
+	if a == c {
+		t.Errorf("Url ids not distinct: %q", a)
+	}
+}
+
+func TestParseStyle(t *testing.T) {
+	s, err := ParseStyle("bold Underline:curly fg:#ff8800 bg:Navy ul:color208")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	want := StyleDefault.Bold(true).
+		Underline(UnderlineStyleCurly, PaletteColor(208)).
+		Foreground(NewHexColor(0xff8800)).
+		Background(ColorNavy)
+	if s != want {
+		t.Errorf("Wrong style: %q", FormatStyle(s))
+	}
+
+	for _, spec := range []string{
+		"",
+		"bold italic",
+		"underline fg:red",
+		"reverse underline:dashed bg:color100",
+		"dim strikethrough bg:#123456 url:https://example.com/",
+		"transparent bg:black",
+	} {
+		s, err := ParseStyle(spec)
+		if err != nil {
+			t.Errorf("%q: %v", spec, err)
+			continue
+		}
+		if got := FormatStyle(s); got != spec {
+			t.Errorf("%q: formatted as %q", spec, got)
+		}
+	}
+
+	for _, spec := range []string{"heavy", "fg:nocolor", "underline:wavy", "bold:yes", "url:"} {
+		if _, err := ParseStyle(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestConsoleVtUnderline(t *testing.T) {
+	cases := []struct {
+		us   UnderlineStyle
+		uc   Color
+		want string
+	}{
+		{UnderlineStyleNone, ColorRed, ""},
+		{UnderlineStyleSolid, ColorDefault, "\x1b[4m"},
+		{UnderlineStyleDouble, ColorDefault, "\x1b[4m\x1b[21m"},
+		{UnderlineStyleCurly, ColorDefault, "\x1b[4m\x1b[4:3m"},
+		{UnderlineStyleDotted, ColorDefault, "\x1b[4m\x1b[4:4m"},
+		{UnderlineStyleDashed, ColorDefault, "\x1b[4m\x1b[4:5m"},
+		{UnderlineStyleCurly, ColorRed, "\x1b[58;5;9m\x1b[4m\x1b[4:3m"},
+		{UnderlineStyleSolid, NewRGBColor(1, 2, 3), "\x1b[58;2;1;2;3m\x1b[4m"},
+		{UnderlineStyleSolid, ColorReset, "\x1b[59m\x1b[4m"},
+	}
+	for _, c := range cases {
+		if s := vtUnderlineString(c.us, c.uc); s != c.want {
+			t.Errorf("underline %d color %v: got %q, want %q", c.us, c.uc, s, c.want)
+		}
+	}
+}
@@ -0,0 +1,445 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package telnet adapts a telnet connection into a tcell.Tty, so that
+// tcell applications can be served to remote users over the network, in
+// the style of classic BBS services.  The server side of the telnet
+// negotiation is handled here: we offer to echo and to suppress go-ahead
+// (so that the client enters character-at-a-time mode), and we ask the
+// client to report its window size (NAWS) and terminal type (TTYPE).
+package telnet
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Telnet command bytes.  See RFC 854.
+const (
+	cmdSE   = 240
+	cmdSB   = 250
+	cmdWILL = 251
+	cmdWONT = 252
+	cmdDO   = 253
+	cmdDONT = 254
+	cmdIAC  = 255
+)
+
+// Telnet options that we negotiate.
+const (
+	optBinary = 0  // RFC 856
+	optEcho   = 1  // RFC 857
+	optSGA    = 3  // RFC 858
+	optTType  = 24 // RFC 1091
+	optNAWS   = 31 // RFC 1073
+)
+
+// Terminal type subnegotiation codes.
+const (
+	ttypeIs   = 0
+	ttypeSend = 1
+)
+
+// input parser states
+const (
+	stData = iota
+	stCR
+	stIAC
+	stOpt
+	stSB
+	stSBIAC
+	stSBSkip    // discarding a subnegotiation that is too long
+	stSBSkipIAC // as stSBSkip, after an IAC
+)
+
+// maxSB is the longest subnegotiation we keep.  The ones we understand
+// (NAWS and TTYPE) are much shorter; longer ones are discarded, so that a
+// client cannot use memory without limit.
+const maxSB = 64
+
+// Tty is an implementation of tcell.Tty backed by a telnet connection.
+// It is safe to use from multiple goroutines, although only a single
+// reader is supported (which is how tcell uses it).
+type Tty struct {
+	conn     net.Conn
+	cb       func()
+	ws       tcell.WindowSize
+	term     string
+	assumed  string // term used in place of an unknown one
+	offered  bool
+	gotNAWS  bool
+	gotTType bool
+	pending  []byte
+	state    int
+	cmd      byte
+	sb       []byte
+	wl       sync.Mutex // serializes writes
+	l        sync.Mutex
+}
+
+// NewTty returns a Tty that uses the supplied connection.  The connection
+// is owned by the Tty, and will be closed when the Tty is closed.
+func NewTty(conn net.Conn) *Tty {
+	return &Tty{conn: conn}
+}
+
+// NewScreen negotiates with the remote client, and then returns a Screen
+// suitable for use with it.  The terminal type reported by the client is
+// used to select a terminfo entry; if the client does not report one,
+// or we don't know about it, then "xterm" is assumed.  The screen must
+// still be initialized with Init.
+func NewScreen(conn net.Conn) (tcell.Screen, error) {
+	tty := NewTty(conn)
+	if err := tty.Negotiate(time.Second); err != nil {
+		return nil, err
+	}
+	s, err := tcell.NewTerminfoScreenFromTty(tty)
+	if err != nil {
+		tty.l.Lock()
+		tty.assumed = "xterm"
+		tty.l.Unlock()
+		s, err = tcell.NewTerminfoScreenFromTty(tty)
+	}
+	return s, err
+}
+
+// Negotiate sends our option requests to the client, and waits up to the
+// given timeout for the client to report its window size and terminal type.
+// It is not necessary to call this, as Start will send the same requests,
+// but doing so allows the terminal type to be known before the screen is
+// created.  Any ordinary input received while waiting is retained, and will
+// be returned by subsequent calls to Read.
+func (t *Tty) Negotiate(timeout time.Duration) error {
+	if err := t.offer(); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	buf := make([]byte, 128)
+	for {
+		t.l.Lock()
+		done := t.gotNAWS && t.gotTType
+		t.l.Unlock()
+		if done || !time.Now().Before(deadline) {
+			break
+		}
+		_ = t.conn.SetReadDeadline(deadline)
+		n, err := t.conn.Read(buf)
+		if n > 0 {
+			data := t.parse(buf[:n])
+			t.l.Lock()
+			t.pending = append(t.pending, data...)
+			t.l.Unlock()
+		}
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				break
+			}
+			return err
+		}
+	}
+	_ = t.conn.SetReadDeadline(time.Time{})
+	return nil
+}
+
+// Term returns the terminal type reported by the client, converted to
+// lower case.  It will be empty if the client has not reported one.
+func (t *Tty) Term() string {
+	t.l.Lock()
+	defer t.l.Unlock()
+	return t.term
+}
+
+// Getenv implements tcell.TtyEnviron, so that the screen describes the
+// client's terminal rather than that of the server process.  Only TERM is
+// known, from the terminal type reported by the client.
+func (t *Tty) Getenv(name string) string {
+	if name != "TERM" {
+		return ""
+	}
+	t.l.Lock()
+	defer t.l.Unlock()
+	if t.assumed != "" {
+		return t.assumed
+	}
+	return t.term
+}
+
+// Start implements tcell.Tty.  The first time it is called, it sends the
+// option negotiation requests to the client.
+func (t *Tty) Start() error {
+	_ = t.conn.SetReadDeadline(time.Time{})
+	return t.offer()
+}
+
+// Drain implements tcell.Tty, arranging for a blocked Read to return.
+func (t *Tty) Drain() error {
+	return t.conn.SetReadDeadline(time.Now())
+}
+
+// Stop implements tcell.Tty.  There is no local terminal state to restore.
+func (t *Tty) Stop() error {
+	return nil
+}
+
+// Close closes the underlying connection.
+func (t *Tty) Close() error {
+	return t.conn.Close()
+}
+
+// NotifyResize implements tcell.Tty.  The callback is executed whenever the
+// client reports a new window size.
+func (t *Tty) NotifyResize(cb func()) {
+	t.l.Lock()
+	t.cb = cb
+	t.l.Unlock()
+}
+
+// WindowSize implements tcell.Tty, returning the size most recently reported
+// by the client.  If the client has not reported a size, 80x25 is assumed.
+func (t *Tty) WindowSize() (tcell.WindowSize, error) {
+	t.l.Lock()
+	ws := t.ws
+	t.l.Unlock()
+	if ws.Width == 0 {
+		ws.Width = 80
+	}
+	if ws.Height == 0 {
+		ws.Height = 25
+	}
+	return ws, nil
+}
+
+// Write sends data to the client, escaping any IAC bytes.
+func (t *Tty) Write(b []byte) (int, error) {
+	esc := b
+	if bytes.IndexByte(b, cmdIAC) >= 0 {
+		esc = bytes.ReplaceAll(b, []byte{cmdIAC}, []byte{cmdIAC, cmdIAC})
+	}
+	if err := t.send(esc); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Read returns data received from the client, with telnet protocol
+// commands removed.  It blocks until at least some data is available.
+func (t *Tty) Read(b []byte) (int, error) {
+	t.l.Lock()
+	if len(t.pending) > 0 {
+		n := copy(b, t.pending)
+		t.pending = t.pending[n:]
+		t.l.Unlock()
+		return n, nil
+	}
+	t.l.Unlock()
+
+	buf := make([]byte, len(b))
+	for {
+		n, err := t.conn.Read(buf)
+		if n > 0 {
+			data := t.parse(buf[:n])
+			if len(data) > 0 {
+				// data can never be longer than the raw input
+				return copy(b, data), nil
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (t *Tty) send(b []byte) error {
+	t.wl.Lock()
+	defer t.wl.Unlock()
+	_, err := t.conn.Write(b)
+	return err
+}
+
+func (t *Tty) offer() error {
+	t.l.Lock()
+	if t.offered {
+		t.l.Unlock()
+		return nil
+	}
+	t.offered = true
+	t.l.Unlock()
+	return t.send([]byte{
+		cmdIAC, cmdWILL, optEcho,
+		cmdIAC, cmdWILL, optSGA,
+		cmdIAC, cmdDO, optSGA,
+		cmdIAC, cmdWILL, optBinary,
+		cmdIAC, cmdDO, optBinary,
+		cmdIAC, cmdDO, optNAWS,
+		cmdIAC, cmdDO, optTType,
+	})
+}
+
+// parse runs the telnet state machine over the input, handling
+// any commands, and returning the residual application data.
+func (t *Tty) parse(in []byte) []byte {
+	out := make([]byte, 0, len(in))
+	for _, c := range in {
+		switch t.state {
+		case stData:
+			switch c {
+			case cmdIAC:
+				t.state = stIAC
+			case '\r':
+				out = append(out, c)
+				t.state = stCR
+			default:
+				out = append(out, c)
+			}
+		case stCR:
+			// Network virtual terminals send CR as CR NUL, and
+			// end of line as CR LF.  Applications want just the CR.
+			t.state = stData
+			switch c {
+			case 0, '\n':
+			case cmdIAC:
+				t.state = stIAC
+			case '\r':
+				out = append(out, c)
+				t.state = stCR
+			default:
+				out = append(out, c)
+			}
+		case stIAC:
+			switch c {
+			case cmdIAC:
+				out = append(out, c)
+				t.state = stData
+			case cmdWILL, cmdWONT, cmdDO, cmdDONT:
+				t.cmd = c
+				t.state = stOpt
+			case cmdSB:
+				t.sb = t.sb[:0]
+				t.state = stSB
+			default:
+				// NOP, GA, and friends
+				t.state = stData
+			}
+		case stOpt:
+			t.option(t.cmd, c)
+			t.state = stData
+		case stSB:
+			if c == cmdIAC {
+				t.state = stSBIAC
+			} else if len(t.sb) < maxSB {
+				t.sb = append(t.sb, c)
+			} else {
+				t.state = stSBSkip
+			}
+		case stSBIAC:
+			switch c {
+			case cmdIAC:
+				t.sb = append(t.sb, c)
+				t.state = stSB
+				if len(t.sb) > maxSB {
+					t.state = stSBSkip
+				}
+			case cmdSE:
+				t.subneg(t.sb)
+				t.state = stData
+			default:
+				// protocol violation, abandon the subnegotiation
+				t.state = stData
+			}
+		case stSBSkip:
+			if c == cmdIAC {
+				t.state = stSBSkipIAC
+			}
+		case stSBSkipIAC:
+			switch c {
+			case cmdIAC:
+				t.state = stSBSkip
+			default:
+				// SE, or a protocol violation, ends it
+				t.state = stData
+			}
+		}
+	}
+	return out
+}
+
+func (t *Tty) option(cmd byte, opt byte) {
+	switch cmd {
+	case cmdDO:
+		switch opt {
+		case optEcho, optSGA, optBinary:
+			// acknowledging our offer
+		default:
+			_ = t.send([]byte{cmdIAC, cmdWONT, opt})
+		}
+	case cmdWILL:
+		switch opt {
+		case optSGA, optBinary, optNAWS:
+			// acknowledging our request
+		case optTType:
+			_ = t.send([]byte{cmdIAC, cmdSB, optTType, ttypeSend, cmdIAC, cmdSE})
+		default:
+			_ = t.send([]byte{cmdIAC, cmdDONT, opt})
+		}
+	case cmdWONT:
+		// The client refuses; stop waiting for it.
+		t.l.Lock()
+		switch opt {
+		case optNAWS:
+			t.gotNAWS = true
+		case optTType:
+			t.gotTType = true
+		}
+		t.l.Unlock()
+	}
+}
+
+func (t *Tty) subneg(sb []byte) {
+	if len(sb) == 0 {
+		return
+	}
+	switch sb[0] {
+	case optNAWS:
+		if len(sb) < 5 {
+			return
+		}
+		w := int(sb[1])<<8 | int(sb[2])
+		h := int(sb[3])<<8 | int(sb[4])
+		t.l.Lock()
+		t.gotNAWS = true
+		changed := w != t.ws.Width || h != t.ws.Height
+		t.ws.Width = w
+		t.ws.Height = h
+		cb := t.cb
+		t.l.Unlock()
+		if changed && cb != nil {
+			cb()
+		}
+	case optTType:
+		if len(sb) < 2 || sb[1] != ttypeIs {
+			return
+		}
+		t.l.Lock()
+		t.gotTType = true
+		t.term = strings.ToLower(string(sb[2:]))
+		t.l.Unlock()
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telnet
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// client simulates a telnet client that agrees to everything.
+func client(c net.Conn, w, h int, term string) {
+	buf := make([]byte, 256)
+	for {
+		n, err := c.Read(buf)
+		if err != nil {
+			return
+		}
+		b := buf[:n]
+		if bytes.Contains(b, []byte{cmdIAC, cmdDO, optNAWS}) {
+			_, _ = c.Write([]byte{cmdIAC, cmdWILL, optNAWS,
+				cmdIAC, cmdSB, optNAWS, byte(w >> 8), byte(w), byte(h >> 8), byte(h), cmdIAC, cmdSE})
+		}
+		if bytes.Contains(b, []byte{cmdIAC, cmdDO, optTType}) {
+			_, _ = c.Write([]byte{cmdIAC, cmdWILL, optTType})
+		}
+		if bytes.Contains(b, []byte{cmdIAC, cmdSB, optTType, ttypeSend}) {
+			msg := []byte{cmdIAC, cmdSB, optTType, ttypeIs}
+			msg = append(msg, []byte(term)...)
+			msg = append(msg, cmdIAC, cmdSE)
+			_, _ = c.Write(msg)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	srv, cli := net.Pipe()
+	defer cli.Close()
+	go client(cli, 132, 43, "XTERM-256COLOR")
+
+	tty := NewTty(srv)
+	defer tty.Close()
+	if err := tty.Negotiate(time.Second); err != nil {
+		t.Fatalf("negotiate failed: %v", err)
+	}
+	if tty.Term() != "xterm-256color" {
+		t.Errorf("wrong term: %q", tty.Term())
+	}
+	ws, err := tty.WindowSize()
+	if err != nil {
+		t.Fatalf("window size failed: %v", err)
+	}
+	if ws.Width != 132 || ws.Height != 43 {
+		t.Errorf("wrong size: %d x %d", ws.Width, ws.Height)
+	}
+}
+
+func TestReadFiltering(t *testing.T) {
+	srv, cli := net.Pipe()
+	defer cli.Close()
+	tty := NewTty(srv)
+	defer tty.Close()
+
+	resized := make(chan struct{}, 1)
+	tty.NotifyResize(func() { resized <- struct{}{} })
+
+	go func() {
+		_, _ = cli.Write([]byte{'a', cmdIAC, cmdIAC, '\r', 0, 'b', '\r', '\n',
+			cmdIAC, cmdSB, optNAWS, 0, 100, 0, 50, cmdIAC, cmdSE, 'c'})
+	}()
+
+	var got []byte
+	buf := make([]byte, 64)
+	for len(got) < 6 {
+		n, err := tty.Read(buf)
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		got = append(got, buf[:n]...)
+	}
+	if want := []byte{'a', cmdIAC, '\r', 'b', '\r', 'c'}; !bytes.Equal(got, want) {
+		t.Errorf("wrong data: %v", got)
+	}
+	select {
+	case <-resized:
+	case <-time.After(time.Second):
+		t.Errorf("no resize notification")
+	}
+	if ws, _ := tty.WindowSize(); ws.Width != 100 || ws.Height != 50 {
+		t.Errorf("wrong size: %d x %d", ws.Width, ws.Height)
+	}
+}
+
+func TestLongSubnegotiation(t *testing.T) {
+	tty := &Tty{}
+	in := []byte{'a', cmdIAC, cmdSB, optTType, ttypeIs}
+	// the escaped IAC is still data to be discarded
+	in = append(in, bytes.Repeat([]byte{'x', cmdIAC, cmdIAC}, 1000)...)
+	in = append(in, cmdIAC, cmdSE, 'b')
+	if out := tty.parse(in); !bytes.Equal(out, []byte("ab")) {
+		t.Errorf("wrong data: %q", out)
+	}
+	if len(tty.sb) > maxSB || cap(tty.sb) > 2*maxSB {
+		t.Errorf("subnegotiation kept: %d bytes", len(tty.sb))
+	}
+	if tty.Term() != "" {
+		t.Errorf("long terminal type used: %q", tty.Term())
+	}
+}
+
+func TestWriteEscaping(t *testing.T) {
+	srv, cli := net.Pipe()
+	defer cli.Close()
+	tty := NewTty(srv)
+	defer tty.Close()
+
+	go func() {
+		_, _ = tty.Write([]byte{'x', cmdIAC, 'y'})
+		_ = srv.Close()
+	}()
+	got, _ := io.ReadAll(cli)
+	if want := []byte{'x', cmdIAC, cmdIAC, 'y'}; !bytes.Equal(got, want) {
+		t.Errorf("wrong data: %v", got)
+	}
+}
+
+func TestMultipleScreens(t *testing.T) {
+	type conn struct {
+		srv, cli net.Conn
+		w, h     int
+	}
+	conns := []conn{{w: 100, h: 30}, {w: 60, h: 20}}
+	for i := range conns {
+		conns[i].srv, conns[i].cli = net.Pipe()
+		defer conns[i].cli.Close()
+		go client(conns[i].cli, conns[i].w, conns[i].h, "xterm")
+	}
+
+	var screens []tcell.Screen
+	for _, c := range conns {
+		s, err := NewScreen(c.srv)
+		if err != nil {
+			t.Fatalf("screen failed: %v", err)
+		}
+		if err = s.Init(); err != nil {
+			t.Fatalf("init failed: %v", err)
+		}
+		screens = append(screens, s)
+	}
+	defer screens[1].Fini()
+
+	for i, s := range screens {
+		if w, h := s.Size(); w != conns[i].w || h != conns[i].h {
+			t.Errorf("screen %d wrong size: %d x %d", i, w, h)
+		}
+		if s.Colors() != 8 {
+			t.Errorf("screen %d wrong colors: %d", i, s.Colors())
+		}
+	}
+
+	screens[0].Fini()
+	if err := screens[1].PostEvent(tcell.NewEventInterrupt(nil)); err != nil {
+		t.Fatalf("post failed: %v", err)
+	}
+	for {
+		switch screens[1].PollEvent().(type) {
+		case *tcell.EventInterrupt:
+			return
+		case nil:
+			t.Fatalf("screen stopped by Fini of another")
+		}
+	}
+}
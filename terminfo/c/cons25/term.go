@@ -0,0 +1,103 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This terminal definition is hand-coded, as it is frequently missing from
+// the terminfo database of systems other than FreeBSD (for example when
+// logged in remotely), and the ANSI fallback gets the keys wrong.  This is
+// the syscons console of FreeBSD (before vt(4)) and DragonFly BSD, which
+// uses SCO style function keys, and has no DEC private modes.  Shifted
+// function keys are F13 to F24, except for Shift-F2, which sends the same
+// sequence as Shift-Tab, and control function keys are F25 to F36.
+
+package cons25
+
+import "github.com/gdamore/tcell/v2/terminfo"
+
+func init() {
+
+	// FreeBSD console (25-line ansi mode)
+	terminfo.AddTerminfo(&terminfo.Terminfo{
+		Name:         "cons25",
+		Aliases:      []string{"ansis", "cons25w"},
+		Columns:      80,
+		Lines:        25,
+		Colors:       8,
+		Bell:         "\a",
+		Clear:        "\x1b[H\x1b[J",
+		ShowCursor:   "\x1b[=0C",
+		HideCursor:   "\x1b[=1C",
+		AttrOff:      "\x1b[m",
+		Underline:    "\x1b[4m",
+		Bold:         "\x1b[1m",
+		Blink:        "\x1b[5m",
+		Reverse:      "\x1b[7m",
+		SetFg:        "\x1b[3%p1%dm",
+		SetBg:        "\x1b[4%p1%dm",
+		SetFgBg:      "\x1b[3%p1%d;4%p2%dm",
+		ResetFgBg:    "\x1b[x",
+		PadChar:      "\x00",
+		SetCursor:    "\x1b[%i%p1%d;%p2%dH",
+		CursorBack1:  "\b",
+		CursorUp1:    "\x1b[A",
+		KeyUp:        "\x1b[A",
+		KeyDown:      "\x1b[B",
+		KeyRight:     "\x1b[C",
+		KeyLeft:      "\x1b[D",
+		KeyInsert:    "\x1b[L",
+		KeyDelete:    "\x7f",
+		KeyBackspace: "\b",
+		KeyHome:      "\x1b[H",
+		KeyEnd:       "\x1b[F",
+		KeyPgUp:      "\x1b[I",
+		KeyPgDn:      "\x1b[G",
+		KeyF1:        "\x1b[M",
+		KeyF2:        "\x1b[N",
+		KeyF3:        "\x1b[O",
+		KeyF4:        "\x1b[P",
+		KeyF5:        "\x1b[Q",
+		KeyF6:        "\x1b[R",
+		KeyF7:        "\x1b[S",
+		KeyF8:        "\x1b[T",
+		KeyF9:        "\x1b[U",
+		KeyF10:       "\x1b[V",
+		KeyF11:       "\x1b[W",
+		KeyF12:       "\x1b[X",
+		KeyF13:       "\x1b[Y",
+		KeyF15:       "\x1b[a",
+		KeyF16:       "\x1b[b",
+		KeyF17:       "\x1b[c",
+		KeyF18:       "\x1b[d",
+		KeyF19:       "\x1b[e",
+		KeyF20:       "\x1b[f",
+		KeyF21:       "\x1b[g",
+		KeyF22:       "\x1b[h",
+		KeyF23:       "\x1b[i",
+		KeyF24:       "\x1b[j",
+		KeyF25:       "\x1b[k",
+		KeyF26:       "\x1b[l",
+		KeyF27:       "\x1b[m",
+		KeyF28:       "\x1b[n",
+		KeyF29:       "\x1b[o",
+		KeyF30:       "\x1b[p",
+		KeyF31:       "\x1b[q",
+		KeyF32:       "\x1b[r",
+		KeyF33:       "\x1b[s",
+		KeyF34:       "\x1b[t",
+		KeyF35:       "\x1b[u",
+		KeyF36:       "\x1b[v",
+		KeyBacktab:   "\x1b[Z",
+		AutoMargin:   true,
+		InsertChar:   "\x1b[@",
+	})
+}
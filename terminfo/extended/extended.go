@@ -25,6 +25,7 @@ import (
 	_ "github.com/gdamore/tcell/v2/terminfo/a/alacritty"
 	_ "github.com/gdamore/tcell/v2/terminfo/a/ansi"
 	_ "github.com/gdamore/tcell/v2/terminfo/b/beterm"
+	_ "github.com/gdamore/tcell/v2/terminfo/c/cons25"
 	_ "github.com/gdamore/tcell/v2/terminfo/c/cygwin"
 	_ "github.com/gdamore/tcell/v2/terminfo/d/dtterm"
 	_ "github.com/gdamore/tcell/v2/terminfo/e/emacs"
@@ -47,6 +48,7 @@ import (
 	_ "github.com/gdamore/tcell/v2/terminfo/v/vt400"
 	_ "github.com/gdamore/tcell/v2/terminfo/v/vt420"
 	_ "github.com/gdamore/tcell/v2/terminfo/v/vt52"
+	_ "github.com/gdamore/tcell/v2/terminfo/w/wsvt25"
 	_ "github.com/gdamore/tcell/v2/terminfo/w/wy50"
 	_ "github.com/gdamore/tcell/v2/terminfo/w/wy60"
 	_ "github.com/gdamore/tcell/v2/terminfo/w/wy99_ansi"
@@ -691,6 +691,14 @@ func AddTerminfo(t *Terminfo) {
 
 // LookupTerminfo attempts to find a definition for the named $TERM.
 func LookupTerminfo(name string) (*Terminfo, error) {
+	return LookupTerminfoEnv(name, os.Getenv)
+}
+
+// LookupTerminfoEnv is like LookupTerminfo, but looks up variables such as
+// $COLORTERM with getenv, rather than in the environment of the process.
+// This is useful for terminals other than our own, such as those of remote
+// clients.
+func LookupTerminfoEnv(name string, getenv func(string) string) (*Terminfo, error) {
 	if name == "" {
 		// else on windows: index out of bounds
 		// on the name[0] reference below
@@ -699,7 +707,7 @@ func LookupTerminfo(name string) (*Terminfo, error) {
 
 	addtruecolor := false
 	add256color := false
-	switch os.Getenv("COLORTERM") {
+	switch getenv("COLORTERM") {
 	case "truecolor", "24bit", "24-bit":
 		addtruecolor = true
 	}
@@ -721,7 +729,7 @@ func LookupTerminfo(name string) (*Terminfo, error) {
 		}
 		base := name[:len(name)-len("-truecolor")]
 		for _, s := range suffixes {
-			if t, _ = LookupTerminfo(base + s); t != nil {
+			if t, _ = LookupTerminfoEnv(base+s, getenv); t != nil {
 				addtruecolor = true
 				break
 			}
@@ -736,7 +744,7 @@ func LookupTerminfo(name string) (*Terminfo, error) {
 		}
 		base := name[:len(name)-len("-256color")]
 		for _, s := range suffixes {
-			if t, _ = LookupTerminfo(base + s); t != nil {
+			if t, _ = LookupTerminfoEnv(base+s, getenv); t != nil {
 				add256color = true
 				break
 			}
@@ -747,7 +755,7 @@ func LookupTerminfo(name string) (*Terminfo, error) {
 		return nil, ErrTermNotFound
 	}
 
-	switch os.Getenv("TCELL_TRUECOLOR") {
+	switch getenv("TCELL_TRUECOLOR") {
 	case "":
 	case "disable":
 		addtruecolor = false
@@ -763,6 +771,11 @@ func LookupTerminfo(name string) (*Terminfo, error) {
 		t.SetFgRGB == "" &&
 		t.SetBgRGB == "" {
 
+		// Amend a copy, as the entry in the database is shared
+		// by every screen, and other callers may not want this.
+		c := *t
+		t = &c
+
 		// Supply vanilla ISO 8613-6:1994 24-bit color sequences.
 		t.SetFgRGB = "\x1b[38;2;%p1%d;%p2%d;%p3%dm"
 		t.SetBgRGB = "\x1b[48;2;%p1%d;%p2%d;%p3%dm"
@@ -771,6 +784,8 @@ func LookupTerminfo(name string) (*Terminfo, error) {
 	}
 
 	if add256color {
+		c := *t
+		t = &c
 		t.Colors = 256
 		t.SetFg = "\x1b[%?%p1%{8}%<%t3%p1%d%e%p1%{16}%<%t9%p1%{8}%-%d%e38;5;%p1%d%;m"
 		t.SetBg = "\x1b[%?%p1%{8}%<%t4%p1%d%e%p1%{16}%<%t10%p1%{8}%-%d%e48;5;%p1%d%;m"
@@ -0,0 +1,83 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This terminal definition is hand-coded, as it is often missing from the
+// terminfo database of other systems (for example when logged in
+// remotely).  This is the wscons console of NetBSD and OpenBSD, which is
+// a VT220 with colors, and with the function keys of the PC keyboard
+// numbered as on a VT220 (F1 is CSI 11 ~, rather than SS3 P).  The pccon
+// entries of OpenBSD describe the same console, with the same keys.
+
+package wsvt25
+
+import "github.com/gdamore/tcell/v2/terminfo"
+
+func init() {
+
+	// NetBSD wscons in 25 line DEC VT220 mode
+	terminfo.AddTerminfo(&terminfo.Terminfo{
+		Name:              "wsvt25",
+		Aliases:           []string{"wsvt25m", "pccon", "pccon0"},
+		Columns:           80,
+		Lines:             25,
+		Colors:            8,
+		Bell:              "\a",
+		Clear:             "\x1b[H\x1b[J",
+		ShowCursor:        "\x1b[?25h",
+		HideCursor:        "\x1b[?25l",
+		AttrOff:           "\x1b[m\x1b(B",
+		Underline:         "\x1b[4m",
+		Bold:              "\x1b[1m",
+		Blink:             "\x1b[5m",
+		Reverse:           "\x1b[7m",
+		SetFg:             "\x1b[3%p1%dm",
+		SetBg:             "\x1b[4%p1%dm",
+		SetFgBg:           "\x1b[3%p1%d;4%p2%dm",
+		ResetFgBg:         "\x1b[m",
+		PadChar:           "\x00",
+		AltChars:          "``aaffggjjkkllmmnnooppqqrrssttuuvvwwxxyyzz{{||}}~~",
+		EnterAcs:          "\x1b(0",
+		ExitAcs:           "\x1b(B",
+		EnableAcs:         "\x1b)0",
+		EnableAutoMargin:  "\x1b[?7h",
+		DisableAutoMargin: "\x1b[?7l",
+		SetCursor:         "\x1b[%i%p1%d;%p2%dH",
+		CursorBack1:       "\b",
+		CursorUp1:         "\x1b[A",
+		KeyUp:             "\x1b[A",
+		KeyDown:           "\x1b[B",
+		KeyRight:          "\x1b[C",
+		KeyLeft:           "\x1b[D",
+		KeyInsert:         "\x1b[2~",
+		KeyDelete:         "\x1b[3~",
+		KeyBackspace:      "\x7f",
+		KeyHome:           "\x1b[7~",
+		KeyEnd:            "\x1b[8~",
+		KeyPgUp:           "\x1b[5~",
+		KeyPgDn:           "\x1b[6~",
+		KeyF1:             "\x1b[11~",
+		KeyF2:             "\x1b[12~",
+		KeyF3:             "\x1b[13~",
+		KeyF4:             "\x1b[14~",
+		KeyF5:             "\x1b[15~",
+		KeyF6:             "\x1b[17~",
+		KeyF7:             "\x1b[18~",
+		KeyF8:             "\x1b[19~",
+		KeyF9:             "\x1b[20~",
+		KeyF10:            "\x1b[21~",
+		KeyF11:            "\x1b[23~",
+		KeyF12:            "\x1b[24~",
+		AutoMargin:        true,
+	})
+}
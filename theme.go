@@ -0,0 +1,132 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"sync"
+	"time"
+)
+
+// StyleRole names the purpose of a style, such as the style for selected
+// text, so that the actual style can be looked up in a Theme.  Applications
+// may define their own roles in addition to those here.
+type StyleRole string
+
+const (
+	RoleNormal    = StyleRole("normal")    // Ordinary content.
+	RoleSelection = StyleRole("selection") // Selected or highlighted content.
+	RoleBorder    = StyleRole("border")    // Borders and separators.
+	RoleTitle     = StyleRole("title")     // Titles and headings.
+	RoleStatus    = StyleRole("status")    // Status and menu bars.
+	RoleError     = StyleRole("error")     // Error messages.
+	RoleWarning   = StyleRole("warning")   // Warning messages.
+	RoleDisabled  = StyleRole("disabled")  // Content that cannot be used.
+)
+
+// Theme maps style roles to the styles used for them.  Roles that are not
+// in the theme use the style for RoleNormal.
+type Theme struct {
+	Name   string
+	Dark   bool // true if the theme has a dark background
+	Styles map[StyleRole]Style
+}
+
+// Style returns the style for the role.  A nil Theme returns
+// StyleDefault for every role.
+func (th *Theme) Style(role StyleRole) Style {
+	if th == nil {
+		return StyleDefault
+	}
+	if s, ok := th.Styles[role]; ok {
+		return s
+	}
+	return th.Styles[RoleNormal]
+}
+
+// NewDarkTheme returns a theme with light text on a black background,
+// using only the first 16 colors, so that it works on most terminals.
+func NewDarkTheme() *Theme {
+	normal := StyleDefault.Foreground(ColorSilver).Background(ColorBlack)
+	return &Theme{
+		Name: "dark",
+		Dark: true,
+		Styles: map[StyleRole]Style{
+			RoleNormal:    normal,
+			RoleSelection: normal.Foreground(ColorWhite).Background(ColorNavy),
+			RoleBorder:    normal.Foreground(ColorGray),
+			RoleTitle:     normal.Foreground(ColorWhite).Bold(true),
+			RoleStatus:    normal.Foreground(ColorBlack).Background(ColorSilver),
+			RoleError:     normal.Foreground(ColorRed).Bold(true),
+			RoleWarning:   normal.Foreground(ColorYellow),
+			RoleDisabled:  normal.Foreground(ColorGray),
+		},
+	}
+}
+
+// NewLightTheme returns a theme with dark text on a white background,
+// using only the first 16 colors, so that it works on most terminals.
+func NewLightTheme() *Theme {
+	normal := StyleDefault.Foreground(ColorBlack).Background(ColorWhite)
+	return &Theme{
+		Name: "light",
+		Styles: map[StyleRole]Style{
+			RoleNormal:    normal,
+			RoleSelection: normal.Foreground(ColorWhite).Background(ColorBlue),
+			RoleBorder:    normal.Foreground(ColorGray),
+			RoleTitle:     normal.Bold(true),
+			RoleStatus:    normal.Foreground(ColorWhite).Background(ColorNavy),
+			RoleError:     normal.Foreground(ColorMaroon).Bold(true),
+			RoleWarning:   normal.Foreground(ColorOlive),
+			RoleDisabled:  normal.Foreground(ColorSilver),
+		},
+	}
+}
+
+// EventThemeChange is delivered when the theme is changed with
+// Screen.SetTheme.  Applications should redraw using the new theme.
+type EventThemeChange struct {
+	t     time.Time
+	theme *Theme
+}
+
+// When returns the time when the theme was changed.
+func (ev *EventThemeChange) When() time.Time {
+	return ev.t
+}
+
+// Theme returns the new theme.
+func (ev *EventThemeChange) Theme() *Theme {
+	return ev.theme
+}
+
+// theming holds the current theme of a screen.
+type theming struct {
+	theme *Theme
+	l     sync.Mutex
+}
+
+func (b *baseScreen) SetTheme(theme *Theme) {
+	b.theme.l.Lock()
+	b.theme.theme = theme
+	b.theme.l.Unlock()
+	b.SetStyle(theme.Style(RoleNormal))
+	b.InjectEvent(&EventThemeChange{t: time.Now(), theme: theme})
+}
+
+func (b *baseScreen) Theme() *Theme {
+	b.theme.l.Lock()
+	defer b.theme.l.Unlock()
+	return b.theme.theme
+}
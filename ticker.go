@@ -0,0 +1,100 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"sync"
+	"time"
+)
+
+// EventTick is delivered by the frame clock started with Screen.Ticker,
+// just after the screen has been updated for a frame.
+type EventTick struct {
+	t     time.Time
+	frame uint64
+}
+
+// When returns the time of the frame.
+func (ev *EventTick) When() time.Time {
+	return ev.t
+}
+
+// Frame returns the number of the frame, counting from one when the
+// ticker was started.  Frames that were skipped, because the application
+// had not yet received the previous tick, are counted, so this can be
+// used to advance animations at a steady rate.
+func (ev *EventTick) Frame() uint64 {
+	return ev.frame
+}
+
+// ticker is the state of the frame clock.
+type ticker struct {
+	stop    chan struct{}
+	pending bool // a tick has been posted, but not yet received
+	l       sync.Mutex
+}
+
+func (b *baseScreen) Ticker(fps int) {
+	b.tick.l.Lock()
+	defer b.tick.l.Unlock()
+	if b.tick.stop != nil {
+		close(b.tick.stop)
+		b.tick.stop = nil
+	}
+	b.tick.pending = false
+	if fps <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	b.tick.stop = stop
+	go b.runTicker(time.Second/time.Duration(fps), stop)
+}
+
+func (b *baseScreen) runTicker(interval time.Duration, stop chan struct{}) {
+	tk := time.NewTicker(interval)
+	defer tk.Stop()
+	frame := uint64(0)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-b.StopQ():
+			return
+		case now := <-tk.C:
+			frame++
+			b.tick.l.Lock()
+			skip := b.tick.pending || b.tick.stop != stop
+			b.tick.l.Unlock()
+			if skip {
+				// the application is still working on the last frame
+				continue
+			}
+			b.Show()
+			b.tick.l.Lock()
+			b.tick.pending = b.PostEvent(&EventTick{t: now, frame: frame}) == nil
+			b.tick.l.Unlock()
+		}
+	}
+}
+
+// handleTick notes that the application has received a tick, so that
+// the next one can be delivered.
+func (b *baseScreen) handleTick(ev Event) {
+	if _, ok := ev.(*EventTick); ok {
+		b.tick.l.Lock()
+		b.tick.pending = false
+		b.tick.l.Unlock()
+	}
+}
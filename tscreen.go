@@ -21,17 +21,21 @@ import (
 	"bytes"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
+	"unicode"
 	"unicode/utf8"
 
 	"golang.org/x/term"
 	"golang.org/x/text/transform"
 
+	"github.com/gdamore/tcell/v2/runes"
 	"github.com/gdamore/tcell/v2/terminfo"
 )
 
@@ -50,7 +54,13 @@ func NewTerminfoScreen() (Screen, error) {
 // LookupTerminfo attempts to find a definition for the named $TERM falling
 // back to attempting to parse the output from infocmp.
 func LookupTerminfo(name string) (ti *terminfo.Terminfo, e error) {
-	ti, e = terminfo.LookupTerminfo(name)
+	return lookupTerminfo(name, os.Getenv)
+}
+
+// lookupTerminfo is like LookupTerminfo, but looks up variables such as
+// $COLORTERM with getenv.
+func lookupTerminfo(name string, getenv func(string) string) (ti *terminfo.Terminfo, e error) {
+	ti, e = terminfo.LookupTerminfoEnv(name, getenv)
 	if e != nil {
 		ti, e = loadDynamicTerminfo(name)
 		if e != nil {
@@ -70,14 +80,47 @@ func LookupTerminfo(name string) (ti *terminfo.Terminfo, e error) {
 // If passed terminfo is nil, then TERM environment variable is queried for
 // terminal specification.
 func NewTerminfoScreenFromTtyTerminfo(tty Tty, ti *terminfo.Terminfo) (s Screen, e error) {
+	t, e := newTScreen(tty, ti)
+	if e != nil {
+		return nil, e
+	}
+	return &baseScreen{screenImpl: t}, nil
+}
+
+// NewInlineScreen returns a Screen that occupies only the given number of
+// rows of the user's terminal, starting at the current cursor position,
+// rather than taking over the entire display.  Existing content of the
+// terminal is preserved, and the terminal is scrolled if needed to make room.
+// The region will move up if the terminal is resized such that it would no
+// longer fit.  By default the region is cleared on exit, leaving the cursor
+// where it was at start; use SetDisplayOptions with KeepOnExit (and the same
+// InlineRows) to leave the final content visible instead.
+func NewInlineScreen(height int) (Screen, error) {
+	t, e := newTScreen(nil, nil)
+	if e != nil {
+		return nil, e
+	}
+	t.opts.InlineRows = height
+	t.anchored = true
+	return &baseScreen{screenImpl: t}, nil
+}
+
+func newTScreen(tty Tty, ti *terminfo.Terminfo) (*tScreen, error) {
+	guessed := false
 	if ti == nil {
-		ti, e = LookupTerminfo(os.Getenv("TERM"))
+		var e error
+		getenv := func(name string) string { return ttyGetenv(tty, name) }
+		ti, e = lookupTerminfo(getenv("TERM"), getenv)
 		if e != nil {
-			return
+			if ti = guessTerminfo(getenv("TERM"), getenv); ti == nil {
+				return nil, e
+			}
+			logDebug("terminfo not found, guessing", "term", getenv("TERM"), "error", e)
+			guessed = true
 		}
 	}
 
-	t := &tScreen{ti: ti, tty: tty}
+	t := &tScreen{ti: ti, tty: tty, initCursor: CursorStyleDefault, guessed: guessed}
 
 	t.keyexist = make(map[Key]bool)
 	t.keycodes = make(map[string]*tKeyCode)
@@ -86,13 +129,15 @@ func NewTerminfoScreenFromTtyTerminfo(tty Tty, ti *terminfo.Terminfo) (s Screen,
 	}
 	t.prepareKeys()
 	t.buildAcsMap()
+	t.ident.MouseQuirks = mouseQuirksFor(ti.Name, TerminalIdentity{})
 	t.resizeQ = make(chan bool, 1)
 	t.fallback = make(map[rune]string)
 	for k, v := range RuneFallbacks {
 		t.fallback[k] = v
 	}
+	t.nofallback = make(map[rune]bool)
 
-	return &baseScreen{screenImpl: t}, nil
+	return t, nil
 }
 
 // NewTerminfoScreenFromTty returns a Screen using a custom Tty implementation.
@@ -103,6 +148,34 @@ func NewTerminfoScreenFromTty(tty Tty) (Screen, error) {
 	return NewTerminfoScreenFromTtyTerminfo(tty, nil)
 }
 
+// clearToEnd is the ANSI sequence to erase from the cursor to the end of the
+// display.  Terminfo has this as "ed", but we don't carry it in our database.
+const clearToEnd = "\x1b[J"
+
+// queryCursor asks the terminal to report the cursor position (CPR).
+const queryCursor = "\x1b[6n"
+
+// queryVersion asks the terminal to report its name and version (XTVERSION).
+const queryVersion = "\x1b[>0q"
+
+// queryDA1 and queryDA2 ask for the primary and secondary device attributes.
+const queryDA1 = "\x1b[c"
+const queryDA2 = "\x1b[>c"
+
+// queryKittyKeys asks whether the kitty keyboard protocol is supported.
+const queryKittyKeys = "\x1b[?u"
+
+// queryCursorStyle asks for the cursor style (DECSCUSR) with DECRQSS, so
+// that it can be restored on exit.
+const queryCursorStyle = "\x1bP$q q\x1b\\"
+
+// queryClusters asks (with DECRQM) whether the terminal supports grapheme
+// clustering (mode 2027), in which it gives each grapheme cluster a single
+// width, instead of adding up the widths of its code points.
+const queryClusters = "\x1b[?2027$p"
+const enableClusters = "\x1b[?2027h"
+const disableClusters = "\x1b[?2027l"
+
 // tKeyCode represents a combination of a key code and modifiers.
 type tKeyCode struct {
 	key Key
@@ -120,17 +193,27 @@ type tScreen struct {
 	buffering    bool // true if we are collecting writes to buf instead of sending directly to out
 	buf          bytes.Buffer
 	curstyle     Style
+	styleStack   bool  // OptimizeStyleStack is in effect for this draw
+	pushed       bool  // the style was saved with pushSGR
+	pushedStyle  Style // the style that was saved
 	style        Style
 	resizeQ      chan bool
 	quit         chan struct{}
 	keyexist     map[Key]bool
 	keycodes     map[string]*tKeyCode
-	keychan      chan []byte
+	keychan      chan inputChunk
+	chunkQ       chan []byte
+	evbuf        []Event
 	keytimer     *time.Timer
 	keyexpire    time.Time
+	inputOpts    InputOptions
+	kittyKeys    bool
+	kittyFlags   int  // the kitty keyboard protocol flags pushed
+	keypadApp    bool // keypad in application mode for KeypadKeys
 	cx           int
 	cy           int
 	mouse        []byte
+	consoleMouse *consoleMouse
 	clear        bool
 	cursorx      int
 	cursory      int
@@ -139,12 +222,15 @@ type tScreen struct {
 	encoder      transform.Transformer
 	decoder      transform.Transformer
 	fallback     map[rune]string
+	nofallback   map[rune]bool // unregistered, so not from package runes
+	doubleByte   bool          // the charset is a doubleByteCharset
 	colors       map[Color]Color
 	palette      []Color
 	truecolor    bool
 	escaped      bool
+	skipping     int // see skipString
 	buttondn     bool
-	finiOnce     sync.Once
+	life         lifecycle
 	enablePaste  string
 	disablePaste string
 	enterUrl     string
@@ -170,42 +256,88 @@ type tScreen struct {
 	running      bool
 	wg           sync.WaitGroup
 	mouseFlags   MouseFlags
+	mouseMode    int  // tracking mode sent to the terminal, -1 if unknown
+	locator      bool // DEC locator reports are enabled
 	pasteEnabled bool
 	focusEnabled bool
 	setTitle     string
 	saveTitle    string
 	restoreTitle string
 	title        string
+	tabColor     Color
+	badge        string
 	setClipboard string
+	passthrough  bool
+	semanticMark string
+	marks        []semanticMark
+	restoreW     io.Writer
+	restoreSent  string // the restore sequence last written to restoreW
+	opts         DisplayOptions
+	yoff         int  // row offset of the screen region, for inline mode
+	anchored     bool // inline region is anchored at the cursor
+	cprQ         chan cursorReport
+	xtverQ       chan string
+	daQ          chan struct{}
+	identified   bool
+	kittyProto   bool // terminal replied to queryKittyKeys
+	repeatChar   string
+	initCursor   CursorStyle // cursor style at start, from queryCursorStyle
+	clusterMode  int         // reply to queryClusters, 0 if none
+	setClusters  bool        // we enabled grapheme clustering
+	ident        TerminalIdentity
+	outerChecked bool
+	guessed      bool // ti is from guessTerminfo
+	ioErr        error
+	recovering   bool       // recoverTty is running
+	ioLock       sync.Mutex // protects ioErr and recovering
 
 	sync.Mutex
 }
 
 func (t *tScreen) Init() error {
+	return t.life.init(t.setup)
+}
+
+func (t *tScreen) setup() error {
 	if e := t.initialize(); e != nil {
 		return e
 	}
 
-	t.keychan = make(chan []byte, 10)
+	t.keychan = make(chan inputChunk, 10)
+	t.chunkQ = make(chan []byte, 11)
 	t.keytimer = time.NewTimer(time.Millisecond * 50)
 	t.charset = "UTF-8"
 
-	t.charset = getCharset()
+	if _, ok := t.tty.(TtyEnviron); ok {
+		t.charset = localeCharset(t.getenv)
+	} else {
+		t.charset = getCharset()
+	}
 	if enc := GetEncoding(t.charset); enc != nil {
 		t.encoder = enc.NewEncoder()
 		t.decoder = enc.NewDecoder()
 	} else {
 		return ErrNoCharset
 	}
+	if doubleByteCharset(t.charset) {
+		t.doubleByte = true
+		// As runewidth would, if we did not turn that off, we take
+		// characters of ambiguous width to be wide, as terminals
+		// using these character sets display them so.  This is only
+		// for this screen, as others may be on other terminals.
+		if os.Getenv("RUNEWIDTH_EASTASIAN") == "" {
+			t.cells.setAmbiguousWide(true)
+		}
+	}
 	ti := t.ti
 
 	// environment overrides
 	w := ti.Columns
 	h := ti.Lines
-	if i, _ := strconv.Atoi(os.Getenv("LINES")); i != 0 {
+	if i, _ := strconv.Atoi(t.getenv("LINES")); i != 0 {
 		h = i
 	}
-	if i, _ := strconv.Atoi(os.Getenv("COLUMNS")); i != 0 {
+	if i, _ := strconv.Atoi(t.getenv("COLUMNS")); i != 0 {
 		w = i
 	}
 	if t.ti.SetFgBgRGB != "" || t.ti.SetFgRGB != "" || t.ti.SetBgRGB != "" {
@@ -216,17 +348,7 @@ func (t *tScreen) Init() error {
 	if os.Getenv("TCELL_TRUECOLOR") == "disable" {
 		t.truecolor = false
 	}
-	nColors := t.nColors()
-	if nColors > 256 {
-		nColors = 256 // clip to reasonable limits
-	}
-	t.colors = make(map[Color]Color, nColors)
-	t.palette = make([]Color, nColors)
-	for i := 0; i < nColors; i++ {
-		t.palette[i] = Color(i) | ColorValid
-		// identity map for our builtin colors
-		t.colors[Color(i)|ColorValid] = Color(i) | ColorValid
-	}
+	t.buildPalette()
 
 	t.quit = make(chan struct{})
 	t.eventQ = make(chan Event, 10)
@@ -244,10 +366,28 @@ func (t *tScreen) Init() error {
 	if err := t.engage(); err != nil {
 		return err
 	}
+	t.identify()
+	t.anchorInline()
+	t.resolveOuter()
 
 	return nil
 }
 
+// buildPalette makes the palette of built-in colors the terminal has.
+func (t *tScreen) buildPalette() {
+	nColors := t.nColors()
+	if nColors > 256 {
+		nColors = 256 // clip to reasonable limits
+	}
+	t.colors = make(map[Color]Color, nColors)
+	t.palette = make([]Color, nColors)
+	for i := 0; i < nColors; i++ {
+		t.palette[i] = Color(i) | ColorValid
+		// identity map for our builtin colors
+		t.colors[Color(i)|ColorValid] = Color(i) | ColorValid
+	}
+}
+
 func (t *tScreen) prepareKeyMod(key Key, mod ModMask, val string) {
 	if val != "" {
 		// Do not override codes that already exist
@@ -420,11 +560,17 @@ func (t *tScreen) prepareExtendedOSC() {
 	if t.ti.EnterUrl != "" {
 		t.enterUrl = t.ti.EnterUrl
 		t.exitUrl = t.ti.ExitUrl
-	} else if t.ti.Mouse != "" || t.ti.XTermLike {
+	} else if t.hyperlinks() {
 		t.enterUrl = "\x1b]8;%p2%s;%p1%s\x1b\\"
 		t.exitUrl = "\x1b]8;;\x1b\\"
 	}
 
+	// Terminals that understand hyperlinks are modern enough to
+	// either understand or ignore the shell integration marks.
+	if t.hyperlinks() {
+		t.semanticMark = "\x1b]133;%p1%s\x1b\\"
+	}
+
 	if t.ti.SetWindowSize != "" {
 		t.setWinSize = t.ti.SetWindowSize
 	} else if t.ti.Mouse != "" || t.ti.XTermLike {
@@ -451,12 +597,151 @@ func (t *tScreen) prepareExtendedOSC() {
 		t.setTitle = "\x1b[>2t\x1b]2;%p1%s\x1b\\"
 	}
 
+	if t.ti.XTermLike {
+		t.repeatChar = "\x1b[%p1%db"
+	}
+
 	if t.setClipboard == "" && t.ti.XTermLike {
 		// this string takes a base64 string and sends it to the clipboard.
 		// it will also be able to retrieve the clipboard using "?" as the
 		// sent string, when we support that.
 		t.setClipboard = "\x1b]52;c;%p1%s\x1b\\"
 	}
+
+	// Inside tmux, $TERM usually names "screen" or "tmux", but the outer
+	// terminal is generally modern enough for the clipboard.
+	if t.passthrough = t.tmuxPassthrough(); t.passthrough && t.setClipboard == "" {
+		t.setClipboard = "\x1b]52;c;%p1%s\x1b\\"
+	}
+}
+
+// ttyGetenv looks up a variable describing the terminal, in the
+// environment of the tty if it has one, or else in our own.
+func ttyGetenv(tty Tty, name string) string {
+	if env, ok := tty.(TtyEnviron); ok && !strings.HasPrefix(name, "TCELL_") {
+		return env.Getenv(name)
+	}
+	return os.Getenv(name)
+}
+
+func (t *tScreen) getenv(name string) string {
+	return ttyGetenv(t.tty, name)
+}
+
+// tmuxPassthrough returns true if we are running inside tmux, and should
+// send sequences that tmux might not handle itself (such as setting the
+// clipboard) to the outer terminal, using the tmux passthrough sequence.
+// Depending on its configuration, tmux either handles these sequences
+// itself, or passes them through if allow-passthrough is set, so we send
+// both forms.  This can be disabled by setting TCELL_TMUX_PASSTHROUGH
+// to "disable".
+func (t *tScreen) tmuxPassthrough() bool {
+	if t.getenv("TMUX") == "" {
+		return false
+	}
+	return os.Getenv("TCELL_TMUX_PASSTHROUGH") != "disable"
+}
+
+// tmuxWrap wraps a sequence in the tmux passthrough sequence, which
+// requires doubling any escape characters it contains.
+func tmuxWrap(s string) string {
+	return "\x1bPtmux;" + strings.Replace(s, "\x1b", "\x1b\x1b", -1) + "\x1b\\"
+}
+
+// hyperlinkTerms are terminals known to support OSC 8 hyperlinks, matched
+// against the start of $TERM.
+var hyperlinkTerms = []string{
+	"alacritty",
+	"contour",
+	"foot",
+	"wezterm",
+	"xterm-ghostty",
+	"xterm-kitty",
+}
+
+// hyperlinkPrograms are terminals known to support OSC 8 hyperlinks, matched
+// against $TERM_PROGRAM, for emulators that claim to be something else in $TERM.
+var hyperlinkPrograms = []string{
+	"ghostty",
+	"iTerm.app",
+	"vscode",
+	"WezTerm",
+}
+
+// brokenHyperlinkTerms are terminals known to display the OSC 8 sequences
+// as garbage, rather than ignoring them.
+var brokenHyperlinkTerms = []string{
+	"Eterm",
+	"cons25",
+	"linux",
+	"screen",
+	"sun",
+	"wsvt25",
+}
+
+// consoleTerms are the system consoles of the BSDs (syscons and wscons)
+// and illumos, matched against the start of $TERM.  Unlike xterm clones,
+// they answer none of the queries we make when starting, and they may
+// show OSC and DCS strings that they do not understand as garbage, so we
+// send them neither.
+var consoleTerms = []string{
+	"cons25",
+	"sun",
+	"wsvt25",
+}
+
+// systemConsole returns true if the terminal is one of the consoleTerms.
+func (t *tScreen) systemConsole() bool {
+	for _, prefix := range consoleTerms {
+		if strings.HasPrefix(t.ti.Name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hyperlinks decides whether to emit OSC 8 hyperlinks.  Terminals that are
+// known to support them get them, as do other XTerm-like terminals unless
+// they are known to display the sequences instead of ignoring them.  This can
+// be overridden by setting TCELL_HYPERLINKS to "enable" or "disable".
+func (t *tScreen) hyperlinks() bool {
+	switch os.Getenv("TCELL_HYPERLINKS") {
+	case "enable":
+		return true
+	case "disable":
+		return false
+	}
+	name := t.ti.Name
+	for _, prefix := range hyperlinkTerms {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	prog := t.getenv("TERM_PROGRAM")
+	for _, p := range hyperlinkPrograms {
+		if prog == p {
+			return true
+		}
+	}
+	// VTE based terminals (GNOME Terminal, etc.) support it since 0.50,
+	// as does Windows Terminal, and Konsole.
+	if v, _ := strconv.Atoi(t.getenv("VTE_VERSION")); v >= 5000 {
+		return true
+	}
+	if t.getenv("WT_SESSION") != "" || t.getenv("KONSOLE_VERSION") != "" {
+		return true
+	}
+	// tmux either supports them, or discards them cleanly, even though
+	// it often uses "screen" for $TERM.
+	if t.getenv("TMUX") != "" {
+		return true
+	}
+	for _, prefix := range brokenHyperlinkTerms {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+	return t.ti.Mouse != "" || t.ti.XTermLike
 }
 
 func (t *tScreen) prepareCursorStyles() {
@@ -485,6 +770,12 @@ func (t *tScreen) prepareCursorStyles() {
 			CursorStyleSteadyBar:         "\x1b[6 q",
 		}
 	}
+	if t.cursorStyles != nil && (t.ti.Mouse != "" || t.ti.XTermLike) {
+		// blinking without a shape is done with the att610 mode
+		def := t.cursorStyles[CursorStyleDefault]
+		t.cursorStyles[CursorStyleBlinkingDefault] = def + "\x1b[?12h"
+		t.cursorStyles[CursorStyleSteadyDefault] = def + "\x1b[?12l"
+	}
 	if t.ti.CursorColorRGB != "" {
 		// if it was X11 style with just a single %p1%s, then convert
 		t.cursorRGB = t.ti.CursorColorRGB
@@ -492,7 +783,7 @@ func (t *tScreen) prepareCursorStyles() {
 	if t.ti.CursorColorReset != "" {
 		t.cursorFg = t.ti.CursorColorReset
 	}
-	if t.cursorRGB == "" {
+	if t.cursorRGB == "" && !t.systemConsole() {
 		t.cursorRGB = "\x1b]12;%p1%s\007"
 		t.cursorFg = "\x1b]112\007"
 	}
@@ -639,6 +930,30 @@ func (t *tScreen) prepareKeys() {
 		t.prepareKey(KeyRight, "\x1bOC")
 		t.prepareKey(KeyLeft, "\x1bOD")
 		t.prepareKey(KeyHome, "\x1bOH")
+		t.prepareKey(KeyEnd, "\x1bOF")
+	}
+
+	// Keypad application mode, which is used for KeypadKeys even when
+	// the terminfo entry does not use it.
+	for _, kp := range keypadKeys {
+		t.prepareKey(kp.key, "\x1bO"+string(kp.ss3))
+	}
+
+	// The wscons console of OpenBSD and NetBSD is usually used with TERM
+	// set to vt220 (or vt100), but it numbers the function keys of the
+	// PC keyboard as the later DEC models did, so that F1 is CSI 11 ~
+	// rather than SS3 P, and it has Home and End keys, which the DEC
+	// terminals lacked.  None of these conflict with the DEC sequences.
+	if strings.HasPrefix(ti.Name, "vt") {
+		t.prepareKey(KeyF1, "\x1b[11~")
+		t.prepareKey(KeyF2, "\x1b[12~")
+		t.prepareKey(KeyF3, "\x1b[13~")
+		t.prepareKey(KeyF4, "\x1b[14~")
+		t.prepareKey(KeyF5, "\x1b[15~")
+		t.prepareKey(KeyHome, "\x1b[7~")
+		t.prepareKey(KeyEnd, "\x1b[8~")
+		t.prepareKey(KeyHome, "\x1b[1~")
+		t.prepareKey(KeyEnd, "\x1b[4~")
 	}
 
 	t.prepareKey(keyPasteStart, ti.PasteStart)
@@ -676,10 +991,24 @@ outer:
 }
 
 func (t *tScreen) Fini() {
-	t.finiOnce.Do(t.finish)
+	t.life.fini(t.finish)
+}
+
+func (t *tScreen) Done() <-chan struct{} {
+	return t.life.doneQ()
 }
 
 func (t *tScreen) finish() {
+	t.Lock()
+	t.fini = true
+	t.Unlock()
+	if t.quit == nil {
+		// never initialized, but the tty is still ours
+		if t.tty != nil {
+			_ = t.tty.Close()
+		}
+		return
+	}
 	close(t.quit)
 	t.finalize()
 }
@@ -694,7 +1023,8 @@ func (t *tScreen) SetStyle(style Style) {
 
 func (t *tScreen) encodeRune(r rune, buf []byte) []byte {
 
-	nb := make([]byte, 6)
+	// room for the escape sequences of stateful encodings like ISO-2022-JP
+	nb := make([]byte, 16)
 	ob := make([]byte, 6)
 	num := utf8.EncodeRune(ob, r)
 	ob = ob[:num]
@@ -711,7 +1041,10 @@ func (t *tScreen) encodeRune(r rune, buf []byte) []byte {
 				buf = append(buf, []byte(acs)...)
 			} else if fb, ok := t.fallback[r]; ok {
 				buf = append(buf, []byte(fb)...)
+			} else if fb := runes.FallbackFor(t.charset, r); fb != "" && !t.nofallback[r] {
+				buf = append(buf, []byte(fb)...)
 			} else {
+				logDebug("cannot display character", "rune", r, "charset", t.charset)
 				buf = append(buf, '?')
 			}
 		}
@@ -803,7 +1136,7 @@ func (t *tScreen) sendFgBg(fg Color, bg Color, attr AttrMask) AttrMask {
 	return attr
 }
 
-func (t *tScreen) drawCell(x, y int) int {
+func (t *tScreen) drawCell(x, y int, wideSkip bool) int {
 
 	ti := t.ti
 
@@ -817,29 +1150,40 @@ func (t *tScreen) drawCell(x, y int) int {
 		// we write to the second to the last cell what we want in the last cell, then we
 		// insert a character at that 2nd to last position to shift the last column into
 		// place, then we rewrite that 2nd to last cell.  Old terminals suck.
-		t.TPuts(ti.TGoto(x-1, y))
+		t.moveTo(x-1, y)
 		defer func() {
-			t.TPuts(ti.TGoto(x-1, y))
+			t.moveTo(x-1, y)
 			t.TPuts(ti.InsertChar)
 			t.cy = y
 			t.cx = x - 1
 			t.cells.SetDirty(x-1, y, true)
-			_ = t.drawCell(x-1, y)
-			t.TPuts(t.ti.TGoto(0, 0))
+			_ = t.drawCell(x-1, y, false)
+			t.moveTo(0, 0)
 			t.cy = 0
 			t.cx = 0
 		}()
 	} else if t.cy != y || t.cx != x {
-		t.TPuts(ti.TGoto(x, y))
+		t.moveTo(x, y)
 		t.cx = x
 		t.cy = y
 	}
 
-	if style == StyleDefault {
-		style = t.style
+	style = style.inherit(t.style)
+	if t.pushed && style != t.curstyle {
+		// back to the saved style, or else it is of no more use
+		t.popStyle()
 	}
 	if style != t.curstyle {
+		if t.styleStack && t.worthPushing(x, y, width, style) {
+			t.TPuts(pushSGR)
+			t.pushed = true
+			t.pushedStyle = t.curstyle
+		}
 		fg, bg, attrs := style.fg, style.bg, style.attrs
+		if attrs&AttrTransparent != 0 {
+			// leave the terminal's background alone
+			bg = ColorDefault
+		}
 
 		t.TPuts(ti.AttrOff)
 
@@ -900,7 +1244,11 @@ func (t *tScreen) drawCell(x, y int) int {
 		// URL string can be long, so don't send it unless we really need to
 		if t.enterUrl != "" && t.curstyle != style {
 			if style.url != "" {
-				t.TPuts(ti.TParm(t.enterUrl, style.url, style.urlId))
+				id := style.urlId
+				if id == "" {
+					id = autoUrlId(style.url)
+				}
+				t.TPuts(ti.TParm(t.enterUrl, style.url, id))
 			} else {
 				t.TPuts(t.exitUrl)
 			}
@@ -930,7 +1278,14 @@ func (t *tScreen) drawCell(x, y int) int {
 	if width > 1 && str == "?" {
 		// No FullWidth character support
 		str = "? "
-		t.cx = -1
+		wideSkip = false
+	}
+	if width == 1 && t.doubleByte && len(combc) == 0 && encodedWidth(buf) > 1 {
+		// the terminal would display it two cells wide, which
+		// would move the rest of the line
+		if str = runes.FallbackFor(t.charset, mainc); len(str) != 1 {
+			str = "?"
+		}
 	}
 
 	if x > t.w-width {
@@ -941,13 +1296,139 @@ func (t *tScreen) drawCell(x, y int) int {
 	t.writeString(str)
 	t.cx += width
 	t.cells.SetDirty(x, y, false)
-	if width > 1 {
+	if width > 1 && !wideSkip {
 		t.cx = -1
 	}
 
 	return width
 }
 
+// optimizations returns the output optimizations to use.
+func (t *tScreen) optimizations() Optimizations {
+	var opt Optimizations
+	if t.ident.Name != "" {
+		opt |= OptimizeScroll | OptimizeRepeat
+	}
+	if v, err := strconv.Atoi(t.ident.Version); err == nil && t.ident.Name == "XTerm" && v >= 334 {
+		opt |= OptimizeStyleStack
+	}
+	opt = opt&^t.opts.NoOptimize | t.opts.Optimize
+	return adjustOptimizations(opt, os.Getenv("TCELL_OPTIMIZE"))
+}
+
+// worthPushing returns true if the style should be saved with pushSGR
+// before changing to style, to draw the cell at x (of the given width),
+// because the cells that follow in style end with one in the current
+// style.  The hyperlink is not part of what is saved, so it must be the
+// same, and saving a plain style is no cheaper than sending it again.
+func (t *tScreen) worthPushing(x, y, width int, style Style) bool {
+	cur := t.curstyle
+	if cur == styleInvalid || cur.url != style.url || cur.urlId != style.urlId {
+		return false
+	}
+	if cur.fg == ColorDefault && cur.bg == ColorDefault && cur.attrs == AttrNone && cur.ulStyle == UnderlineStyleNone {
+		return false
+	}
+	if width < 1 {
+		width = 1
+	}
+	for i := x + width; i < t.w; i += width {
+		_, _, s, w := t.cells.GetContent(i, y)
+		if !t.cells.Dirty(i, y) {
+			return false
+		}
+		s = s.inherit(t.style)
+		if s == cur {
+			return true
+		}
+		if s != style {
+			return false
+		}
+		if width = w; width < 1 {
+			width = 1
+		}
+	}
+	return false
+}
+
+// popStyle restores the style saved with pushSGR.
+func (t *tScreen) popStyle() {
+	t.TPuts(popSGR)
+	t.curstyle = t.pushedStyle
+	t.pushed = false
+}
+
+// repeatCell sends the character just drawn at x again, using REP, for as
+// many following cells as have the same content and need drawing.  It
+// returns the number of cells drawn this way.
+func (t *tScreen) repeatCell(x, y int) int {
+	mainc, combc, style, _ := t.cells.GetContent(x, y)
+	if len(combc) != 0 || mainc < ' ' || mainc >= 0x7f || t.cx != x+1 || t.cy != y {
+		return 0
+	}
+	end := t.w
+	if y == t.h-1 {
+		end-- // the last cell needs special care, leave it to drawCell
+	}
+	n := 0
+	for i := x + 1; i < end; i++ {
+		m, c, s, w := t.cells.GetContent(i, y)
+		if m != mainc || len(c) != 0 || s != style || w != 1 || !t.cells.Dirty(i, y) {
+			break
+		}
+		n++
+	}
+	if n < repeatMin {
+		return 0
+	}
+	t.TPuts(t.ti.TParm(t.repeatChar, n))
+	for i := x + 1; i <= x+n; i++ {
+		t.cells.SetDirty(i, y, false)
+	}
+	t.cx += n
+	return n
+}
+
+// scrollUp scrolls the display if most of the content has moved up by
+// whole lines since it was last drawn, so that less needs to be drawn.
+func (t *tScreen) scrollUp() {
+	if t.clear || t.opts.InlineRows != 0 || t.h < 4 {
+		return
+	}
+	same := 0
+	for y := 0; y < t.h; y++ {
+		if t.cells.rowShown(y, y) {
+			same++
+		}
+	}
+	if same >= t.h/2 {
+		return
+	}
+	best, most := 0, 0
+	for n := 1; n <= t.h/2; n++ {
+		rows := 0
+		for y := 0; y+n < t.h; y++ {
+			if t.cells.rowShown(y, y+n) {
+				rows++
+			}
+		}
+		if rows > most {
+			best, most = n, rows
+		}
+	}
+	if most < t.h/2 || most <= same+best {
+		return
+	}
+	// new lines get the default colors
+	t.TPuts(t.ti.AttrOff)
+	t.TPuts(t.exitUrl)
+	t.curstyle = styleInvalid
+	t.moveTo(0, t.h-1)
+	t.writeString(strings.Repeat("\n", best))
+	t.cx, t.cy = -1, -1
+	t.cells.scrolled(best)
+}
+
 func (t *tScreen) ShowCursor(x, y int) {
 	t.Lock()
 	t.cursorx = x
@@ -966,6 +1447,14 @@ func (t *tScreen) HideCursor() {
 	t.ShowCursor(-1, -1)
 }
 
+func (t *tScreen) getCursor() (int, int, CursorStyle, Color) {
+	return t.cursorx, t.cursory, t.cursorStyle, t.cursorColor
+}
+
+func (t *tScreen) getStyle() Style {
+	return t.style
+}
+
 func (t *tScreen) showCursor() {
 
 	x, y := t.cursorx, t.cursory
@@ -974,7 +1463,7 @@ func (t *tScreen) showCursor() {
 		t.hideCursor()
 		return
 	}
-	t.TPuts(t.ti.TGoto(x, y))
+	t.moveTo(x, y)
 	t.TPuts(t.ti.ShowCursor)
 	if t.cursorStyles != nil {
 		if esc, ok := t.cursorStyles[t.cursorStyle]; ok {
@@ -1003,91 +1492,341 @@ func (t *tScreen) writeString(s string) {
 	if t.buffering {
 		_, _ = io.WriteString(&t.buf, s)
 	} else {
-		_, _ = io.WriteString(t.tty, s)
+		_, _ = io.WriteString(ttyWriter{t}, s)
 	}
 }
 
-func (t *tScreen) TPuts(s string) {
-	if t.buffering {
-		t.ti.TPuts(&t.buf, s)
-	} else {
-		t.ti.TPuts(t.tty, s)
-	}
+// ttyWriter writes to the terminal of a screen, with tScreen.write.
+type ttyWriter struct {
+	t *tScreen
 }
 
-func (t *tScreen) Show() {
-	t.Lock()
-	if !t.fini {
-		t.resize()
-		t.draw()
+func (w ttyWriter) Write(b []byte) (int, error) {
+	return w.t.write(b)
+}
+
+// write sends b to the terminal.  Once the terminal has failed, nothing
+// more is sent to it until it is started again, so that one that has gone
+// away is not written to over and over.
+func (t *tScreen) write(b []byte) (int, error) {
+	if err := t.Err(); err != nil {
+		return 0, err
 	}
-	t.Unlock()
+	n, err := t.tty.Write(b)
+	if err != nil {
+		t.failed(err)
+	}
+	return n, err
 }
 
-func (t *tScreen) clearScreen() {
-	t.TPuts(t.ti.AttrOff)
-	t.TPuts(t.exitUrl)
-	_ = t.sendFgBg(t.style.fg, t.style.bg, AttrNone)
-	t.TPuts(t.ti.Clear)
-	t.clear = false
+// failed records a failure of the terminal, to be returned by Err and
+// posted as an EventError.  Only the first failure is recorded.  With the
+// Recover display option, we then try to start the terminal again.
+func (t *tScreen) failed(err error) {
+	t.ioLock.Lock()
+	first := t.ioErr == nil
+	if first {
+		t.ioErr = err
+	}
+	recovering := t.recovering
+	t.ioLock.Unlock()
+	if !first {
+		return
+	}
+	logDebug("terminal failed", "error", err)
+	go t.postEvent(NewEventError(err))
+	if !recovering {
+		go t.recoverTty()
+	}
 }
 
-func (t *tScreen) hideCursor() {
-	// does not update cursor position
-	if t.ti.HideCursor != "" {
-		t.TPuts(t.ti.HideCursor)
-	} else {
-		// No way to hide cursor, stick it
-		// at bottom right of screen
-		t.cx, t.cy = t.cells.Size()
-		t.TPuts(t.ti.TGoto(t.cx, t.cy))
+// readError returns the error for a failure to read from the terminal,
+// which is ErrHangup if the terminal has gone away.
+func readError(err error) error {
+	if err == io.EOF || errors.Is(err, syscall.EIO) {
+		return fmt.Errorf("%w: %v", ErrHangup, err)
 	}
+	return err
 }
 
-func (t *tScreen) draw() {
-	// clobber cursor position, because we're going to change it all
-	t.cx = -1
-	t.cy = -1
-	// make no style assumptions
-	t.curstyle = styleInvalid
+// recoverTries is how many times recoverTty tries to start the terminal.
+const recoverTries = 5
 
-	t.buf.Reset()
-	t.buffering = true
+// recoverTty starts the terminal again after it failed, if the Recover
+// display option is set, as Suspend and Resume would.  It tries a few
+// times, waiting longer each time.  If it works, the whole screen is drawn
+// again, and Err returns nil.  Otherwise the screen is left suspended, and
+// the application can try again with Resume.
+func (t *tScreen) recoverTty() {
+	t.Lock()
+	retry := t.opts.Recover
+	t.Unlock()
+	if !retry {
+		return
+	}
+	t.ioLock.Lock()
+	t.recovering = true
+	t.ioLock.Unlock()
 	defer func() {
-		t.buffering = false
+		t.ioLock.Lock()
+		t.recovering = false
+		t.ioLock.Unlock()
 	}()
 
-	// hide the cursor while we move stuff around
-	t.hideCursor()
-
-	if t.clear {
-		t.clearScreen()
-	}
-
-	for y := 0; y < t.h; y++ {
-		for x := 0; x < t.w; x++ {
-			width := t.drawCell(x, y)
-			if width > 1 {
-				if x+1 < t.w {
-					// this is necessary so that if we ever
-					// go back to drawing that cell, we
-					// actually will *draw* it.
-					t.cells.SetDirty(x+1, y, true)
-				}
+	delay := time.Millisecond * 100
+	for i := 0; i < recoverTries; i++ {
+		time.Sleep(delay)
+		delay *= 2
+		var err error
+		if i == 0 {
+			err = t.life.restart(t.disengage, t.resume)
+		} else {
+			err = t.Resume()
+		}
+		if err == ErrScreenClosed {
+			return
+		}
+		if err == nil {
+			err = t.Err()
+		}
+		logDebug("terminal recovery", "attempt", i+1, "error", err)
+		if err == nil {
+			t.Lock()
+			running := t.running
+			t.Unlock()
+			if running {
+				t.Sync()
 			}
-			x += width - 1
+			return
 		}
 	}
+}
 
-	// restore the cursor
-	t.showCursor()
+func (t *tScreen) Err() error {
+	t.ioLock.Lock()
+	defer t.ioLock.Unlock()
+	return t.ioErr
+}
 
-	_, _ = t.buf.WriteTo(t.tty)
+// moveTo moves the cursor to the given location, which is relative
+// to the screen region (which differs from the display in inline mode).
+func (t *tScreen) moveTo(x, y int) {
+	t.TPuts(t.ti.TGoto(x, y+t.yoff))
 }
 
-func (t *tScreen) EnableMouse(flags ...MouseFlags) {
-	var f MouseFlags
-	flagsPresent := false
+func (t *tScreen) TPuts(s string) {
+	if t.buffering {
+		t.ti.TPuts(&t.buf, s)
+	} else {
+		t.ti.TPuts(ttyWriter{t}, s)
+	}
+}
+
+func (t *tScreen) Show() {
+	t.Lock()
+	if !t.fini {
+		t.resize()
+		t.draw()
+	}
+	t.Unlock()
+}
+
+func (t *tScreen) showCells(x, y, width int) {
+	t.Lock()
+	defer t.Unlock()
+	if t.fini {
+		return
+	}
+	w, h := t.w, t.h
+	t.resize()
+	if t.clear || t.w != w || t.h != h {
+		// everything has to be drawn anyway
+		t.draw()
+		return
+	}
+	if y < 0 || y >= t.h {
+		return
+	}
+	end := x + width
+	if x < 0 {
+		x = 0
+	}
+	if end > t.w {
+		end = t.w
+	}
+	if x > 0 {
+		// a wide character that covers the first cell is drawn whole
+		if _, _, _, cw := t.cells.GetContent(x-1, y); cw > 1 {
+			x--
+		}
+	}
+
+	t.cx, t.cy = -1, -1
+	t.curstyle = styleInvalid
+	t.buf.Reset()
+	t.buffering = true
+	defer func() {
+		t.buffering = false
+	}()
+	t.hideCursor()
+	opt := t.optimizations()
+	wideSkip := opt&OptimizeWideSkip != 0
+	t.styleStack = opt&OptimizeStyleStack != 0
+	for ; x < end; x++ {
+		cw := t.drawCell(x, y, wideSkip)
+		if cw > 1 && x+1 < t.w {
+			t.cells.SetDirty(x+1, y, true)
+		}
+		x += cw - 1
+	}
+	if t.pushed {
+		t.popStyle()
+	}
+	t.showCursor()
+	_, _ = t.buf.WriteTo(ttyWriter{t})
+	t.buf.Reset()
+}
+
+func (t *tScreen) clearScreen() {
+	t.TPuts(t.ti.AttrOff)
+	t.TPuts(t.exitUrl)
+	bg := t.style.bg
+	if t.style.attrs&AttrTransparent != 0 {
+		bg = ColorDefault
+	}
+	_ = t.sendFgBg(t.style.fg, bg, AttrNone)
+	if t.opts.InlineRows > 0 {
+		t.moveTo(0, 0)
+		t.TPuts(clearToEnd)
+	} else {
+		t.TPuts(t.ti.Clear)
+	}
+	t.clear = false
+}
+
+func (t *tScreen) hideCursor() {
+	// does not update cursor position
+	if t.ti.HideCursor != "" {
+		t.TPuts(t.ti.HideCursor)
+	} else {
+		// No way to hide cursor, stick it
+		// at bottom right of screen
+		t.cx, t.cy = t.cells.Size()
+		t.moveTo(t.cx, t.cy)
+	}
+}
+
+func (t *tScreen) draw() {
+	// clobber cursor position, because we're going to change it all
+	t.cx = -1
+	t.cy = -1
+	// make no style assumptions
+	t.curstyle = styleInvalid
+
+	t.buf.Reset()
+	t.buffering = true
+	defer func() {
+		t.buffering = false
+	}()
+
+	// hide the cursor while we move stuff around
+	t.hideCursor()
+
+	if t.clear {
+		t.clearScreen()
+	}
+
+	opt := t.optimizations()
+	if opt&OptimizeScroll != 0 {
+		t.scrollUp()
+	}
+	wideSkip := opt&OptimizeWideSkip != 0
+	repeat := opt&OptimizeRepeat != 0 && t.repeatChar != ""
+	t.styleStack = opt&OptimizeStyleStack != 0
+
+	for y := 0; y < t.h; y++ {
+		for x := 0; x < t.w; x++ {
+			dirty := t.cells.Dirty(x, y)
+			width := t.drawCell(x, y, wideSkip)
+			if dirty && width == 1 && repeat {
+				x += t.repeatCell(x, y)
+			}
+			if width > 1 {
+				if x+1 < t.w {
+					// this is necessary so that if we ever
+					// go back to drawing that cell, we
+					// actually will *draw* it.
+					t.cells.SetDirty(x+1, y, true)
+				}
+			}
+			x += width - 1
+		}
+	}
+
+	if t.pushed {
+		t.popStyle()
+	}
+
+	t.drawMarks()
+
+	// restore the cursor
+	t.showCursor()
+
+	_, _ = t.buf.WriteTo(ttyWriter{t})
+	t.buf.Reset() // in case the write failed
+}
+
+// semanticMark is a pending shell integration mark.
+type semanticMark struct {
+	x, y   int
+	mark   SemanticMark
+	status int
+}
+
+func (t *tScreen) AddSemanticMark(x, y int, mark SemanticMark, status int) {
+	t.Lock()
+	if t.semanticMark != "" {
+		t.marks = append(t.marks, semanticMark{x: x, y: y, mark: mark, status: status})
+	}
+	t.Unlock()
+}
+
+// drawMarks sends any pending shell integration marks.
+func (t *tScreen) drawMarks() {
+	if len(t.marks) == 0 {
+		return
+	}
+	for _, m := range t.marks {
+		if m.x < 0 || m.y < 0 || m.x >= t.w || m.y >= t.h {
+			continue
+		}
+		var param string
+		switch m.mark {
+		case MarkPromptStart:
+			param = "A"
+		case MarkCommandStart:
+			param = "B"
+		case MarkOutputStart:
+			param = "C"
+		case MarkCommandEnd:
+			param = "D"
+			if m.status >= 0 {
+				param += ";" + strconv.Itoa(m.status)
+			}
+		default:
+			continue
+		}
+		t.moveTo(m.x, m.y)
+		t.TPuts(t.ti.TParm(t.semanticMark, param))
+	}
+	t.marks = nil
+	// the cursor was moved
+	t.cx, t.cy = -1, -1
+}
+
+func (t *tScreen) EnableMouse(flags ...MouseFlags) {
+	var f MouseFlags
+	flagsPresent := false
 	for _, flag := range flags {
 		f |= flag
 		flagsPresent = true
@@ -1099,36 +1838,124 @@ func (t *tScreen) EnableMouse(flags ...MouseFlags) {
 	t.Lock()
 	t.mouseFlags = f
 	t.enableMouse(f)
+	t.writeRestore()
 	t.Unlock()
 }
 
+// mouseMode returns the XTerm tracking mode for the flags: 1003 reports
+// all motion, 1002 only motion with a button held, and 1000 only presses
+// and releases.  Each mode includes those below it, so only the highest
+// one asked for is needed.
+func mouseMode(f MouseFlags) int {
+	switch {
+	case f&MouseMotionEvents != 0:
+		return 1003
+	case f&MouseDragEvents != 0:
+		return 1002
+	case f&MouseButtonEvents != 0:
+		return 1000
+	}
+	return 0
+}
+
 func (t *tScreen) enableMouse(f MouseFlags) {
+	// The DEC locator replaces XTerm mouse tracking.  Terminals that have
+	// both only report one way, and turning either off turns off both, so
+	// the one in use is turned off before the other is turned on.
+	locator := f != 0 && t.inputOpts.Locator
+	xf := f
+	if locator {
+		xf = 0
+	} else {
+		t.enableLocator(false)
+	}
 	// Rather than using terminfo to find mouse escape sequences, we rely on the fact that
 	// pretty much *every* terminal that supports mouse tracking follows the
 	// XTerm standards (the modern ones).
 	if len(t.mouse) != 0 {
-		// start by disabling all tracking.
-		t.TPuts("\x1b[?1000l\x1b[?1002l\x1b[?1003l\x1b[?1006l")
-		if f&MouseButtonEvents != 0 {
-			t.TPuts("\x1b[?1000h")
+		urxvt := t.ident.MouseQuirks&MouseQuirkURXVT != 0
+		mode := mouseMode(xf)
+		old := t.mouseMode
+		if old < 0 {
+			// we don't know what the terminal has, so disable all tracking.
+			t.TPuts("\x1b[?1000l\x1b[?1002l\x1b[?1003l\x1b[?1006l")
+			if urxvt {
+				t.TPuts("\x1b[?1015l")
+			}
+			old = 0
 		}
-		if f&MouseDragEvents != 0 {
-			t.TPuts("\x1b[?1002h")
+		// Changing the level only switches the tracking mode, so that
+		// no reports are lost, and none of the wrong kind are sent, while
+		// the change is made.
+		if old != mode && old != 0 {
+			t.TPuts(fmt.Sprintf("\x1b[?%dl", old))
 		}
-		if f&MouseMotionEvents != 0 {
-			t.TPuts("\x1b[?1003h")
+		if old != mode && mode != 0 {
+			t.TPuts(fmt.Sprintf("\x1b[?%dh", mode))
 		}
-		if f&(MouseButtonEvents|MouseDragEvents|MouseMotionEvents) != 0 {
+		switch {
+		case old == 0 && mode != 0:
+			// SGR reports are preferred, where both are understood
+			if urxvt {
+				t.TPuts("\x1b[?1015h")
+			}
 			t.TPuts("\x1b[?1006h")
+		case old != 0 && mode == 0:
+			t.TPuts("\x1b[?1006l")
+			if urxvt {
+				t.TPuts("\x1b[?1015l")
+			}
 		}
+		t.mouseMode = mode
 	}
+	t.enableLocator(locator)
+	t.enableConsoleMouse(f)
+}
 
+// enableLocator turns the DEC locator (see InputOptions.Locator) on or
+// off.  It reports in character cells (DECELR), when buttons are pressed
+// and released (DECSLE).
+func (t *tScreen) enableLocator(on bool) {
+	if on == t.locator {
+		return
+	}
+	if on {
+		t.TPuts("\x1b[1;2'z\x1b[1;3'{")
+	} else {
+		t.TPuts("\x1b[0'z")
+	}
+	t.locator = on
+}
+
+// enableConsoleMouse starts or stops getting the mouse from the console
+// (see InputOptions.ConsoleMouse), for terminals without a mouse.
+func (t *tScreen) enableConsoleMouse(f MouseFlags) {
+	if f == 0 || !t.inputOpts.ConsoleMouse || len(t.mouse) != 0 {
+		if t.consoleMouse != nil {
+			t.consoleMouse.close()
+			t.consoleMouse = nil
+		}
+		return
+	}
+	if t.consoleMouse == nil {
+		cm, err := openConsoleMouse(t.tty)
+		if err != nil {
+			return
+		}
+		cm.size = t.Size
+		cm.post = t.postEvent
+		cm.x, cm.y = t.w/2, t.h/2
+		t.consoleMouse = cm
+		go cm.run()
+	}
+	t.consoleMouse.setFlags(f)
 }
 
 func (t *tScreen) DisableMouse() {
 	t.Lock()
 	t.mouseFlags = 0
 	t.enableMouse(0)
+	t.writeRestore()
 	t.Unlock()
 }
 
@@ -1172,49 +1999,587 @@ func (t *tScreen) DisableFocus() {
 	t.Unlock()
 }
 
-func (t *tScreen) enableFocusReporting() {
-	if t.enableFocus != "" {
-		t.TPuts(t.enableFocus)
+func (t *tScreen) enableFocusReporting() {
+	if t.enableFocus != "" {
+		t.TPuts(t.enableFocus)
+	}
+}
+
+func (t *tScreen) disableFocusReporting() {
+	if t.disableFocus != "" {
+		t.TPuts(t.disableFocus)
+	}
+}
+
+func (t *tScreen) Size() (int, int) {
+	t.Lock()
+	w, h := t.w, t.h
+	t.Unlock()
+	return w, h
+}
+
+func (t *tScreen) resize() {
+	ws, err := t.tty.WindowSize()
+	if err != nil {
+		return
+	}
+	yoff := t.inlineSize(&ws)
+	if ws.Width == t.w && ws.Height == t.h && yoff == t.yoff {
+		return
+	}
+	t.cx = -1
+	t.cy = -1
+
+	t.cells.Resize(ws.Width, ws.Height)
+	t.cells.Invalidate()
+	t.h = ws.Height
+	t.w = ws.Width
+	t.yoff = yoff
+	ev := &EventResize{t: time.Now(), ws: ws}
+	select {
+	case t.eventQ <- ev:
+	default:
+	}
+}
+
+// inlineSize adjusts the window size for inline mode, returning
+// the row offset of the screen region within the display.
+func (t *tScreen) inlineSize(ws *WindowSize) int {
+	rows := t.opts.InlineRows
+	if rows <= 0 || rows >= ws.Height {
+		return 0
+	}
+	yoff := ws.Height - rows
+	if t.anchored && t.yoff < yoff {
+		yoff = t.yoff
+	}
+	if ws.PixelHeight != 0 {
+		ws.PixelHeight = ws.PixelHeight * rows / ws.Height
+	}
+	ws.Height = rows
+	return yoff
+}
+
+// identify asks the terminal to identify itself (see TerminalID).  The
+// primary device attributes are asked for last, since all terminals we
+// know of reply to that, and replies come in order, so once that reply
+// has arrived we have all the replies we will get.  The replies are
+// waited for in the background (see awaitIdentity).  This can be disabled
+// by setting TCELL_IDENTIFY to "disable", in which case TerminalID only
+// has the zero value.  System consoles (see consoleTerms) are not asked,
+// as they do not reply.
+func (t *tScreen) identify() {
+	if t.identified || os.Getenv("TCELL_IDENTIFY") == "disable" || t.systemConsole() {
+		return
+	}
+	t.identified = true
+
+	q := make(chan struct{}, 1)
+	t.Lock()
+	t.daQ = q
+	t.ident.Capabilities = make(map[string]string)
+	caps := termCaps
+	if t.guessed {
+		caps = append(append([]string(nil), termCaps...), guessCaps...)
+	}
+	t.TPuts(queryVersion + queryKittyKeys + queryCursorStyle + xtgettcapQuery(caps) + queryClusters + queryDA2 + queryDA1)
+	t.Unlock()
+	go t.awaitIdentity(q)
+}
+
+// awaitIdentity waits for the replies to the queries of identify, which
+// are all in when the reply to DA1 arrives, as terminals answer in order,
+// and then uses them.  It runs in the background, so that Init is not
+// held up by terminals that are slow to answer, or do not answer at all.
+func (t *tScreen) awaitIdentity(q chan struct{}) {
+	select {
+	case <-q:
+	case <-t.quit:
+		t.Lock()
+		t.daQ = nil
+		t.Unlock()
+		return
+	case <-time.After(time.Millisecond * 250):
+		logDebug("no reply to device attributes query")
+	}
+
+	t.Lock()
+	t.daQ = nil
+	before := t.colorCount()
+	logDebug("terminal identified", "name", t.ident.Name, "version", t.ident.Version,
+		"class", t.ident.Class, "features", t.ident.Features, "capabilities", t.ident.Capabilities)
+	if t.guessed {
+		t.refineGuess()
+	}
+	t.applyTermCaps()
+	t.enableClusters()
+	if q := mouseQuirksFor(t.ti.Name, t.ident); q != t.ident.MouseQuirks {
+		logDebug("mouse quirks", "quirks", q)
+		if t.mouseFlags != 0 {
+			// the modes to disable depend on the old quirks
+			t.enableMouse(0)
+			t.ident.MouseQuirks = q
+			t.enableMouse(t.mouseFlags)
+		} else {
+			t.ident.MouseQuirks = q
+		}
+	}
+	colors := t.colorCount()
+	if colors != before {
+		// what is already drawn was in the colors we had before
+		t.clear = true
+		t.cells.Invalidate()
+	}
+	t.writeRestore()
+	t.Unlock()
+	if colors != before {
+		t.postEvent(NewEventColors(colors))
+	}
+}
+
+// enableClusters turns on grapheme clustering if the terminal supports it,
+// so that a cell holding a grapheme cluster (such as an emoji ZWJ sequence
+// or a flag) has the width the terminal gives it.  It can be disabled by
+// setting TCELL_GRAPHEMES to "disable".
+func (t *tScreen) enableClusters() {
+	if os.Getenv("TCELL_GRAPHEMES") == "disable" {
+		return
+	}
+	switch t.clusterMode {
+	case 1, 3: // set, or permanently set
+		t.cells.setClusters(true)
+	case 2: // reset
+		t.TPuts(enableClusters)
+		t.setClusters = true
+		t.cells.setClusters(true)
+	}
+}
+
+// applyTermCaps uses the capabilities that the terminal reported with
+// XTGETTCAP, in preference to guesses based on the terminfo entry.
+func (t *tScreen) applyTermCaps() {
+	caps := t.ident.Capabilities
+	if _, ok := caps["RGB"]; ok && !t.truecolor && os.Getenv("TCELL_TRUECOLOR") != "disable" {
+		t.addTrueColor()
+		t.truecolor = true
+	}
+	if smulx := caps["Smulx"]; smulx != "" {
+		t.doubleUnder = t.ti.TParm(smulx, 2)
+		t.curlyUnder = t.ti.TParm(smulx, 3)
+		t.dottedUnder = t.ti.TParm(smulx, 4)
+		t.dashedUnder = t.ti.TParm(smulx, 5)
+		if t.underFg == "" {
+			t.underFg = "\x1b[59m"
+		}
+	}
+	if _, ok := caps["Setulc"]; ok && t.underRGB == "" {
+		// Setulc takes the color as one number, but we pass the
+		// components separately, so use the standard sequences.
+		t.underColor = "\x1b[58:5:%p1%dm"
+		t.underRGB = "\x1b[58:2::%p1%d:%p2%d:%p3%dm"
+		t.underFg = "\x1b[59m"
+	}
+	if ms := caps["Ms"]; ms != "" && t.setClipboard == "" {
+		t.setClipboard = ms
+	}
+}
+
+func (t *tScreen) TerminalID() TerminalIdentity {
+	t.Lock()
+	defer t.Unlock()
+	id := t.ident
+	id.Features = append([]int(nil), id.Features...)
+	if id.Capabilities != nil {
+		id.Capabilities = make(map[string]string, len(t.ident.Capabilities))
+		for name, value := range t.ident.Capabilities {
+			id.Capabilities[name] = value
+		}
+	}
+	return id
+}
+
+func (t *tScreen) ProbeColors() {
+	t.Lock()
+	defer t.Unlock()
+	if !t.running || t.daQ != nil {
+		return
+	}
+	before := t.colorCount()
+	if t.ident.Capabilities == nil {
+		// the terminal is not to be asked (see identify)
+		if t.updateColors(before) {
+			go t.postEvent(NewEventColors(t.colorCount()))
+		}
+		return
+	}
+	caps := []string{"RGB"}
+	if t.guessed {
+		caps = append(caps, guessCaps...)
+	}
+	for _, name := range caps {
+		delete(t.ident.Capabilities, name)
+	}
+	q := make(chan struct{}, 1)
+	t.daQ = q
+	t.TPuts(xtgettcapQuery(caps) + queryDA1)
+	go t.awaitColors(q, before)
+}
+
+// awaitColors waits for the replies to the queries of ProbeColors, which
+// like identify knows it has them all when the reply to DA1 arrives.
+func (t *tScreen) awaitColors(q chan struct{}, before int) {
+	select {
+	case <-q:
+	case <-t.quit:
+		return
+	case <-time.After(time.Millisecond * 250):
+	}
+	t.Lock()
+	t.daQ = nil
+	changed := t.updateColors(before)
+	colors := t.colorCount()
+	t.Unlock()
+	if changed {
+		t.postEvent(NewEventColors(colors))
+	}
+}
+
+// updateColors works out the colors of the terminal again, from the
+// terminfo entry, the environment, and what the terminal reported, much
+// as when the screen was started.  If the number of colors is no longer
+// before, the screen is redrawn in full by the next Show, and it returns
+// true.  This is called with the lock held.
+func (t *tScreen) updateColors(before int) bool {
+	ti := t.ti
+	truecolor := ti.SetFgBgRGB != "" || ti.SetFgRGB != "" || ti.SetBgRGB != ""
+	switch t.getenv("COLORTERM") {
+	case "truecolor", "24bit", "24-bit":
+		truecolor = true
+	}
+	if _, ok := t.ident.Capabilities["RGB"]; ok {
+		truecolor = true
+	}
+	if os.Getenv("TCELL_TRUECOLOR") == "disable" {
+		truecolor = false
+	}
+	if truecolor && ti.SetFgBgRGB == "" && ti.SetFgRGB == "" && ti.SetBgRGB == "" {
+		t.addTrueColor()
+	}
+	t.truecolor = truecolor
+	if t.guessed {
+		t.refineGuess()
+	}
+	if t.colorCount() == before {
+		return false
+	}
+	t.clear = true
+	t.cells.Invalidate()
+	return true
+}
+
+func (t *tScreen) FeatureReport() map[Feature]Support {
+	t.Lock()
+	defer t.Unlock()
+	report := make(map[Feature]Support)
+	colorSupport(report, t.colorCount())
+	if t.ident.HasFeature(22) {
+		report[FeatureColor] = SupportConfirmed
+	}
+	report[FeatureMouse] = supportIf(len(t.mouse) != 0)
+	report[FeatureBracketedPaste] = supportIf(t.enablePaste != "")
+	report[FeatureFocus] = supportIf(t.enableFocus != "")
+	report[FeatureKittyKeyboard] = SupportNone
+	if t.kittyProto {
+		report[FeatureKittyKeyboard] = SupportConfirmed
+	}
+	report[FeatureGraphics] = SupportNone
+	if t.ident.HasFeature(4) {
+		report[FeatureGraphics] = SupportConfirmed
+	}
+	report[FeatureHyperlinks] = supportIf(t.enterUrl != "")
+	report[FeatureClipboard] = supportIf(t.setClipboard != "")
+	report[FeatureTitle] = supportIf(t.setTitle != "")
+	report[FeatureCursorStyle] = supportIf(len(t.cursorStyles) != 0)
+	report[FeatureStyledUnderline] = supportIf(t.curlyUnder != "")
+	report[FeatureUnderlineColor] = supportIf(t.underColor != "" || t.underRGB != "")
+	report[FeatureGraphemeClusters] = SupportNone
+	if t.cells.clusters {
+		report[FeatureGraphemeClusters] = SupportConfirmed
+	}
+	report[FeatureTabColor] = SupportNone
+	report[FeatureBadge] = SupportNone
+	if t.iterm() {
+		report[FeatureTabColor] = SupportAssumed
+		report[FeatureBadge] = SupportAssumed
+		if t.ident.Name == "iTerm2" {
+			report[FeatureTabColor] = SupportConfirmed
+			report[FeatureBadge] = SupportConfirmed
+		}
+	}
+	return report
+}
+
+// multiplexerWrap returns a function that wraps a sequence so that the
+// terminal multiplexer we are running in (if any) passes it through to the
+// outer terminal, or nil if we are not in a multiplexer.
+func (t *tScreen) multiplexerWrap() func(string) string {
+	if t.getenv("TMUX") != "" {
+		return tmuxWrap
+	}
+	if t.getenv("STY") != "" {
+		// GNU screen passes the contents of a DCS through unmodified.
+		return func(s string) string {
+			return "\x1bP" + s + "\x1b\\"
+		}
+	}
+	return nil
+}
+
+// resolveOuter asks the terminal outside of tmux or screen to identify
+// itself, when TCELL_OUTER_QUERY is set to "enable".  Inside a multiplexer
+// $TERM names the multiplexer, so that for example true color is not
+// enabled even if the outer terminal supports it.  A terminal that answers
+// the query (XTVERSION) is modern enough to support true color, and
+// multiplexers convert it as needed anyway.  This is opt-in, since the
+// multiplexer must be configured to let the query and reply through, and
+// otherwise Init is delayed waiting for the reply.
+func (t *tScreen) resolveOuter() {
+	wrap := t.multiplexerWrap()
+	if t.outerChecked || wrap == nil || os.Getenv("TCELL_OUTER_QUERY") != "enable" {
+		return
+	}
+	t.outerChecked = true
+
+	q := make(chan string, 1)
+	t.Lock()
+	t.xtverQ = q
+	t.TPuts(wrap(queryVersion))
+	t.Unlock()
+
+	name := ""
+	select {
+	case name = <-q:
+	case <-time.After(time.Millisecond * 250):
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	t.xtverQ = nil
+	if name == "" || t.truecolor || os.Getenv("TCELL_TRUECOLOR") == "disable" {
+		return
+	}
+	t.addTrueColor()
+	t.truecolor = true
+}
+
+// probeWidth writes the string at the start of the screen region, and
+// asks the terminal where the cursor ended up, to find out how many cells
+// the terminal really uses for it.  It returns -1 if the terminal does not
+// answer.  The screen contents are left damaged, so the caller should Sync
+// when done probing.
+func (t *tScreen) probeWidth(s string) int {
+	t.Lock()
+	if t.fini || t.charset != "UTF-8" {
+		t.Unlock()
+		return -1
+	}
+	q := make(chan cursorReport, 1)
+	t.cprQ = q
+	t.TPuts(t.ti.AttrOff)
+	t.moveTo(0, 0)
+	t.writeString(s)
+	t.TPuts(queryCursor)
+	t.cx, t.cy = -1, -1
+	t.Unlock()
+
+	width := -1
+	select {
+	case cr := <-q:
+		width = cr.x
+	case <-time.After(time.Millisecond * 500):
+	}
+
+	t.Lock()
+	t.cprQ = nil
+	t.Unlock()
+	return width
+}
+
+// addTrueColor adds the standard 24-bit color sequences to the terminfo.
+func (t *tScreen) addTrueColor() {
+	// copy, as the terminfo is shared
+	ti := *t.ti
+	ti.SetFgRGB = "\x1b[38;2;%p1%d;%p2%d;%p3%dm"
+	ti.SetBgRGB = "\x1b[48;2;%p1%d;%p2%d;%p3%dm"
+	ti.SetFgBgRGB = "\x1b[38;2;%p1%d;%p2%d;%p3%d;" +
+		"48;2;%p4%d;%p5%d;%p6%dm"
+	t.ti = &ti
+}
+
+// anchorInline positions the region of an inline screen at the cursor.
+// We have to ask the terminal where the cursor is, and wait for the reply,
+// which will be collected by the input loop.  If the terminal doesn't reply
+// in a reasonable time, we use the bottom of the display.
+func (t *tScreen) anchorInline() {
+	t.Lock()
+	if !t.anchored || t.opts.InlineRows <= 0 {
+		t.Unlock()
+		return
+	}
+	q := make(chan cursorReport, 1)
+	t.cprQ = q
+	t.TPuts(queryCursor)
+	t.Unlock()
+
+	row := -1
+	select {
+	case cr := <-q:
+		row = cr.y
+	case <-time.After(time.Millisecond * 500):
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	t.cprQ = nil
+	ws, err := t.tty.WindowSize()
+	if err != nil {
+		return
+	}
+	_, h := t.cells.Size()
+	if row < 0 || row+h > ws.Height {
+		// Scroll up to make room.
+		if row >= 0 {
+			t.TPuts(t.ti.TGoto(0, ws.Height-1))
+			for i := ws.Height - h; i < row; i++ {
+				t.writeString("\n")
+			}
+		}
+		row = ws.Height - h
+	}
+	t.yoff = row
+	t.cx, t.cy = -1, -1
+	t.cells.Invalidate()
+	t.clear = true
+}
+
+// altScreen returns true if the alternate screen buffer should be used.
+func (t *tScreen) altScreen() bool {
+	if t.opts.NoAltScreen || t.opts.InlineRows > 0 {
+		return false
+	}
+	return os.Getenv("TCELL_ALTSCREEN") != "disable"
+}
+
+func (t *tScreen) SetDisplayOptions(opts DisplayOptions) {
+	t.Lock()
+	t.opts = opts
+	t.Unlock()
+}
+
+func (t *tScreen) SetInputOptions(opts InputOptions) {
+	t.Lock()
+	t.inputOpts = opts
+	if t.running {
+		t.enableKittyKeys(kittyFlags(opts))
+		t.enableKeypad(opts.KeypadKeys)
+		t.enableMouse(t.mouseFlags)
+		t.writeRestore()
+	}
+	t.Unlock()
+}
+
+// kittyFlags returns the kitty keyboard protocol flags needed for the
+// input options.  These are to disambiguate keys (1) and report alternate
+// keys (4) for AlternateKeys.  ModifierKeys also needs the event types (2),
+// and all keys reported as escape codes (8), as otherwise the modifier
+// keys are not reported; the alternate keys give the shifted characters.
+func kittyFlags(opts InputOptions) int {
+	switch {
+	case opts.ModifierKeys:
+		return 1 | 2 | 4 | 8
+	case opts.AlternateKeys:
+		return 1 | 4
 	}
+	return 0
 }
 
-func (t *tScreen) disableFocusReporting() {
-	if t.disableFocus != "" {
-		t.TPuts(t.disableFocus)
+// enableKittyKeys pushes (or pops) the kitty keyboard protocol flags,
+// replacing those pushed before.
+func (t *tScreen) enableKittyKeys(flags int) {
+	if flags == t.kittyFlags {
+		return
+	}
+	if t.kittyFlags != 0 {
+		t.TPuts("\x1b[<u")
 	}
+	if flags != 0 {
+		t.TPuts(fmt.Sprintf("\x1b[>%du", flags))
+	}
+	t.kittyFlags = flags
+	t.kittyKeys = flags != 0
 }
 
-func (t *tScreen) Size() (int, int) {
-	t.Lock()
-	w, h := t.w, t.h
-	t.Unlock()
-	return w, h
+// enableKeypad puts the keypad in application mode (DECKPAM), or back in
+// numeric mode (DECKPNM).  Many terminfo entries put it in application
+// mode already, and leave it so.
+func (t *tScreen) enableKeypad(on bool) {
+	if on == t.keypadApp {
+		return
+	}
+	if !strings.Contains(t.ti.EnterKeypad, "\x1b=") {
+		if on {
+			t.TPuts("\x1b=")
+		} else {
+			t.TPuts("\x1b>")
+		}
+	}
+	t.keypadApp = on
 }
 
-func (t *tScreen) resize() {
-	ws, err := t.tty.WindowSize()
-	if err != nil {
-		return
+// keyDelay returns how long to wait for more input, given the unprocessed
+// input in buf.  Zero means to wait until more input arrives.
+func (t *tScreen) keyDelay(buf *bytes.Buffer) time.Duration {
+	t.Lock()
+	opts := t.inputOpts
+	t.Unlock()
+	delay := opts.EscapeDelay
+	if delay <= 0 {
+		delay = time.Millisecond * 50
 	}
-	if ws.Width == t.w && ws.Height == t.h {
-		return
+	if b := buf.Bytes(); len(b) == 1 && b[0] == '\x1b' {
+		return delay
 	}
-	t.cx = -1
-	t.cy = -1
+	if opts.WaitComplete {
+		return 0
+	}
+	if opts.SequenceDelay > 0 {
+		return opts.SequenceDelay
+	}
+	return delay
+}
 
-	t.cells.Resize(ws.Width, ws.Height)
-	t.cells.Invalidate()
-	t.h = ws.Height
-	t.w = ws.Width
-	ev := &EventResize{t: time.Now(), ws: ws}
-	select {
-	case t.eventQ <- ev:
-	default:
+// resetKeyTimer stops the key timer, and restarts it with the given
+// duration if that is non-zero.
+func (t *tScreen) resetKeyTimer(d time.Duration) {
+	if !t.keytimer.Stop() {
+		select {
+		case <-t.keytimer.C:
+		default:
+		}
+	}
+	if d > 0 {
+		t.keytimer.Reset(d)
 	}
 }
 
 func (t *tScreen) Colors() int {
-	// this doesn't change, no need for lock
+	t.Lock()
+	defer t.Unlock()
+	return t.colorCount()
+}
+
+// colorCount is Colors, with the lock held.
+func (t *tScreen) colorCount() int {
 	if t.truecolor {
 		return 1 << 24
 	}
@@ -1448,6 +2813,9 @@ func (t *tScreen) parseSgrMouse(buf *bytes.Buffer, evs *[]Event) (bool, bool) {
 
 			motion = (btn & 32) != 0
 			scroll = (btn & 0x42) == 0x40
+			if scroll && t.ident.MouseQuirks&MouseQuirkWheelMotion != 0 {
+				motion = false
+			}
 			btn &^= 32
 			if b[i] == 'm' {
 				// mouse release, clear all buttons
@@ -1462,7 +2830,7 @@ func (t *tScreen) parseSgrMouse(buf *bytes.Buffer, evs *[]Event) (bool, bool) {
 				 * We resolve these by looking for a non-motion
 				 * event first.
 				 */
-				if !t.buttondn {
+				if !t.buttondn && t.ident.MouseQuirks&MouseQuirkMotionButton != 0 {
 					btn |= 3
 					btn &^= 0x40
 				}
@@ -1512,6 +2880,21 @@ func (t *tScreen) parseFocus(buf *bytes.Buffer, evs *[]Event) (bool, bool) {
 	return true, false
 }
 
+// parseRaw reports any complete CSI, OSC, or DCS sequence as an EventRaw.
+// It must be called after the other parsers, so that it only sees the
+// sequences that they do not understand.
+func (t *tScreen) parseRaw(buf *bytes.Buffer, evs *[]Event) (bool, bool) {
+	n, ok := rawSequenceLen(buf.Bytes())
+	if !ok {
+		return false, false
+	}
+	if n == 0 {
+		return true, false
+	}
+	*evs = append(*evs, NewEventRaw(append([]byte(nil), buf.Next(n)...)))
+	return true, true
+}
+
 func (t *tScreen) parseClipboard(buf *bytes.Buffer, evs *[]Event) (bool, bool) {
 	b := buf.Bytes()
 	state := 0
@@ -1525,9 +2908,12 @@ func (t *tScreen) parseClipboard(buf *bytes.Buffer, evs *[]Event) (bool, bool) {
 		// definitely not a match
 		return false, false
 	}
+	if !bytes.HasPrefix(b, prefix) {
+		return false, false
+	}
 	b = b[len(prefix):]
 
-	for _, c := range b {
+	for i, c := range b {
 		// valid base64 digits
 		if state == 0 {
 			if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || (c == '+') || (c == '/') || (c == '=') {
@@ -1539,7 +2925,7 @@ func (t *tScreen) parseClipboard(buf *bytes.Buffer, evs *[]Event) (bool, bool) {
 			}
 			if c == '\a' {
 				// matched with BEL instead of ST
-				b = b[:len(b)-1] // drop the trailing BEL
+				b = b[:i] // drop the trailing BEL
 				decoded := make([]byte, base64.StdEncoding.DecodedLen(len(b)))
 				if num, err := base64.StdEncoding.Decode(decoded, b); err == nil {
 					*evs = append(*evs, NewEventClipboard(decoded[:num]))
@@ -1551,7 +2937,7 @@ func (t *tScreen) parseClipboard(buf *bytes.Buffer, evs *[]Event) (bool, bool) {
 		}
 		if state == 1 {
 			if c == '\\' {
-				b = b[:len(b)-2] // drop the trailing ST (\x1b\\)
+				b = b[:i-1] // drop the trailing ST (\x1b\\)
 				// now decode the data
 				decoded := make([]byte, base64.StdEncoding.DecodedLen(len(b)))
 				if num, err := base64.StdEncoding.Decode(decoded, b); err == nil {
@@ -1563,21 +2949,533 @@ func (t *tScreen) parseClipboard(buf *bytes.Buffer, evs *[]Event) (bool, bool) {
 			return false, false
 		}
 	}
-	// not enough data yet (not terminated)
-	return true, false
+	// not enough data yet (not terminated)
+	return true, false
+}
+
+// parseXtermMouse is like parseSgrMouse, but it parses a legacy
+// X11 mouse record.
+func (t *tScreen) parseXtermMouse(buf *bytes.Buffer, evs *[]Event) (bool, bool) {
+
+	b := buf.Bytes()
+
+	state := 0
+	btn := 0
+	x := 0
+	y := 0
+
+	for i := range b {
+		switch state {
+		case 0:
+			switch b[i] {
+			case '\x1b':
+				state = 1
+			case '\x9b':
+				state = 2
+			default:
+				return false, false
+			}
+		case 1:
+			if b[i] != '[' {
+				return false, false
+			}
+			state = 2
+		case 2:
+			if b[i] != 'M' {
+				return false, false
+			}
+			state++
+		case 3:
+			btn = int(b[i])
+			state++
+		case 4:
+			x = int(b[i]) - 32 - 1
+			state++
+		case 5:
+			y = int(b[i]) - 32 - 1
+			for i >= 0 {
+				_, _ = buf.ReadByte()
+				i--
+			}
+			*evs = append(*evs, t.buildMouseEvent(x, y, btn))
+			return true, true
+		}
+	}
+	return true, false
+}
+
+// parseURXVTMouse parses a mouse report of rxvt-unicode's mode 1015, which
+// is the legacy X11 record with the values in decimal, as
+// CSI Cb ; Cx ; Cy M.  As in the X11 record, the button has 32 added, and
+// release is reported as button 3.
+func (t *tScreen) parseURXVTMouse(buf *bytes.Buffer, evs *[]Event) (bool, bool) {
+	b := buf.Bytes()
+	state := 0
+	var params [3]int
+	np := 0
+	for i := range b {
+		switch state {
+		case 0:
+			switch b[i] {
+			case '\x1b':
+				state = 1
+			case '\x9b':
+				state = 2
+			default:
+				return false, false
+			}
+		case 1:
+			if b[i] != '[' {
+				return false, false
+			}
+			state = 2
+		case 2:
+			switch {
+			case b[i] >= '0' && b[i] <= '9':
+				params[np] = params[np]*10 + int(b[i]-'0')
+			case b[i] == ';' && np < len(params)-1:
+				np++
+			case b[i] == 'M' && np == len(params)-1:
+				buf.Next(i + 1)
+				*evs = append(*evs, t.buildMouseEvent(params[1]-1, params[2]-1, params[0]-32))
+				return true, true
+			default:
+				return false, false
+			}
+		}
+	}
+	return true, false
+}
+
+// parseLocator parses a DEC locator report (DECLRP), which is sent by
+// terminals that implement the DEC locator protocol (DECELR) rather than
+// (or as well as) xterm mouse tracking.  The report has the form
+// CSI Pe ; Pb ; Pr ; Pc ; Pp & w, where Pe is the event, Pb the buttons
+// held down, and Pr and Pc the (1-based) row and column.
+func (t *tScreen) parseLocator(buf *bytes.Buffer, evs *[]Event) (bool, bool) {
+	b := buf.Bytes()
+	state := 0
+	var params [5]int
+	np := 0
+	val := 0
+	for i := range b {
+		switch state {
+		case 0:
+			switch b[i] {
+			case '\x1b':
+				state = 1
+			case '\x9b':
+				state = 2
+			default:
+				return false, false
+			}
+		case 1:
+			if b[i] != '[' {
+				return false, false
+			}
+			state = 2
+		case 2:
+			switch {
+			case b[i] >= '0' && b[i] <= '9':
+				val = val*10 + int(b[i]-'0')
+			case b[i] == ';' && np < len(params)-1:
+				params[np] = val
+				np++
+				val = 0
+			case b[i] == '&':
+				params[np] = val
+				np++
+				state = 3
+			default:
+				return false, false
+			}
+		case 3:
+			// the report that the locator is unavailable has only Pe
+			if b[i] != 'w' || (np < 4 && params[0] != 0) {
+				return false, false
+			}
+			buf.Next(i + 1)
+			// event 0 means the locator is unavailable, and 10 means
+			// it left the filter rectangle; neither has a position
+			if pe := params[0]; pe < 1 || pe > 9 {
+				return true, true
+			}
+			button := ButtonNone
+			if params[1]&4 != 0 {
+				button |= Button1
+			}
+			if params[1]&1 != 0 {
+				button |= Button2
+			}
+			if params[1]&2 != 0 {
+				button |= Button3
+			}
+			if params[1]&8 != 0 {
+				button |= Button4
+			}
+			x, y := t.clip(params[3]-1, params[2]-1)
+			*evs = append(*evs, NewEventMouse(x, y, button, ModNone))
+			return true, true
+		}
+	}
+	return true, false
+}
+
+// kittyKeys maps the functional keys of the kitty keyboard protocol that we
+// can report.  Keypad keys are handled by keypadKeyEvent.
+var kittyKeys = map[int]Key{
+	9:   KeyTab,
+	13:  KeyEnter,
+	27:  KeyEsc,
+	127: KeyBackspace2,
+}
+
+// keypadKeys are the keys of the numeric keypad, in the order of the kitty
+// keyboard protocol, with the characters they produce, and the final
+// characters of the SS3 sequences they send in application mode.
+var keypadKeys = []struct {
+	key Key
+	ch  rune
+	ss3 byte
+}{
+	{KeyKP0, '0', 'p'},
+	{KeyKP1, '1', 'q'},
+	{KeyKP2, '2', 'r'},
+	{KeyKP3, '3', 's'},
+	{KeyKP4, '4', 't'},
+	{KeyKP5, '5', 'u'},
+	{KeyKP6, '6', 'v'},
+	{KeyKP7, '7', 'w'},
+	{KeyKP8, '8', 'x'},
+	{KeyKP9, '9', 'y'},
+	{KeyKPDecimal, '.', 'n'},
+	{KeyKPDivide, '/', 'o'},
+	{KeyKPMultiply, '*', 'j'},
+	{KeyKPSubtract, '-', 'm'},
+	{KeyKPAdd, '+', 'k'},
+	{KeyKPEnter, '\r', 'M'},
+	{KeyKPEqual, '=', 'X'},
+	{KeyKPSeparator, ',', 'l'},
+}
+
+// keypadKeyEvent returns the event for a key of the numeric keypad, which
+// is the keypad key itself only if distinct (the KeypadKeys input option).
+func keypadKeyEvent(k Key, mod ModMask, distinct bool) *EventKey {
+	ch := keypadKeys[k-KeyKP0].ch
+	switch {
+	case distinct:
+		return NewEventKey(k, ch, mod)
+	case k == KeyKPEnter:
+		return NewEventKey(KeyEnter, ch, mod)
+	}
+	return NewEventKey(KeyRune, ch, mod)
+}
+
+// parseKittyKey parses a key reported with the kitty keyboard protocol,
+// which has the form CSI code:shifted:base ; modifiers:event ; text u.
+// The code is the key's character without Shift in the current layout,
+// shifted is the character with Shift, and base is the key's character
+// on a US layout (when different from code).  We only expect these when
+// the protocol has been enabled.  With event types, the keys that have
+// legacy sequences, such as the arrow keys, are reported in those forms
+// with the event type added (CSI 1 ; modifiers:event A), which we parse
+// here as well.
+func (t *tScreen) parseKittyKey(buf *bytes.Buffer, evs *[]Event) (bool, bool) {
+	b := buf.Bytes()
+	state := 0
+	var params [2][3]int
+	field, sub := 0, 0
+	digits := false
+	events := false // the event type is present
+	for i := range b {
+		switch state {
+		case 0:
+			switch b[i] {
+			case '\x1b':
+				state = 1
+			case '\x9b':
+				state = 2
+			default:
+				return false, false
+			}
+		case 1:
+			if b[i] != '[' {
+				return false, false
+			}
+			state = 2
+		case 2:
+			switch {
+			case b[i] >= '0' && b[i] <= '9':
+				// the text field is not needed
+				if field < len(params) {
+					params[field][sub] = params[field][sub]*10 + int(b[i]-'0')
+				}
+				digits = true
+			case b[i] == ':' && (sub < 2 || field == 2):
+				if field < len(params) {
+					sub++
+				}
+				events = events || field == 1
+			case b[i] == ';' && field < 2:
+				field++
+				sub = 0
+			case b[i] == 'u' && digits:
+				buf.Next(i + 1)
+				if ev := kittyKeyEvent(params[0], params[1], t.inputOpts); ev != nil {
+					*evs = append(*evs, ev)
+				}
+				return true, true
+			case events && strings.IndexByte("ABCDEFHPQS~", b[i]) >= 0:
+				k, ok := kittyLegacyKey(b[i], params[0][0])
+				if !ok {
+					return false, false
+				}
+				buf.Next(i + 1)
+				if action := kittyAction(params[1][1]); action != KeyActionRelease {
+					ev := NewEventKey(k, 0, kittyMods(params[1][0]))
+					ev.action = action
+					*evs = append(*evs, ev)
+				}
+				return true, true
+			default:
+				return false, false
+			}
+		}
+	}
+	return true, false
+}
+
+// kittyLegacyKey returns the key for a legacy sequence of the kitty
+// keyboard protocol, from its final character, and number for "~".
+func kittyLegacyKey(final byte, num int) (Key, bool) {
+	if final != '~' {
+		k, ok := map[byte]Key{
+			'A': KeyUp, 'B': KeyDown, 'C': KeyRight, 'D': KeyLeft,
+			'E': KeyCenter, 'F': KeyEnd, 'H': KeyHome,
+			'P': KeyF1, 'Q': KeyF2, 'S': KeyF4,
+		}[final]
+		return k, ok
+	}
+	switch {
+	case num == 2:
+		return KeyInsert, true
+	case num == 3:
+		return KeyDelete, true
+	case num == 5:
+		return KeyPgUp, true
+	case num == 6:
+		return KeyPgDn, true
+	case num == 7:
+		return KeyHome, true
+	case num == 8:
+		return KeyEnd, true
+	case num >= 11 && num <= 15:
+		return KeyF1 + Key(num-11), true
+	case num >= 17 && num <= 21:
+		return KeyF6 + Key(num-17), true
+	case num == 23 || num == 24:
+		return KeyF11 + Key(num-23), true
+	}
+	return KeyNUL, false
+}
+
+// kittyMods returns the modifiers of a kitty keyboard protocol report.
+func kittyMods(m int) ModMask {
+	mod := ModNone
+	if m--; m > 0 {
+		if m&1 != 0 {
+			mod |= ModShift
+		}
+		if m&2 != 0 {
+			mod |= ModAlt
+		}
+		if m&4 != 0 {
+			mod |= ModCtrl
+		}
+		if m&(8|32) != 0 { // super or meta
+			mod |= ModMeta
+		}
+	}
+	return mod
+}
+
+// kittyAction returns the action for a kitty keyboard protocol event type.
+func kittyAction(e int) KeyAction {
+	switch e {
+	case 2:
+		return KeyActionRepeat
+	case 3:
+		return KeyActionRelease
+	}
+	return KeyActionPress
+}
+
+// kittyModifierKeys are the modifier keys of the kitty keyboard protocol,
+// from 57441, which are the left and then the right keys.
+var kittyModifierKeys = []Key{KeyShift, KeyCtrl, KeyAlt, KeyMeta, KeyMeta, KeyMeta}
+
+// kittyKeyEvent converts a kitty keyboard protocol report to a key event,
+// or returns nil for keys that we do not report (such as key releases, or
+// media keys).
+func kittyKeyEvent(key [3]int, mods [3]int, opts InputOptions) *EventKey {
+	code, shifted, base := key[0], key[1], key[2]
+	mod := kittyMods(mods[0])
+	action := kittyAction(mods[1])
+	if code >= 57441 && code <= 57452 {
+		if !opts.ModifierKeys {
+			return nil
+		}
+		ev := NewEventKey(kittyModifierKeys[(code-57441)%6], 0, mod)
+		ev.action = action
+		return ev
+	}
+	if action == KeyActionRelease {
+		return nil
+	}
+	if base == 0 && code < 0x80 {
+		base = code
+	}
+
+	var ev *EventKey
+	k, ok := kittyKeys[code]
+	switch {
+	case code >= 57399 && code <= 57416:
+		// keypad keys
+		ev = keypadKeyEvent(KeyKP0+Key(code-57399), mod, opts.KeypadKeys)
+	case ok:
+		if k == KeyTab && mod&ModShift != 0 {
+			k = KeyBacktab
+		}
+		ev = NewEventKey(k, rune(code), mod)
+	case code >= 57376 && code <= 57398:
+		ev = NewEventKey(KeyF13+Key(code-57376), 0, mod)
+	case code >= 0xe000 && code <= 0xf8ff:
+		// other functional keys, in the private use area
+		return nil
+	case code >= 'a' && code <= 'z' && mod&ModCtrl != 0:
+		// report these as control keys, the same as other terminals
+		ev = NewEventKey(KeyCtrlA+Key(code-'a'), rune(code-'a'+1), mod)
+	default:
+		ch := rune(code)
+		if shifted != 0 && mod&ModShift != 0 {
+			ch = rune(shifted)
+		} else if m := mods[0] - 1; m > 0 && m&64 != 0 && mod&ModShift == 0 {
+			// Caps Lock, when all keys are reported as escape codes
+			ch = unicode.ToUpper(ch)
+		}
+		ev = NewEventKey(KeyRune, ch, mod)
+	}
+	ev.base = rune(base)
+	ev.action = action
+	return ev
+}
+
+// parseVersion parses the reply to XTVERSION, which has the form
+// DCS > | text ST.  If we are asking the terminal outside of a multiplexer
+// (see resolveOuter), the reply is for that, otherwise it is the
+// identity of our terminal.
+func (t *tScreen) parseVersion(buf *bytes.Buffer) (bool, bool) {
+	b := buf.Bytes()
+	prefix := []byte("\x1bP>|")
+	if len(b) < len(prefix) {
+		if bytes.HasPrefix(prefix, b) {
+			return true, false
+		}
+		return false, false
+	}
+	if !bytes.HasPrefix(b, prefix) {
+		return false, false
+	}
+	end := bytes.Index(b, []byte("\x1b\\"))
+	if end < 0 {
+		return true, false
+	}
+	name := string(b[len(prefix):end])
+	buf.Next(end + 2)
+	if !utf8.ValidString(name) {
+		logDebug("invalid terminal version", "version", name)
+		name = ""
+	}
+	if t.xtverQ != nil {
+		select {
+		case t.xtverQ <- name:
+		default:
+		}
+		t.xtverQ = nil
+		return true, true
+	}
+	t.ident.Name, t.ident.Version = name, ""
+	if i := strings.IndexByte(name, '('); i > 0 && strings.HasSuffix(name, ")") {
+		t.ident.Name, t.ident.Version = name[:i], name[i+1:len(name)-1]
+	} else if i := strings.IndexByte(name, ' '); i > 0 {
+		t.ident.Name, t.ident.Version = name[:i], name[i+1:]
+	}
+	return true, true
 }
 
-// parseXtermMouse is like parseSgrMouse, but it parses a legacy
-// X11 mouse record.
-func (t *tScreen) parseXtermMouse(buf *bytes.Buffer, evs *[]Event) (bool, bool) {
+// parseClusterMode parses the reply to queryClusters, which has the form
+// CSI ? 2027 ; Ps $ y, where Ps is 0 if the mode is not recognized, 1 or 2
+// if it is set or reset, and 3 or 4 if it is permanently set or reset.
+func (t *tScreen) parseClusterMode(buf *bytes.Buffer) (bool, bool) {
+	b := buf.Bytes()
+	prefix := []byte("\x1b[?2027;")
+	if len(b) < len(prefix)+3 {
+		if bytes.HasPrefix(prefix, b) || bytes.HasPrefix(b, prefix) {
+			return true, false
+		}
+		return false, false
+	}
+	if !bytes.HasPrefix(b, prefix) {
+		return false, false
+	}
+	ps := b[len(prefix)]
+	if ps < '0' || ps > '4' || b[len(prefix)+1] != '$' || b[len(prefix)+2] != 'y' {
+		return false, false
+	}
+	t.clusterMode = int(ps - '0')
+	buf.Next(len(prefix) + 3)
+	return true, true
+}
 
+// parseCursorStyle parses the reply to queryCursorStyle, which has the
+// form DCS 1 $ r Ps SP q ST when the request is understood.
+func (t *tScreen) parseCursorStyle(buf *bytes.Buffer) (bool, bool) {
 	b := buf.Bytes()
+	prefix := []byte("\x1bP1$r")
+	if len(b) < len(prefix) {
+		if bytes.HasPrefix(prefix, b) {
+			return true, false
+		}
+		return false, false
+	}
+	if !bytes.HasPrefix(b, prefix) {
+		return false, false
+	}
+	end := bytes.Index(b, []byte("\x1b\\"))
+	if end < 0 {
+		return true, false
+	}
+	reply := string(b[len(prefix):end])
+	if !strings.HasSuffix(reply, " q") {
+		return false, false // some other setting
+	}
+	buf.Next(end + 2)
+	if v, err := strconv.Atoi(reply[:len(reply)-2]); err == nil && v >= 0 && v <= int(CursorStyleSteadyBar) {
+		// DECSCUSR values are the same as our styles
+		t.initCursor = CursorStyle(v)
+	}
+	return true, true
+}
 
+// parseDeviceAttrs parses the replies to the device attribute queries,
+// which are CSI ? Pp ; Pv ... c for the primary attributes, and
+// CSI > Pp ; Pv ; Pc c for the secondary ones.
+func (t *tScreen) parseDeviceAttrs(buf *bytes.Buffer) (bool, bool) {
+	b := buf.Bytes()
 	state := 0
-	btn := 0
-	x := 0
-	y := 0
-
+	var params []int
+	val := 0
+	secondary := false
 	for i := range b {
 		switch state {
 		case 0:
@@ -1595,24 +3493,98 @@ func (t *tScreen) parseXtermMouse(buf *bytes.Buffer, evs *[]Event) (bool, bool)
 			}
 			state = 2
 		case 2:
-			if b[i] != 'M' {
+			switch b[i] {
+			case '?':
+			case '>':
+				secondary = true
+			default:
 				return false, false
 			}
-			state++
+			state = 3
 		case 3:
-			btn = int(b[i])
-			state++
-		case 4:
-			x = int(b[i]) - 32 - 1
-			state++
-		case 5:
-			y = int(b[i]) - 32 - 1
-			for i >= 0 {
-				_, _ = buf.ReadByte()
-				i--
+			switch {
+			case b[i] >= '0' && b[i] <= '9':
+				val = val*10 + int(b[i]-'0')
+			case b[i] == ';':
+				params = append(params, val)
+				val = 0
+			case b[i] == 'u' && !secondary && len(params) == 0:
+				// the kitty keyboard flags, so the protocol is supported
+				buf.Next(i + 1)
+				t.kittyProto = true
+				return true, true
+			case b[i] == 'c':
+				params = append(params, val)
+				buf.Next(i + 1)
+				if secondary {
+					for len(params) < 3 {
+						params = append(params, 0)
+					}
+					t.ident.Type, t.ident.Firmware, t.ident.ROM = params[0], params[1], params[2]
+					return true, true
+				}
+				t.ident.Class, t.ident.Features = params[0], params[1:]
+				if t.daQ != nil {
+					select {
+					case t.daQ <- struct{}{}:
+					default:
+					}
+				}
+				return true, true
+			default:
+				return false, false
+			}
+		}
+	}
+	return true, false
+}
+
+// cursorReport is the cursor position reported by the terminal, with
+// zero based coordinates.
+type cursorReport struct {
+	x, y int
+}
+
+// parseCursorPosition parses a cursor position report, which we only
+// expect in response to a query, since it is ambiguous with some function
+// keys.
+func (t *tScreen) parseCursorPosition(buf *bytes.Buffer) (bool, bool) {
+	b := buf.Bytes()
+	state := 0
+	row, col := 0, 0
+	for i := range b {
+		switch state {
+		case 0:
+			if b[i] != '\x1b' {
+				return false, false
+			}
+			state = 1
+		case 1:
+			if b[i] != '[' {
+				return false, false
+			}
+			state = 2
+		case 2, 3:
+			switch {
+			case b[i] >= '0' && b[i] <= '9':
+				if state == 2 {
+					row = row*10 + int(b[i]-'0')
+				} else {
+					col = col*10 + int(b[i]-'0')
+				}
+			case b[i] == ';' && state == 2:
+				state = 3
+			case b[i] == 'R' && state == 3:
+				buf.Next(i + 1)
+				select {
+				case t.cprQ <- cursorReport{x: col - 1, y: row - 1}:
+				default:
+				}
+				t.cprQ = nil
+				return true, true
+			default:
+				return false, false
 			}
-			*evs = append(*evs, t.buildMouseEvent(x, y, btn))
-			return true, true
 		}
 	}
 	return true, false
@@ -1637,11 +3609,13 @@ func (t *tScreen) parseFunctionKey(buf *bytes.Buffer, evs *[]Event) (bool, bool)
 				mod |= ModAlt
 				t.escaped = false
 			}
-			switch k.key {
-			case keyPasteStart:
+			switch {
+			case k.key == keyPasteStart:
 				*evs = append(*evs, NewEventPaste(true))
-			case keyPasteEnd:
+			case k.key == keyPasteEnd:
 				*evs = append(*evs, NewEventPaste(false))
+			case k.key >= KeyKP0 && k.key <= KeyKPSeparator:
+				*evs = append(*evs, keypadKeyEvent(k.key, mod, t.inputOpts.KeypadKeys))
 			default:
 				*evs = append(*evs, NewEventKey(k.key, r, mod))
 			}
@@ -1704,10 +3678,28 @@ func (t *tScreen) parseRune(buf *bytes.Buffer, evs *[]Event) (bool, bool) {
 	return true, false
 }
 
-func (t *tScreen) scanInput(buf *bytes.Buffer, expire bool) {
+// inputChunk is a chunk of input, and the time it was read.
+type inputChunk struct {
+	data []byte
+	when time.Time
+}
+
+// scanInput delivers the events in buf.  Input events are stamped with
+// the time when the bytes were read, so that the time spent waiting in
+// our queues, or by the application, is visible.  (Neither the kitty
+// keyboard protocol nor the Windows input records report when the key
+// was pressed, so this is as close as we can get.)
+func (t *tScreen) scanInput(buf *bytes.Buffer, expire bool, when time.Time) {
 	evs := t.collectEventsFromInput(buf, expire)
 
+	// the array is reused, but the events should not be kept alive
+	defer func() {
+		for i := range evs {
+			evs[i] = nil
+		}
+	}()
 	for _, ev := range evs {
+		stampEvent(ev, when)
 		select {
 		case t.eventQ <- ev:
 		case <-t.quit:
@@ -1718,10 +3710,14 @@ func (t *tScreen) scanInput(buf *bytes.Buffer, expire bool) {
 
 // Return an array of Events extracted from the supplied buffer. This is done
 // while holding the screen's lock - the events can then be queued for
-// application processing with the lock released.
+// application processing with the lock released.  The array is reused by
+// the next call.
 func (t *tScreen) collectEventsFromInput(buf *bytes.Buffer, expire bool) []Event {
 
-	res := make([]Event, 0, 20)
+	res := t.evbuf[:0]
+	defer func() {
+		t.evbuf = res[:0]
+	}()
 
 	t.Lock()
 	defer t.Unlock()
@@ -1733,8 +3729,52 @@ func (t *tScreen) collectEventsFromInput(buf *bytes.Buffer, expire bool) []Event
 			return res
 		}
 
+		if t.skipString(buf, &res) {
+			continue
+		}
+
 		partials := 0
 
+		if t.cprQ != nil {
+			if part, comp := t.parseCursorPosition(buf); comp {
+				continue
+			} else if part {
+				partials++
+			}
+		}
+
+		if part, comp := t.parseVersion(buf); comp {
+			continue
+		} else if part {
+			partials++
+		}
+
+		if part, comp := t.parseDeviceAttrs(buf); comp {
+			continue
+		} else if part {
+			partials++
+		}
+
+		if part, comp := t.parseCursorStyle(buf); comp {
+			continue
+		} else if part {
+			partials++
+		}
+
+		if part, comp := t.parseClusterMode(buf); comp {
+			continue
+		} else if part {
+			partials++
+		}
+
+		if t.ident.Capabilities != nil {
+			if part, comp := parseXTGetTCap(buf, t.ident.Capabilities); comp {
+				continue
+			} else if part {
+				partials++
+			}
+		}
+
 		if part, comp := t.parseRune(buf, &res); comp {
 			continue
 		} else if part {
@@ -1753,6 +3793,30 @@ func (t *tScreen) collectEventsFromInput(buf *bytes.Buffer, expire bool) []Event
 			partials++
 		}
 
+		if t.locator || t.ident.MouseQuirks&MouseQuirkLocator != 0 {
+			if part, comp := t.parseLocator(buf, &res); comp {
+				continue
+			} else if part {
+				partials++
+			}
+		}
+
+		if t.kittyKeys {
+			if part, comp := t.parseKittyKey(buf, &res); comp {
+				continue
+			} else if part {
+				partials++
+			}
+		}
+
+		// key records from terminals in win32-input-mode, such as
+		// Windows Terminal when the console beneath it asked for them
+		if part, comp := parseWin32Input(buf, &res, t.inputOpts.ModifierKeys); comp {
+			continue
+		} else if part {
+			partials++
+		}
+
 		// Only parse mouse records if this term claims to have
 		// mouse support
 
@@ -1768,6 +3832,14 @@ func (t *tScreen) collectEventsFromInput(buf *bytes.Buffer, expire bool) []Event
 			} else if part {
 				partials++
 			}
+
+			if t.ident.MouseQuirks&MouseQuirkURXVT != 0 {
+				if part, comp := t.parseURXVTMouse(buf, &res); comp {
+					continue
+				} else if part {
+					partials++
+				}
+			}
 		}
 
 		if t.setClipboard != "" {
@@ -1778,7 +3850,26 @@ func (t *tScreen) collectEventsFromInput(buf *bytes.Buffer, expire bool) []Event
 			}
 		}
 
+		if len(t.inputOpts.OSC) > 0 {
+			if part, comp := parseOSC(buf, &res, t.inputOpts.OSC); comp {
+				continue
+			} else if part {
+				partials++
+			}
+		}
+
+		if t.inputOpts.RawSequences {
+			if part, comp := t.parseRaw(buf, &res); comp {
+				continue
+			} else if part {
+				partials++
+			}
+		}
+
 		if partials == 0 || expire {
+			if expire && partials > 0 {
+				logDebug("incomplete input expired", "input", string(b))
+			}
 			if b[0] == '\x1b' {
 				if len(b) == 1 {
 					res = append(res, NewEventKey(KeyEsc, 0, ModNone))
@@ -1814,6 +3905,7 @@ func (t *tScreen) collectEventsFromInput(buf *bytes.Buffer, expire bool) []Event
 func (t *tScreen) mainLoop(stopQ chan struct{}) {
 	defer t.wg.Done()
 	buf := &bytes.Buffer{}
+	var when time.Time // when the last input was read
 	for {
 		select {
 		case <-stopQ:
@@ -1835,37 +3927,41 @@ func (t *tScreen) mainLoop(stopQ chan struct{}) {
 			// then we assume the escape sequence reached its
 			// conclusion, and process the chunk independently.
 			// This lets us detect conflicts such as a lone ESC.
-			if buf.Len() > 0 {
-				if time.Now().After(t.keyexpire) {
-					t.scanInput(buf, true)
-				}
+			if buf.Len() > 0 && !time.Now().Before(t.keyexpire) {
+				t.scanInput(buf, true, when)
 			}
 			if buf.Len() > 0 {
-				if !t.keytimer.Stop() {
-					select {
-					case <-t.keytimer.C:
-					default:
-					}
-				}
-				t.keytimer.Reset(time.Millisecond * 50)
+				t.resetKeyTimer(time.Until(t.keyexpire))
 			}
 		case chunk := <-t.keychan:
-			buf.Write(chunk)
-			t.keyexpire = time.Now().Add(time.Millisecond * 50)
-			t.scanInput(buf, false)
-			if !t.keytimer.Stop() {
-				select {
-				case <-t.keytimer.C:
-				default:
-				}
+			buf.Write(chunk.data)
+			select {
+			case t.chunkQ <- chunk.data:
+			default:
+			}
+			when = chunk.when
+			now := time.Now()
+			t.scanInput(buf, false, when)
+			if buf.Len() > maxInputLength {
+				logDebug("input overflow", "length", buf.Len())
+				buf.Reset()
+				t.postEvent(NewEventError(ErrInputOverflow))
 			}
+			delay := time.Duration(0)
 			if buf.Len() > 0 {
-				t.keytimer.Reset(time.Millisecond * 50)
+				delay = t.keyDelay(buf)
+				t.keyexpire = now.Add(delay)
 			}
+			t.resetKeyTimer(delay)
 		}
 	}
 }
 
+// maxInputLength is the most input we hold while waiting for the end of
+// an escape sequence.  It is large, as replies with the contents of the
+// clipboard can be.
+const maxInputLength = 1 << 22
+
 func (t *tScreen) inputLoop(stopQ chan struct{}) {
 
 	defer t.wg.Done()
@@ -1875,8 +3971,14 @@ func (t *tScreen) inputLoop(stopQ chan struct{}) {
 			return
 		default:
 		}
-		chunk := make([]byte, 128)
-		n, e := t.tty.Read(chunk)
+		// reuse the chunks returned by mainLoop, to avoid garbage
+		var chunk []byte
+		select {
+		case chunk = <-t.chunkQ:
+		default:
+			chunk = make([]byte, 128)
+		}
+		n, e := t.tty.Read(chunk[:cap(chunk)])
 		switch e {
 		case nil:
 		default:
@@ -1884,15 +3986,12 @@ func (t *tScreen) inputLoop(stopQ chan struct{}) {
 			running := t.running
 			t.Unlock()
 			if running {
-				select {
-				case t.eventQ <- NewEventError(e):
-				case <-t.quit:
-				}
+				t.failed(readError(e))
 			}
 			return
 		}
 		if n > 0 {
-			t.keychan <- chunk[:n]
+			t.keychan <- inputChunk{data: chunk[:n], when: time.Now()}
 		}
 	}
 }
@@ -1917,12 +4016,14 @@ func (t *tScreen) CharacterSet() string {
 func (t *tScreen) RegisterRuneFallback(orig rune, fallback string) {
 	t.Lock()
 	t.fallback[orig] = fallback
+	delete(t.nofallback, orig)
 	t.Unlock()
 }
 
 func (t *tScreen) UnregisterRuneFallback(orig rune) {
 	t.Lock()
 	delete(t.fallback, orig)
+	t.nofallback[orig] = true
 	t.Unlock()
 }
 
@@ -1950,7 +4051,7 @@ func (t *tScreen) CanDisplay(r rune, checkFallbacks bool) bool {
 	if _, ok := t.fallback[r]; ok {
 		return true
 	}
-	return false
+	return runes.FallbackFor(t.charset, r) != "" && !t.nofallback[r]
 }
 
 func (t *tScreen) HasMouse() bool {
@@ -1975,12 +4076,22 @@ func (t *tScreen) SetSize(w, h int) {
 func (t *tScreen) Resize(int, int, int, int) {}
 
 func (t *tScreen) Suspend() error {
-	t.disengage()
-	return nil
+	return t.life.suspend(func() error {
+		t.disengage()
+		return nil
+	})
 }
 
 func (t *tScreen) Resume() error {
-	return t.engage()
+	return t.life.resume(t.resume)
+}
+
+func (t *tScreen) resume() error {
+	if err := t.engage(); err != nil {
+		return err
+	}
+	t.anchorInline()
+	return nil
 }
 
 func (t *tScreen) Tty() (Tty, bool) {
@@ -2008,20 +4119,30 @@ func (t *tScreen) engage() error {
 	if err := t.tty.Start(); err != nil {
 		return err
 	}
+	t.ioLock.Lock()
+	t.ioErr = nil
+	t.ioLock.Unlock()
 	t.running = true
 	if ws, err := t.tty.WindowSize(); err == nil && ws.Width != 0 && ws.Height != 0 {
+		t.yoff = t.inlineSize(&ws)
 		t.cells.Resize(ws.Width, ws.Height)
 	}
 	stopQ := make(chan struct{})
 	t.stopQ = stopQ
+	t.mouseMode = -1
 	t.enableMouse(t.mouseFlags)
 	t.enablePasting(t.pasteEnabled)
 	if t.focusEnabled {
 		t.enableFocusReporting()
 	}
+	t.enableKittyKeys(kittyFlags(t.inputOpts))
+	t.enableKeypad(t.inputOpts.KeypadKeys)
+	if t.setClusters {
+		t.TPuts(enableClusters)
+	}
 
 	ti := t.ti
-	if os.Getenv("TCELL_ALTSCREEN") != "disable" {
+	if t.altScreen() {
 		// Technically this may not be right, but every terminal we know about
 		// (even Wyse 60) uses this to enter the alternate screen buffer, and
 		// possibly save and restore the window title and/or icon.
@@ -2036,10 +4157,26 @@ func (t *tScreen) engage() error {
 	t.TPuts(ti.HideCursor)
 	t.TPuts(ti.EnableAcs)
 	t.TPuts(ti.DisableAutoMargin)
-	t.TPuts(ti.Clear)
+	if t.opts.InlineRows > 0 {
+		if !t.anchored {
+			// Scroll the existing content up to make room for our region.
+			// (Anchored regions are positioned later by anchorInline.)
+			_, h := t.cells.Size()
+			t.moveTo(0, h-1)
+			for i := 0; i < h; i++ {
+				t.writeString("\n")
+			}
+			t.moveTo(0, 0)
+			t.TPuts(clearToEnd)
+		}
+	} else if !t.opts.NoClear {
+		t.TPuts(ti.Clear)
+	}
 	if t.title != "" && t.setTitle != "" {
 		t.TPuts(t.ti.TParm(t.setTitle, t.title))
 	}
+	t.sendTabExtras(false)
+	t.writeRestore()
 
 	t.wg.Add(2)
 	go t.inputLoop(stopQ)
@@ -2070,32 +4207,88 @@ func (t *tScreen) disengage() {
 
 	// shutdown the screen and disable special modes (e.g. mouse and bracketed paste)
 	ti := t.ti
+	_, h := t.cells.Size()
 	t.cells.Resize(0, 0)
 	t.TPuts(ti.ShowCursor)
-	if t.cursorStyles != nil && t.cursorStyle != CursorStyleDefault {
-		t.TPuts(t.cursorStyles[CursorStyleDefault])
+	if t.cursorStyles != nil && t.cursorStyle != t.initCursor {
+		t.TPuts(t.cursorStyles[t.initCursor])
 	}
 	if t.cursorFg != "" && t.cursorColor.Valid() {
 		t.TPuts(t.cursorFg)
 	}
 	t.TPuts(ti.ResetFgBg)
 	t.TPuts(ti.AttrOff)
-	t.TPuts(ti.ExitKeypad)
 	t.TPuts(ti.EnableAutoMargin)
-	if os.Getenv("TCELL_ALTSCREEN") != "disable" {
+	t.sendTabExtras(true)
+	if t.altScreen() {
 		if t.restoreTitle != "" {
 			t.TPuts(t.restoreTitle)
 		}
 		t.TPuts(ti.Clear) // only needed if ExitCA is empty
 		t.TPuts(ti.ExitCA)
+	} else if t.opts.KeepOnExit {
+		// leave the cursor on a fresh line below the final frame
+		if h > 0 {
+			t.moveTo(0, h-1)
+		}
+		t.writeString("\r\n")
+	} else if t.opts.InlineRows > 0 {
+		t.moveTo(0, 0)
+		t.TPuts(clearToEnd)
+	} else if t.opts.NoAltScreen {
+		t.TPuts(ti.Clear)
 	}
-	t.enableMouse(0)
-	t.enablePasting(false)
-	t.disableFocusReporting()
+	t.TPuts(t.modesOff())
+	t.keypadApp = false
+	t.mouseMode = 0
+	t.locator = false
+	t.enableConsoleMouse(0)
+	t.kittyFlags = 0
+	t.kittyKeys = false
 
 	_ = t.tty.Stop()
 }
 
+// modesOff returns the sequences that turn off the modes that are on: the
+// application keypad, mouse tracking and the DEC locator, bracketed paste,
+// focus reporting, the kitty keyboard flags and grapheme clustering.  The
+// modes are not marked as off; disengage does that after sending these.
+// They are also part of the restore sequence (see writeRestore).
+func (t *tScreen) modesOff() string {
+	ti := t.ti
+	buf := &bytes.Buffer{}
+	if t.keypadApp && !strings.Contains(ti.EnterKeypad, "\x1b=") {
+		_, _ = buf.WriteString("\x1b>")
+	}
+	ti.TPuts(buf, ti.ExitKeypad)
+	if len(t.mouse) != 0 {
+		urxvt := t.ident.MouseQuirks&MouseQuirkURXVT != 0
+		switch {
+		case t.mouseMode < 0:
+			_, _ = buf.WriteString("\x1b[?1000l\x1b[?1002l\x1b[?1003l\x1b[?1006l")
+		case t.mouseMode > 0:
+			_, _ = fmt.Fprintf(buf, "\x1b[?%dl\x1b[?1006l", t.mouseMode)
+		default:
+			urxvt = false
+		}
+		if urxvt {
+			_, _ = buf.WriteString("\x1b[?1015l")
+		}
+	}
+	if t.locator {
+		_, _ = buf.WriteString("\x1b[0'z")
+	}
+	ti.TPuts(buf, t.disablePaste)
+	ti.TPuts(buf, t.disableFocus)
+	if t.kittyFlags != 0 {
+		_, _ = buf.WriteString("\x1b[<u")
+	}
+	if t.setClusters {
+		_, _ = buf.WriteString(disableClusters)
+	}
+	return buf.String()
+}
+
 // Beep emits a beep to the terminal.
 func (t *tScreen) Beep() error {
 	t.writeString(string(byte(7)))
@@ -2135,7 +4328,13 @@ func (t *tScreen) SetClipboard(data []byte) {
 	t.Lock()
 	if t.setClipboard != "" {
 		encoded := base64.StdEncoding.EncodeToString(data)
-		t.TPuts(t.ti.TParm(t.setClipboard, encoded))
+		seq := t.ti.TParm(t.setClipboard, encoded)
+		t.TPuts(seq)
+		if t.passthrough {
+			t.TPuts(tmuxWrap(seq))
+		}
+	} else if t.opts.SystemClipboard {
+		copySystemClipboard(data, t.opts, t.postEvent)
 	}
 	t.Unlock()
 }
@@ -2144,6 +4343,86 @@ func (t *tScreen) GetClipboard() {
 	t.Lock()
 	if t.setClipboard != "" {
 		t.TPuts(t.ti.TParm(t.setClipboard, "?"))
+	} else if t.opts.SystemClipboard {
+		pasteSystemClipboard(t.opts, t.postEvent)
+	}
+	t.Unlock()
+}
+
+func (t *tScreen) postEvent(ev Event) {
+	select {
+	case t.eventQ <- ev:
+	case <-t.quit:
+	}
+}
+
+func (t *tScreen) SendOSC(code int, data string) {
+	t.Lock()
+	if t.running {
+		t.putOSC(oscString(code, data))
+	}
+	t.Unlock()
+}
+
+func (t *tScreen) SetRestoreWriter(w io.Writer) {
+	t.Lock()
+	t.restoreW = w
+	t.restoreSent = ""
+	if t.running {
+		t.writeRestore()
 	}
 	t.Unlock()
 }
+
+// restoreTruncater is a restore writer (such as an *os.File) that can be
+// written again from the start.
+type restoreTruncater interface {
+	io.Seeker
+	Truncate(size int64) error
+}
+
+// writeRestore writes the restoration sequence to the restore writer, if
+// one is set and the sequence has changed since it was last written, as
+// it does when modes are turned on or off.  If the writer can be, it is
+// truncated first, otherwise the new sequence is written after the old,
+// which it supersedes.  Must be called with the lock held.
+func (t *tScreen) writeRestore() {
+	if t.restoreW == nil || !t.running {
+		return
+	}
+	str := t.restoreString()
+	if str == t.restoreSent {
+		return
+	}
+	if tr, ok := t.restoreW.(restoreTruncater); ok && t.restoreSent != "" {
+		if err := tr.Truncate(0); err == nil {
+			_, _ = tr.Seek(0, io.SeekStart)
+		}
+	}
+	t.restoreSent = str
+	_, _ = io.WriteString(t.restoreW, str)
+}
+
+// restoreString returns the escape sequences needed to return the terminal
+// to its normal state, which turn off the same modes as disengage does
+// (see modesOff), as well as restoring the cursor and the main screen.
+func (t *tScreen) restoreString() string {
+	ti := t.ti
+	buf := &bytes.Buffer{}
+	_, _ = buf.WriteString(t.modesOff())
+	ti.TPuts(buf, t.exitUrl)
+	ti.TPuts(buf, ti.ResetFgBg)
+	ti.TPuts(buf, ti.AttrOff)
+	if t.cursorStyles != nil {
+		ti.TPuts(buf, t.cursorStyles[t.initCursor])
+	}
+	ti.TPuts(buf, t.cursorFg)
+	ti.TPuts(buf, ti.ShowCursor)
+	ti.TPuts(buf, ti.EnableAutoMargin)
+	if t.altScreen() {
+		ti.TPuts(buf, t.restoreTitle)
+		ti.TPuts(buf, ti.Clear)
+		ti.TPuts(buf, ti.ExitCA)
+	}
+	return buf.String()
+}
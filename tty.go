@@ -54,3 +54,17 @@ type Tty interface {
 
 	io.ReadWriteCloser
 }
+
+// TtyEnviron may be implemented by a Tty that is not the terminal of this
+// process, such as one for a remote client, to supply the environment of
+// that terminal.  When it is implemented, variables describing the terminal
+// (such as TERM, COLORTERM, LANG, or TMUX) are looked up using Getenv rather
+// than in the environment of the process, so that several screens on
+// different kinds of terminal can be used at the same time.  Variables that
+// configure tcell itself (those starting with TCELL_) are always taken from
+// the process environment.
+type TtyEnviron interface {
+	// Getenv returns the value of the named variable in the terminal's
+	// environment, or the empty string if it is not set.
+	Getenv(name string) string
+}
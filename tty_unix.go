@@ -45,6 +45,10 @@ type devTty struct {
 	l     sync.Mutex
 }
 
+func (tty *devTty) device() string {
+	return tty.dev
+}
+
 func (tty *devTty) Read(b []byte) (int, error) {
 	return tty.f.Read(b)
 }
@@ -15,27 +15,100 @@
 package views
 
 import (
+	"os"
+	"os/signal"
 	"sync"
+	"sync/atomic"
+	"syscall"
 
 	"github.com/gdamore/tcell/v2"
 )
 
 // Application represents an event-driven application running on a screen.
+//
+// The application draws the root widget after handling events.  Events
+// that arrive together are all handled before drawing once.  With
+// SetRedrawOnDemand, it only draws when something may have changed.
+//
+// The terminal is restored when the application exits, even if it
+// panics (the panic then continues, so that it is reported as usual).
+// With SetQuitSignals, signals such as SIGINT make the application quit
+// gracefully, running the functions given to OnShutdown.
 type Application struct {
 	widget   Widget
 	screen   tcell.Screen
 	style    tcell.Style
+	theme    *tcell.Theme
 	err      error
 	wg       sync.WaitGroup
 	paste    bool
 	stopQ    chan struct{}
 	eventQ   chan tcell.Event
 	stopOnce sync.Once
+	redraw   appRedraw
+	onDemand bool
+	signals  []os.Signal
+	hooks    []func()
+}
+
+// appRedraw watches the root widget, noting when it needs to be drawn.
+type appRedraw struct {
+	dirty int32
+}
+
+func (r *appRedraw) HandleEvent(tcell.Event) bool {
+	r.mark()
+	return false
+}
+
+func (r *appRedraw) mark() {
+	atomic.StoreInt32(&r.dirty, 1)
+}
+
+// take returns true if a draw is needed, and clears the need.
+func (r *appRedraw) take() bool {
+	return atomic.SwapInt32(&r.dirty, 0) != 0
 }
 
 // SetRootWidget sets the primary (root, main) Widget to be displayed.
 func (app *Application) SetRootWidget(widget Widget) {
+	if app.widget != nil {
+		app.widget.Unwatch(&app.redraw)
+	}
 	app.widget = widget
+	if widget != nil {
+		widget.Watch(&app.redraw)
+	}
+	app.redraw.mark()
+}
+
+// SetRedrawOnDemand makes the application draw the root widget only when
+// something may have changed: when a widget handles an event (returning
+// true), or reports a change of its content or size, when a function
+// posted with PostFunc has run, and when the screen is resized.  This
+// avoids needless drawing, but widgets that change without doing one of
+// these must call Update.  It must be called before Start or Run.
+func (app *Application) SetRedrawOnDemand(on bool) {
+	app.onDemand = on
+}
+
+// SetQuitSignals sets the signals that make the application quit
+// gracefully, as if Quit were called.  If none are given, SIGINT and
+// SIGTERM are used.  By default the application does not handle signals,
+// leaving them to the program.  It must be called before Start or Run.
+func (app *Application) SetQuitSignals(sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	app.signals = append([]os.Signal(nil), sigs...)
+}
+
+// OnShutdown adds a function to be called when the application quits,
+// in the application's event loop, before the screen is finalized.  The
+// functions are called in the order they were added.  They are not called
+// if the application panics.
+func (app *Application) OnShutdown(fn func()) {
+	app.hooks = append(app.hooks, fn)
 }
 
 // EnablePaste enables or disables pasting support in the application.
@@ -72,8 +145,11 @@ func (app *Application) Refresh() {
 
 // Update asks the application to draw any screen updates that have not
 // been drawn yet.  It is not necessary to call this from inside an
-// event handler, as a draw will be done implicitly after handling events.
+// event handler, as a draw will be done implicitly after handling events
+// (with SetRedrawOnDemand, only if the handler returns true).  The root
+// widget is also drawn again, after the next event.
 func (app *Application) Update() {
+	app.redraw.mark()
 	if scr := app.screen; scr != nil {
 		scr.Show()
 	}
@@ -108,6 +184,57 @@ func (app *Application) SetStyle(style tcell.Style) {
 	}
 }
 
+// SetTheme sets the theme used by the application.  The default style of
+// the screen is set to the style for tcell.RoleNormal, and widgets that
+// have a style role (see for example Text.SetStyleRole) take their style
+// from the theme.  This can be called again while the application is
+// running, for example to switch between light and dark themes.
+func (app *Application) SetTheme(theme *tcell.Theme) {
+	app.theme = theme
+	if app.screen != nil && app.eventQ != nil {
+		app.screen.SetTheme(theme)
+	}
+}
+
+// handleEvent handles an event in the application loop, noting whether
+// the screen needs to be drawn again.
+func (app *Application) handleEvent(ev tcell.Event) {
+	switch nev := ev.(type) {
+	case *eventAppFunc:
+		nev.fn()
+		app.redraw.mark()
+	case *tcell.EventResize:
+		app.screen.Sync()
+		if app.widget != nil {
+			app.widget.Resize()
+		}
+		app.redraw.mark()
+	default:
+		if app.widget != nil && app.widget.HandleEvent(ev) || !app.onDemand {
+			app.redraw.mark()
+		}
+	}
+}
+
+// watchSignals makes the signals for quitting call Quit, until stopQ is
+// closed.
+func (app *Application) watchSignals(stopQ chan struct{}) {
+	sigs := app.signals
+	if len(sigs) == 0 {
+		return
+	}
+	sigQ := make(chan os.Signal, 1)
+	signal.Notify(sigQ, sigs...)
+	go func() {
+		defer signal.Stop(sigQ)
+		select {
+		case <-sigQ:
+			app.Quit()
+		case <-stopQ:
+		}
+	}()
+}
+
 func (app *Application) run() {
 
 	screen := app.screen
@@ -125,42 +252,63 @@ func (app *Application) run() {
 		screen = app.screen
 	}
 	defer func() {
+		// Restore the terminal even when panicking, so that the panic
+		// can be read.
+		r := recover()
+		if r == nil && app.err == nil {
+			for _, fn := range app.hooks {
+				fn()
+			}
+		}
 		screen.Fini()
 		app.wg.Done()
+		if r != nil {
+			panic(r)
+		}
 	}()
-	screen.Init()
+	if app.err = screen.Init(); app.err != nil {
+		return
+	}
 	screen.EnableMouse()
 	if app.paste {
 		screen.EnablePaste()
 	}
 	screen.Clear()
 	widget.SetView(screen)
+	if app.theme != nil {
+		screen.SetTheme(app.theme)
+	}
 
 	app.eventQ = make(chan tcell.Event, 16)
 	app.stopQ = make(chan struct{})
+	app.watchSignals(app.stopQ)
 	go screen.ChannelEvents(app.eventQ, app.stopQ)
+	app.redraw.mark()
 
-loop:
 	for {
 		if widget = app.widget; widget == nil {
 			break
 		}
-		widget.Draw()
-		screen.Show()
+		if app.redraw.take() {
+			widget.Draw()
+			screen.Show()
+		}
 
 		ev := <-app.eventQ
 		if ev == nil {
-			screen.Fini()
-			break loop
+			break
 		}
-		switch nev := ev.(type) {
-		case *eventAppFunc:
-			nev.fn()
-		case *tcell.EventResize:
-			screen.Sync()
-			widget.Resize()
-		default:
-			widget.HandleEvent(ev)
+		app.handleEvent(ev)
+		// handle the events already waiting before drawing again
+		for ev != nil {
+			select {
+			case ev = <-app.eventQ:
+				if ev != nil {
+					app.handleEvent(ev)
+				}
+			default:
+				ev = nil
+			}
 		}
 	}
 }
@@ -0,0 +1,167 @@
+// Copyright 2024 The Tcell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package views
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// drawCounter counts its draws, and handles the key 'h'.
+type drawCounter struct {
+	Text
+	draws int32
+}
+
+func (d *drawCounter) Draw() {
+	atomic.AddInt32(&d.draws, 1)
+	d.Text.Draw()
+}
+
+func (d *drawCounter) HandleEvent(ev tcell.Event) bool {
+	if ev, ok := ev.(*tcell.EventKey); ok {
+		return ev.Rune() == 'h'
+	}
+	return false
+}
+
+func TestApplication(t *testing.T) {
+	s := tcell.NewSimulationScreen("")
+	// initialized first, so that functions can be posted before the
+	// application loop has started
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	w := &drawCounter{}
+	app := &Application{}
+	app.SetScreen(s)
+	app.SetRootWidget(w)
+	app.SetRedrawOnDemand(true)
+	var order []string
+	app.OnShutdown(func() { order = append(order, "first") })
+	app.OnShutdown(func() { order = append(order, "second") })
+	app.Start()
+
+	// wait for the loop to be running, and drawn once
+	started := make(chan struct{})
+	app.PostFunc(func() { close(started) })
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatalf("application did not start")
+	}
+	settle := func() int32 {
+		done := make(chan struct{})
+		app.PostFunc(func() { close(done) })
+		<-done
+		// the draw after the function
+		time.Sleep(20 * time.Millisecond)
+		return atomic.LoadInt32(&w.draws)
+	}
+	base := settle()
+
+	// keys that are not handled do not cause a draw
+	s.InjectKey(tcell.KeyRune, 'x', tcell.ModNone)
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&w.draws); n != base {
+		t.Errorf("drawn for an unhandled key: %d", n-base)
+	}
+
+	// a change of content does
+	app.PostFunc(func() { w.SetText("changed") })
+	if n := settle(); n <= base {
+		t.Errorf("not drawn after a change")
+	}
+
+	app.Quit()
+	if err := app.Wait(); err != nil {
+		t.Errorf("application failed: %v", err)
+	}
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Errorf("shutdown hooks called wrongly: %v", order)
+	}
+}
+
+// panicker panics when drawn.
+type panicker struct {
+	Text
+}
+
+func (p *panicker) Draw() {
+	panic("drawing failed")
+}
+
+func TestApplicationPanic(t *testing.T) {
+	s := tcell.NewSimulationScreen("")
+	app := &Application{}
+	app.SetScreen(s)
+	app.SetRootWidget(&panicker{})
+	hooked := false
+	app.OnShutdown(func() { hooked = true })
+	app.wg.Add(1)
+	func() {
+		defer func() {
+			if r := recover(); r != "drawing failed" {
+				t.Errorf("wrong panic: %v", r)
+			}
+		}()
+		app.run()
+	}()
+	select {
+	case <-s.Done():
+	default:
+		t.Errorf("screen not finalized")
+	}
+	if hooked {
+		t.Errorf("shutdown hook called after panic")
+	}
+}
+
+func TestApplicationRedraw(t *testing.T) {
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	w := &drawCounter{}
+	app := &Application{}
+	app.SetScreen(s)
+	app.SetRootWidget(w)
+	app.Start()
+	defer func() {
+		app.Quit()
+		_ = app.Wait()
+	}()
+
+	settle := func() int32 {
+		done := make(chan struct{})
+		app.PostFunc(func() { close(done) })
+		<-done
+		time.Sleep(20 * time.Millisecond)
+		return atomic.LoadInt32(&w.draws)
+	}
+	base := settle()
+	// by default, even events that are not handled cause a draw
+	s.InjectKey(tcell.KeyRune, 'x', tcell.ModNone)
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&w.draws) == base {
+		if time.Now().After(deadline) {
+			t.Fatalf("not drawn after an unhandled key")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -24,6 +24,7 @@ type BoxLayout struct {
 	view    View
 	orient  Orientation
 	style   tcell.Style // backing style
+	role    tcell.StyleRole
 	cells   []*boxLayoutCell
 	width   int
 	height  int
@@ -229,6 +230,9 @@ func (b *BoxLayout) SetView(view View) {
 // watch for those so that if the child changes, we can arrange
 // to update our layout.
 func (b *BoxLayout) HandleEvent(ev tcell.Event) bool {
+	if style, ok := themeStyle(ev, b.role); ok {
+		b.SetStyle(style)
+	}
 	switch ev.(type) {
 	case *EventWidgetContent:
 		// This can only have come from one of our children.
@@ -310,6 +314,11 @@ func (b *BoxLayout) Widgets() []Widget {
 	return w
 }
 
+// FocusChain returns the children, in order, for a FocusManager.
+func (b *BoxLayout) FocusChain() []Widget {
+	return b.Widgets()
+}
+
 // SetOrientation sets the orientation as either Horizontal or Vertical.
 func (b *BoxLayout) SetOrientation(orient Orientation) {
 	if b.orient != orient {
@@ -325,6 +334,15 @@ func (b *BoxLayout) SetStyle(style tcell.Style) {
 	b.PostEventWidgetContent(b)
 }
 
+// SetStyleRole sets the role used to find the backing style in the theme.
+// When the theme changes, the style is set (as if by SetStyle) to the
+// style for the role in the new theme.  The empty role (the default)
+// leaves the style alone.  The change is also passed on to the children,
+// so that they can apply their own roles.
+func (b *BoxLayout) SetStyleRole(role tcell.StyleRole) {
+	b.role = role
+}
+
 // NewBoxLayout creates an empty BoxLayout.
 func NewBoxLayout(orient Orientation) *BoxLayout {
 	return &BoxLayout{orient: orient}
@@ -15,6 +15,7 @@
 package views
 
 import (
+	"strings"
 	"sync"
 
 	"github.com/gdamore/tcell/v2"
@@ -37,10 +38,16 @@ type CellView struct {
 	view    View
 	content Widget
 	style   tcell.Style
+	role    tcell.StyleRole
 	lines   []string
 	model   CellModel
 	once    sync.Once
 
+	sel       bool // there is a selection, from sx0, sy0 to sx1, sy1
+	sx0, sy0  int
+	sx1, sy1  int
+	wordSpace func(rune) bool
+
 	WidgetWatchers
 }
 
@@ -83,6 +90,8 @@ func (a *CellView) Draw() {
 			}
 			if en && x == cx && y == cy && sh {
 				style = style.Reverse(true)
+			} else if a.selected(x, y) {
+				style = style.Reverse(true)
 			}
 			port.SetContent(x, y, ch, comb, style)
 			x += wid - 1
@@ -183,10 +192,16 @@ func (a *CellView) MakeCursorVisible() {
 // HandleEvent handles events.  In particular, it handles certain key events
 // to move the cursor or pan the view.
 func (a *CellView) HandleEvent(e tcell.Event) bool {
+	if style, ok := themeStyle(e, a.role); ok {
+		a.SetStyle(style)
+		return false
+	}
 	if a.model == nil {
 		return false
 	}
 	switch e := e.(type) {
+	case *tcell.EventClick:
+		return a.handleClick(e)
 	case *tcell.EventKey:
 		switch e.Key() {
 		case tcell.KeyUp, tcell.KeyCtrlP:
@@ -218,6 +233,142 @@ func (a *CellView) HandleEvent(e tcell.Event) bool {
 	return false
 }
 
+// handleClick selects a word on a double click, and a line on a triple
+// click.  A single click clears the selection, and moves the cursor if it
+// is enabled.
+func (a *CellView) handleClick(ev *tcell.EventClick) bool {
+	if ev.Button() != tcell.Button1 {
+		return false
+	}
+	x, y := ev.Position()
+	x, y, ok := contentPosition(a.port, x, y)
+	if !ok {
+		return false
+	}
+	w, h := a.model.GetBounds()
+	if y >= h {
+		return false
+	}
+	switch ev.Count() {
+	case 1:
+		a.ClearSelection()
+		if _, _, en, _ := a.model.GetCursor(); en {
+			a.model.SetCursor(x, y)
+		}
+		return true
+	case 2:
+		ch, _, _, _ := a.model.GetCell(x, y)
+		if ch == 0 {
+			return false
+		}
+		space := a.isWordSpace(ch)
+		same := func(x int) bool {
+			ch, _, _, _ := a.model.GetCell(x, y)
+			return ch != 0 && a.isWordSpace(ch) == space
+		}
+		x0, x1 := x, x
+		for x0 > 0 && same(x0-1) {
+			x0--
+		}
+		for x1 < w-1 && same(x1+1) {
+			x1++
+		}
+		a.Select(x0, y, x1, y)
+	default:
+		x1 := 0
+		for x1 < w-1 {
+			if ch, _, _, _ := a.model.GetCell(x1+1, y); ch == 0 {
+				break
+			}
+			x1++
+		}
+		a.Select(0, y, x1, y)
+	}
+	return true
+}
+
+func (a *CellView) isWordSpace(r rune) bool {
+	if a.wordSpace != nil {
+		return a.wordSpace(r)
+	}
+	return tcell.IsWordSpace(r)
+}
+
+// SetWordSpace sets the rule for where words end, when a word is selected
+// with a double click.  The default (nil) is tcell.IsWordSpace.  This is
+// meant to be the same rule as given to tcell.LayoutText, if that is used
+// to arrange the content.
+func (a *CellView) SetWordSpace(fn func(r rune) bool) {
+	a.wordSpace = fn
+}
+
+// Select selects the content from x0, y0 to x1, y1 inclusive, in reading
+// order, which is shown in reverse video.
+func (a *CellView) Select(x0, y0, x1, y1 int) {
+	if y1 < y0 || (y1 == y0 && x1 < x0) {
+		x0, y0, x1, y1 = x1, y1, x0, y0
+	}
+	a.sel = true
+	a.sx0, a.sy0, a.sx1, a.sy1 = x0, y0, x1, y1
+	a.PostEventWidgetContent(a)
+}
+
+// ClearSelection removes the selection, if there is one.
+func (a *CellView) ClearSelection() {
+	if a.sel {
+		a.sel = false
+		a.PostEventWidgetContent(a)
+	}
+}
+
+// Selection returns the start and end of the selection, inclusive, and
+// whether there is one.
+func (a *CellView) Selection() (x0, y0, x1, y1 int, ok bool) {
+	return a.sx0, a.sy0, a.sx1, a.sy1, a.sel
+}
+
+// SelectedText returns the selected content, with a newline between
+// lines.
+func (a *CellView) SelectedText() string {
+	if !a.sel || a.model == nil {
+		return ""
+	}
+	w, _ := a.model.GetBounds()
+	var sb strings.Builder
+	for y := a.sy0; y <= a.sy1; y++ {
+		if y > a.sy0 {
+			sb.WriteByte('\n')
+		}
+		for x := 0; x < w; x++ {
+			if !a.selected(x, y) {
+				continue
+			}
+			ch, _, comb, _ := a.model.GetCell(x, y)
+			if ch == 0 {
+				break
+			}
+			sb.WriteRune(ch)
+			for _, r := range comb {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// selected returns true if the cell is within the selection.
+func (a *CellView) selected(x, y int) bool {
+	switch {
+	case !a.sel || y < a.sy0 || y > a.sy1:
+		return false
+	case y == a.sy0 && x < a.sx0:
+		return false
+	case y == a.sy1 && x > a.sx1:
+		return false
+	}
+	return true
+}
+
 // Size returns the content size, based on the model.
 func (a *CellView) Size() (int, int) {
 	// We always return a minimum of two rows, and two columns.
@@ -301,6 +452,20 @@ func (a *CellView) SetStyle(s tcell.Style) {
 	a.style = s
 }
 
+// SetStyleRole sets the role used to find the fill style in the theme.
+// When the theme changes, the style is set (as if by SetStyle) to the
+// style for the role in the new theme.  The empty role (the default)
+// leaves the style alone.
+func (a *CellView) SetStyleRole(role tcell.StyleRole) {
+	a.role = role
+}
+
+// CanFocus returns true, as a CellView can always have the focus of a
+// FocusManager.
+func (a *CellView) CanFocus() bool {
+	return true
+}
+
 // Init initializes a new CellView for use.
 func (a *CellView) Init() {
 	a.once.Do(func() {
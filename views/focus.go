@@ -0,0 +1,248 @@
+// Copyright 2024 The Tcell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package views
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// Focusable is implemented by widgets that can have the keyboard focus.
+// CanFocus may return false while the widget has nothing to offer, such
+// as when it is disabled.
+type Focusable interface {
+	Widget
+	CanFocus() bool
+}
+
+// FocusContainer is implemented by container widgets, to give the widgets
+// within them, in the order the focus moves through them.  Only the
+// widgets that are shown are given, so that the focus never moves to a
+// hidden one.  A container may give itself, if it can also be focused.
+type FocusContainer interface {
+	Widget
+	FocusChain() []Widget
+}
+
+// EventWidgetFocus is delivered to a widget, through its HandleEvent,
+// when it gains or loses the keyboard focus.  Widget returns the widget.
+type EventWidgetFocus struct {
+	focused bool
+	widgetEvent
+}
+
+// Focused returns true if the widget gained the focus, and false if it
+// lost it.
+func (ev *EventWidgetFocus) Focused() bool {
+	return ev.focused
+}
+
+// FocusManager is a Widget that wraps the root widget of an application,
+// and sends key events only to the widget that has the keyboard focus,
+// rather than offering them to every widget in turn.  Tab and Backtab
+// (Shift-Tab) move the focus to the next or previous widget in the focus
+// chain, if the focused widget does not use them.  Other events are given
+// to the content, as before.
+//
+// The focus chain is found from the content, by following FocusChain of
+// containers (such as BoxLayout and TabbedPanel), and taking the widgets
+// that are Focusable.  It can also be set by the application, with
+// SetChain.  Widgets are told when they gain or lose the focus with an
+// EventWidgetFocus.
+type FocusManager struct {
+	content Widget
+	chain   []Widget // set by the application
+	focus   Widget
+
+	WidgetWatchers
+}
+
+// NewFocusManager creates a FocusManager for the content.
+func NewFocusManager(content Widget) *FocusManager {
+	fm := &FocusManager{content: content}
+	content.Watch(fm)
+	return fm
+}
+
+// appendChain appends the focusable widgets for w to chain.
+func appendChain(chain []Widget, w Widget) []Widget {
+	fc, ok := w.(FocusContainer)
+	if !ok {
+		if fw, ok := w.(Focusable); ok && fw.CanFocus() {
+			chain = append(chain, w)
+		}
+		return chain
+	}
+	for _, c := range fc.FocusChain() {
+		if c != w {
+			chain = appendChain(chain, c)
+		} else if fw, ok := w.(Focusable); ok && fw.CanFocus() {
+			chain = append(chain, w)
+		}
+	}
+	return chain
+}
+
+// Chain returns the widgets that the focus moves through, in order.
+func (fm *FocusManager) Chain() []Widget {
+	var chain []Widget
+	if fm.chain != nil {
+		for _, w := range fm.chain {
+			chain = appendChain(chain, w)
+		}
+	} else {
+		chain = appendChain(chain, fm.content)
+	}
+	return chain
+}
+
+// SetChain sets the widgets that the focus moves through, in order,
+// instead of those found in the content.  Containers are replaced by the
+// widgets in them.  With no widgets, the chain is found from the content
+// again.
+func (fm *FocusManager) SetChain(widgets ...Widget) {
+	fm.chain = nil
+	if len(widgets) > 0 {
+		fm.chain = append([]Widget(nil), widgets...)
+	}
+}
+
+// setFocus moves the focus to w, telling the widgets involved.
+func (fm *FocusManager) setFocus(w Widget) {
+	old := fm.focus
+	if old == w {
+		return
+	}
+	fm.focus = w
+	if old != nil {
+		ev := &EventWidgetFocus{focused: false}
+		ev.SetWidget(old)
+		ev.SetEventNow()
+		old.HandleEvent(ev)
+	}
+	if w != nil {
+		ev := &EventWidgetFocus{focused: true}
+		ev.SetWidget(w)
+		ev.SetEventNow()
+		w.HandleEvent(ev)
+	}
+	fm.PostEventWidgetContent(fm)
+}
+
+// index returns the index of the focused widget in the chain, after
+// moving the focus to the first widget if the focused one is no longer
+// in the chain (for example, because its tab was closed).
+func (fm *FocusManager) index(chain []Widget) int {
+	for i, w := range chain {
+		if w == fm.focus {
+			return i
+		}
+	}
+	if len(chain) == 0 {
+		fm.setFocus(nil)
+		return -1
+	}
+	fm.setFocus(chain[0])
+	return 0
+}
+
+// Focus moves the focus to the widget.  It does nothing if the widget is
+// not in the focus chain.
+func (fm *FocusManager) Focus(w Widget) {
+	for _, c := range fm.Chain() {
+		if c == w {
+			fm.setFocus(w)
+			return
+		}
+	}
+}
+
+// Focused returns the widget with the focus, or nil if there are no
+// widgets to focus.
+func (fm *FocusManager) Focused() Widget {
+	fm.index(fm.Chain())
+	return fm.focus
+}
+
+// Next moves the focus to the next widget, after the last one going back
+// to the first.
+func (fm *FocusManager) Next() {
+	fm.move(1)
+}
+
+// Prev moves the focus to the previous widget, before the first one going
+// back to the last.
+func (fm *FocusManager) Prev() {
+	fm.move(-1)
+}
+
+func (fm *FocusManager) move(delta int) {
+	chain := fm.Chain()
+	i := fm.index(chain)
+	if i < 0 {
+		return
+	}
+	fm.setFocus(chain[(i+delta+len(chain))%len(chain)])
+}
+
+// HandleEvent gives key events to the focused widget, and moves the focus
+// for Tab and Backtab if it does not use them.  Without widgets to focus,
+// keys are given to the content.
+func (fm *FocusManager) HandleEvent(ev tcell.Event) bool {
+	switch ev := ev.(type) {
+	case *EventWidgetContent:
+		fm.PostEventWidgetContent(fm)
+		return true
+	case *tcell.EventKey:
+		w := fm.Focused()
+		if w == nil {
+			return fm.content.HandleEvent(ev)
+		}
+		if w.HandleEvent(ev) {
+			return true
+		}
+		switch ev.Key() {
+		case tcell.KeyTab:
+			fm.Next()
+			return true
+		case tcell.KeyBacktab:
+			fm.Prev()
+			return true
+		}
+		return false
+	}
+	return fm.content.HandleEvent(ev)
+}
+
+// Draw draws the content, after making sure that a widget has the focus,
+// so that it can be drawn as focused.
+func (fm *FocusManager) Draw() {
+	fm.Focused()
+	fm.content.Draw()
+}
+
+// Resize resizes the content.
+func (fm *FocusManager) Resize() {
+	fm.content.Resize()
+}
+
+// SetView sets the View of the content.
+func (fm *FocusManager) SetView(view View) {
+	fm.content.SetView(view)
+}
+
+// Size returns the size of the content.
+func (fm *FocusManager) Size() (int, int) {
+	return fm.content.Size()
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 The Tcell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package views
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// focusWidget records the focus events it is given.
+type focusWidget struct {
+	Text
+	focused bool
+	changes int
+}
+
+func (w *focusWidget) CanFocus() bool {
+	return true
+}
+
+func (w *focusWidget) HandleEvent(ev tcell.Event) bool {
+	if ev, ok := ev.(*EventWidgetFocus); ok {
+		w.focused = ev.Focused()
+		w.changes++
+		return true
+	}
+	return false
+}
+
+func TestFocusManager(t *testing.T) {
+	a, b := &focusWidget{}, &focusWidget{}
+	prompt := NewPrompt("> ")
+	tabs := NewTabbedPanel()
+	tabs.AddTab("one", b)
+	tabs.AddTab("two", prompt)
+	box := NewBoxLayout(Vertical)
+	box.AddWidget(a, 0)
+	box.AddWidget(NewText(), 0) // not focusable
+	box.AddWidget(tabs, 1)
+
+	fm := NewFocusManager(box)
+	if w := fm.Focused(); w != Widget(a) || !a.focused {
+		t.Fatalf("first widget not focused")
+	}
+	if chain := fm.Chain(); len(chain) != 3 || chain[1] != Widget(tabs) || chain[2] != Widget(b) {
+		t.Errorf("wrong chain: %v", chain)
+	}
+	key := func(k tcell.Key, mod tcell.ModMask) bool {
+		return fm.HandleEvent(tcell.NewEventKey(k, 0, mod))
+	}
+	key(tcell.KeyTab, tcell.ModNone)
+	if fm.Focused() != Widget(tabs) || a.focused || a.changes != 2 {
+		t.Errorf("focus not moved to the tabs")
+	}
+	// the tab row has the focus, so the arrows choose a tab
+	key(tcell.KeyRight, tcell.ModNone)
+	if i, w := tabs.Current(); i != 1 || w != Widget(prompt) {
+		t.Errorf("wrong tab: %d", i)
+	}
+	key(tcell.KeyTab, tcell.ModNone)
+	if fm.Focused() != Widget(prompt) {
+		t.Errorf("focus not moved to the prompt of the second tab")
+	}
+	fm.HandleEvent(tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone))
+	if prompt.Text() != "x" {
+		t.Errorf("key not given to the focused widget")
+	}
+	key(tcell.KeyTab, tcell.ModNone)
+	if fm.Focused() != Widget(a) {
+		t.Errorf("focus did not wrap around")
+	}
+	key(tcell.KeyBacktab, tcell.ModNone)
+	if fm.Focused() != Widget(prompt) {
+		t.Errorf("focus did not go back")
+	}
+
+	// when the tab is changed, the hidden widget loses the focus
+	tabs.SetCurrent(0)
+	if fm.Focused() != Widget(a) {
+		t.Errorf("focus not moved from hidden widget")
+	}
+	fm.SetChain(tabs)
+	if fm.Focused() != Widget(tabs) || a.focused {
+		t.Errorf("focus not moved into the new chain")
+	}
+	fm.Focus(prompt) // hidden, so not in the chain
+	if fm.Focused() != Widget(tabs) {
+		t.Errorf("hidden widget focused")
+	}
+}
+
+func TestTabbedPanel(t *testing.T) {
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	s.SetSize(20, 3)
+
+	one, two := NewText(), NewText()
+	one.SetText("first")
+	two.SetText("second")
+	tabs := NewTabbedPanel()
+	tabs.SetView(s)
+	tabs.AddTab("One", one)
+	tabs.AddTab("Two", two)
+	row := func(y int) string {
+		var str string
+		for x := 0; x < 10; x++ {
+			r, _, _, _ := s.GetContent(x, y)
+			str += string(r)
+		}
+		return str
+	}
+	tabs.Draw()
+	if got := row(0); got != " One  Two " {
+		t.Errorf("wrong tabs: %q", got)
+	}
+	if got := row(1); got != "first     " {
+		t.Errorf("wrong content: %q", got)
+	}
+	if _, _, style, _ := s.GetContent(1, 0); style != tcell.StyleDefault.Reverse(true) {
+		t.Errorf("current tab not highlighted")
+	}
+
+	tabs.HandleEvent(tcell.NewEventClick(6, 0, tcell.Button1, tcell.ModNone, 1))
+	tabs.Draw()
+	if got := row(1); got != "second    " {
+		t.Errorf("click did not change tab: %q", got)
+	}
+	tabs.HandleEvent(tcell.NewEventKey(tcell.KeyPgDn, 0, tcell.ModCtrl))
+	if i, _ := tabs.Current(); i != 0 {
+		t.Errorf("Ctrl-PgDn did not wrap around: %d", i)
+	}
+	// the arrows are only for the tabs when they have the focus
+	if tabs.HandleEvent(tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModNone)) {
+		t.Errorf("arrow handled without focus")
+	}
+	tabs.RemoveTab(one)
+	if i, w := tabs.Current(); i != 0 || w != Widget(two) {
+		t.Errorf("wrong tab after removal: %d", i)
+	}
+	if w, h := tabs.Size(); w != 6 || h != 2 {
+		t.Errorf("wrong size %d x %d", w, h)
+	}
+}
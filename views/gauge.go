@@ -0,0 +1,205 @@
+// Copyright 2023 The Tcell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package views
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// viewGlyphs returns the best glyph set for gauges drawn in the view,
+// as detected for the screen beneath it (see tcell.DetectGlyphs).  If the
+// view is not on a screen, block elements are assumed to be available.
+func viewGlyphs(v View) tcell.GlyphSet {
+	for {
+		switch view := v.(type) {
+		case tcell.Screen:
+			return tcell.DetectGlyphs(view)
+		case *ViewPort:
+			v = view.v
+		case *layerView:
+			v = view.owner.view
+		default:
+			return tcell.GlyphsBlocks
+		}
+	}
+}
+
+// gauge holds what is common to the gauge widgets.
+type gauge struct {
+	view   View
+	style  tcell.Style
+	role   tcell.StyleRole
+	glyphs tcell.GlyphSet
+	fixed  bool   // glyphs set by the application
+	self   Widget // the widget, for events
+
+	WidgetWatchers
+}
+
+func (g *gauge) glyphSet() tcell.GlyphSet {
+	if g.fixed {
+		return g.glyphs
+	}
+	return viewGlyphs(g.view)
+}
+
+// SetGlyphs sets the glyph set used to draw, instead of the best one for
+// the screen.
+func (g *gauge) SetGlyphs(glyphs tcell.GlyphSet) {
+	g.glyphs, g.fixed = glyphs, true
+	g.PostEventWidgetContent(g.self)
+}
+
+// SetStyle sets the style.  The background of the style is the track of
+// the gauge.
+func (g *gauge) SetStyle(style tcell.Style) {
+	g.style = style
+	g.PostEventWidgetContent(g.self)
+}
+
+// SetStyleRole sets the role used to find the style in the theme.  When
+// the theme changes, the style is set (as if by SetStyle) to the style for
+// the role in the new theme.  The empty role (the default) leaves the
+// style alone.
+func (g *gauge) SetStyleRole(role tcell.StyleRole) {
+	g.role = role
+}
+
+// SetView sets the View used for drawing.
+func (g *gauge) SetView(view View) {
+	g.view = view
+}
+
+// Resize is called when the View size changes.
+func (g *gauge) Resize() {
+	g.PostEventWidgetResize(g.self)
+}
+
+// HandleEvent handles a change of theme.  Gauges handle no other events.
+func (g *gauge) HandleEvent(ev tcell.Event) bool {
+	if style, ok := themeStyle(ev, g.role); ok {
+		g.SetStyle(style)
+	}
+	return false
+}
+
+// ProgressBar is a Widget that shows progress towards completion, as a
+// bar filling the width of its view.
+type ProgressBar struct {
+	value float64
+	gauge
+}
+
+// SetValue sets the progress, as a fraction between 0 and 1.
+func (p *ProgressBar) SetValue(fraction float64) {
+	p.value = fraction
+	p.PostEventWidgetContent(p)
+}
+
+// Value returns the progress.
+func (p *ProgressBar) Value() float64 {
+	return p.value
+}
+
+// Draw draws the bar on the first row of the view.
+func (p *ProgressBar) Draw() {
+	if p.view == nil {
+		return
+	}
+	width, _ := p.view.Size()
+	for x, r := range tcell.ProgressRunes(width, p.value, p.glyphSet()) {
+		p.view.SetContent(x, 0, r, nil, p.style)
+	}
+}
+
+// Size returns a small width, as the bar can be shown in any width.
+func (p *ProgressBar) Size() (int, int) {
+	return 10, 1
+}
+
+// NewProgressBar creates an empty ProgressBar.
+func NewProgressBar() *ProgressBar {
+	p := &ProgressBar{}
+	p.self = p
+	return p
+}
+
+// Sparkline is a Widget that shows a small bar chart of a series of
+// values, such as the recent history of a measurement.  The most recent
+// values that fit in the view are shown.
+type Sparkline struct {
+	values []float64
+	max    float64
+	gauge
+}
+
+// SetValues sets the values to show.
+func (s *Sparkline) SetValues(values []float64) {
+	s.values = append(s.values[:0], values...)
+	s.PostEventWidgetContent(s)
+}
+
+// AddValue adds a value to the end of the series.  Values that can no
+// longer be shown are discarded.
+func (s *Sparkline) AddValue(v float64) {
+	s.values = append(s.values, v)
+	if s.view != nil {
+		width, _ := s.view.Size()
+		if keep := width * 2; len(s.values) > keep {
+			s.values = append(s.values[:0], s.values[len(s.values)-keep:]...)
+		}
+	}
+	s.PostEventWidgetContent(s)
+}
+
+// Values returns the values.
+func (s *Sparkline) Values() []float64 {
+	return append([]float64(nil), s.values...)
+}
+
+// SetMax sets the value at which the bars reach the top of the view.  If
+// it is zero (the default), the largest value shown is used.
+func (s *Sparkline) SetMax(max float64) {
+	s.max = max
+	s.PostEventWidgetContent(s)
+}
+
+// Draw draws the sparkline, filling the view.
+func (s *Sparkline) Draw() {
+	if s.view == nil {
+		return
+	}
+	width, height := s.view.Size()
+	for y, runes := range tcell.SparklineRunes(width, height, s.values, s.max, s.glyphSet()) {
+		for x, r := range runes {
+			s.view.SetContent(x, y, r, nil, s.style)
+		}
+	}
+}
+
+// Size returns the width needed to show all of the values, and one row.
+func (s *Sparkline) Size() (int, int) {
+	if s.glyphSet() == tcell.GlyphsBraille {
+		return (len(s.values) + 1) / 2, 1
+	}
+	return len(s.values), 1
+}
+
+// NewSparkline creates an empty Sparkline.
+func NewSparkline() *Sparkline {
+	s := &Sparkline{}
+	s.self = s
+	return s
+}
@@ -0,0 +1,45 @@
+package views
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestGauges(t *testing.T) {
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	s.SetSize(10, 3)
+
+	p := NewProgressBar()
+	p.SetView(NewViewPort(s, 0, 0, 4, 1))
+	p.SetValue(0.5)
+	p.Draw()
+	if r, _, _, _ := s.GetContent(1, 0); r != tcell.RuneBlock {
+		t.Errorf("progress not drawn: %q", r)
+	}
+	p.SetGlyphs(tcell.GlyphsASCII)
+	p.Draw()
+	if r, _, _, _ := s.GetContent(1, 0); r != '#' {
+		t.Errorf("progress not drawn in ASCII: %q", r)
+	}
+
+	sl := NewSparkline()
+	sl.SetView(NewViewPort(s, 0, 1, 2, 2))
+	for _, v := range []float64{1, 2, 3, 4, 5, 6} {
+		sl.AddValue(v)
+	}
+	if n := len(sl.Values()); n != 4 {
+		t.Errorf("wrong number of values kept: %d", n)
+	}
+	if w, h := sl.Size(); w != 2 || h != 1 {
+		t.Errorf("wrong size %dx%d", w, h)
+	}
+	sl.Draw()
+	if r, _, _, _ := s.GetContent(1, 1); r != '⣾' {
+		t.Errorf("sparkline not drawn with braille: %q", r)
+	}
+}
@@ -0,0 +1,451 @@
+// Copyright 2023 The Tcell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package views
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// Layers is a container Widget that stacks widgets on top of each other,
+// each in a rectangle of its own, like windows on a desktop.  It is the
+// basis for dialogs, palettes, and other pop ups.  Layers are drawn from
+// the bottom up, so higher layers hide the ones below.  Mouse events go to
+// the highest layer under the pointer, and other events go to each layer
+// from the top down until one handles it.  A modal layer keeps events from
+// reaching the layers below it.
+//
+// If mouse events are processed with a tcell.GestureDetector, the title
+// row (the first row) of a movable layer can be dragged to move it, and
+// the bottom right corner of a resizable layer can be dragged to resize
+// it.  Pressing a button on a layer raises it to the top, unless a modal
+// layer is in the way.
+type Layers struct {
+	view   View
+	layers []*Layer // from the bottom up
+	style  tcell.Style
+	role   tcell.StyleRole
+
+	drag       *Layer // layer being moved or resized
+	dragResize bool
+	dragX      int // position (or size) of the layer when the drag started
+	dragY      int
+
+	WidgetWatchers
+}
+
+// Layer is a widget placed in Layers.
+type Layer struct {
+	widget    Widget
+	owner     *Layers
+	port      *ViewPort
+	view      *layerView
+	x, y      int
+	width     int
+	height    int
+	modal     bool
+	shadow    bool
+	backdrop  bool
+	movable   bool
+	resizable bool
+}
+
+// layerView is the view that a layer draws on.  It draws through to the
+// view of the Layers, changing the style of cells that are in the shadow
+// of a higher layer, or behind a backdrop.
+type layerView struct {
+	owner *Layers
+	layer *Layer // nil for the background
+}
+
+func (lv *layerView) SetContent(x, y int, ch rune, comb []rune, style tcell.Style) {
+	if lv.owner.view != nil {
+		lv.owner.view.SetContent(x, y, ch, comb, lv.owner.shade(lv.layer, x, y, style))
+	}
+}
+
+func (lv *layerView) Size() (int, int) {
+	if lv.owner.view == nil {
+		return 0, 0
+	}
+	return lv.owner.view.Size()
+}
+
+func (lv *layerView) Resize(int, int, int, int) {}
+
+func (lv *layerView) Fill(ch rune, style tcell.Style) {
+	w, h := lv.Size()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			lv.SetContent(x, y, ch, nil, style)
+		}
+	}
+}
+
+func (lv *layerView) Clear() {
+	lv.Fill(' ', tcell.StyleDefault)
+}
+
+// shade returns the style for a cell of the layer, which is dimmed if a
+// higher layer has a backdrop, or casts a shadow on it.
+func (l *Layers) shade(layer *Layer, x, y int, style tcell.Style) tcell.Style {
+	above := layer == nil
+	for _, o := range l.layers {
+		if !above {
+			above = o == layer
+			continue
+		}
+		if o.backdrop {
+			style = style.Dim(true)
+		}
+		if o.shadow && o.inShadow(x, y) {
+			style = style.Background(tcell.ColorBlack).Foreground(tcell.ColorGray).Dim(true)
+		}
+	}
+	return style
+}
+
+// inShadow returns true if the cell is in the shadow cast by the layer,
+// which is two columns to the right, and one row below it.
+func (layer *Layer) inShadow(x, y int) bool {
+	right := x >= layer.x+layer.width && x < layer.x+layer.width+2 &&
+		y > layer.y && y <= layer.y+layer.height
+	below := y == layer.y+layer.height && x >= layer.x+2 && x < layer.x+layer.width+2
+	return right || below
+}
+
+// contains returns true if the cell is within the layer.
+func (layer *Layer) contains(x, y int) bool {
+	return x >= layer.x && y >= layer.y && x < layer.x+layer.width && y < layer.y+layer.height
+}
+
+// Widget returns the widget of the layer.
+func (layer *Layer) Widget() Widget {
+	return layer.widget
+}
+
+// Bounds returns the position and size of the layer.
+func (layer *Layer) Bounds() (x, y, width, height int) {
+	return layer.x, layer.y, layer.width, layer.height
+}
+
+// SetBounds moves and resizes the layer.  The position is relative to the
+// view of the Layers.  A width or height of zero (or less) uses the size
+// of the widget.
+func (layer *Layer) SetBounds(x, y, width, height int) {
+	if width <= 0 || height <= 0 {
+		ww, wh := layer.widget.Size()
+		if width <= 0 {
+			width = ww
+		}
+		if height <= 0 {
+			height = wh
+		}
+	}
+	moved := x != layer.x || y != layer.y
+	layer.x, layer.y, layer.width, layer.height = x, y, width, height
+	layer.layout()
+	if moved {
+		layer.owner.PostEventWidgetMove(layer.widget)
+	}
+	layer.owner.PostEventWidgetContent(layer.owner)
+}
+
+// layout fits the layer within the view of the Layers.
+func (layer *Layer) layout() {
+	vw, vh := layer.view.Size()
+	if layer.x+layer.width > vw {
+		layer.x = vw - layer.width
+	}
+	if layer.y+layer.height > vh {
+		layer.y = vh - layer.height
+	}
+	if layer.x < 0 {
+		layer.x = 0
+	}
+	if layer.y < 0 {
+		layer.y = 0
+	}
+	layer.port.Resize(layer.x, layer.y, layer.width, layer.height)
+	layer.widget.Resize()
+}
+
+// SetModal makes the layer modal, so that while it is shown, the layers
+// below it receive no events.
+func (layer *Layer) SetModal(on bool) {
+	layer.modal = on
+}
+
+// SetShadow makes the layer cast a shadow on the layers below.
+func (layer *Layer) SetShadow(on bool) {
+	layer.shadow = on
+	layer.owner.PostEventWidgetContent(layer.owner)
+}
+
+// SetBackdrop dims the layers below, to draw attention to this one.  This
+// is typically used together with SetModal.
+func (layer *Layer) SetBackdrop(on bool) {
+	layer.backdrop = on
+	layer.owner.PostEventWidgetContent(layer.owner)
+}
+
+// SetMovable allows the layer to be moved by dragging its first row.
+func (layer *Layer) SetMovable(on bool) {
+	layer.movable = on
+}
+
+// SetResizable allows the layer to be resized by dragging its bottom
+// right corner.
+func (layer *Layer) SetResizable(on bool) {
+	layer.resizable = on
+}
+
+// AddLayer adds a widget as the top layer, at the given position and size
+// (see Layer.SetBounds), and returns the new layer.
+func (l *Layers) AddLayer(widget Widget, x, y, width, height int) *Layer {
+	layer := &Layer{widget: widget, owner: l}
+	layer.view = &layerView{owner: l, layer: layer}
+	layer.port = NewViewPort(layer.view, 0, 0, 0, 0)
+	widget.SetView(layer.port)
+	widget.Watch(l)
+	l.layers = append(l.layers, layer)
+	layer.SetBounds(x, y, width, height)
+	return layer
+}
+
+// RemoveLayer removes a layer.
+func (l *Layers) RemoveLayer(layer *Layer) {
+	if i := l.index(layer); i >= 0 {
+		l.layers = append(l.layers[:i], l.layers[i+1:]...)
+		layer.widget.Unwatch(l)
+		if l.drag == layer {
+			l.drag = nil
+		}
+		l.PostEventWidgetContent(l)
+	}
+}
+
+// Raise moves the layer to the top.
+func (l *Layers) Raise(layer *Layer) {
+	if i := l.index(layer); i >= 0 && i < len(l.layers)-1 {
+		l.layers = append(append(l.layers[:i], l.layers[i+1:]...), layer)
+		l.PostEventWidgetContent(l)
+	}
+}
+
+// Lower moves the layer to the bottom.
+func (l *Layers) Lower(layer *Layer) {
+	if i := l.index(layer); i > 0 {
+		copy(l.layers[1:i+1], l.layers[:i])
+		l.layers[0] = layer
+		l.PostEventWidgetContent(l)
+	}
+}
+
+// Layers returns the layers, from the bottom up.
+func (l *Layers) Layers() []*Layer {
+	return append([]*Layer(nil), l.layers...)
+}
+
+// FocusChain returns the widgets of the layers, from the top down, for a
+// FocusManager.  The layers below a modal layer are left out.
+func (l *Layers) FocusChain() []Widget {
+	var chain []Widget
+	for i := len(l.layers) - 1; i >= 0; i-- {
+		chain = append(chain, l.layers[i].widget)
+		if l.layers[i].modal {
+			break
+		}
+	}
+	return chain
+}
+
+func (l *Layers) index(layer *Layer) int {
+	for i, o := range l.layers {
+		if o == layer {
+			return i
+		}
+	}
+	return -1
+}
+
+// hit returns the highest layer at the screen position, and whether the
+// position is reachable, which it is not if it is below a modal layer.
+func (l *Layers) hit(sx, sy int) (*Layer, bool) {
+	x, y, ok := contentPosition(l.view, sx, sy)
+	if !ok {
+		return nil, false
+	}
+	for i := len(l.layers) - 1; i >= 0; i-- {
+		layer := l.layers[i]
+		if layer.contains(x, y) {
+			return layer, true
+		}
+		if layer.modal {
+			return nil, false
+		}
+	}
+	return nil, true
+}
+
+// handleDrag moves or resizes a layer.
+func (l *Layers) handleDrag(ev *tcell.EventDrag) bool {
+	if ev.Phase() == tcell.DragStart {
+		l.drag = nil
+		sx, sy := ev.StartPosition()
+		layer, _ := l.hit(sx, sy)
+		if layer == nil || ev.Button() != tcell.Button1 {
+			return false
+		}
+		x, y, _ := contentPosition(l.view, sx, sy)
+		switch {
+		case layer.resizable && x == layer.x+layer.width-1 && y == layer.y+layer.height-1:
+			l.drag, l.dragResize = layer, true
+			l.dragX, l.dragY = layer.width, layer.height
+		case layer.movable && y == layer.y:
+			l.drag, l.dragResize = layer, false
+			l.dragX, l.dragY = layer.x, layer.y
+		default:
+			return false
+		}
+		l.Raise(layer)
+	}
+	layer := l.drag
+	if layer == nil {
+		return false
+	}
+	sx, sy := ev.StartPosition()
+	x, y := ev.Position()
+	dx, dy := x-sx, y-sy
+	if l.dragResize {
+		w, h := l.dragX+dx, l.dragY+dy
+		if w < 1 {
+			w = 1
+		}
+		if h < 1 {
+			h = 1
+		}
+		layer.SetBounds(layer.x, layer.y, w, h)
+	} else {
+		layer.SetBounds(l.dragX+dx, l.dragY+dy, layer.width, layer.height)
+	}
+	if ev.Phase() == tcell.DragEnd {
+		l.drag = nil
+	}
+	return true
+}
+
+// HandleEvent routes events to the layers.  Changes of content from the
+// layers are passed on to watchers of the Layers.
+func (l *Layers) HandleEvent(ev tcell.Event) bool {
+	if style, ok := themeStyle(ev, l.role); ok {
+		l.SetStyle(style)
+	}
+	var pos interface{ Position() (int, int) }
+	switch ev := ev.(type) {
+	case *EventWidgetContent:
+		l.PostEventWidgetContent(l)
+		return true
+	case *tcell.EventDrag:
+		if l.handleDrag(ev) {
+			return true
+		}
+		pos = ev
+	case *tcell.EventMouse:
+		pos = ev
+		if ev.Buttons()&tcell.Button1 != 0 {
+			if layer, ok := l.hit(ev.Position()); ok && layer != nil {
+				l.Raise(layer)
+			}
+		}
+	case *tcell.EventClick:
+		pos = ev
+	}
+	if pos != nil {
+		layer, ok := l.hit(pos.Position())
+		if !ok {
+			return true // blocked by a modal layer
+		}
+		return layer != nil && layer.widget.HandleEvent(ev)
+	}
+	for i := len(l.layers) - 1; i >= 0; i-- {
+		layer := l.layers[i]
+		if layer.widget.HandleEvent(ev) {
+			return true
+		}
+		if layer.modal {
+			break
+		}
+	}
+	return false
+}
+
+// Draw draws the background, and then the layers from the bottom up.
+func (l *Layers) Draw() {
+	if l.view == nil {
+		return
+	}
+	bg := &layerView{owner: l}
+	bg.Fill(' ', l.style)
+	for _, layer := range l.layers {
+		layer.widget.Draw()
+	}
+}
+
+// Resize fits the layers within the resized view.
+func (l *Layers) Resize() {
+	for _, layer := range l.layers {
+		layer.layout()
+	}
+	l.PostEventWidgetResize(l)
+}
+
+// Size returns the size needed to show all of the layers.
+func (l *Layers) Size() (int, int) {
+	w, h := 0, 0
+	for _, layer := range l.layers {
+		if r := layer.x + layer.width; r > w {
+			w = r
+		}
+		if b := layer.y + layer.height; b > h {
+			h = b
+		}
+	}
+	return w, h
+}
+
+// SetView sets the View used for the layers.
+func (l *Layers) SetView(view View) {
+	l.view = view
+	l.Resize()
+}
+
+// SetStyle sets the style of the background, where there are no layers.
+func (l *Layers) SetStyle(style tcell.Style) {
+	l.style = style
+	l.PostEventWidgetContent(l)
+}
+
+// SetStyleRole sets the role used to find the background style in the
+// theme.  When the theme changes, the style is set (as if by SetStyle) to
+// the style for the role in the new theme.  The empty role (the default)
+// leaves the style alone.
+func (l *Layers) SetStyleRole(role tcell.StyleRole) {
+	l.role = role
+}
+
+// NewLayers creates an empty Layers.
+func NewLayers() *Layers {
+	return &Layers{}
+}
@@ -0,0 +1,120 @@
+package views
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// fillWidget fills its view with a rune, and counts the events it handles.
+type fillWidget struct {
+	view   View
+	ch     rune
+	events int
+	WidgetWatchers
+}
+
+func (w *fillWidget) Draw()                           { w.view.Fill(w.ch, tcell.StyleDefault) }
+func (w *fillWidget) Resize()                         {}
+func (w *fillWidget) SetView(view View)               { w.view = view }
+func (w *fillWidget) Size() (int, int)                { return 4, 3 }
+func (w *fillWidget) HandleEvent(ev tcell.Event) bool { w.events++; return true }
+
+func TestLayers(t *testing.T) {
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	s.SetSize(20, 10)
+
+	l := NewLayers()
+	l.SetView(s)
+	a, b := &fillWidget{ch: 'a'}, &fillWidget{ch: 'b'}
+	la := l.AddLayer(a, 1, 1, 0, 0)
+	lb := l.AddLayer(b, 3, 2, 6, 4)
+	lb.SetShadow(true)
+	l.Draw()
+
+	cell := func(x, y int) (rune, tcell.Style) {
+		r, _, style, _ := s.GetContent(x, y)
+		return r, style
+	}
+	if r, _ := cell(1, 1); r != 'a' {
+		t.Errorf("lower layer not drawn: %q", r)
+	}
+	if r, _ := cell(3, 2); r != 'b' {
+		t.Errorf("upper layer not on top: %q", r)
+	}
+	if r, style := cell(5, 6); r != ' ' || style == tcell.StyleDefault {
+		t.Errorf("shadow not drawn: %q", r)
+	}
+	if x, y, w, h := la.Bounds(); x != 1 || y != 1 || w != 4 || h != 3 {
+		t.Errorf("wrong bounds: %d,%d %dx%d", x, y, w, h)
+	}
+
+	// Mouse events go to the top-most layer, and raise it.
+	l.HandleEvent(tcell.NewEventMouse(1, 1, tcell.Button1, tcell.ModNone))
+	if a.events != 1 || b.events != 0 {
+		t.Errorf("mouse event routed wrongly: %d %d", a.events, b.events)
+	}
+	if layers := l.Layers(); layers[1] != la {
+		t.Errorf("clicked layer not raised")
+	}
+	if l.HandleEvent(tcell.NewEventMouse(15, 8, tcell.Button1, tcell.ModNone)) {
+		t.Errorf("event outside of layers handled")
+	}
+
+	// A modal layer blocks the layers below.
+	l.Raise(lb)
+	lb.SetModal(true)
+	lb.SetBackdrop(true)
+	a.events = 0
+	l.HandleEvent(tcell.NewEventMouse(1, 1, tcell.Button1, tcell.ModNone))
+	l.HandleEvent(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	if a.events != 0 {
+		t.Errorf("modal layer did not block events")
+	}
+	if l.Layers()[1] != lb {
+		t.Errorf("layer raised above modal layer")
+	}
+	l.Draw()
+	if _, style := cell(1, 1); style != tcell.StyleDefault.Dim(true) {
+		t.Errorf("backdrop not drawn")
+	}
+	lb.SetModal(false)
+	lb.SetBackdrop(false)
+
+	// Dragging the first row moves a movable layer.
+	lb.SetMovable(true)
+	lb.SetResizable(true)
+	g := tcell.NewGestureDetector()
+	drag := func(x0, y0, x1, y1 int) {
+		for _, ev := range []*tcell.EventMouse{
+			tcell.NewEventMouse(x0, y0, tcell.Button1, tcell.ModNone),
+			tcell.NewEventMouse(x1, y1, tcell.Button1, tcell.ModNone),
+			tcell.NewEventMouse(x1, y1, tcell.ButtonNone, tcell.ModNone),
+		} {
+			for _, gev := range g.Process(ev) {
+				l.HandleEvent(gev)
+			}
+		}
+	}
+	drag(4, 2, 8, 3)
+	if x, y, _, _ := lb.Bounds(); x != 7 || y != 3 {
+		t.Errorf("layer not moved: %d,%d", x, y)
+	}
+	drag(12, 6, 14, 7)
+	if _, _, w, h := lb.Bounds(); w != 8 || h != 5 {
+		t.Errorf("layer not resized: %dx%d", w, h)
+	}
+	drag(0, 0, 30, 30)
+	if x, y, w, h := lb.Bounds(); x != 7 || y != 3 || w != 8 || h != 5 {
+		t.Errorf("drag on the background changed the layer: %d,%d %dx%d", x, y, w, h)
+	}
+
+	l.RemoveLayer(lb)
+	if len(l.Layers()) != 1 {
+		t.Errorf("layer not removed")
+	}
+}
@@ -0,0 +1,499 @@
+// Copyright 2023 The Tcell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package views
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/uniseg"
+)
+
+// Prompt is a Widget for editing a single line of text, such as a command
+// line, or the answer to a question in a status line.  The text follows a
+// prompt, and is edited with keys similar to those of readline and Emacs:
+//
+//	Left, Ctrl-B / Right, Ctrl-F    move by a character
+//	Alt-B / Alt-F                   move by a word
+//	Home, Ctrl-A / End, Ctrl-E      move to the start or end
+//	Backspace / Delete, Ctrl-D      delete a character
+//	Ctrl-K / Ctrl-U / Ctrl-W        kill to the end, the start, or a word
+//	Ctrl-Y / Alt-Y                  yank killed text, cycle the kill ring
+//	Up, Ctrl-P / Down, Ctrl-N       recall earlier or later history
+//	Ctrl-R                          search the history incrementally
+//	Tab                             complete (see SetCompleteFunc)
+//	Enter                           submit (see SetSubmitFunc)
+//
+// Editing works on grapheme clusters, so that a character together with
+// its combining marks, or an emoji sequence, is moved over and deleted as
+// a single character.
+type Prompt struct {
+	view     View
+	prompt   string
+	text     []string // grapheme clusters
+	pos      int      // cursor, as an index into text
+	offset   int      // first cluster shown
+	style    tcell.Style
+	role     tcell.StyleRole
+	submit   func(string)
+	complete func(string, int) (string, int)
+
+	history []string
+	histPos int
+	saved   string // the line being edited, while recalling history
+
+	kills     []string
+	killing   bool // the last command was a kill, so kills are joined
+	yanked    int  // clusters inserted by the last yank, if it was one
+	yankIndex int
+
+	searching bool
+	query     string
+	match     int // index in history of the current search match
+	original  string
+
+	WidgetWatchers
+}
+
+const maxKills = 16
+
+// line returns the text, and the cursor as a byte offset.
+func (p *Prompt) line() (string, int) {
+	return strings.Join(p.text, ""), len(strings.Join(p.text[:p.pos], ""))
+}
+
+// setLine sets the text, with the cursor at a byte offset.  The cursor is
+// placed after the cluster containing the offset.
+func (p *Prompt) setLine(s string, pos int) {
+	p.text = p.text[:0]
+	p.pos = 0
+	g := uniseg.NewGraphemes(s)
+	for g.Next() {
+		p.text = append(p.text, g.Str())
+		if start, _ := g.Positions(); start < pos {
+			p.pos = len(p.text)
+		}
+	}
+	p.PostEventWidgetContent(p)
+}
+
+// insert inserts text at the cursor.
+func (p *Prompt) insert(s string) {
+	line, pos := p.line()
+	p.setLine(line[:pos]+s+line[pos:], pos+len(s))
+}
+
+// remove removes the clusters from start to end, and returns them.
+func (p *Prompt) remove(start, end int) string {
+	s := strings.Join(p.text[start:end], "")
+	p.text = append(p.text[:start], p.text[end:]...)
+	if p.pos > end {
+		p.pos -= end - start
+	} else if p.pos > start {
+		p.pos = start
+	}
+	p.PostEventWidgetContent(p)
+	return s
+}
+
+// kill removes the clusters from start to end, and saves them in the kill
+// ring.  Consecutive kills are joined, as in Emacs.
+func (p *Prompt) kill(start, end int) {
+	backward := end <= p.pos && start < p.pos
+	s := p.remove(start, end)
+	switch {
+	case p.killing && len(p.kills) > 0 && backward:
+		p.kills[len(p.kills)-1] = s + p.kills[len(p.kills)-1]
+	case p.killing && len(p.kills) > 0:
+		p.kills[len(p.kills)-1] += s
+	case s != "":
+		if len(p.kills) == maxKills {
+			p.kills = p.kills[1:]
+		}
+		p.kills = append(p.kills, s)
+	}
+}
+
+// yank inserts the entry of the kill ring at index.
+func (p *Prompt) yank(index int) {
+	if len(p.kills) == 0 {
+		return
+	}
+	p.yankIndex = (index + len(p.kills)) % len(p.kills)
+	n := len(p.text)
+	p.insert(p.kills[p.yankIndex])
+	p.yanked = len(p.text) - n
+}
+
+func (p *Prompt) isSpace(i int) bool {
+	for _, r := range p.text[i] {
+		return tcell.IsWordSpace(r)
+	}
+	return true
+}
+
+// wordStart returns the start of the word before the cursor.
+func (p *Prompt) wordStart() int {
+	i := p.pos
+	for i > 0 && p.isSpace(i-1) {
+		i--
+	}
+	for i > 0 && !p.isSpace(i-1) {
+		i--
+	}
+	return i
+}
+
+// wordEnd returns the end of the word after the cursor.
+func (p *Prompt) wordEnd() int {
+	i := p.pos
+	for i < len(p.text) && p.isSpace(i) {
+		i++
+	}
+	for i < len(p.text) && !p.isSpace(i) {
+		i++
+	}
+	return i
+}
+
+// recall shows the history entry at index, or the saved line if the index
+// is past the end of the history.
+func (p *Prompt) recall(index int) {
+	if index < 0 || index > len(p.history) || index == p.histPos {
+		return
+	}
+	if p.histPos == len(p.history) {
+		p.saved, _ = p.line()
+	}
+	p.histPos = index
+	line := p.saved
+	if index < len(p.history) {
+		line = p.history[index]
+	}
+	p.setLine(line, len(line))
+}
+
+// search finds the latest history entry at or before index that contains
+// the query.
+func (p *Prompt) search(index int) {
+	if index >= len(p.history) {
+		index = len(p.history) - 1
+	}
+	for ; index >= 0; index-- {
+		if i := strings.Index(p.history[index], p.query); i >= 0 {
+			p.match = index
+			p.setLine(p.history[index], i)
+			return
+		}
+	}
+	p.PostEventWidgetContent(p) // no match, but the query changed
+}
+
+// endSearch ends an incremental search, keeping the matched line.
+func (p *Prompt) endSearch() {
+	p.searching = false
+	if p.match < len(p.history) {
+		p.histPos = p.match
+	}
+	p.PostEventWidgetContent(p)
+}
+
+// handleSearch handles keys during an incremental search.
+func (p *Prompt) handleSearch(ev *tcell.EventKey) bool {
+	switch {
+	case ev.MatchesRune('r', tcell.ModCtrl):
+		p.search(p.match - 1)
+		return true
+	case ev.MatchesRune('g', tcell.ModCtrl), ev.Key() == tcell.KeyEscape:
+		p.searching = false
+		p.setLine(p.original, len(p.original))
+		return true
+	case ev.Key() == tcell.KeyBackspace, ev.Key() == tcell.KeyBackspace2:
+		if q := []rune(p.query); len(q) > 0 {
+			p.query = string(q[:len(q)-1])
+			p.search(len(p.history) - 1)
+		}
+		return true
+	case ev.Key() == tcell.KeyRune && ev.Modifiers()&(tcell.ModCtrl|tcell.ModAlt) == 0:
+		p.query += string(ev.Rune())
+		p.search(p.match)
+		return true
+	}
+	p.endSearch()
+	return false
+}
+
+func (p *Prompt) handleKey(ev *tcell.EventKey) bool {
+	if p.searching && p.handleSearch(ev) {
+		return true
+	}
+	killing, yanked := false, 0
+	defer func() {
+		p.killing, p.yanked = killing, yanked
+	}()
+
+	switch {
+	case ev.Key() == tcell.KeyLeft, ev.MatchesRune('b', tcell.ModCtrl):
+		if p.pos > 0 {
+			p.pos--
+		}
+	case ev.Key() == tcell.KeyRight, ev.MatchesRune('f', tcell.ModCtrl):
+		if p.pos < len(p.text) {
+			p.pos++
+		}
+	case ev.MatchesRune('b', tcell.ModAlt):
+		p.pos = p.wordStart()
+	case ev.MatchesRune('f', tcell.ModAlt):
+		p.pos = p.wordEnd()
+	case ev.Key() == tcell.KeyHome, ev.MatchesRune('a', tcell.ModCtrl):
+		p.pos = 0
+	case ev.Key() == tcell.KeyEnd, ev.MatchesRune('e', tcell.ModCtrl):
+		p.pos = len(p.text)
+	case ev.Key() == tcell.KeyBackspace, ev.Key() == tcell.KeyBackspace2:
+		if p.pos > 0 {
+			p.remove(p.pos-1, p.pos)
+		}
+	case ev.Key() == tcell.KeyDelete, ev.MatchesRune('d', tcell.ModCtrl):
+		if p.pos < len(p.text) {
+			p.remove(p.pos, p.pos+1)
+		}
+	case ev.MatchesRune('k', tcell.ModCtrl):
+		p.kill(p.pos, len(p.text))
+		killing = true
+	case ev.MatchesRune('u', tcell.ModCtrl):
+		p.kill(0, p.pos)
+		killing = true
+	case ev.MatchesRune('w', tcell.ModCtrl):
+		p.kill(p.wordStart(), p.pos)
+		killing = true
+	case ev.MatchesRune('y', tcell.ModCtrl):
+		p.yank(len(p.kills) - 1)
+		yanked = p.yanked
+	case ev.MatchesRune('y', tcell.ModAlt):
+		if p.yanked == 0 {
+			return true
+		}
+		p.remove(p.pos-p.yanked, p.pos)
+		p.yank(p.yankIndex - 1)
+		yanked = p.yanked
+	case ev.Key() == tcell.KeyUp, ev.MatchesRune('p', tcell.ModCtrl):
+		p.recall(p.histPos - 1)
+	case ev.Key() == tcell.KeyDown, ev.MatchesRune('n', tcell.ModCtrl):
+		p.recall(p.histPos + 1)
+	case ev.MatchesRune('r', tcell.ModCtrl):
+		p.searching = true
+		p.query = ""
+		p.match = p.histPos
+		p.original, _ = p.line()
+	case ev.Key() == tcell.KeyTab:
+		if p.complete == nil {
+			return false
+		}
+		p.setLine(p.complete(p.line()))
+	case ev.Key() == tcell.KeyEnter:
+		line, _ := p.line()
+		p.AddHistory(line)
+		p.setLine("", 0)
+		if p.submit != nil {
+			p.submit(line)
+		}
+	case ev.Key() == tcell.KeyRune && ev.Modifiers()&(tcell.ModCtrl|tcell.ModAlt) == 0:
+		p.insert(string(ev.Rune()))
+	default:
+		return false
+	}
+	p.PostEventWidgetContent(p)
+	return true
+}
+
+// HandleEvent handles keys to edit the text.
+func (p *Prompt) HandleEvent(ev tcell.Event) bool {
+	if style, ok := themeStyle(ev, p.role); ok {
+		p.SetStyle(style)
+		return false
+	}
+	if ev, ok := ev.(*tcell.EventKey); ok {
+		return p.handleKey(ev)
+	}
+	return false
+}
+
+// promptText returns the prompt that is shown, which during a search
+// shows the query instead.
+func (p *Prompt) promptText() string {
+	if p.searching {
+		return "(reverse-i-search)`" + p.query + "': "
+	}
+	return p.prompt
+}
+
+func clusterCells(s string) ([]rune, int) {
+	runes := []rune(s)
+	w := uniseg.StringWidth(s)
+	if w > 2 {
+		w = 2
+	}
+	return runes, w
+}
+
+// Draw draws the prompt and the text, scrolling the text horizontally to
+// keep the cursor in view.  The cursor is shown in reverse video.
+func (p *Prompt) Draw() {
+	if p.view == nil {
+		return
+	}
+	p.view.Fill(' ', p.style)
+	width, _ := p.view.Size()
+	x := 0
+	for _, r := range p.promptText() {
+		p.view.SetContent(x, 0, r, nil, p.style)
+		x += uniseg.StringWidth(string(r))
+	}
+
+	// keep the cursor, and the cell it needs, within the view
+	avail := width - x - 1
+	if p.offset > p.pos {
+		p.offset = p.pos
+	}
+	for {
+		used := 0
+		for _, c := range p.text[p.offset:p.pos] {
+			_, w := clusterCells(c)
+			used += w
+		}
+		if used <= avail || p.offset >= p.pos {
+			break
+		}
+		p.offset++
+	}
+
+	for i := p.offset; i <= len(p.text) && x < width; i++ {
+		style := p.style
+		if i == p.pos {
+			style = style.Reverse(true)
+		}
+		if i == len(p.text) {
+			if i == p.pos {
+				p.view.SetContent(x, 0, ' ', nil, style)
+			}
+			break
+		}
+		runes, w := clusterCells(p.text[i])
+		if x+w > width {
+			break
+		}
+		p.view.SetContent(x, 0, runes[0], runes[1:], style)
+		x += w
+	}
+}
+
+// Resize is called when the View size changes.
+func (p *Prompt) Resize() {
+	p.PostEventWidgetResize(p)
+}
+
+// Size returns the width needed for the prompt and the text (with room
+// for the cursor at the end), and a height of one line.
+func (p *Prompt) Size() (int, int) {
+	w := uniseg.StringWidth(p.promptText()) + 1
+	for _, c := range p.text {
+		_, cw := clusterCells(c)
+		w += cw
+	}
+	return w, 1
+}
+
+// SetView sets the View object used for the prompt.
+func (p *Prompt) SetView(view View) {
+	p.view = view
+}
+
+// CanFocus returns true, as a Prompt can always have the focus of a
+// FocusManager.
+func (p *Prompt) CanFocus() bool {
+	return true
+}
+
+// SetPrompt sets the prompt shown before the text.
+func (p *Prompt) SetPrompt(prompt string) {
+	p.prompt = prompt
+	p.PostEventWidgetContent(p)
+}
+
+// SetText sets the text being edited, with the cursor at the end.
+func (p *Prompt) SetText(s string) {
+	p.setLine(s, len(s))
+}
+
+// Text returns the text being edited.
+func (p *Prompt) Text() string {
+	line, _ := p.line()
+	return line
+}
+
+// SetStyle sets the style used for the prompt and the text.
+func (p *Prompt) SetStyle(style tcell.Style) {
+	p.style = style
+	p.PostEventWidgetContent(p)
+}
+
+// SetStyleRole sets the role used to find the style in the theme.  When
+// the theme changes, the style is set (as if by SetStyle) to the style for
+// the role in the new theme.  The empty role (the default) leaves the
+// style alone.
+func (p *Prompt) SetStyleRole(role tcell.StyleRole) {
+	p.role = role
+}
+
+// SetSubmitFunc sets a function that is called with the text when Enter
+// is pressed.  The text is added to the history, and the line is cleared,
+// before it is called.
+func (p *Prompt) SetSubmitFunc(fn func(text string)) {
+	p.submit = fn
+}
+
+// SetCompleteFunc sets a function that is called to complete the text
+// when Tab is pressed.  It is given the text and the cursor (as a byte
+// offset), and returns the completed text and the new cursor.  Without
+// one, Tab is not handled.
+func (p *Prompt) SetCompleteFunc(fn func(text string, pos int) (string, int)) {
+	p.complete = fn
+}
+
+// AddHistory adds an entry to the end of the history, unless it is empty
+// or the same as the last entry.  Recalling history starts again from the
+// end.
+func (p *Prompt) AddHistory(s string) {
+	if s != "" && (len(p.history) == 0 || p.history[len(p.history)-1] != s) {
+		p.history = append(p.history, s)
+	}
+	p.histPos = len(p.history)
+}
+
+// SetHistory replaces the history, which is ordered from oldest to newest.
+func (p *Prompt) SetHistory(history []string) {
+	p.history = append([]string(nil), history...)
+	p.histPos = len(p.history)
+}
+
+// History returns the history, from oldest to newest.
+func (p *Prompt) History() []string {
+	return append([]string(nil), p.history...)
+}
+
+// NewPrompt creates an empty Prompt.
+func NewPrompt(prompt string) *Prompt {
+	return &Prompt{prompt: prompt}
+}
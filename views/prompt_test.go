@@ -0,0 +1,153 @@
+package views
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestPromptEditing(t *testing.T) {
+	p := NewPrompt("> ")
+	typeText := func(s string) {
+		for _, r := range s {
+			p.HandleEvent(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+		}
+	}
+	key := func(k tcell.Key) {
+		if !p.HandleEvent(tcell.NewEventKey(k, 0, tcell.ModNone)) {
+			t.Errorf("key %v not handled", k)
+		}
+	}
+	ctrl := func(r rune) {
+		// as read from a terminal, which sends control characters
+		if !p.HandleEvent(tcell.NewEventKey(tcell.KeyRune, r-'a'+1, tcell.ModNone)) {
+			t.Errorf("Ctrl-%c not handled", r)
+		}
+	}
+	alt := func(r rune) {
+		p.HandleEvent(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModAlt))
+	}
+
+	typeText("café au lait")
+	key(tcell.KeyHome)
+	key(tcell.KeyRight)
+	key(tcell.KeyRight)
+	key(tcell.KeyRight)
+	key(tcell.KeyDelete) // the e and its accent are one character
+	if text := p.Text(); text != "caf au lait" {
+		t.Errorf("wrong text after delete: %q", text)
+	}
+
+	// Consecutive kills are joined, and yanked together.
+	key(tcell.KeyEnd)
+	ctrl('w')
+	ctrl('w')
+	if text := p.Text(); text != "caf " {
+		t.Errorf("wrong text after kill: %q", text)
+	}
+	ctrl('a')
+	ctrl('k')
+	ctrl('y')
+	if text := p.Text(); text != "caf " {
+		t.Errorf("wrong text after yank: %q", text)
+	}
+	typeText("x")
+	ctrl('y')
+	alt('y')
+	if text := p.Text(); text != "caf xau lait" {
+		t.Errorf("wrong text after yank pop: %q", text)
+	}
+	alt('b')
+	if p.pos != 8 {
+		t.Errorf("wrong position after word left: %d", p.pos)
+	}
+
+	var submitted string
+	p.SetSubmitFunc(func(text string) { submitted = text })
+	p.SetText("hello")
+	key(tcell.KeyEnter)
+	if submitted != "hello" || p.Text() != "" {
+		t.Errorf("wrong submit: %q, left %q", submitted, p.Text())
+	}
+
+	p.SetCompleteFunc(func(text string, pos int) (string, int) {
+		return text + "lo world", pos + 8
+	})
+	typeText("hel")
+	key(tcell.KeyTab)
+	if text := p.Text(); text != "hello world" {
+		t.Errorf("wrong completion: %q", text)
+	}
+}
+
+func TestPromptHistory(t *testing.T) {
+	p := NewPrompt("")
+	p.SetHistory([]string{"make test", "git status", "make build"})
+	key := func(k tcell.Key) {
+		p.HandleEvent(tcell.NewEventKey(k, 0, tcell.ModNone))
+	}
+	ctrl := func(r rune) {
+		p.HandleEvent(tcell.NewEventKey(tcell.KeyRune, r-'a'+1, tcell.ModNone))
+	}
+	p.SetText("draft")
+	key(tcell.KeyUp)
+	key(tcell.KeyUp)
+	if text := p.Text(); text != "git status" {
+		t.Errorf("wrong history recalled: %q", text)
+	}
+	key(tcell.KeyDown)
+	key(tcell.KeyDown)
+	if text := p.Text(); text != "draft" {
+		t.Errorf("draft not restored: %q", text)
+	}
+
+	ctrl('r')
+	for _, r := range "make" {
+		p.HandleEvent(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+	if text := p.Text(); text != "make build" {
+		t.Errorf("wrong search match: %q", text)
+	}
+	ctrl('r')
+	if text := p.Text(); text != "make test" {
+		t.Errorf("wrong second search match: %q", text)
+	}
+	ctrl('g')
+	if text := p.Text(); text != "draft" || p.searching {
+		t.Errorf("search not cancelled: %q", text)
+	}
+	ctrl('r')
+	p.HandleEvent(tcell.NewEventKey(tcell.KeyRune, 'g', tcell.ModNone))
+	key(tcell.KeyEnd)
+	if text := p.Text(); text != "git status" || p.searching || p.pos != len(p.text) {
+		t.Errorf("search not accepted: %q", text)
+	}
+	key(tcell.KeyUp)
+	if text := p.Text(); text != "make test" {
+		t.Errorf("history not continued from match: %q", text)
+	}
+}
+
+func TestPromptDraw(t *testing.T) {
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	s.SetSize(10, 1)
+
+	p := NewPrompt("> ")
+	p.SetView(s)
+	p.SetText("abcdefghijkl")
+	p.Draw()
+	// the text is scrolled to keep the cursor in view
+	if r, _, _, _ := s.GetContent(8, 0); r != 'l' {
+		t.Errorf("text not scrolled: %q", r)
+	}
+	if _, _, style, _ := s.GetContent(9, 0); style != tcell.StyleDefault.Reverse(true) {
+		t.Errorf("cursor not drawn")
+	}
+	if w, h := p.Size(); w != 15 || h != 1 {
+		t.Errorf("wrong size %dx%d", w, h)
+	}
+}
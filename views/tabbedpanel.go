@@ -0,0 +1,310 @@
+// Copyright 2024 The Tcell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package views
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/uniseg"
+)
+
+// TabbedPanel is a container Widget that shows one of several widgets at a
+// time, below a row of tabs with their titles.  A tab is chosen by
+// clicking on it, or with Ctrl-PgUp and Ctrl-PgDn.  When the row of tabs
+// has the focus of a FocusManager, Left and Right (and Home and End) also
+// choose a tab, and the focus moves on to the widget shown with Tab.
+type TabbedPanel struct {
+	view    View
+	tabs    []*tab
+	cur     int
+	focused bool
+	style   tcell.Style
+	role    tcell.StyleRole
+
+	WidgetWatchers
+}
+
+type tab struct {
+	title  string
+	widget Widget
+	port   *ViewPort
+}
+
+// layout places the widgets below the row of tabs.
+func (tp *TabbedPanel) layout() {
+	if tp.view == nil {
+		return
+	}
+	w, h := tp.view.Size()
+	for _, t := range tp.tabs {
+		t.port.Resize(0, 1, w, h-1)
+		t.widget.Resize()
+	}
+}
+
+// tabAt returns the index of the tab at column x of the row of tabs, or
+// -1 if there is none.
+func (tp *TabbedPanel) tabAt(x int) int {
+	for i, t := range tp.tabs {
+		w := uniseg.StringWidth(t.title) + 2
+		if x < w {
+			return i
+		}
+		x -= w
+	}
+	return -1
+}
+
+// AddTab adds a tab with the title, showing the widget, after the other
+// tabs.
+func (tp *TabbedPanel) AddTab(title string, widget Widget) {
+	t := &tab{title: title, widget: widget, port: NewViewPort(tp.view, 0, 0, 0, 0)}
+	widget.SetView(t.port)
+	widget.Watch(tp)
+	tp.tabs = append(tp.tabs, t)
+	tp.layout()
+	tp.PostEventWidgetContent(tp)
+}
+
+// RemoveTab removes the tab showing the widget.  If it was the current
+// tab, the next one (or the last one) becomes current.
+func (tp *TabbedPanel) RemoveTab(widget Widget) {
+	for i, t := range tp.tabs {
+		if t.widget == widget {
+			widget.Unwatch(tp)
+			tp.tabs = append(tp.tabs[:i], tp.tabs[i+1:]...)
+			if tp.cur > i || tp.cur >= len(tp.tabs) {
+				tp.cur--
+			}
+			if tp.cur < 0 {
+				tp.cur = 0
+			}
+			tp.PostEventWidgetContent(tp)
+			return
+		}
+	}
+}
+
+// SetCurrent makes the tab at the index the current one.
+func (tp *TabbedPanel) SetCurrent(index int) {
+	if index < 0 || index >= len(tp.tabs) || index == tp.cur {
+		return
+	}
+	tp.cur = index
+	tp.PostEventWidgetContent(tp)
+}
+
+// Current returns the index of the current tab, and the widget it shows,
+// which is nil if there are no tabs.
+func (tp *TabbedPanel) Current() (int, Widget) {
+	if len(tp.tabs) == 0 {
+		return 0, nil
+	}
+	return tp.cur, tp.tabs[tp.cur].widget
+}
+
+// SetTitle sets the title of the tab at the index.
+func (tp *TabbedPanel) SetTitle(index int, title string) {
+	if index >= 0 && index < len(tp.tabs) {
+		tp.tabs[index].title = title
+		tp.PostEventWidgetContent(tp)
+	}
+}
+
+// Widgets returns the widgets of the tabs, in order.
+func (tp *TabbedPanel) Widgets() []Widget {
+	w := make([]Widget, 0, len(tp.tabs))
+	for _, t := range tp.tabs {
+		w = append(w, t.widget)
+	}
+	return w
+}
+
+// CanFocus returns true if there are tabs, so that the row of tabs can
+// have the focus of a FocusManager.
+func (tp *TabbedPanel) CanFocus() bool {
+	return len(tp.tabs) > 0
+}
+
+// FocusChain returns the panel itself, for the row of tabs, followed by
+// the widget shown.  The widgets of the other tabs are hidden, so they
+// cannot have the focus.
+func (tp *TabbedPanel) FocusChain() []Widget {
+	if len(tp.tabs) == 0 {
+		return nil
+	}
+	return []Widget{tp, tp.tabs[tp.cur].widget}
+}
+
+func (tp *TabbedPanel) handleKey(ev *tcell.EventKey) bool {
+	switch {
+	case ev.Key() == tcell.KeyPgUp && ev.Modifiers()&tcell.ModCtrl != 0:
+		tp.SetCurrent((tp.cur + len(tp.tabs) - 1) % len(tp.tabs))
+	case ev.Key() == tcell.KeyPgDn && ev.Modifiers()&tcell.ModCtrl != 0:
+		tp.SetCurrent((tp.cur + 1) % len(tp.tabs))
+	case !tp.focused:
+		return false
+	case ev.Key() == tcell.KeyLeft:
+		tp.SetCurrent(tp.cur - 1)
+	case ev.Key() == tcell.KeyRight:
+		tp.SetCurrent(tp.cur + 1)
+	case ev.Key() == tcell.KeyHome:
+		tp.SetCurrent(0)
+	case ev.Key() == tcell.KeyEnd:
+		tp.SetCurrent(len(tp.tabs) - 1)
+	default:
+		return false
+	}
+	return true
+}
+
+// HandleEvent handles clicks on the tabs, and keys to choose a tab, and
+// passes other events to the widget shown.  Keys are also passed to the
+// widget shown first, unless the row of tabs has the focus.
+func (tp *TabbedPanel) HandleEvent(ev tcell.Event) bool {
+	if style, ok := themeStyle(ev, tp.role); ok {
+		tp.SetStyle(style)
+	}
+	switch ev := ev.(type) {
+	case *tcell.EventThemeChange:
+		for _, t := range tp.tabs {
+			t.widget.HandleEvent(ev)
+		}
+		return false
+	case *EventWidgetFocus:
+		if ev.Widget() == Widget(tp) {
+			tp.focused = ev.Focused()
+			tp.PostEventWidgetContent(tp)
+			return true
+		}
+	case *EventWidgetContent:
+		// This can only have come from one of our tabs.
+		tp.PostEventWidgetContent(tp)
+		return true
+	case *tcell.EventKey:
+		if len(tp.tabs) == 0 {
+			return false
+		}
+		if !tp.focused && tp.tabs[tp.cur].widget.HandleEvent(ev) {
+			return true
+		}
+		return tp.handleKey(ev)
+	case *tcell.EventClick:
+		x, y := ev.Position()
+		if x, y, ok := contentPosition(tp.view, x, y); ok && y == 0 {
+			if i := tp.tabAt(x); i >= 0 && ev.Button() == tcell.Button1 {
+				tp.SetCurrent(i)
+				return true
+			}
+			return false
+		}
+	}
+	if len(tp.tabs) == 0 {
+		return false
+	}
+	return tp.tabs[tp.cur].widget.HandleEvent(ev)
+}
+
+// Draw draws the row of tabs, with the current one in reverse video (and
+// underlined if it has the focus), and the widget of the current tab.
+func (tp *TabbedPanel) Draw() {
+	if tp.view == nil {
+		return
+	}
+	width, _ := tp.view.Size()
+	for x := 0; x < width; x++ {
+		tp.view.SetContent(x, 0, ' ', nil, tp.style)
+	}
+	x := 0
+	for i, t := range tp.tabs {
+		style := tp.style
+		if i == tp.cur {
+			style = style.Reverse(true).Underline(tp.focused)
+		}
+		tp.view.SetContent(x, 0, ' ', nil, style)
+		x++
+		g := uniseg.NewGraphemes(t.title)
+		for g.Next() {
+			runes, w := clusterCells(g.Str())
+			if x+w > width {
+				break
+			}
+			tp.view.SetContent(x, 0, runes[0], runes[1:], style)
+			x += w
+		}
+		if x < width {
+			tp.view.SetContent(x, 0, ' ', nil, style)
+		}
+		x++
+	}
+	if len(tp.tabs) > 0 {
+		t := tp.tabs[tp.cur]
+		t.port.Fill(' ', tp.style)
+		t.widget.Draw()
+	}
+}
+
+// Resize is called when the View size changes.
+func (tp *TabbedPanel) Resize() {
+	tp.layout()
+	tp.PostEventWidgetResize(tp)
+}
+
+// Size returns the size needed for the row of tabs and the largest of the
+// widgets, so that the size does not change when the tab does.
+func (tp *TabbedPanel) Size() (int, int) {
+	w, h := 0, 0
+	for _, t := range tp.tabs {
+		w += uniseg.StringWidth(t.title) + 2
+	}
+	for _, t := range tp.tabs {
+		tw, th := t.widget.Size()
+		if tw > w {
+			w = tw
+		}
+		if th > h {
+			h = th
+		}
+	}
+	return w, h + 1
+}
+
+// SetView sets the View object used for the panel.
+func (tp *TabbedPanel) SetView(view View) {
+	tp.view = view
+	for _, t := range tp.tabs {
+		t.port.SetView(view)
+	}
+	tp.layout()
+}
+
+// SetStyle sets the style used for the row of tabs, and behind the
+// widgets.
+func (tp *TabbedPanel) SetStyle(style tcell.Style) {
+	tp.style = style
+	tp.PostEventWidgetContent(tp)
+}
+
+// SetStyleRole sets the role used to find the style in the theme.  When
+// the theme changes, the style is set (as if by SetStyle) to the style for
+// the role in the new theme.  The empty role (the default) leaves the
+// style alone.  The change is also passed on to the widgets of all tabs.
+func (tp *TabbedPanel) SetStyleRole(role tcell.StyleRole) {
+	tp.role = role
+}
+
+// NewTabbedPanel creates an empty TabbedPanel.
+func NewTabbedPanel() *TabbedPanel {
+	return &TabbedPanel{}
+}
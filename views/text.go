@@ -25,7 +25,9 @@ import (
 type Text struct {
 	view    View
 	align   Alignment
+	dir     tcell.TextDirection
 	style   tcell.Style
+	role    tcell.StyleRole
 	text    []rune
 	widths  []int
 	styles  []tcell.Style
@@ -97,7 +99,9 @@ func (t *Text) Draw() {
 	var comb []rune
 	line := 0
 	newline := true
-	for i, l := range t.text {
+	order, runes := t.visualOrder()
+	for k, i := range order {
+		l := runes[k]
 
 		if newline {
 			x = t.calcX(width, line)
@@ -132,6 +136,32 @@ func (t *Text) Draw() {
 	}
 }
 
+// visualOrder returns the indices of the text in display order, and
+// the runes to display, with each line shaped (see tcell.SetShaper) and
+// reordered for bidirectional text.
+func (t *Text) visualOrder() ([]int, []rune) {
+	order := make([]int, 0, len(t.text))
+	runes := make([]rune, 0, len(t.text))
+	start := 0
+	for i := 0; i <= len(t.text); i++ {
+		if i < len(t.text) && t.text[i] != '\n' {
+			continue
+		}
+		line := tcell.ShapeText(t.text[start:i])
+		p := tcell.NewBidiParagraph(string(line), t.dir)
+		for v := 0; v < i-start; v++ {
+			order = append(order, start+p.VisualToLogical(v))
+		}
+		runes = append(runes, p.Runes()...)
+		if i < len(t.text) {
+			order = append(order, i)
+			runes = append(runes, '\n')
+		}
+		start = i + 1
+	}
+	return order, runes
+}
+
 // Size returns the width and height in character cells of the Text.
 func (t *Text) Size() (int, int) {
 	if len(t.text) != 0 {
@@ -155,13 +185,32 @@ func (t *Text) Alignment() Alignment {
 	return t.align
 }
 
+// SetDirection sets the paragraph direction used when displaying
+// bidirectional text.  The default, tcell.DirectionAuto, uses the direction
+// of the first strongly directional character of each line.
+func (t *Text) SetDirection(dir tcell.TextDirection) {
+	if dir != t.dir {
+		t.dir = dir
+		t.PostEventWidgetContent(t)
+	}
+}
+
+// Direction returns the paragraph direction of the Text.
+func (t *Text) Direction() tcell.TextDirection {
+	return t.dir
+}
+
 // SetView sets the View object used for the text bar.
 func (t *Text) SetView(view View) {
 	t.view = view
 }
 
-// HandleEvent implements a tcell.EventHandler, but does nothing.
-func (t *Text) HandleEvent(tcell.Event) bool {
+// HandleEvent implements a tcell.EventHandler.  The only event handled
+// is a change of theme, if a style role has been set.
+func (t *Text) HandleEvent(ev tcell.Event) bool {
+	if style, ok := themeStyle(ev, t.role); ok {
+		t.SetStyle(style)
+	}
 	return false
 }
 
@@ -238,6 +287,14 @@ func (t *Text) SetStyle(style tcell.Style) {
 	t.PostEventWidgetContent(t)
 }
 
+// SetStyleRole sets the role used to find the style of the text in the
+// theme.  When the theme changes, the style is set (as if by SetStyle) to
+// the style for the role in the new theme.  The empty role (the default)
+// leaves the style alone.
+func (t *Text) SetStyleRole(role tcell.StyleRole) {
+	t.role = role
+}
+
 // Style returns the previously set default style.  Note that
 // individual characters may have different styles.
 func (t *Text) Style() tcell.Style {
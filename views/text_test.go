@@ -1,6 +1,10 @@
 package views
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
 
 func TestText(t *testing.T) {
 	text := &Text{}
@@ -17,3 +21,69 @@ Long
 		t.Errorf("Incorrect width: %d, expected: %d", text.width, 20)
 	}
 }
+
+func TestTextBidi(t *testing.T) {
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	s.SetSize(10, 2)
+
+	text := NewText()
+	text.SetView(s)
+	text.SetText("ab אבג\nאבג!")
+	text.SetStyleAt(3, tcell.StyleDefault.Bold(true))
+	text.Draw()
+
+	row := func(y, n int) string {
+		rs := make([]rune, n)
+		for x := range rs {
+			rs[x], _, _, _ = s.GetContent(x, y)
+		}
+		return string(rs)
+	}
+	if r := row(0, 6); r != "ab גבא" {
+		t.Errorf("Wrong first line: %q", r)
+	}
+	if r := row(1, 4); r != "!גבא" {
+		t.Errorf("Wrong second line: %q", r)
+	}
+	if _, _, style, _ := s.GetContent(5, 0); style != tcell.StyleDefault.Bold(true) {
+		t.Errorf("Style did not follow the character")
+	}
+
+	text.SetDirection(tcell.DirectionLTR)
+	text.Draw()
+	if r := row(1, 4); r != "גבא!" {
+		t.Errorf("Wrong forced direction line: %q", r)
+	}
+}
+
+func TestTextTheme(t *testing.T) {
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+
+	plain := NewText()
+	plain.SetText("plain")
+	themed := NewText()
+	themed.SetText("themed")
+	themed.SetStyleRole(tcell.RoleTitle)
+
+	box := NewBoxLayout(Vertical)
+	box.AddWidget(plain, 0)
+	box.AddWidget(themed, 0)
+
+	light := tcell.NewLightTheme()
+	s.SetTheme(light)
+	box.HandleEvent(s.PollEvent())
+	if themed.Style() != light.Style(tcell.RoleTitle) {
+		t.Errorf("Theme not applied")
+	}
+	if plain.Style() != tcell.StyleDefault {
+		t.Errorf("Theme applied to text without a role")
+	}
+}
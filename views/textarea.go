@@ -115,6 +115,17 @@ func (ta *TextArea) SetStyle(style tcell.Style) {
 	ta.CellView.SetStyle(style)
 }
 
+// HandleEvent handles events.  This is the same as for CellView, except
+// that a change of theme also changes the style of the text.
+func (ta *TextArea) HandleEvent(ev tcell.Event) bool {
+	if style, ok := themeStyle(ev, ta.role); ok {
+		ta.Init()
+		ta.SetStyle(style)
+		return false
+	}
+	return ta.CellView.HandleEvent(ev)
+}
+
 // EnableCursor enables a soft cursor in the TextArea.
 func (ta *TextArea) EnableCursor(on bool) {
 	ta.Init()
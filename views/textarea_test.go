@@ -1,6 +1,10 @@
 package views
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
 
 func TestSetContent(t *testing.T) {
 	ta := &TextArea{}
@@ -15,3 +19,47 @@ func TestSetContent(t *testing.T) {
 		t.Errorf("Incorrect width: %d, expected: %d", ta.model.width, 11)
 	}
 }
+
+func TestTextAreaClickSelection(t *testing.T) {
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	s.SetSize(20, 5)
+
+	ta := NewTextArea()
+	ta.SetContent("hello world/foo\nsecond line")
+	view := NewViewPort(s, 2, 1, 15, 3)
+	ta.SetView(view)
+
+	click := func(x, y, count int) {
+		if !ta.HandleEvent(tcell.NewEventClick(x, y, tcell.Button1, tcell.ModNone, count)) {
+			t.Errorf("click at %d,%d not handled", x, y)
+		}
+	}
+	click(10, 1, 2) // "world/foo", offset by the view
+	if text := ta.SelectedText(); text != "world/foo" {
+		t.Errorf("wrong word selected: %q", text)
+	}
+	ta.SetWordSpace(func(r rune) bool { return r == ' ' || r == '/' })
+	click(10, 1, 2)
+	if text := ta.SelectedText(); text != "world" {
+		t.Errorf("wrong word selected with custom rule: %q", text)
+	}
+	click(3, 2, 3)
+	if text := ta.SelectedText(); text != "second line" {
+		t.Errorf("wrong line selected: %q", text)
+	}
+	ta.Draw()
+	if _, _, style, _ := s.GetContent(4, 2); style != tcell.StyleDefault.Reverse(true) {
+		t.Errorf("selection not drawn")
+	}
+	click(3, 2, 1)
+	if _, _, _, _, ok := ta.Selection(); ok {
+		t.Errorf("selection not cleared")
+	}
+	if ta.HandleEvent(tcell.NewEventClick(0, 0, tcell.Button1, tcell.ModNone, 2)) {
+		t.Errorf("click outside the view handled")
+	}
+}
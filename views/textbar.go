@@ -27,6 +27,7 @@ import (
 type TextBar struct {
 	changed bool
 	style   tcell.Style
+	role    tcell.StyleRole
 	left    Text
 	right   Text
 	center  Text
@@ -78,6 +79,14 @@ func (t *TextBar) SetStyle(style tcell.Style) {
 	t.style = style
 }
 
+// SetStyleRole sets the role used to find the style of the textbar in
+// the theme.  When the theme changes, the style of the textbar, and of
+// the text in it, is set to the style for the role in the new theme.
+// The empty role (the default) leaves the styles alone.
+func (t *TextBar) SetStyleRole(role tcell.StyleRole) {
+	t.role = role
+}
+
 func (t *TextBar) initialize() {
 	t.once.Do(func() {
 		t.center.SetView(&t.cview)
@@ -177,6 +186,14 @@ func (t *TextBar) Size() (int, int) {
 // those for the Text objects; when those change, the TextBar adjusts
 // the layout to accommodate.
 func (t *TextBar) HandleEvent(ev tcell.Event) bool {
+	if style, ok := themeStyle(ev, t.role); ok {
+		t.SetStyle(style)
+		t.left.SetStyle(style)
+		t.center.SetStyle(style)
+		t.right.SetStyle(style)
+		t.changed = true
+		return false
+	}
 	switch ev.(type) {
 	case *EventWidgetContent:
 		t.changed = true
@@ -0,0 +1,589 @@
+// Copyright 2024 The Tcell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package views
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/uniseg"
+)
+
+// TreeNode is a node in a Tree.  A node has a line of text, and children,
+// which may be loaded only when the node is first expanded (see
+// Tree.SetLoadFunc).
+type TreeNode struct {
+	text     string
+	style    tcell.Style
+	styled   bool
+	data     interface{}
+	parent   *TreeNode
+	children []*TreeNode
+	loaded   bool // children have been set or loaded
+	leaf     bool
+	expanded bool
+	selected bool
+}
+
+// NewTreeNode creates a node with the given text.
+func NewTreeNode(text string) *TreeNode {
+	return &TreeNode{text: text}
+}
+
+// Text returns the text of the node.
+func (n *TreeNode) Text() string {
+	return n.text
+}
+
+// SetText sets the text of the node.
+func (n *TreeNode) SetText(text string) {
+	n.text = text
+}
+
+// SetStyle sets the style of the node, instead of the style of the tree.
+func (n *TreeNode) SetStyle(style tcell.Style) {
+	n.style, n.styled = style, true
+}
+
+// Data returns the data of the node.
+func (n *TreeNode) Data() interface{} {
+	return n.data
+}
+
+// SetData sets data for the application's use, such as the path of a
+// file.
+func (n *TreeNode) SetData(data interface{}) {
+	n.data = data
+}
+
+// SetLeaf marks the node as one that never has children, so that it is
+// not loaded, and is not shown as expandable.
+func (n *TreeNode) SetLeaf(leaf bool) {
+	n.leaf = leaf
+}
+
+// AddChild adds a child to the end of the children, and returns it.
+func (n *TreeNode) AddChild(child *TreeNode) *TreeNode {
+	child.parent = n
+	n.children = append(n.children, child)
+	n.loaded = true
+	return child
+}
+
+// SetChildren replaces the children.
+func (n *TreeNode) SetChildren(children []*TreeNode) {
+	for _, c := range n.children {
+		c.parent = nil
+	}
+	n.children = nil
+	n.loaded = true
+	for _, c := range children {
+		n.AddChild(c)
+	}
+}
+
+// ClearChildren removes the children, and collapses the node, so that
+// they are loaded again when it is next expanded.
+func (n *TreeNode) ClearChildren() {
+	n.SetChildren(nil)
+	n.loaded = false
+	n.expanded = false
+}
+
+// Children returns the children, which are only those loaded so far.
+func (n *TreeNode) Children() []*TreeNode {
+	return append([]*TreeNode(nil), n.children...)
+}
+
+// Parent returns the parent, or nil for the root.
+func (n *TreeNode) Parent() *TreeNode {
+	return n.parent
+}
+
+// Expanded returns true if the children of the node are shown.
+func (n *TreeNode) Expanded() bool {
+	return n.expanded
+}
+
+// Selected returns true if the node is selected.
+func (n *TreeNode) Selected() bool {
+	return n.selected
+}
+
+// isAncestorOf returns true if m is below n in the tree.
+func (n *TreeNode) isAncestorOf(m *TreeNode) bool {
+	for m = m.parent; m != nil; m = m.parent {
+		if m == n {
+			return true
+		}
+	}
+	return false
+}
+
+// treeRow is a node shown in the tree, at a depth below the root.
+type treeRow struct {
+	node  *TreeNode
+	depth int
+}
+
+// Tree is a Widget that shows a tree of nodes, one per line, indented by
+// their depth, such as the directories and files of a file browser.  The
+// children of a node can be loaded when it is first expanded, so that
+// large trees are only read as far as they are shown.
+//
+// One node is the current one, shown in reverse video, and any number of
+// nodes may be selected.  The tree is used with these keys:
+//
+//	Up, Ctrl-P / Down, Ctrl-N    move to the previous or next node
+//	Shift-Up / Shift-Down        move, selecting the nodes passed
+//	PgUp / PgDn, Home / End      move by a page, or to the first or last
+//	Right, + / Left, -           expand / collapse, or move to the parent
+//	Space                        select or deselect the node
+//	Enter                        activate (see SetActivateFunc)
+//
+// A click makes a node current, a click on the marker before it expands
+// or collapses it, and a click with Ctrl selects it.  A double click
+// activates it.
+type Tree struct {
+	view     View
+	root     *TreeNode
+	hideRoot bool
+	cur      *TreeNode
+	offset   int // first row shown
+	style    tcell.Style
+	selStyle tcell.Style
+	role     tcell.StyleRole
+	load     func(*TreeNode) []*TreeNode
+	activate func(*TreeNode)
+
+	WidgetWatchers
+}
+
+const treeIndent = 2
+
+// rows returns the nodes that are shown, in order.
+func (t *Tree) rows() []treeRow {
+	var rows []treeRow
+	var walk func(n *TreeNode, depth int)
+	walk = func(n *TreeNode, depth int) {
+		rows = append(rows, treeRow{n, depth})
+		if n.expanded {
+			for _, c := range n.children {
+				walk(c, depth+1)
+			}
+		}
+	}
+	switch {
+	case t.root == nil:
+	case t.hideRoot:
+		for _, c := range t.root.children {
+			walk(c, 0)
+		}
+	default:
+		walk(t.root, 0)
+	}
+	return rows
+}
+
+// index returns the row of the current node, or -1 if there are no rows.
+// If the current node is no longer shown, the nearest node above it that
+// is shown becomes the current one.
+func (t *Tree) index(rows []treeRow) int {
+	for n := t.cur; n != nil; n = n.parent {
+		for i, r := range rows {
+			if r.node == n {
+				t.cur = n
+				return i
+			}
+		}
+	}
+	if len(rows) == 0 {
+		return -1
+	}
+	t.cur = rows[0].node
+	return 0
+}
+
+// expandable returns true if the node has, or may have, children.
+func (t *Tree) expandable(n *TreeNode) bool {
+	if n.leaf {
+		return false
+	}
+	if n.loaded || t.load == nil {
+		return len(n.children) > 0
+	}
+	return true
+}
+
+// Expand shows the children of the node, loading them first if they have
+// not been loaded.
+func (t *Tree) Expand(n *TreeNode) {
+	if !n.loaded && !n.leaf && t.load != nil {
+		n.SetChildren(t.load(n))
+	}
+	n.expanded = len(n.children) > 0
+	t.PostEventWidgetContent(t)
+}
+
+// Collapse hides the children of the node.  If the current node was one of
+// them, the node becomes the current one.
+func (t *Tree) Collapse(n *TreeNode) {
+	n.expanded = false
+	if t.cur != nil && n.isAncestorOf(t.cur) {
+		t.cur = n
+	}
+	t.PostEventWidgetContent(t)
+}
+
+// Toggle expands the node if it is collapsed, and collapses it otherwise.
+func (t *Tree) Toggle(n *TreeNode) {
+	if n.expanded {
+		t.Collapse(n)
+	} else {
+		t.Expand(n)
+	}
+}
+
+// SetRoot sets the root of the tree.  The root becomes the current node.
+func (t *Tree) SetRoot(root *TreeNode) {
+	t.root = root
+	t.cur = nil
+	t.offset = 0
+	if root != nil && t.hideRoot && !root.expanded {
+		t.Expand(root)
+	}
+	t.PostEventWidgetContent(t)
+}
+
+// Root returns the root of the tree.
+func (t *Tree) Root() *TreeNode {
+	return t.root
+}
+
+// SetShowRoot shows or hides the root.  If it is hidden, its children are
+// shown at the top level, as is usual for file browsers.
+func (t *Tree) SetShowRoot(show bool) {
+	t.hideRoot = !show
+	t.SetRoot(t.root)
+}
+
+// SetLoadFunc sets a function that returns the children of a node, which
+// is called when a node is expanded for the first time.  Without one, only
+// the children added to the nodes are shown.  Nodes not yet loaded are
+// shown as expandable, unless marked as leaves.
+func (t *Tree) SetLoadFunc(fn func(node *TreeNode) []*TreeNode) {
+	t.load = fn
+}
+
+// SetActivateFunc sets a function that is called with the current node
+// when Enter is pressed, or a node is double clicked.  Without one, these
+// expand or collapse the node instead.
+func (t *Tree) SetActivateFunc(fn func(node *TreeNode)) {
+	t.activate = fn
+}
+
+// Current returns the current node, or nil if the tree is empty.
+func (t *Tree) Current() *TreeNode {
+	t.index(t.rows())
+	return t.cur
+}
+
+// SetCurrent makes the node the current one, expanding the nodes above it
+// so that it is shown.
+func (t *Tree) SetCurrent(n *TreeNode) {
+	for p := n.parent; p != nil; p = p.parent {
+		if !p.expanded {
+			t.Expand(p)
+		}
+	}
+	t.cur = n
+	t.PostEventWidgetContent(t)
+}
+
+// Select selects or deselects the node.
+func (t *Tree) Select(n *TreeNode, on bool) {
+	n.selected = on
+	t.PostEventWidgetContent(t)
+}
+
+// Selected returns the selected nodes, in the order they are in the tree.
+// Only nodes that have been loaded are considered.
+func (t *Tree) Selected() []*TreeNode {
+	var sel []*TreeNode
+	var walk func(n *TreeNode)
+	walk = func(n *TreeNode) {
+		if n.selected {
+			sel = append(sel, n)
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	if t.root != nil {
+		walk(t.root)
+	}
+	return sel
+}
+
+// ClearSelection deselects all nodes.
+func (t *Tree) ClearSelection() {
+	for _, n := range t.Selected() {
+		n.selected = false
+	}
+	t.PostEventWidgetContent(t)
+}
+
+// move moves the current node by delta rows, selecting the nodes on the
+// way if sel is true.
+func (t *Tree) move(delta int, sel bool) {
+	rows := t.rows()
+	i := t.index(rows)
+	if i < 0 {
+		return
+	}
+	j := i + delta
+	if j < 0 {
+		j = 0
+	}
+	if j > len(rows)-1 {
+		j = len(rows) - 1
+	}
+	if sel {
+		lo, hi := i, j
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		for _, r := range rows[lo : hi+1] {
+			r.node.selected = true
+		}
+	}
+	t.cur = rows[j].node
+	t.PostEventWidgetContent(t)
+}
+
+func (t *Tree) pageSize() int {
+	if t.view == nil {
+		return 1
+	}
+	_, h := t.view.Size()
+	if h < 2 {
+		return 1
+	}
+	return h - 1
+}
+
+// activateNode activates the node, or toggles it if there is no function
+// to activate it.
+func (t *Tree) activateNode(n *TreeNode) {
+	if t.activate != nil {
+		t.activate(n)
+	} else if t.expandable(n) {
+		t.Toggle(n)
+	}
+}
+
+func (t *Tree) handleKey(ev *tcell.EventKey) bool {
+	cur := t.Current()
+	if cur == nil {
+		return false
+	}
+	shift := ev.Modifiers()&tcell.ModShift != 0
+	switch {
+	case ev.Key() == tcell.KeyUp, ev.MatchesRune('p', tcell.ModCtrl):
+		t.move(-1, shift)
+	case ev.Key() == tcell.KeyDown, ev.MatchesRune('n', tcell.ModCtrl):
+		t.move(1, shift)
+	case ev.Key() == tcell.KeyPgUp:
+		t.move(-t.pageSize(), false)
+	case ev.Key() == tcell.KeyPgDn:
+		t.move(t.pageSize(), false)
+	case ev.Key() == tcell.KeyHome:
+		t.move(-len(t.rows()), false)
+	case ev.Key() == tcell.KeyEnd:
+		t.move(len(t.rows()), false)
+	case ev.Key() == tcell.KeyRight, ev.Key() == tcell.KeyRune && ev.Rune() == '+':
+		switch {
+		case !cur.expanded && t.expandable(cur):
+			t.Expand(cur)
+		case cur.expanded:
+			t.move(1, false)
+		}
+	case ev.Key() == tcell.KeyLeft, ev.Key() == tcell.KeyRune && ev.Rune() == '-':
+		switch {
+		case cur.expanded:
+			t.Collapse(cur)
+		case cur.parent != nil && (cur.parent != t.root || !t.hideRoot):
+			t.cur = cur.parent
+			t.PostEventWidgetContent(t)
+		}
+	case ev.Key() == tcell.KeyRune && ev.Rune() == ' ' && ev.Modifiers() == tcell.ModNone:
+		t.Select(cur, !cur.selected)
+	case ev.Key() == tcell.KeyEnter:
+		t.activateNode(cur)
+	default:
+		return false
+	}
+	return true
+}
+
+func (t *Tree) handleClick(ev *tcell.EventClick) bool {
+	if ev.Button() != tcell.Button1 {
+		return false
+	}
+	x, y := ev.Position()
+	x, y, ok := contentPosition(t.view, x, y)
+	rows := t.rows()
+	y += t.offset
+	if !ok || y >= len(rows) {
+		return false
+	}
+	r := rows[y]
+	t.cur = r.node
+	switch {
+	case ev.Modifiers()&tcell.ModCtrl != 0:
+		t.Select(r.node, !r.node.selected)
+	case ev.Count() == 2:
+		t.activateNode(r.node)
+	case x >= r.depth*treeIndent && x < (r.depth+1)*treeIndent && t.expandable(r.node):
+		t.Toggle(r.node)
+	default:
+		t.PostEventWidgetContent(t)
+	}
+	return true
+}
+
+// HandleEvent handles keys and clicks to move in the tree, expand and
+// collapse nodes, and select them.
+func (t *Tree) HandleEvent(ev tcell.Event) bool {
+	if tev, ok := ev.(*tcell.EventThemeChange); ok && t.role != "" {
+		t.SetStyle(tev.Theme().Style(t.role))
+		t.SetSelectionStyle(tev.Theme().Style(tcell.RoleSelection))
+		return false
+	}
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		return t.handleKey(ev)
+	case *tcell.EventClick:
+		return t.handleClick(ev)
+	}
+	return false
+}
+
+// Draw draws the nodes, scrolling to keep the current one in view.  Each
+// node is preceded by a marker showing whether it can be expanded (+) or
+// collapsed (-).
+func (t *Tree) Draw() {
+	if t.view == nil {
+		return
+	}
+	t.view.Fill(' ', t.style)
+	width, height := t.view.Size()
+	rows := t.rows()
+	i := t.index(rows)
+	if i < t.offset {
+		t.offset = i
+	}
+	if i >= t.offset+height {
+		t.offset = i - height + 1
+	}
+	if t.offset < 0 {
+		t.offset = 0
+	}
+	for y := 0; y < height && t.offset+y < len(rows); y++ {
+		r := rows[t.offset+y]
+		n := r.node
+		style := t.style
+		if n.selected {
+			style = t.selStyle
+		}
+		if n.styled {
+			style = n.style
+		}
+		if n == t.cur {
+			style = style.Reverse(true)
+		}
+		x := r.depth * treeIndent
+		switch {
+		case n.expanded:
+			t.view.SetContent(x, y, '-', nil, t.style)
+		case t.expandable(n):
+			t.view.SetContent(x, y, '+', nil, t.style)
+		}
+		x += treeIndent
+		g := uniseg.NewGraphemes(n.text)
+		for g.Next() {
+			runes, w := clusterCells(g.Str())
+			if x+w > width {
+				break
+			}
+			t.view.SetContent(x, y, runes[0], runes[1:], style)
+			x += w
+		}
+	}
+}
+
+// Resize is called when the View size changes.
+func (t *Tree) Resize() {
+	t.PostEventWidgetResize(t)
+}
+
+// Size returns the width of the widest node shown, and the number of
+// nodes shown.
+func (t *Tree) Size() (int, int) {
+	rows := t.rows()
+	w := 0
+	for _, r := range rows {
+		if rw := (r.depth+1)*treeIndent + uniseg.StringWidth(r.node.text); rw > w {
+			w = rw
+		}
+	}
+	return w, len(rows)
+}
+
+// SetView sets the View object used for the tree.
+func (t *Tree) SetView(view View) {
+	t.view = view
+}
+
+// CanFocus returns true if the tree has nodes, so that it can have the
+// focus of a FocusManager.
+func (t *Tree) CanFocus() bool {
+	return t.root != nil
+}
+
+// SetStyle sets the style used for nodes without a style of their own.
+func (t *Tree) SetStyle(style tcell.Style) {
+	t.style = style
+	t.PostEventWidgetContent(t)
+}
+
+// SetSelectionStyle sets the style used for selected nodes, without a
+// style of their own.
+func (t *Tree) SetSelectionStyle(style tcell.Style) {
+	t.selStyle = style
+	t.PostEventWidgetContent(t)
+}
+
+// SetStyleRole sets the role used to find the style in the theme.  When
+// the theme changes, the style is set (as if by SetStyle) to the style for
+// the role in the new theme, and the selection style to the style for
+// tcell.RoleSelection.  The empty role (the default) leaves the styles
+// alone.
+func (t *Tree) SetStyleRole(role tcell.StyleRole) {
+	t.role = role
+}
+
+// NewTree creates an empty Tree.
+func NewTree() *Tree {
+	return &Tree{selStyle: tcell.StyleDefault.Bold(true)}
+}
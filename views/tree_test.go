@@ -0,0 +1,189 @@
+// Copyright 2024 The Tcell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package views
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func treeNames(nodes []*TreeNode) []string {
+	var names []string
+	for _, n := range nodes {
+		names = append(names, n.Text())
+	}
+	return names
+}
+
+func TestTreeLazyLoading(t *testing.T) {
+	loads := 0
+	tr := NewTree()
+	tr.SetLoadFunc(func(n *TreeNode) []*TreeNode {
+		loads++
+		var kids []*TreeNode
+		if len(n.Text()) < 3 {
+			for i := 0; i < 2; i++ {
+				kids = append(kids, NewTreeNode(fmt.Sprintf("%s%d", n.Text(), i)))
+			}
+		}
+		return kids
+	})
+	tr.SetRoot(NewTreeNode("r"))
+	if loads != 0 {
+		t.Errorf("children loaded before expansion")
+	}
+	key := func(k tcell.Key, mod tcell.ModMask) {
+		if !tr.HandleEvent(tcell.NewEventKey(k, 0, mod)) {
+			t.Errorf("key %v not handled", k)
+		}
+	}
+	key(tcell.KeyRight, tcell.ModNone)
+	key(tcell.KeyRight, tcell.ModNone) // moves to the first child
+	key(tcell.KeyRight, tcell.ModNone)
+	if loads != 2 {
+		t.Errorf("wrong number of loads: %d", loads)
+	}
+	if names := treeNames(rowNodes(tr)); !reflect.DeepEqual(names, []string{"r", "r0", "r00", "r01", "r1"}) {
+		t.Errorf("wrong rows: %v", names)
+	}
+	key(tcell.KeyDown, tcell.ModNone)
+	key(tcell.KeyRight, tcell.ModNone) // r00 has no children
+	if n := tr.Current(); n.Text() != "r00" || n.Expanded() {
+		t.Errorf("wrong current node: %s", n.Text())
+	}
+	key(tcell.KeyLeft, tcell.ModNone)
+	key(tcell.KeyLeft, tcell.ModNone)
+	if n := tr.Current(); n.Text() != "r0" || n.Expanded() {
+		t.Errorf("not collapsed to parent: %s", n.Text())
+	}
+	// expanding again does not load again
+	key(tcell.KeyRight, tcell.ModNone)
+	if loads != 3 {
+		t.Errorf("wrong number of loads: %d", loads)
+	}
+	tr.Current().ClearChildren()
+	key(tcell.KeyRight, tcell.ModNone)
+	if loads != 4 {
+		t.Errorf("cleared children not loaded again: %d", loads)
+	}
+}
+
+func rowNodes(tr *Tree) []*TreeNode {
+	var nodes []*TreeNode
+	for _, r := range tr.rows() {
+		nodes = append(nodes, r.node)
+	}
+	return nodes
+}
+
+func TestTreeSelection(t *testing.T) {
+	root := NewTreeNode("root")
+	for _, name := range []string{"a", "b", "c", "d"} {
+		root.AddChild(NewTreeNode(name))
+	}
+	tr := NewTree()
+	tr.SetRoot(root)
+	tr.SetShowRoot(false)
+	key := func(k tcell.Key, mod tcell.ModMask) {
+		tr.HandleEvent(tcell.NewEventKey(k, 0, mod))
+	}
+	key(tcell.KeyDown, tcell.ModShift)
+	key(tcell.KeyDown, tcell.ModShift)
+	if names := treeNames(tr.Selected()); !reflect.DeepEqual(names, []string{"a", "b", "c"}) {
+		t.Errorf("wrong selection: %v", names)
+	}
+	tr.HandleEvent(tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone))
+	key(tcell.KeyEnd, tcell.ModNone)
+	tr.HandleEvent(tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone))
+	if names := treeNames(tr.Selected()); !reflect.DeepEqual(names, []string{"a", "b", "d"}) {
+		t.Errorf("wrong selection after toggling: %v", names)
+	}
+	key(tcell.KeyLeft, tcell.ModNone)
+	if n := tr.Current(); n.Text() != "d" {
+		t.Errorf("moved to hidden root: %s", n.Text())
+	}
+	tr.ClearSelection()
+	if sel := tr.Selected(); len(sel) != 0 {
+		t.Errorf("selection not cleared: %v", treeNames(sel))
+	}
+
+	var activated *TreeNode
+	tr.SetActivateFunc(func(n *TreeNode) { activated = n })
+	key(tcell.KeyHome, tcell.ModNone)
+	key(tcell.KeyEnter, tcell.ModNone)
+	if activated == nil || activated.Text() != "a" {
+		t.Errorf("wrong node activated: %v", activated)
+	}
+}
+
+func TestTreeDraw(t *testing.T) {
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatalf("Failed to initialize screen: %v", err)
+	}
+	defer s.Fini()
+	s.SetSize(12, 3)
+
+	root := NewTreeNode("root")
+	docs := root.AddChild(NewTreeNode("docs"))
+	docs.AddChild(NewTreeNode("a.txt"))
+	file := root.AddChild(NewTreeNode("b.txt"))
+	file.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorRed))
+	root.AddChild(NewTreeNode("c.txt"))
+
+	tr := NewTree()
+	tr.SetView(s)
+	tr.SetRoot(root)
+	tr.Expand(root)
+	row := func(y int) string {
+		var str string
+		for x := 0; x < 12; x++ {
+			r, _, _, _ := s.GetContent(x, y)
+			str += string(r)
+		}
+		return str
+	}
+	tr.Draw()
+	for y, want := range []string{"- root      ", "  + docs    ", "    b.txt   "} {
+		if got := row(y); got != want {
+			t.Errorf("row %d: got %q, expected %q", y, got, want)
+		}
+	}
+	if _, _, style, _ := s.GetContent(2, 0); style != tcell.StyleDefault.Reverse(true) {
+		t.Errorf("current node not highlighted")
+	}
+	if _, _, style, _ := s.GetContent(4, 2); style != tcell.StyleDefault.Foreground(tcell.ColorRed) {
+		t.Errorf("node style not used")
+	}
+
+	// the view scrolls to keep the current node shown
+	tr.SetCurrent(root.Children()[2])
+	tr.Draw()
+	if got := row(2); got != "    c.txt   " {
+		t.Errorf("not scrolled: %q", got)
+	}
+
+	// clicking on the marker expands
+	tr.HandleEvent(tcell.NewEventClick(2, 0, tcell.Button1, tcell.ModNone, 1))
+	if !docs.Expanded() || tr.Current() != docs {
+		t.Errorf("click did not expand")
+	}
+	if w, h := tr.Size(); w != 11 || h != 5 {
+		t.Errorf("wrong size %d x %d", w, h)
+	}
+}
@@ -276,6 +276,28 @@ func (v *ViewPort) Resize(x, y, width, height int) {
 	v.height = height
 }
 
+// contentPosition converts a position on the screen to a position in the
+// content of the view, and reports whether it is within the visible part
+// of the view.  Views other than ViewPorts (such as the screen itself) are
+// taken to cover the screen from its origin.
+func contentPosition(v View, x, y int) (int, int, bool) {
+	vp, ok := v.(*ViewPort)
+	if !ok {
+		return x, y, true
+	}
+	if vp.v != nil {
+		if x, y, ok = contentPosition(vp.v, x, y); !ok {
+			return 0, 0, false
+		}
+	}
+	x -= vp.physx
+	y -= vp.physy
+	if x < 0 || y < 0 || x >= vp.width || y >= vp.height {
+		return 0, 0, false
+	}
+	return x + vp.viewx, y + vp.viewy, true
+}
+
 // SetView is called during setup, to provide the parent View.
 func (v *ViewPort) SetView(view View) {
 	v.v = view
@@ -64,6 +64,15 @@ type Widget interface {
 	Unwatch(handler tcell.EventHandler)
 }
 
+// themeStyle returns the style for the role, if the event is a change of
+// theme (see Application.SetTheme) and a role is set.
+func themeStyle(ev tcell.Event, role tcell.StyleRole) (tcell.Style, bool) {
+	if tev, ok := ev.(*tcell.EventThemeChange); ok && role != "" {
+		return tev.Theme().Style(role), true
+	}
+	return tcell.StyleDefault, false
+}
+
 // EventWidget is an event delivered by a specific widget.
 type EventWidget interface {
 	Widget() Widget
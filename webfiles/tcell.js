@@ -263,6 +263,14 @@ term.addEventListener("focus", (e) => {
 term.addEventListener("blur", (e) => {
   onFocus(false);
 });
+
+document.addEventListener("visibilitychange", (e) => {
+  if (document.hidden) {
+    onFocus(false);
+  } else if (document.activeElement === term) {
+    onFocus(true);
+  }
+});
 term.tabIndex = 0;
 
 document.addEventListener("paste", (e) => {
@@ -0,0 +1,119 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"strings"
+
+	runewidth "github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
+)
+
+// forCells splits s into the characters that each occupy a cell (or two,
+// for wide characters) when drawn, which are a character together with
+// any combining characters that follow it.  This is how DrawSafeString
+// and the screen treat text.  For each of them, fn is called with the
+// byte offset of its end, and its width, until fn returns false.
+func forCells(s string, fn func(end, width int) bool) {
+	width := -1
+	for i, r := range s {
+		rw := runewidth.RuneWidth(r)
+		if rw == 0 {
+			if width < 0 {
+				// a leading combining character gets a space to sit on
+				width = 1
+			}
+			continue
+		}
+		if width >= 0 && !fn(i, width) {
+			return
+		}
+		width = rw
+	}
+	if width >= 0 {
+		fn(len(s), width)
+	}
+}
+
+// runeWidth returns the width of r according to cond, or to the default
+// condition of runewidth if cond is nil.
+func runeWidth(cond *runewidth.Condition, r rune) int {
+	if cond != nil {
+		return cond.RuneWidth(r)
+	}
+	return runewidth.RuneWidth(r)
+}
+
+// clusterWidth returns the width of a cell holding a whole grapheme
+// cluster, as a terminal that supports grapheme clustering (mode 2027)
+// displays it.  The width of a lone rune is according to cond.  Unlike runewidth, this takes the rest of the cluster into
+// account, so that for example an emoji presentation selector makes a
+// symbol wide, and a pair of regional indicators is a single wide flag.
+func clusterWidth(cond *runewidth.Condition, mainc rune, combc []rune) int {
+	if len(combc) == 0 {
+		return runeWidth(cond, mainc)
+	}
+	w := uniseg.StringWidth(string(mainc) + string(combc))
+	if w > 2 {
+		w = 2 // a cell holds one cluster, which is never wider than this
+	}
+	return w
+}
+
+// StringWidth returns the number of cells that s occupies when drawn.
+// Wide characters take two cells, and combining characters take none.
+func StringWidth(s string) int {
+	total := 0
+	forCells(s, func(_, width int) bool {
+		total += width
+		return true
+	})
+	return total
+}
+
+// TruncateToWidth returns s shortened so that it occupies at most w
+// cells.  If s has to be shortened, tail (for example "…") is appended,
+// and fits within the w cells as well.  A wide character, or a character
+// with combining characters, is never split; if there is only room for
+// half of a wide character, the result is one cell narrower than w.
+func TruncateToWidth(s string, w int, tail string) string {
+	if StringWidth(s) <= w {
+		return s
+	}
+	tw := StringWidth(tail)
+	if tw > w {
+		return TruncateToWidth(tail, w, "")
+	}
+	cut, used := 0, 0
+	forCells(s, func(end, width int) bool {
+		if used+width > w-tw {
+			return false
+		}
+		used += width
+		cut = end
+		return true
+	})
+	return s[:cut] + tail
+}
+
+// PadToWidth returns s truncated or padded with spaces so that it
+// occupies exactly w cells.
+func PadToWidth(s string, w int) string {
+	if w <= 0 {
+		return ""
+	}
+	s = TruncateToWidth(s, w, "")
+	return s + strings.Repeat(" ", w-StringWidth(s))
+}
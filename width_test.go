@@ -0,0 +1,104 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"testing"
+)
+
+func TestStringWidth(t *testing.T) {
+	cases := []struct {
+		s     string
+		width int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"日本", 4},
+		{"e\u0301e", 2},
+		{"\u0301a", 2},
+	}
+	for _, c := range cases {
+		if w := StringWidth(c.s); w != c.width {
+			t.Errorf("%q: width %d, expected %d", c.s, w, c.width)
+		}
+	}
+}
+
+func TestTruncateToWidth(t *testing.T) {
+	cases := []struct {
+		s    string
+		w    int
+		tail string
+		want string
+	}{
+		{"hello", 5, "…", "hello"},
+		{"hello world", 8, "…", "hello w…"},
+		{"hello world", 8, "", "hello wo"},
+		{"日本語", 5, "", "日本"},
+		{"日本語", 5, "…", "日本…"},
+		{"日本語", 4, "…", "日…"},
+		{"cafe\u0301s", 4, "", "cafe\u0301"},
+		{"hello", 2, "...", ".."},
+		{"hello", 0, "…", ""},
+	}
+	for _, c := range cases {
+		if got := TruncateToWidth(c.s, c.w, c.tail); got != c.want {
+			t.Errorf("%q in %d: got %q, expected %q", c.s, c.w, got, c.want)
+		}
+	}
+
+	if got := PadToWidth("日本語", 5); got != "日本 " {
+		t.Errorf("wrong padding: %q", got)
+	}
+	if got := PadToWidth("ab", 4); got != "ab  " {
+		t.Errorf("wrong padding: %q", got)
+	}
+}
+
+func TestClusterWidths(t *testing.T) {
+	cases := []struct {
+		mainc  rune
+		combc  []rune
+		narrow int
+		wide   int
+	}{
+		{'a', nil, 1, 1},
+		{'e', []rune{'\u0301'}, 1, 1},
+		{'\u2764', []rune{'\uFE0F'}, 1, 2},
+		{'\U0001F1FA', []rune{'\U0001F1F8'}, 1, 2},
+		{'\U0001F468', []rune{'\u200D', '\U0001F469', '\u200D', '\U0001F467'}, 2, 2},
+	}
+	cb := &CellBuffer{}
+	cb.Resize(len(cases), 1)
+	for x, c := range cases {
+		cb.SetContent(x, 0, c.mainc, c.combc, StyleDefault)
+		if _, _, _, w := cb.GetContent(x, 0); w != c.narrow {
+			t.Errorf("%q: width %d, expected %d", c.mainc, w, c.narrow)
+		}
+	}
+	cb.setClusters(true)
+	for x, c := range cases {
+		if _, _, _, w := cb.GetContent(x, 0); w != c.wide {
+			t.Errorf("%q: cluster width %d, expected %d", c.mainc, w, c.wide)
+		}
+		if !cb.Dirty(x, 0) {
+			t.Errorf("%q: not redrawn", c.mainc)
+		}
+	}
+	cb.SetContent(0, 0, '\u2764', []rune{'\uFE0F'}, StyleDefault)
+	if _, _, _, w := cb.GetContent(0, 0); w != 2 {
+		t.Errorf("new cluster has width %d", w)
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"bytes"
+)
+
+// This file has the translation of Windows key records to key events.  It
+// is used for the records read from the Windows console, and also for
+// win32-input-mode, where a terminal (such as Windows Terminal or the
+// console in VT input mode) reports each key record as an escape sequence.
+// Keeping it portable means that keys are reported the same way, no
+// matter how they reach us.
+
+// enableWin32Input and disableWin32Input turn win32-input-mode on and off.
+const (
+	enableWin32Input  = "\x1b[?9001h"
+	disableWin32Input = "\x1b[?9001l"
+)
+
+const (
+	// Constants per Microsoft.  We don't put the modifiers
+	// here.
+	vkCancel = 0x03
+	vkBack   = 0x08 // Backspace
+	vkTab    = 0x09
+	vkClear  = 0x0c
+	vkReturn = 0x0d
+	vkPause  = 0x13
+	vkEscape = 0x1b
+	vkSpace  = 0x20
+	vkPrior  = 0x21 // PgUp
+	vkNext   = 0x22 // PgDn
+	vkEnd    = 0x23
+	vkHome   = 0x24
+	vkLeft   = 0x25
+	vkUp     = 0x26
+	vkRight  = 0x27
+	vkDown   = 0x28
+	vkPrint  = 0x2a
+	vkPrtScr = 0x2c
+	vkInsert = 0x2d
+	vkDelete = 0x2e
+	vkHelp   = 0x2f
+	vkF1     = 0x70
+	vkF2     = 0x71
+	vkF3     = 0x72
+	vkF4     = 0x73
+	vkF5     = 0x74
+	vkF6     = 0x75
+	vkF7     = 0x76
+	vkF8     = 0x77
+	vkF9     = 0x78
+	vkF10    = 0x79
+	vkF11    = 0x7a
+	vkF12    = 0x7b
+	vkF13    = 0x7c
+	vkF14    = 0x7d
+	vkF15    = 0x7e
+	vkF16    = 0x7f
+	vkF17    = 0x80
+	vkF18    = 0x81
+	vkF19    = 0x82
+	vkF20    = 0x83
+	vkF21    = 0x84
+	vkF22    = 0x85
+	vkF23    = 0x86
+	vkF24    = 0x87
+)
+
+var vkKeys = map[uint16]Key{
+	vkCancel: KeyCancel,
+	vkBack:   KeyBackspace,
+	vkTab:    KeyTab,
+	vkClear:  KeyClear,
+	vkPause:  KeyPause,
+	vkPrint:  KeyPrint,
+	vkPrtScr: KeyPrint,
+	vkPrior:  KeyPgUp,
+	vkNext:   KeyPgDn,
+	vkReturn: KeyEnter,
+	vkEnd:    KeyEnd,
+	vkHome:   KeyHome,
+	vkLeft:   KeyLeft,
+	vkUp:     KeyUp,
+	vkRight:  KeyRight,
+	vkDown:   KeyDown,
+	vkInsert: KeyInsert,
+	vkDelete: KeyDelete,
+	vkHelp:   KeyHelp,
+	vkEscape: KeyEscape,
+	vkSpace:  ' ',
+	vkF1:     KeyF1,
+	vkF2:     KeyF2,
+	vkF3:     KeyF3,
+	vkF4:     KeyF4,
+	vkF5:     KeyF5,
+	vkF6:     KeyF6,
+	vkF7:     KeyF7,
+	vkF8:     KeyF8,
+	vkF9:     KeyF9,
+	vkF10:    KeyF10,
+	vkF11:    KeyF11,
+	vkF12:    KeyF12,
+	vkF13:    KeyF13,
+	vkF14:    KeyF14,
+	vkF15:    KeyF15,
+	vkF16:    KeyF16,
+	vkF17:    KeyF17,
+	vkF18:    KeyF18,
+	vkF19:    KeyF19,
+	vkF20:    KeyF20,
+	vkF21:    KeyF21,
+	vkF22:    KeyF22,
+	vkF23:    KeyF23,
+	vkF24:    KeyF24,
+}
+
+// vkOem maps the virtual key codes of punctuation keys to the characters
+// they produce (without Shift) on a US keyboard.
+var vkOem = map[uint16]rune{
+	0xba: ';',
+	0xbb: '=',
+	0xbc: ',',
+	0xbd: '-',
+	0xbe: '.',
+	0xbf: '/',
+	0xc0: '`',
+	0xdb: '[',
+	0xdc: '\\',
+	0xdd: ']',
+	0xde: '\'',
+}
+
+// vkBase returns the character for a virtual key code on a US keyboard,
+// or zero if there is none.
+func vkBase(vk uint16) rune {
+	switch {
+	case vk >= 'A' && vk <= 'Z':
+		return rune(vk-'A') + 'a'
+	case vk >= '0' && vk <= '9', vk == vkSpace:
+		return rune(vk)
+	}
+	return vkOem[vk]
+}
+
+// Convert windows dwControlKeyState to modifier mask
+func mod2mask(cks uint32) ModMask {
+	mm := ModNone
+	// Left or right control
+	ctrl := (cks & (0x0008 | 0x0004)) != 0
+	// Left or right alt
+	alt := (cks & (0x0002 | 0x0001)) != 0
+	// Filter out ctrl+alt (it means AltGr)
+	if !(ctrl && alt) {
+		if ctrl {
+			mm |= ModCtrl
+		}
+		if alt {
+			mm |= ModAlt
+		}
+	}
+	// Any shift
+	if (cks & 0x0010) != 0 {
+		mm |= ModShift
+	}
+	return mm
+}
+
+// vkModifiers are the virtual key codes of the modifier keys, both the
+// generic ones and those for the left and right keys.
+var vkModifiers = map[uint16]Key{
+	0x10: KeyShift,
+	0x11: KeyCtrl,
+	0x12: KeyAlt,  // VK_MENU
+	0x5b: KeyMeta, // left Windows key
+	0x5c: KeyMeta, // right Windows key
+	0xa0: KeyShift,
+	0xa1: KeyShift,
+	0xa2: KeyCtrl,
+	0xa3: KeyCtrl,
+	0xa4: KeyAlt,
+	0xa5: KeyAlt,
+}
+
+// win32KeyEvents converts a key record to key events, one for each
+// repetition.  Key releases, and keys that we do not report (such as
+// modifier keys on their own, unless modifiers is true), produce no
+// events.
+func win32KeyEvents(down bool, repeat int, vk uint16, ch uint16, cks uint32, modifiers bool) []Event {
+	if k, ok := vkModifiers[vk]; ok && modifiers {
+		ev := NewEventKey(k, 0, mod2mask(cks))
+		if !down {
+			ev.action = KeyActionRelease
+		}
+		return []Event{ev}
+	}
+	if !down || repeat < 1 {
+		return nil
+	}
+	mod := mod2mask(cks)
+	var evs []Event
+	if ch != 0 {
+		for ; repeat > 0; repeat-- {
+			// convert shift+tab to backtab
+			if mod == ModShift && ch == vkTab {
+				evs = append(evs, NewEventKey(KeyBacktab, 0, ModNone))
+			} else {
+				ev := NewEventKey(KeyRune, rune(ch), mod)
+				ev.base = vkBase(vk)
+				evs = append(evs, ev)
+			}
+		}
+		return evs
+	}
+	key, ok := vkKeys[vk]
+	if !ok {
+		return nil
+	}
+	for ; repeat > 0; repeat-- {
+		evs = append(evs, NewEventKey(key, rune(ch), mod))
+	}
+	return evs
+}
+
+// parseWin32Input parses a key record reported in win32-input-mode, which
+// has the form CSI Vk ; Sc ; Uc ; Kd ; Cs ; Rc _ where any parameter may be
+// omitted.  They are the virtual key code, the scan code, the character
+// (as a UTF-16 code unit), whether the key is down, the control key state,
+// and the repeat count (which defaults to 1).  Modifier keys on their own
+// are reported if modifiers is true.
+func parseWin32Input(buf *bytes.Buffer, evs *[]Event, modifiers bool) (bool, bool) {
+	b := buf.Bytes()
+	state := 0
+	params := [6]int{0, 0, 0, 0, 0, 1}
+	field := 0
+	digits := false
+	for i := range b {
+		switch state {
+		case 0:
+			switch b[i] {
+			case '\x1b':
+				state = 1
+			case '\x9b':
+				state = 2
+			default:
+				return false, false
+			}
+		case 1:
+			if b[i] != '[' {
+				return false, false
+			}
+			state = 2
+		case 2:
+			switch {
+			case b[i] >= '0' && b[i] <= '9':
+				if !digits {
+					params[field] = 0
+					digits = true
+				}
+				params[field] = params[field]*10 + int(b[i]-'0')
+			case b[i] == ';' && field < len(params)-1:
+				field++
+				digits = false
+			case b[i] == '_':
+				buf.Next(i + 1)
+				vk, ch, down, cks, repeat := params[0], params[2], params[3] != 0, params[4], params[5]
+				*evs = append(*evs, win32KeyEvents(down, repeat, uint16(vk), uint16(ch), uint32(cks), modifiers)...)
+				return true, true
+			default:
+				return false, false
+			}
+		}
+	}
+	return true, false
+}
@@ -20,6 +20,7 @@ package tcell
 import (
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"sync"
 	"syscall/js"
@@ -47,17 +48,25 @@ type wScreen struct {
 	mouseFlags   MouseFlags
 
 	cursorStyle CursorStyle
+	cursorColor Color
+	cursorx     int
+	cursory     int
 
 	quit     chan struct{}
 	evch     chan Event
 	fallback map[rune]string
-	finiOnce sync.Once
+	life     lifecycle
 
 	sync.Mutex
 }
 
 func (t *wScreen) Init() error {
+	return t.life.init(t.setup)
+}
+
+func (t *wScreen) setup() error {
 	t.w, t.h = 80, 24 // default for html as of now
+	t.cursorx, t.cursory = -1, -1
 	t.evch = make(chan Event, 10)
 	t.quit = make(chan struct{})
 
@@ -76,11 +85,17 @@ func (t *wScreen) Init() error {
 }
 
 func (t *wScreen) Fini() {
-	t.finiOnce.Do(func() {
-		close(t.quit)
+	t.life.fini(func() {
+		if t.quit != nil {
+			close(t.quit)
+		}
 	})
 }
 
+func (t *wScreen) Done() <-chan struct{} {
+	return t.life.doneQ()
+}
+
 func (t *wScreen) SetStyle(style Style) {
 	t.Lock()
 	t.style = style
@@ -127,9 +142,7 @@ func (t *wScreen) drawCell(x, y int) int {
 		return width
 	}
 
-	if style == StyleDefault {
-		style = t.style
-	}
+	style = style.inherit(t.style)
 
 	fg, bg := paletteColor(style.fg), paletteColor(style.bg)
 	if fg == -1 {
@@ -138,6 +151,9 @@ func (t *wScreen) drawCell(x, y int) int {
 	if bg == -1 {
 		bg = 0x000000
 	}
+	if style.attrs&AttrTransparent != 0 {
+		bg = -1
+	}
 	us, uc := style.ulStyle, paletteColor(style.ulColor)
 	if uc == -1 {
 		uc = 0x000000
@@ -161,15 +177,17 @@ func (t *wScreen) drawCell(x, y int) int {
 
 func (t *wScreen) ShowCursor(x, y int) {
 	t.Lock()
+	t.cursorx, t.cursory = x, y
 	js.Global().Call("showCursor", x, y)
 	t.Unlock()
 }
 
 func (t *wScreen) SetCursor(cs CursorStyle, cc Color) {
+	t.Lock()
+	t.cursorStyle, t.cursorColor = cs, cc
 	if !cc.Valid() {
 		cc = ColorLightGray
 	}
-	t.Lock()
 	js.Global().Call("setCursorStyle", curStyleClasses[cs], fmt.Sprintf("#%06x", cc.Hex()))
 	t.Unlock()
 }
@@ -178,6 +196,18 @@ func (t *wScreen) HideCursor() {
 	t.ShowCursor(-1, -1)
 }
 
+func (t *wScreen) getCursor() (int, int, CursorStyle, Color) {
+	return t.cursorx, t.cursory, t.cursorStyle, t.cursorColor
+}
+
+func (t *wScreen) getStyle() Style {
+	return t.style
+}
+
+func (t *wScreen) showCells(int, int, int) {
+	t.Show()
+}
+
 func (t *wScreen) Show() {
 	t.Lock()
 	t.resize()
@@ -477,6 +507,10 @@ func (t *wScreen) Resize(int, int, int, int) {}
 // Suspend simply pauses all input and output, and clears the screen.
 // There isn't a "default terminal" to go back to.
 func (t *wScreen) Suspend() error {
+	return t.life.suspend(t.disengage)
+}
+
+func (t *wScreen) disengage() error {
 	t.Lock()
 	if !t.running {
 		t.Unlock()
@@ -491,6 +525,10 @@ func (t *wScreen) Suspend() error {
 }
 
 func (t *wScreen) Resume() error {
+	return t.life.resume(t.engage)
+}
+
+func (t *wScreen) engage() error {
 	t.Lock()
 
 	if t.running {
@@ -532,6 +570,41 @@ func (t *wScreen) SetTitle(title string) {
 	js.Global().Call("setTitle", title)
 }
 
+func (t *wScreen) SetTabColor(Color) {}
+
+func (t *wScreen) SetBadge(string) {}
+
+func (t *wScreen) SendOSC(int, string) {}
+
+func (t *wScreen) SetRestoreWriter(io.Writer) {}
+
+func (t *wScreen) SetDisplayOptions(DisplayOptions) {}
+
+func (t *wScreen) SetInputOptions(InputOptions) {}
+
+func (t *wScreen) AddSemanticMark(int, int, SemanticMark, int) {}
+
+func (t *wScreen) TerminalID() TerminalIdentity {
+	return TerminalIdentity{}
+}
+
+func (t *wScreen) ProbeColors() {}
+
+func (t *wScreen) Err() error {
+	return nil
+}
+
+func (t *wScreen) FeatureReport() map[Feature]Support {
+	report := make(map[Feature]Support)
+	colorSupport(report, t.Colors())
+	report[FeatureMouse] = SupportAssumed
+	report[FeatureBracketedPaste] = SupportAssumed
+	report[FeatureFocus] = SupportAssumed
+	report[FeatureTitle] = SupportAssumed
+	report[FeatureCursorStyle] = SupportAssumed
+	return report
+}
+
 // WebKeyNames maps string names reported from HTML
 // (KeyboardEvent.key) to tcell accepted keys.
 var WebKeyNames = map[string]Key{
@@ -663,6 +736,8 @@ var curStyleClasses = map[CursorStyle]string{
 	CursorStyleSteadyUnderline:   "cursor-steady-underline",
 	CursorStyleBlinkingBar:       "cursor-blinking-bar",
 	CursorStyleSteadyBar:         "cursor-steady-bar",
+	CursorStyleBlinkingDefault:   "cursor-blinking-block",
+	CursorStyleSteadyDefault:     "cursor-steady-block",
 }
 
 func LookupTerminfo(name string) (ti *terminfo.Terminfo, e error) {
@@ -0,0 +1,84 @@
+// Copyright 2024 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcell
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+)
+
+// termCaps are the capabilities asked for with XTGETTCAP when the screen
+// starts, as these are the ones most often missing from (or wrong in) the
+// terminfo database for modern terminals.
+var termCaps = []string{
+	"RGB",    // direct color
+	"Smulx",  // styled underlines
+	"Setulc", // underline color
+	"Ms",     // clipboard
+}
+
+// xtgettcapQuery returns the XTGETTCAP request for the capabilities, which
+// is DCS + q followed by the hex encoded names separated by semicolons.
+func xtgettcapQuery(names []string) string {
+	hexNames := make([]string, 0, len(names))
+	for _, name := range names {
+		hexNames = append(hexNames, hex.EncodeToString([]byte(name)))
+	}
+	return "\x1bP+q" + strings.Join(hexNames, ";") + "\x1b\\"
+}
+
+// parseXTGetTCap parses a reply to an XTGETTCAP request, which is
+// DCS 1 + r followed by name=value pairs (or just the name for boolean
+// capabilities) in hex, separated by semicolons.  Capabilities the
+// terminal does not know are reported as DCS 0 + r instead, and are
+// ignored.  Known capabilities are added to caps.
+func parseXTGetTCap(buf *bytes.Buffer, caps map[string]string) (bool, bool) {
+	b := buf.Bytes()
+	valid := []byte("\x1bP1+r")
+	invalid := []byte("\x1bP0+r")
+	if len(b) < len(valid) {
+		// inconclusive so far, if it could still be either reply
+		return bytes.HasPrefix(valid, b) || bytes.HasPrefix(invalid, b), false
+	}
+	if !bytes.HasPrefix(b, valid) && !bytes.HasPrefix(b, invalid) {
+		return false, false
+	}
+	end := bytes.Index(b, []byte("\x1b\\"))
+	if end < 0 {
+		return true, false
+	}
+	known := b[2] == '1'
+	reply := string(b[len(valid):end])
+	buf.Next(end + 2)
+	if !known {
+		return true, true
+	}
+	for _, item := range strings.Split(reply, ";") {
+		kv := strings.SplitN(item, "=", 2)
+		name, err := hex.DecodeString(kv[0])
+		if err != nil || len(name) == 0 {
+			continue
+		}
+		value := []byte{}
+		if len(kv) == 2 {
+			if value, err = hex.DecodeString(kv[1]); err != nil {
+				continue
+			}
+		}
+		caps[string(name)] = string(value)
+	}
+	return true, true
+}